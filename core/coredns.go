@@ -6,26 +6,38 @@ import (
 	_ "github.com/coredns/coredns/core/dnsserver"
 
 	// plug in the standard directives (sorted)
+	_ "github.com/coredns/coredns/middleware/any"
 	_ "github.com/coredns/coredns/middleware/auto"
 	_ "github.com/coredns/coredns/middleware/bind"
 	_ "github.com/coredns/coredns/middleware/cache"
 	_ "github.com/coredns/coredns/middleware/chaos"
+	_ "github.com/coredns/coredns/middleware/cookie"
+	_ "github.com/coredns/coredns/middleware/dns64"
 	_ "github.com/coredns/coredns/middleware/dnssec"
+	_ "github.com/coredns/coredns/middleware/dnstap"
+	_ "github.com/coredns/coredns/middleware/ecs"
 	_ "github.com/coredns/coredns/middleware/erratic"
 	_ "github.com/coredns/coredns/middleware/errors"
 	_ "github.com/coredns/coredns/middleware/etcd"
 	_ "github.com/coredns/coredns/middleware/file"
 	_ "github.com/coredns/coredns/middleware/health"
+	_ "github.com/coredns/coredns/middleware/hosts"
 	_ "github.com/coredns/coredns/middleware/kubernetes"
 	_ "github.com/coredns/coredns/middleware/loadbalance"
 	_ "github.com/coredns/coredns/middleware/log"
+	_ "github.com/coredns/coredns/middleware/loop"
 	_ "github.com/coredns/coredns/middleware/metrics"
+	_ "github.com/coredns/coredns/middleware/minimal"
+	_ "github.com/coredns/coredns/middleware/nsid"
 	_ "github.com/coredns/coredns/middleware/pprof"
 	_ "github.com/coredns/coredns/middleware/proxy"
+	_ "github.com/coredns/coredns/middleware/recursive"
 	_ "github.com/coredns/coredns/middleware/reverse"
 	_ "github.com/coredns/coredns/middleware/rewrite"
 	_ "github.com/coredns/coredns/middleware/root"
+	_ "github.com/coredns/coredns/middleware/rpz"
 	_ "github.com/coredns/coredns/middleware/secondary"
+	_ "github.com/coredns/coredns/middleware/template"
 	_ "github.com/coredns/coredns/middleware/trace"
 	_ "github.com/coredns/coredns/middleware/whoami"
 	_ "github.com/wil3/sddns"
@@ -2,6 +2,7 @@ package dnsserver
 
 import (
 	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/cookie"
 
 	"github.com/mholt/caddy"
 )
@@ -21,6 +22,9 @@ type Config struct {
 	// First consumer is the file middleware to looks for zone files in this place.
 	Root string
 
+	// Cookie is the DNS Cookie configuration, if nil DNS Cookies are not supported.
+	Cookie *cookie.Config
+
 	// Server is the server that handles this config
 	Server *Server
 
@@ -29,6 +33,9 @@ type Config struct {
 
 	// Compiled middleware stack.
 	middlewareChain middleware.Handler
+
+	// Handlers of the compiled middleware stack.
+	handlers []middleware.Handler
 }
 
 // GetConfig gets the Config that corresponds to c.
@@ -60,3 +67,13 @@ func GetMiddleware(c *caddy.Controller, name string) middleware.Handler {
 	}
 	return nil
 }
+
+// registerHandler adds h to the handlers of c.
+func (c *Config) registerHandler(h middleware.Handler) {
+	c.handlers = append(c.handlers, h)
+}
+
+// Handlers returns the compiled middleware handlers of this config. This is only available after the
+// servers have been made, i.e. in OnStartup functions. Middleware use this to find other middleware
+// that implement a specific interface.
+func (c *Config) Handlers() []middleware.Handler { return c.handlers }
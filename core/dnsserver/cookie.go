@@ -0,0 +1,94 @@
+package dnsserver
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/middleware/metrics/vars"
+	"github.com/coredns/coredns/middleware/pkg/cookie"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// checkCookie checks the DNS Cookie in r, RFC 7873, Section 5.2. It returns the ResponseWriter to
+// use for the response, which adds our server cookie to it. If r has been answered already, false
+// is returned.
+func (s *Server) checkCookie(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (dns.ResponseWriter, bool) {
+	state := request.Request{W: w, Req: r}
+	udp := state.Proto() == "udp"
+	addr := "dns://" + s.Addr
+
+	client, server, err := cookie.Parse(r)
+	if err != nil {
+		vars.CookieCount.WithLabelValues(addr, "malformed").Inc()
+		DefaultErrorFunc(ctx, w, r, dns.RcodeFormatError)
+		return w, false
+	}
+
+	if client == nil {
+		vars.CookieCount.WithLabelValues(addr, "none").Inc()
+		if s.cookie.Require && udp {
+			// Make the client retry over TCP, where spoofing the source address isn't possible.
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Truncated = true
+			state.SizeAndDo(m)
+			w.WriteMsg(m)
+			return w, false
+		}
+		return w, true
+	}
+
+	ip := net.ParseIP(state.IP())
+	valid := server != nil && s.cookie.Secret.Valid(client, server, ip)
+	switch {
+	case valid:
+		vars.CookieCount.WithLabelValues(addr, "valid").Inc()
+	case server == nil:
+		vars.CookieCount.WithLabelValues(addr, "client").Inc()
+	default:
+		vars.CookieCount.WithLabelValues(addr, "invalid").Inc()
+	}
+
+	cw := &cookieWriter{ResponseWriter: w, req: r, client: client, server: s.cookie.Secret.Server(client, ip)}
+	if !valid && s.cookie.Require && udp {
+		// Hand out a server cookie, so the client can retry with it.
+		m := new(dns.Msg)
+		m.SetReply(r)
+		state.SizeAndDo(m)
+		cookie.Set(m, cw.client, cw.server)
+		cookie.SetBadCookie(m)
+		w.WriteMsg(m)
+		return w, false
+	}
+	return cw, true
+}
+
+// cookieWriter adds our server cookie to the response.
+type cookieWriter struct {
+	dns.ResponseWriter
+	req    *dns.Msg
+	client []byte
+	server []byte
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *cookieWriter) WriteMsg(res *dns.Msg) error {
+	if res.IsEdns0() == nil {
+		state := request.Request{W: w.ResponseWriter, Req: w.req}
+		state.SizeAndDo(res)
+	}
+	cookie.Set(res, w.client, w.server)
+	return w.ResponseWriter.WriteMsg(res)
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (w *cookieWriter) Write(buf []byte) (int, error) {
+	return w.ResponseWriter.Write(buf)
+}
+
+// Hijack implements the dns.ResponseWriter interface.
+func (w *cookieWriter) Hijack() {
+	w.ResponseWriter.Hijack()
+}
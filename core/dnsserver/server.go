@@ -11,6 +11,7 @@ import (
 
 	"github.com/coredns/coredns/middleware"
 	"github.com/coredns/coredns/middleware/metrics/vars"
+	"github.com/coredns/coredns/middleware/pkg/cookie"
 	"github.com/coredns/coredns/middleware/pkg/edns"
 	"github.com/coredns/coredns/middleware/pkg/rcode"
 	"github.com/coredns/coredns/request"
@@ -31,6 +32,7 @@ type Server struct {
 	m      sync.Mutex     // protects the servers
 
 	zones       map[string]*Config // zones keyed by their address
+	cookie      *cookie.Config     // DNS Cookie configuration, nil if not enabled
 	dnsWg       sync.WaitGroup     // used to wait on outstanding connections
 	connTimeout time.Duration      // the maximum duration of a graceful shutdown
 }
@@ -59,9 +61,15 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 		var stack middleware.Handler
 		for i := len(site.Middleware) - 1; i >= 0; i-- {
 			stack = site.Middleware[i](stack)
+			site.registerHandler(stack)
 		}
 		site.middlewareChain = stack
 		site.Server = s
+
+		// Cookies are handled before the query is routed to a zone, so they are set for the entire server.
+		if site.Cookie != nil {
+			s.cookie = site.Cookie
+		}
 	}
 
 	return s, nil
@@ -161,15 +169,25 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		// In case the user doesn't enable error middleware, we still
 		// need to make sure that we stay alive up here
 		if rec := recover(); rec != nil {
-			DefaultErrorFunc(w, r, dns.RcodeServerFailure)
+			vars.Panic.Inc()
+			DefaultErrorFunc(ctx, w, r, dns.RcodeServerFailure)
 		}
 	}()
 
+	ctx = context.WithValue(ctx, middleware.ServerCtx{}, "dns://"+s.Addr)
+
 	if m, err := edns.Version(r); err != nil { // Wrong EDNS version, return at once.
 		w.WriteMsg(m)
 		return
 	}
 
+	if s.cookie != nil {
+		var ok bool
+		if w, ok = s.checkCookie(ctx, w, r); !ok {
+			return
+		}
+	}
+
 	q := r.Question[0].Name
 	b := make([]byte, len(q))
 	off, end := 0, false
@@ -188,9 +206,9 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 
 		if h, ok := s.zones[string(b[:l])]; ok {
 			if r.Question[0].Qtype != dns.TypeDS {
-				rcode, _ := h.middlewareChain.ServeDNS(ctx, w, r)
+				rcode, _ := middleware.Serve(ctx, h.middlewareChain, w, r)
 				if rcodeNoClientWrite(rcode) {
-					DefaultErrorFunc(w, r, rcode)
+					DefaultErrorFunc(ctx, w, r, rcode)
 				}
 				return
 			}
@@ -209,25 +227,25 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 
 	if dshandler != nil {
 		// DS request, and we found a zone, use the handler for the query
-		rcode, _ := dshandler.middlewareChain.ServeDNS(ctx, w, r)
+		rcode, _ := middleware.Serve(ctx, dshandler.middlewareChain, w, r)
 		if rcodeNoClientWrite(rcode) {
-			DefaultErrorFunc(w, r, rcode)
+			DefaultErrorFunc(ctx, w, r, rcode)
 		}
 		return
 	}
 
 	// Wildcard match, if we have found nothing try the root zone as a last resort.
 	if h, ok := s.zones["."]; ok {
-		rcode, _ := h.middlewareChain.ServeDNS(ctx, w, r)
+		rcode, _ := middleware.Serve(ctx, h.middlewareChain, w, r)
 		if rcodeNoClientWrite(rcode) {
-			DefaultErrorFunc(w, r, rcode)
+			DefaultErrorFunc(ctx, w, r, rcode)
 		}
 		return
 	}
 
 	// Still here? Error out with REFUSED and some logging
 	remoteHost := w.RemoteAddr().String()
-	DefaultErrorFunc(w, r, dns.RcodeRefused)
+	DefaultErrorFunc(ctx, w, r, dns.RcodeRefused)
 	log.Printf("[INFO] \"%s %s %s\" - No such zone at %s (Remote: %s)", dns.Type(r.Question[0].Qtype), dns.Class(r.Question[0].Qclass), q, s.Addr, remoteHost)
 }
 
@@ -244,7 +262,7 @@ func (s *Server) OnStartupComplete() {
 }
 
 // DefaultErrorFunc responds to an DNS request with an error.
-func DefaultErrorFunc(w dns.ResponseWriter, r *dns.Msg, rc int) {
+func DefaultErrorFunc(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, rc int) {
 	state := request.Request{W: w, Req: r}
 
 	answer := new(dns.Msg)
@@ -252,7 +270,7 @@ func DefaultErrorFunc(w dns.ResponseWriter, r *dns.Msg, rc int) {
 
 	state.SizeAndDo(answer)
 
-	vars.Report(state, vars.Dropped, rcode.ToString(rc), answer.Len(), time.Now())
+	vars.Report(ctx, state, vars.Dropped, rcode.ToString(rc), answer.Len(), time.Now())
 
 	w.WriteMsg(answer)
 }
@@ -13,23 +13,35 @@ package dnsserver
 var directives = []string{
 	"root",
 	"bind",
+	"cookie",
 	"trace",
 	"health",
 	"pprof",
 	"prometheus",
+	"nsid",
 	"errors",
+	"dnstap",
 	"log",
+	"loop",
 	"chaos",
+	"rpz",
+	"ecs",
+	"any",
+	"minimal",
 	"cache",
+	"dns64",
 	"rewrite",
 	"loadbalance",
 	"dnssec",
 	"reverse",
+	"hosts",
+	"template",
 	"file",
 	"auto",
 	"secondary",
 	"etcd",
 	"kubernetes",
+	"recursive",
 	"proxy",
 	"whoami",
 	"erratic",
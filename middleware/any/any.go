@@ -0,0 +1,56 @@
+// Package any implements minimal responses to ANY queries, see RFC 8482.
+package any
+
+import (
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/nonwriter"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Any answers queries of type ANY for existing names with a single synthesized HINFO record, or
+// with one RRset of the response of the next middleware.
+type Any struct {
+	Next  middleware.Handler
+	RRset bool // answer with one RRset, instead of a synthesized HINFO record
+}
+
+// ServeDNS implements the middleware.Handler interface.
+func (a Any) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if state.QType() != dns.TypeANY {
+		return middleware.NextOrFailure(a.Name(), a.Next, ctx, w, r)
+	}
+
+	if a.RRset {
+		aw := &ResponseWriter{w}
+		return middleware.NextOrFailure(a.Name(), a.Next, ctx, aw, r)
+	}
+
+	// Only synthesize the HINFO record for names that exist: the next middleware must have
+	// answered. Everything else, like NXDOMAIN and referrals, is returned as is.
+	nw := nonwriter.New(w)
+	rc, err := middleware.NextOrFailure(a.Name(), a.Next, ctx, nw, r)
+	if err != nil || nw.Msg == nil {
+		return rc, err
+	}
+
+	m := nw.Msg
+	if m.Rcode == dns.RcodeSuccess && len(m.Answer) > 0 {
+		hdr := dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeHINFO, Class: state.QClass(), Ttl: hinfoTTL}
+		m.Answer = []dns.RR{&dns.HINFO{Hdr: hdr, Cpu: "RFC8482", Os: ""}}
+		m.Ns, m.Extra = nil, nil
+		state.SizeAndDo(m)
+	}
+
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the Handler interface.
+func (a Any) Name() string { return "any" }
+
+// hinfoTTL is the TTL of the synthesized HINFO record, RFC 8482, Section 4.2 advises a long TTL.
+const hinfoTTL = 8482
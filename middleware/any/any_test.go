@@ -0,0 +1,89 @@
+package any
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// backend answers with everything it has for example.org.
+func backend(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Answer = []dns.RR{
+		test.RRSIG("example.org. 3600 IN RRSIG MX 13 2 3600 20170702091734 20170624061734 18512 example.org. ZOeI"),
+		test.MX("example.org. 3600 IN MX 10 mx1.example.org."),
+		test.A("example.org. 3600 IN A 192.0.2.53"),
+		test.MX("example.org. 3600 IN MX 20 mx2.example.org."),
+		test.RRSIG("example.org. 3600 IN RRSIG A 13 2 3600 20170702091734 20170624061734 18512 example.org. ZOeI"),
+	}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+func TestAny(t *testing.T) {
+	tests := []struct {
+		a             Any
+		qtype         uint16
+		expectedTypes []uint16
+	}{
+		{a: Any{}, qtype: dns.TypeANY, expectedTypes: []uint16{dns.TypeHINFO}},
+		{a: Any{RRset: true}, qtype: dns.TypeANY, expectedTypes: []uint16{dns.TypeRRSIG, dns.TypeMX, dns.TypeMX}},
+		{a: Any{}, qtype: dns.TypeA, expectedTypes: []uint16{dns.TypeRRSIG, dns.TypeMX, dns.TypeA, dns.TypeMX, dns.TypeRRSIG}},
+	}
+
+	for i, tc := range tests {
+		tc.a.Next = test.HandlerFunc(backend)
+
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", tc.qtype)
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		rcode, err := tc.a.ServeDNS(context.TODO(), rec, req)
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %v", i, err)
+		}
+		if rcode != dns.RcodeSuccess {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, dns.RcodeSuccess, rcode)
+		}
+
+		if len(rec.Msg.Answer) != len(tc.expectedTypes) {
+			t.Fatalf("Test %d: expected %d answers, got %d", i, len(tc.expectedTypes), len(rec.Msg.Answer))
+		}
+		for j, rr := range rec.Msg.Answer {
+			if rr.Header().Rrtype != tc.expectedTypes[j] {
+				t.Errorf("Test %d: expected type %s, got %s", i, dns.TypeToString[tc.expectedTypes[j]], dns.TypeToString[rr.Header().Rrtype])
+			}
+		}
+		if h, ok := rec.Msg.Answer[0].(*dns.HINFO); ok && (h.Cpu != "RFC8482" || h.Hdr.Name != "example.org.") {
+			t.Errorf("Test %d: expected an RFC 8482 HINFO record, got %s", i, h)
+		}
+	}
+}
+
+func TestAnyNXDOMAIN(t *testing.T) {
+	nxdomain := func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNameError)
+		m.Ns = []dns.RR{test.SOA("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 60")}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	}
+	a := Any{Next: test.HandlerFunc(nxdomain)}
+
+	req := new(dns.Msg)
+	req.SetQuestion("nx.example.org.", dns.TypeANY)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	if _, err := a.ServeDNS(context.TODO(), rec, req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rec.Msg.Rcode != dns.RcodeNameError {
+		t.Errorf("Expected NXDOMAIN, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+	if len(rec.Msg.Answer) != 0 || len(rec.Msg.Ns) != 1 {
+		t.Errorf("Expected the NXDOMAIN response unchanged, got %v", rec.Msg)
+	}
+}
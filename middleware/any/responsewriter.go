@@ -0,0 +1,61 @@
+package any
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// ResponseWriter is a response writer that only keeps the first RRset, and its signatures, in the
+// answer section.
+type ResponseWriter struct {
+	dns.ResponseWriter
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	res.Answer = first(res.Answer)
+	return w.ResponseWriter.WriteMsg(res)
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) Write(buf []byte) (int, error) {
+	return w.ResponseWriter.Write(buf)
+}
+
+// Hijack implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) Hijack() {
+	w.ResponseWriter.Hijack()
+}
+
+// first returns the first RRset from rrs and the RRSIGs covering it.
+func first(rrs []dns.RR) []dns.RR {
+	var (
+		name  string
+		qtype uint16
+	)
+	for _, rr := range rrs {
+		if rr.Header().Rrtype != dns.TypeRRSIG {
+			name, qtype = rr.Header().Name, rr.Header().Rrtype
+			break
+		}
+	}
+	if qtype == 0 {
+		return rrs
+	}
+
+	var set []dns.RR
+	for _, rr := range rrs {
+		if !strings.EqualFold(rr.Header().Name, name) {
+			continue
+		}
+		t := rr.Header().Rrtype
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			t = sig.TypeCovered
+		}
+		if t == qtype {
+			set = append(set, rr)
+		}
+	}
+	return set
+}
@@ -0,0 +1,60 @@
+package any
+
+import (
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("any", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	a, err := anyParse(c)
+	if err != nil {
+		return middleware.Error("any", err)
+	}
+
+	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
+		a.Next = next
+		return a
+	})
+
+	return nil
+}
+
+func anyParse(c *caddy.Controller) (Any, error) {
+	a := Any{}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return a, c.Err("any can only be specified once per server block")
+		}
+		i++
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			switch args[0] {
+			case "hinfo":
+			case "rrset":
+				a.RRset = true
+			default:
+				return a, c.Errf("unknown mode '%s'", args[0])
+			}
+		default:
+			return a, c.ArgErr()
+		}
+		if c.NextBlock() {
+			return a, c.Errf("unknown property '%s'", c.Val())
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,41 @@
+package any
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupAny(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		rrset     bool
+	}{
+		{`any`, false, false},
+		{`any hinfo`, false, false},
+		{`any rrset`, false, true},
+		// fails
+		{`any all`, true, false},
+		{`any hinfo rrset`, true, false},
+		{`any {
+			rrset
+		}`, true, false},
+		{`any
+any`, true, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		a, err := anyParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if !test.shouldErr && a.RRset != test.rrset {
+			t.Errorf("Test %d expected rrset to be %t, got %t", i, test.rrset, a.RRset)
+		}
+	}
+}
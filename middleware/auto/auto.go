@@ -35,6 +35,7 @@ type (
 		transferTo []string
 		noReload   bool
 		proxy      proxy.Proxy // Proxy for looking up names during the resolution process
+		signer     *file.Signer
 
 		duration time.Duration
 	}
@@ -61,6 +61,12 @@ func setup(c *caddy.Controller) error {
 
 	c.OnShutdown(func() error {
 		close(walkChan)
+
+		a.Zones.RLock()
+		for _, zo := range a.Zones.Z {
+			zo.StopResign()
+		}
+		a.Zones.RUnlock()
 		return nil
 	})
 
@@ -144,6 +150,14 @@ func autoParse(c *caddy.Controller) (Auto, error) {
 				case "no_reload":
 					a.loader.noReload = true
 
+				case "key", "nsec3":
+					if a.loader.signer == nil {
+						a.loader.signer = &file.Signer{}
+					}
+					if err := file.SignParse(c, a.loader.signer); err != nil {
+						return a, err
+					}
+
 				case "upstream":
 					args := c.RemainingArgs()
 					if len(args) == 0 {
@@ -168,5 +182,8 @@ func autoParse(c *caddy.Controller) (Auto, error) {
 
 		}
 	}
+	if a.loader.signer != nil && len(a.loader.signer.Keys) == 0 {
+		return a, c.Errf("No keys configured to sign with")
+	}
 	return a, nil
 }
@@ -6,6 +6,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"time"
 
 	"github.com/coredns/coredns/middleware/file"
 
@@ -55,6 +56,14 @@ func (a Auto) Walk() error {
 		zo.Proxy = a.loader.proxy
 		zo.TransferTo = a.loader.transferTo
 
+		if a.loader.signer != nil {
+			zo.Signer = a.loader.signer
+			if err := zo.Sign(time.Now().UTC()); err != nil {
+				log.Printf("[WARNING] Signing %s failed: %s", origin, err)
+				return nil
+			}
+		}
+
 		a.Zones.Add(zo, origin)
 
 		if a.metrics != nil {
@@ -41,7 +41,7 @@ func (z *Zones) Zones(name string) *file.Zone {
 }
 
 // Add adds a new zone into z. If zo.NoReload is false, the
-// reload goroutine is started.
+// reload goroutine is started. If zo.Signer is set, the re-sign goroutine is started.
 func (z *Zones) Add(zo *file.Zone, name string) {
 	z.Lock()
 
@@ -52,17 +52,21 @@ func (z *Zones) Add(zo *file.Zone, name string) {
 	z.Z[name] = zo
 	z.names = append(z.names, name)
 	zo.Reload()
+	zo.Resign()
 
 	z.Unlock()
 }
 
-// Remove removes the zone named name from z. It also stop the the zone's reload goroutine.
+// Remove removes the zone named name from z. It also stops the zone's reload and re-sign goroutines.
 func (z *Zones) Remove(name string) {
 	z.Lock()
 
 	if zo, ok := z.Z[name]; ok && !zo.NoReload {
 		zo.ReloadShutdown <- true
 	}
+	if zo, ok := z.Z[name]; ok {
+		zo.StopResign()
+	}
 
 	delete(z.Z, name)
 
@@ -44,7 +44,28 @@ func key(m *dns.Msg, t response.Type, do bool) string {
 
 	qtype := m.Question[0].Qtype
 	qname := strings.ToLower(m.Question[0].Name)
-	return rawKey(qname, qtype, do)
+	return rawKey(qname, qtype, do) + subnetKey(m, true)
+}
+
+// subnetKey returns the suffix for the key for the client subnet option in m, or the empty string
+// when m has none. For a response the option only counts when its scope prefix length is non-zero,
+// i.e. when the upstream tailored the answer to the subnet, see RFC 7871, Section 7.3.1.
+func subnetKey(m *dns.Msg, response bool) string {
+	o := m.IsEdns0()
+	if o == nil {
+		return ""
+	}
+	for _, e := range o.Option {
+		s, ok := e.(*dns.EDNS0_SUBNET)
+		if !ok {
+			continue
+		}
+		if response && s.SourceScope == 0 {
+			return ""
+		}
+		return "/" + strconv.Itoa(int(s.Family)) + "/" + s.Address.String() + "/" + strconv.Itoa(int(s.SourceNetmask))
+	}
+	return ""
 }
 
 func rawKey(qname string, qtype uint16, do bool) string {
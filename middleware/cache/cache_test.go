@@ -3,6 +3,7 @@ package cache
 import (
 	"io/ioutil"
 	"log"
+	"net"
 	"testing"
 	"time"
 
@@ -122,7 +123,7 @@ func TestCache(t *testing.T) {
 
 		name := middleware.Name(m.Question[0].Name).Normalize()
 		qtype := m.Question[0].Qtype
-		i, ok, _ := c.get(name, qtype, do)
+		i, ok, _ := c.get(name, qtype, do, "")
 		if ok && m.Truncated {
 			t.Errorf("Truncated message should not have been cached")
 			continue
@@ -149,3 +150,43 @@ func TestCache(t *testing.T) {
 		}
 	}
 }
+
+func TestCacheSubnet(t *testing.T) {
+	c, crr := newTestCache(maxTTL)
+
+	subnet := func(m *dns.Msg, addr string, scope uint8) *dns.Msg {
+		m.SetEdns0(4096, false)
+		e := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: scope, Address: net.ParseIP(addr).To4()}
+		m.IsEdns0().Option = append(m.IsEdns0().Option, e)
+		return m
+	}
+
+	// An answer scoped to 10.0.0.0/24 is only returned to clients in that subnet.
+	m := subnet(new(dns.Msg).SetQuestion("example.org.", dns.TypeA), "10.0.0.0", 24)
+	m.Answer = []dns.RR{test.A("example.org. 3600 IN A 10.0.0.1")}
+	mt, _ := response.Typify(m)
+	crr.set(m, key(m, mt, false), mt, c.pttl)
+
+	q := subnet(new(dns.Msg).SetQuestion("example.org.", dns.TypeA), "10.0.0.0", 0)
+	if _, ok, _ := c.get("example.org.", dns.TypeA, false, subnetKey(q, false)); !ok {
+		t.Errorf("Expected a cache hit for the subnet the answer is scoped to")
+	}
+	q = subnet(new(dns.Msg).SetQuestion("example.org.", dns.TypeA), "10.1.0.0", 0)
+	if _, ok, _ := c.get("example.org.", dns.TypeA, false, subnetKey(q, false)); ok {
+		t.Errorf("Expected a cache miss for another subnet")
+	}
+	if _, ok, _ := c.get("example.org.", dns.TypeA, false, ""); ok {
+		t.Errorf("Expected a cache miss without a subnet")
+	}
+
+	// A scope of zero means the answer applies to all clients.
+	m = subnet(new(dns.Msg).SetQuestion("example.net.", dns.TypeA), "10.0.0.0", 0)
+	m.Answer = []dns.RR{test.A("example.net. 3600 IN A 10.0.0.1")}
+	mt, _ = response.Typify(m)
+	crr.set(m, key(m, mt, false), mt, c.pttl)
+
+	q = subnet(new(dns.Msg).SetQuestion("example.net.", dns.TypeA), "10.1.0.0", 0)
+	if _, ok, _ := c.get("example.net.", dns.TypeA, false, subnetKey(q, false)); !ok {
+		t.Errorf("Expected a cache hit for an answer with a zero scope")
+	}
+}
@@ -24,7 +24,7 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 
 	do := state.Do() // TODO(): might need more from OPT record? Like the actual bufsize?
 
-	if i, ok, expired := c.get(qname, qtype, do); ok && !expired {
+	if i, ok, expired := c.get(qname, qtype, do, subnetKey(r, false)); ok && !expired {
 		resp := i.toMsg(r)
 		state.SizeAndDo(resp)
 		resp, _ = state.Scrub(resp)
@@ -40,20 +40,34 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 // Name implements the Handler interface.
 func (c *Cache) Name() string { return "cache" }
 
-func (c *Cache) get(qname string, qtype uint16, do bool) (*item, bool, bool) {
+// get looks up the item for qname and qtype. With a client subnet an answer scoped to that subnet is
+// preferred over one that applies to all clients.
+func (c *Cache) get(qname string, qtype uint16, do bool, subnet string) (*item, bool, bool) {
 	k := rawKey(qname, qtype, do)
 
+	if subnet != "" {
+		if i, ok := c.lookup(k + subnet); ok {
+			return i, ok, i.expired(time.Now())
+		}
+	}
+	if i, ok := c.lookup(k); ok {
+		return i, ok, i.expired(time.Now())
+	}
+	cacheMisses.Inc()
+	return nil, false, false
+}
+
+func (c *Cache) lookup(k string) (*item, bool) {
 	if i, ok := c.ncache.Get(k); ok {
 		cacheHits.WithLabelValues(Denial).Inc()
-		return i.(*item), ok, i.(*item).expired(time.Now())
+		return i.(*item), ok
 	}
 
 	if i, ok := c.pcache.Get(k); ok {
 		cacheHits.WithLabelValues(Success).Inc()
-		return i.(*item), ok, i.(*item).expired(time.Now())
+		return i.(*item), ok
 	}
-	cacheMisses.Inc()
-	return nil, false, false
+	return nil, false
 }
 
 var (
@@ -2,8 +2,6 @@
 package chaos
 
 import (
-	"os"
-
 	"github.com/coredns/coredns/middleware"
 	"github.com/coredns/coredns/request"
 
@@ -11,12 +9,13 @@ import (
 	"golang.org/x/net/context"
 )
 
-// Chaos allows CoreDNS to reply to CH TXT queries and return author or
-// version information.
+// Chaos allows CoreDNS to reply to CH TXT queries and return author, version
+// or server identity information.
 type Chaos struct {
 	Next    middleware.Handler
 	Version string
 	Authors map[string]bool
+	ID      string // returned for hostname.bind and id.server
 }
 
 // ServeDNS implements the middleware.Handler interface.
@@ -40,11 +39,7 @@ func (c Chaos) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 	case "version.bind.", "version.server.":
 		m.Answer = []dns.RR{&dns.TXT{Hdr: hdr, Txt: []string{trim(c.Version)}}}
 	case "hostname.bind.", "id.server.":
-		hostname, err := os.Hostname()
-		if err != nil {
-			hostname = "localhost"
-		}
-		m.Answer = []dns.RR{&dns.TXT{Hdr: hdr, Txt: []string{trim(hostname)}}}
+		m.Answer = []dns.RR{&dns.TXT{Hdr: hdr, Txt: []string{trim(c.ID)}}}
 	}
 	state.SizeAndDo(m)
 	w.WriteMsg(m)
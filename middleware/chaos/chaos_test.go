@@ -15,6 +15,7 @@ func TestChaos(t *testing.T) {
 	em := Chaos{
 		Version: version,
 		Authors: map[string]bool{"Miek Gieben": true},
+		ID:      "coredns-1234 on node-1",
 	}
 
 	tests := []struct {
@@ -39,6 +40,13 @@ func TestChaos(t *testing.T) {
 			expectedReply: "Miek Gieben",
 			expectedErr:   nil,
 		},
+		{
+			next:          test.NextHandler(dns.RcodeSuccess, nil),
+			qname:         "id.server",
+			expectedCode:  dns.RcodeSuccess,
+			expectedReply: "coredns-1234 on node-1",
+			expectedErr:   nil,
+		},
 		{
 			next:         test.NextHandler(dns.RcodeSuccess, nil),
 			qname:        "authors.bind",
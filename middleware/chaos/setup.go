@@ -1,8 +1,11 @@
 package chaos
 
 import (
+	"strings"
+
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/identity"
 
 	"github.com/mholt/caddy"
 )
@@ -15,37 +18,54 @@ func init() {
 }
 
 func setup(c *caddy.Controller) error {
-	version, authors, err := chaosParse(c)
+	ch, err := chaosParse(c)
 	if err != nil {
 		return middleware.Error("chaos", err)
 	}
 
 	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
-		return Chaos{Next: next, Version: version, Authors: authors}
+		ch.Next = next
+		return ch
 	})
 
 	return nil
 }
 
-func chaosParse(c *caddy.Controller) (string, map[string]bool, error) {
-	version := ""
-	authors := make(map[string]bool)
+func chaosParse(c *caddy.Controller) (Chaos, error) {
+	ch := Chaos{Version: defaultVersion, ID: identity.Hostname()}
 
+	i := 0
 	for c.Next() {
+		if i > 0 {
+			return ch, c.Err("chaos can only be specified once per server block")
+		}
+		i++
+
 		args := c.RemainingArgs()
-		if len(args) == 0 {
-			return defaultVersion, nil, nil
+		if len(args) > 0 {
+			ch.Version = args[0]
 		}
-		if len(args) == 1 {
-			return args[0], nil, nil
+		if len(args) > 1 {
+			ch.Authors = make(map[string]bool)
+			for _, a := range args[1:] {
+				ch.Authors[a] = true
+			}
 		}
-		version = args[0]
-		for _, a := range args[1:] {
-			authors[a] = true
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "id":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return ch, c.ArgErr()
+				}
+				ch.ID = identity.Expand(strings.Join(args, " "))
+			default:
+				return ch, c.Errf("unknown property '%s'", c.Val())
+			}
 		}
-		return version, authors, nil
 	}
-	return version, authors, nil
+	return ch, nil
 }
 
 var defaultVersion = caddy.AppName + "-" + caddy.AppVersion
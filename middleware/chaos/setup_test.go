@@ -2,40 +2,69 @@ package chaos
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 
+	"github.com/coredns/coredns/middleware/pkg/identity"
+
 	"github.com/mholt/caddy"
 )
 
 func TestSetupChaos(t *testing.T) {
+	os.Setenv("POD_NAME", "coredns-1234")
+	os.Setenv("NODE_NAME", "node-1")
+	defer os.Unsetenv("POD_NAME")
+	defer os.Unsetenv("NODE_NAME")
+
 	tests := []struct {
 		input              string
 		shouldErr          bool
 		expectedVersion    string // expected version.
 		expectedAuthor     string // expected author (string, although we get a map).
+		expectedID         string // expected id.server.
 		expectedErrContent string // substring from the expected error. Empty for positive cases.
 	}{
 		// positive
 		{
-			`chaos`, false, defaultVersion, "", "",
+			`chaos`, false, defaultVersion, "", identity.Hostname(), "",
+		},
+		{
+			`chaos v2`, false, "v2", "", identity.Hostname(), "",
+		},
+		{
+			`chaos v3 "Miek Gieben"`, false, "v3", "Miek Gieben", identity.Hostname(), "",
 		},
 		{
-			`chaos v2`, false, "v2", "", "",
+			`chaos v4 {
+				id ns1.example.org
+			}`, false, "v4", "", "ns1.example.org", "",
 		},
 		{
-			`chaos v3 "Miek Gieben"`, false, "v3", "Miek Gieben", "",
+			`chaos {
+				id {pod} on {node}
+			}`, false, defaultVersion, "", "coredns-1234 on node-1", "",
 		},
+		// negative
 		{
 			fmt.Sprintf(`chaos {
 				%s
-			}`, defaultVersion), false, defaultVersion, "", "",
+			}`, defaultVersion), true, "", "", "", "unknown property",
+		},
+		{
+			`chaos {
+				id
+			}`, true, "", "", "", "Wrong argument count",
+		},
+		{
+			`chaos
+chaos`, true, "", "", "", "once per server block",
 		},
 	}
 
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.input)
-		version, authors, err := chaosParse(c)
+		ch, err := chaosParse(c)
 
 		if test.shouldErr && err == nil {
 			t.Errorf("Test %d: Expected error but found %s for input %s", i, err, test.input)
@@ -51,13 +80,16 @@ func TestSetupChaos(t *testing.T) {
 			}
 		}
 
-		if !test.shouldErr && version != test.expectedVersion {
-			t.Errorf("Chaos not correctly set for input %s. Expected: %s, actual: %s", test.input, test.expectedVersion, version)
+		if !test.shouldErr && ch.Version != test.expectedVersion {
+			t.Errorf("Chaos not correctly set for input %s. Expected: %s, actual: %s", test.input, test.expectedVersion, ch.Version)
 		}
-		if !test.shouldErr && authors != nil {
-			if _, ok := authors[test.expectedAuthor]; !ok {
-				t.Errorf("Chaos not correctly set for input %s. Expected: '%s', actual: '%s'", test.input, test.expectedAuthor, "Miek Gieben")
+		if !test.shouldErr && ch.Authors != nil {
+			if _, ok := ch.Authors[test.expectedAuthor]; !ok {
+				t.Errorf("Chaos not correctly set for input %s. Expected: '%s', actual: '%v'", test.input, test.expectedAuthor, ch.Authors)
 			}
 		}
+		if !test.shouldErr && ch.ID != test.expectedID {
+			t.Errorf("Chaos not correctly set for input %s. Expected id: '%s', actual: '%s'", test.input, test.expectedID, ch.ID)
+		}
 	}
 }
@@ -0,0 +1,129 @@
+// Package cookie enables DNS Cookies (RFC 7873) for a server.
+package cookie
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/cookie"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("cookie", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	config, rotate, err := cookieParse(c)
+	if err != nil {
+		return middleware.Error("cookie", err)
+	}
+	dnsserver.GetConfig(c).Cookie = config
+
+	if rotate == 0 {
+		return nil
+	}
+
+	stop := make(chan bool)
+
+	c.OnStartup(func() error {
+		go func() {
+			ticker := time.NewTicker(rotate)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					config.Secret.Rotate()
+				}
+			}
+		}()
+		return nil
+	})
+
+	c.OnShutdown(func() error {
+		close(stop)
+		return nil
+	})
+
+	return nil
+}
+
+func cookieParse(c *caddy.Controller) (*cookie.Config, time.Duration, error) {
+	config := &cookie.Config{}
+	rotate := defaultRotate
+	var secret []byte
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, 0, c.Err("cookie can only be specified once per server block")
+		}
+		i++
+
+		if len(c.RemainingArgs()) != 0 {
+			return nil, 0, c.ArgErr()
+		}
+
+		rotateSet := false
+		for c.NextBlock() {
+			switch c.Val() {
+			case "secret":
+				if !c.NextArg() {
+					return nil, 0, c.ArgErr()
+				}
+				s, err := hex.DecodeString(c.Val())
+				if err != nil || len(s) < cookie.SecretLen {
+					return nil, 0, c.Errf("secret must be at least %d bytes in hex", cookie.SecretLen)
+				}
+				secret = s
+				if c.NextArg() {
+					return nil, 0, c.ArgErr()
+				}
+			case "rotate":
+				if !c.NextArg() {
+					return nil, 0, c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil {
+					return nil, 0, err
+				}
+				if d < 0 {
+					return nil, 0, c.Errf("rotate can not be negative: %s", d)
+				}
+				rotate = d
+				rotateSet = true
+				if c.NextArg() {
+					return nil, 0, c.ArgErr()
+				}
+			case "require":
+				if c.NextArg() {
+					return nil, 0, c.ArgErr()
+				}
+				config.Require = true
+			default:
+				return nil, 0, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+
+		// A configured secret is shared with other servers, it can't be rotated.
+		if secret != nil {
+			if rotateSet && rotate != 0 {
+				return nil, 0, c.Err("rotate can not be used with a configured secret")
+			}
+			rotate = 0
+		}
+	}
+
+	config.Secret = cookie.NewSecret(secret)
+	return config, rotate, nil
+}
+
+const defaultRotate = 1 * time.Hour
@@ -0,0 +1,91 @@
+package cookie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupCookie(t *testing.T) {
+	tests := []struct {
+		input          string
+		shouldErr      bool
+		expectedRotate time.Duration
+		require        bool
+	}{
+		{`cookie`, false, defaultRotate, false},
+		{`cookie {
+			require
+		}`, false, defaultRotate, true},
+		{`cookie {
+			rotate 10m
+		}`, false, 10 * time.Minute, false},
+		{`cookie {
+			rotate 0
+		}`, false, 0, false},
+		{`cookie {
+			secret 000102030405060708090a0b0c0d0e0f
+			require
+		}`, false, 0, true},
+		// fails
+		{`cookie foo`, true, 0, false},
+		{`cookie {
+			secret 0001
+		}`, true, 0, false},
+		{`cookie {
+			secret nothex
+		}`, true, 0, false},
+		{`cookie {
+			secret 000102030405060708090a0b0c0d0e0f
+			rotate 1h
+		}`, true, 0, false},
+		{`cookie {
+			rotate -1s
+		}`, true, 0, false},
+		{`cookie {
+			require yes
+		}`, true, 0, false},
+		{`cookie {
+			unknown
+		}`, true, 0, false},
+		{`cookie
+cookie`, true, 0, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		config, rotate, err := cookieParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+
+		if rotate != test.expectedRotate {
+			t.Errorf("Test %d expected rotate of %s, got %s", i, test.expectedRotate, rotate)
+		}
+		if config.Require != test.require {
+			t.Errorf("Test %d expected require to be %t, got %t", i, test.require, config.Require)
+		}
+		if config.Secret == nil {
+			t.Errorf("Test %d expected a secret", i)
+		}
+	}
+}
+
+func TestSetupCookieConfig(t *testing.T) {
+	c := caddy.NewTestController("dns", `cookie`)
+	if err := setup(c); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+	if dnsserver.GetConfig(c).Cookie == nil {
+		t.Errorf("Expected the config's Cookie to be set")
+	}
+}
@@ -0,0 +1,282 @@
+// Package dns64 implements a middleware that performs DNS64, see RFC 6147.
+package dns64
+
+import (
+	"errors"
+	"net"
+
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/dnsutil"
+	"github.com/coredns/coredns/middleware/pkg/nonwriter"
+	"github.com/coredns/coredns/middleware/proxy"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// DNS64 synthesizes AAAA records from A records for names that have no AAAA records. The AAAA
+// records are made by embedding the IPv4 addresses in Prefix.
+type DNS64 struct {
+	Next     middleware.Handler
+	Prefix   *net.IPNet
+	Upstream *proxy.Proxy // if nil, the A records are looked up through the rest of the chain
+}
+
+// ServeDNS implements the middleware.Handler interface.
+func (d DNS64) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	// A client that validates itself (DO and CD set) can't use synthesized records, RFC 6147,
+	// Section 5.5.
+	if r.CheckingDisabled && state.Do() {
+		return middleware.NextOrFailure(d.Name(), d.Next, ctx, w, r)
+	}
+
+	switch state.QType() {
+	case dns.TypeAAAA:
+		return d.serveAAAA(ctx, state)
+	case dns.TypePTR:
+		if v4 := d.reverse(state.Name()); v4 != "" {
+			return d.servePTR(ctx, state, v4)
+		}
+	}
+	return middleware.NextOrFailure(d.Name(), d.Next, ctx, w, r)
+}
+
+// Name implements the middleware.Handler interface.
+func (d DNS64) Name() string { return "dns64" }
+
+// serveAAAA lets the rest of the chain answer the AAAA query and only when that yields no AAAA
+// records the answer is synthesized from the A records for the name.
+func (d DNS64) serveAAAA(ctx context.Context, state request.Request) (int, error) {
+	nw := nonwriter.New(state.W)
+	rc, err := middleware.NextOrFailure(d.Name(), d.Next, ctx, nw, state.Req)
+	if err != nil || nw.Msg == nil {
+		// Nothing has been written, the server will write the error.
+		return rc, err
+	}
+
+	if !requiresSynthesis(nw.Msg) {
+		state.W.WriteMsg(nw.Msg)
+		return dns.RcodeSuccess, nil
+	}
+
+	a, err := d.lookup(ctx, state, state.Name(), dns.TypeA)
+	if err != nil || a.Rcode != dns.RcodeSuccess {
+		state.W.WriteMsg(nw.Msg)
+		return dns.RcodeSuccess, nil
+	}
+
+	m := d.synthesize(state.Req, nw.Msg, a)
+	if m == nil {
+		// No A records either, the original response (with its SOA) is the right answer.
+		state.W.WriteMsg(nw.Msg)
+		return dns.RcodeSuccess, nil
+	}
+	state.SizeAndDo(m)
+	m, _ = state.Scrub(m)
+	state.W.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// servePTR answers a PTR query for a name in the reverse space of the prefix with a CNAME to the
+// in-addr.arpa name of the embedded IPv4 address, RFC 6147, Section 5.3.1. The PTR for that name
+// is looked up and added to the answer.
+func (d DNS64) servePTR(ctx context.Context, state request.Request, v4 string) (int, error) {
+	target, err := dns.ReverseAddr(v4)
+	if err != nil {
+		return dns.RcodeServerFailure, err
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.RecursionAvailable, m.Compress = true, true
+	m.Answer = []dns.RR{&dns.CNAME{
+		Hdr:    dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: defaultTTL},
+		Target: target,
+	}}
+
+	if ptr, err := d.lookup(ctx, state, target, dns.TypePTR); err == nil {
+		m.Rcode = ptr.Rcode
+		m.Answer = append(m.Answer, ptr.Answer...)
+		m.Ns = ptr.Ns
+	}
+
+	state.SizeAndDo(m)
+	m, _ = state.Scrub(m)
+	state.W.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// lookup looks up name and typ with the upstream, or when there is none, with the rest of the
+// chain.
+func (d DNS64) lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error) {
+	if d.Upstream != nil {
+		return d.Upstream.Lookup(state, name, typ)
+	}
+
+	req := state.Req.Copy()
+	req.Question[0].Name = name
+	req.Question[0].Qtype = typ
+
+	nw := nonwriter.New(state.W)
+	if _, err := middleware.NextOrFailure(d.Name(), d.Next, ctx, nw, req); err != nil {
+		return nil, err
+	}
+	if nw.Msg == nil {
+		return nil, errNoResponse
+	}
+	return nw.Msg, nil
+}
+
+// synthesize returns the response to r with AAAA records made from the A records in a. aaaa is
+// the response to r, its SOA record caps the TTL of the synthesized records, RFC 6147,
+// Section 5.1.7. It returns nil when no AAAA record could be synthesized.
+func (d DNS64) synthesize(r, aaaa, a *dns.Msg) *dns.Msg {
+	ttl := uint32(defaultTTL)
+	for _, rr := range aaaa.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			ttl = soa.Minttl
+			if soa.Hdr.Ttl < ttl {
+				ttl = soa.Hdr.Ttl
+			}
+		}
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Rcode = a.Rcode
+	m.RecursionAvailable, m.Compress = true, true
+
+	synthesized := false
+	for _, rr := range a.Answer {
+		ar, ok := rr.(*dns.A)
+		if !ok {
+			// CNAMEs and such are copied as-is.
+			m.Answer = append(m.Answer, rr)
+			continue
+		}
+		ip, err := to6(d.Prefix, ar.A)
+		if err != nil {
+			continue
+		}
+		rrttl := ar.Hdr.Ttl
+		if ttl < rrttl {
+			rrttl = ttl
+		}
+		m.Answer = append(m.Answer, &dns.AAAA{
+			Hdr:  dns.RR_Header{Name: ar.Hdr.Name, Rrtype: dns.TypeAAAA, Class: ar.Hdr.Class, Ttl: rrttl},
+			AAAA: ip,
+		})
+		synthesized = true
+	}
+	if !synthesized {
+		return nil
+	}
+	return m
+}
+
+// reverse returns the IPv4 address embedded in name, when it is a ip6.arpa name in the reverse
+// space of the prefix.
+func (d DNS64) reverse(name string) string {
+	addr := dnsutil.ExtractAddressFromReverse(name)
+	if addr == "" {
+		return ""
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.To4() != nil || !d.Prefix.Contains(ip) {
+		return ""
+	}
+	v4, err := to4(d.Prefix, ip)
+	if err != nil {
+		return ""
+	}
+	return v4.String()
+}
+
+// requiresSynthesis returns true when m is a NODATA response for an AAAA query, i.e. it
+// doesn't contain any AAAA records.
+func requiresSynthesis(m *dns.Msg) bool {
+	if m.Rcode != dns.RcodeSuccess {
+		return false
+	}
+	for _, rr := range m.Answer {
+		if rr.Header().Rrtype == dns.TypeAAAA {
+			return false
+		}
+	}
+	return true
+}
+
+// to6 embeds the IPv4 address addr in prefix, as described in RFC 6052, Section 2.2.
+func to6(prefix *net.IPNet, addr net.IP) (net.IP, error) {
+	v4 := addr.To4()
+	if v4 == nil {
+		return nil, errNotIPv4
+	}
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, prefix.IP.To16())
+
+	n, _ := prefix.Mask.Size()
+	switch n {
+	case 32:
+		copy(ip[4:8], v4)
+	case 40:
+		copy(ip[5:8], v4[0:3])
+		ip[9] = v4[3]
+	case 48:
+		copy(ip[6:8], v4[0:2])
+		copy(ip[9:11], v4[2:4])
+	case 56:
+		ip[7] = v4[0]
+		copy(ip[9:12], v4[1:4])
+	case 64:
+		copy(ip[9:13], v4)
+	case 96:
+		copy(ip[12:16], v4)
+	default:
+		return nil, errPrefixLength
+	}
+	return ip, nil
+}
+
+// to4 extracts the IPv4 address embedded in ip, the reverse of to6.
+func to4(prefix *net.IPNet, ip net.IP) (net.IP, error) {
+	ip = ip.To16()
+	if ip == nil {
+		return nil, errNotIPv6
+	}
+	v4 := make(net.IP, net.IPv4len)
+
+	n, _ := prefix.Mask.Size()
+	switch n {
+	case 32:
+		copy(v4, ip[4:8])
+	case 40:
+		copy(v4[0:3], ip[5:8])
+		v4[3] = ip[9]
+	case 48:
+		copy(v4[0:2], ip[6:8])
+		copy(v4[2:4], ip[9:11])
+	case 56:
+		v4[0] = ip[7]
+		copy(v4[1:4], ip[9:12])
+	case 64:
+		copy(v4, ip[9:13])
+	case 96:
+		copy(v4, ip[12:16])
+	default:
+		return nil, errPrefixLength
+	}
+	return v4, nil
+}
+
+const defaultTTL = 600
+
+var (
+	errNoResponse   = errors.New("no response from the next middleware")
+	errNotIPv4      = errors.New("not an IPv4 address")
+	errNotIPv6      = errors.New("not an IPv6 address")
+	errPrefixLength = errors.New("prefix length must be one of 32, 40, 48, 56, 64 or 96")
+)
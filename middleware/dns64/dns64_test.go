@@ -0,0 +1,147 @@
+package dns64
+
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// backend is the rest of the chain, it serves a tiny zone.
+func backend(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	soa := test.SOA("example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 3600 3600 300")
+
+	q := r.Question[0]
+	switch {
+	case q.Name == "has6.example." && q.Qtype == dns.TypeAAAA:
+		m.Answer = []dns.RR{test.AAAA("has6.example. 3600 IN AAAA 2001:db8::1")}
+	case q.Name == "only4.example." && q.Qtype == dns.TypeA:
+		m.Answer = []dns.RR{test.A("only4.example. 3600 IN A 192.0.2.1")}
+	case q.Name == "short.example." && q.Qtype == dns.TypeA:
+		m.Answer = []dns.RR{test.A("short.example. 60 IN A 192.0.2.2")}
+	case q.Name == "1.2.0.192.in-addr.arpa." && q.Qtype == dns.TypePTR:
+		m.Answer = []dns.RR{test.PTR("1.2.0.192.in-addr.arpa. 3600 IN PTR only4.example.")}
+	case q.Name == "only4.example." || q.Name == "short.example." || q.Name == "empty.example.":
+		m.Ns = []dns.RR{soa}
+	default:
+		m.Rcode = dns.RcodeNameError
+		m.Ns = []dns.RR{soa}
+	}
+	w.WriteMsg(m)
+	return m.Rcode, nil
+}
+
+func TestDNS64(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR(defaultPrefix)
+	d := DNS64{Next: test.HandlerFunc(backend), Prefix: prefix}
+	ptr, _ := dns.ReverseAddr("64:ff9b::c000:201")
+
+	tests := []test.Case{
+		{
+			Qname: "has6.example.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{test.AAAA("has6.example. 3600 IN AAAA 2001:db8::1")},
+		},
+		{
+			// TTL is capped by the SOA minimum.
+			Qname: "only4.example.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{test.AAAA("only4.example. 300 IN AAAA 64:ff9b::c000:201")},
+		},
+		{
+			Qname: "short.example.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{test.AAAA("short.example. 60 IN AAAA 64:ff9b::c000:202")},
+		},
+		{
+			Qname: "only4.example.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("only4.example. 3600 IN A 192.0.2.1")},
+		},
+		{
+			// No A records either, the NODATA response keeps its SOA.
+			Qname: "empty.example.", Qtype: dns.TypeAAAA,
+			Ns: []dns.RR{test.SOA("example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 3600 3600 300")},
+		},
+		{
+			Qname: "nxdomain.example.", Qtype: dns.TypeAAAA,
+			Rcode: dns.RcodeNameError,
+			Ns:    []dns.RR{test.SOA("example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 3600 3600 300")},
+		},
+		{
+			Qname: ptr, Qtype: dns.TypePTR,
+			Answer: []dns.RR{
+				test.CNAME(ptr + " 600 IN CNAME 1.2.0.192.in-addr.arpa."),
+				test.PTR("1.2.0.192.in-addr.arpa. 3600 IN PTR only4.example."),
+			},
+		},
+	}
+
+	ctx := context.TODO()
+	for _, tc := range tests {
+		m := tc.Msg()
+
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		rcode, err := d.ServeDNS(ctx, rec, m)
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+			continue
+		}
+		if rcode != dns.RcodeSuccess {
+			t.Errorf("Expected rcode %d, got %d", dns.RcodeSuccess, rcode)
+		}
+
+		resp := rec.Msg
+		if !test.Header(t, tc, resp) {
+			t.Logf("%v\n", resp)
+			continue
+		}
+		if !test.Section(t, tc, test.Answer, resp.Answer) {
+			t.Logf("%v\n", resp)
+		}
+		if !test.Section(t, tc, test.Ns, resp.Ns) {
+			t.Logf("%v\n", resp)
+		}
+	}
+}
+
+func TestTo6(t *testing.T) {
+	// Examples from RFC 6052, Section 2.4.
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{"2001:db8::/32", "2001:db8:c000:221::"},
+		{"2001:db8:100::/40", "2001:db8:1c0:2:21::"},
+		{"2001:db8:122::/48", "2001:db8:122:c000:2:2100::"},
+		{"2001:db8:122:300::/56", "2001:db8:122:3c0:0:221::"},
+		{"2001:db8:122:344::/64", "2001:db8:122:344:c0:2:2100:0"},
+		{"2001:db8:122:344::/96", "2001:db8:122:344::c000:221"},
+	}
+
+	v4 := net.ParseIP("192.0.2.33")
+	for i, tc := range tests {
+		prefix, err := parsePrefix(tc.prefix)
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		ip, err := to6(prefix, v4)
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if ip.String() != tc.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.expected, ip)
+		}
+
+		back, err := to4(prefix, ip)
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if !back.Equal(v4) {
+			t.Errorf("Test %d: expected %s, got %s", i, v4, back)
+		}
+	}
+}
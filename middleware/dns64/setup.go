@@ -0,0 +1,107 @@
+package dns64
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/dnsutil"
+	"github.com/coredns/coredns/middleware/proxy"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("dns64", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	d, err := dns64Parse(c)
+	if err != nil {
+		return middleware.Error("dns64", err)
+	}
+
+	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
+		d.Next = next
+		return d
+	})
+
+	return nil
+}
+
+func dns64Parse(c *caddy.Controller) (DNS64, error) {
+	_, prefix, _ := net.ParseCIDR(defaultPrefix)
+	d := DNS64{Prefix: prefix}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return d, c.Err("dns64 can only be specified once")
+		}
+		i++
+
+		args := c.RemainingArgs()
+		if len(args) > 1 {
+			return d, c.ArgErr()
+		}
+		if len(args) == 1 {
+			p, err := parsePrefix(args[0])
+			if err != nil {
+				return d, c.Errf("invalid prefix %q: %v", args[0], err)
+			}
+			d.Prefix = p
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "prefix":
+				if !c.NextArg() {
+					return d, c.ArgErr()
+				}
+				p, err := parsePrefix(c.Val())
+				if err != nil {
+					return d, c.Errf("invalid prefix %q: %v", c.Val(), err)
+				}
+				d.Prefix = p
+				if c.NextArg() {
+					return d, c.ArgErr()
+				}
+			case "upstream":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return d, c.ArgErr()
+				}
+				ups, err := dnsutil.ParseHostPortOrFile(args...)
+				if err != nil {
+					return d, err
+				}
+				p := proxy.NewLookup(ups)
+				d.Upstream = &p
+			default:
+				return d, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	return d, nil
+}
+
+// parsePrefix parses s as an IPv6 network with one of the prefix lengths of RFC 6052.
+func parsePrefix(s string) (*net.IPNet, error) {
+	_, p, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, err
+	}
+	if p.IP.To4() != nil {
+		return nil, errNotIPv6
+	}
+	switch n, _ := p.Mask.Size(); n {
+	case 32, 40, 48, 56, 64, 96:
+		return p, nil
+	}
+	return nil, errPrefixLength
+}
+
+const defaultPrefix = "64:ff9b::/96"
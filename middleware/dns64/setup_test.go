@@ -0,0 +1,61 @@
+package dns64
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupDNS64(t *testing.T) {
+	tests := []struct {
+		input          string
+		shouldErr      bool
+		expectedPrefix string
+		upstream       bool
+	}{
+		{`dns64`, false, "64:ff9b::/96", false},
+		{`dns64 2001:db8::/32`, false, "2001:db8::/32", false},
+		{`dns64 {
+			prefix 2001:db8:122:344::/64
+		}`, false, "2001:db8:122:344::/64", false},
+		{`dns64 {
+			upstream 8.8.8.8
+		}`, false, "64:ff9b::/96", true},
+		// fails
+		{`dns64 2001:db8::/33`, true, "", false},
+		{`dns64 10.0.0.0/8`, true, "", false},
+		{`dns64 foo`, true, "", false},
+		{`dns64 64:ff9b::/96 2001:db8::/32`, true, "", false},
+		{`dns64 {
+			prefix
+		}`, true, "", false},
+		{`dns64 {
+			upstream
+		}`, true, "", false},
+		{`dns64 {
+			unknown
+		}`, true, "", false},
+		{`dns64
+dns64`, true, "", false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		d, err := dns64Parse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if d.Prefix.String() != test.expectedPrefix {
+			t.Errorf("Test %d expected prefix %s, got %s", i, test.expectedPrefix, d.Prefix)
+		}
+		if (d.Upstream != nil) != test.upstream {
+			t.Errorf("Test %d expected upstream to be %t", i, test.upstream)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/coredns/coredns/middleware/pkg/dnskey"
 	"github.com/coredns/coredns/middleware/test"
 	"github.com/coredns/coredns/request"
 
@@ -16,7 +17,7 @@ func TestCacheSet(t *testing.T) {
 	defer rmPriv()
 	defer rmPub()
 
-	dnskey, err := ParseKeyFile(fPub, fPriv)
+	dnsKey, err := dnskey.ParseKeyFile(fPub, fPriv)
 	if err != nil {
 		t.Fatalf("failed to parse key: %v\n", err)
 	}
@@ -25,7 +26,7 @@ func TestCacheSet(t *testing.T) {
 	m := testMsg()
 	state := request.Request{Req: m}
 	k := key(m.Answer) // calculate *before* we add the sig
-	d := New([]string{"miek.nl."}, []*DNSKEY{dnskey}, nil, cache)
+	d := New([]string{"miek.nl."}, []*dnskey.Key{dnsKey}, nil, cache)
 	m = d.Sign(state, "miek.nl.", time.Now().UTC())
 
 	_, ok := d.get(k)
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/dnskey"
 	"github.com/coredns/coredns/middleware/pkg/response"
 	"github.com/coredns/coredns/middleware/pkg/singleflight"
 	"github.com/coredns/coredns/request"
@@ -19,13 +20,13 @@ type Dnssec struct {
 	Next middleware.Handler
 
 	zones    []string
-	keys     []*DNSKEY
+	keys     []*dnskey.Key
 	inflight *singleflight.Group
 	cache    *lru.Cache
 }
 
 // New returns a new Dnssec.
-func New(zones []string, keys []*DNSKEY, next middleware.Handler, cache *lru.Cache) Dnssec {
+func New(zones []string, keys []*dnskey.Key, next middleware.Handler, cache *lru.Cache) Dnssec {
 	return Dnssec{Next: next,
 		zones:    zones,
 		keys:     keys,
@@ -99,8 +100,8 @@ func (d Dnssec) sign(rrs []dns.RR, signerName string, ttl, incep, expir uint32)
 		sigs := make([]dns.RR, len(d.keys))
 		var e error
 		for i, k := range d.keys {
-			sig := k.newRRSIG(signerName, ttl, incep, expir)
-			e = sig.Sign(k.s, rrs)
+			sig, err := k.Sign(rrs, signerName, ttl, origTTL, incep, expir)
+			e = err
 			sigs[i] = sig
 		}
 		d.set(k, sigs)
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/coredns/coredns/middleware/pkg/dnskey"
 	"github.com/coredns/coredns/middleware/test"
 	"github.com/coredns/coredns/request"
 
@@ -38,7 +39,7 @@ func TestZoneSigningDouble(t *testing.T) {
 	defer rmPriv1()
 	defer rmPub1()
 
-	key1, err := ParseKeyFile(fPub1, fPriv1)
+	key1, err := dnskey.ParseKeyFile(fPub1, fPriv1)
 	if err != nil {
 		t.Fatalf("failed to parse key: %v\n", err)
 	}
@@ -62,7 +63,7 @@ func TestSigningDifferentZone(t *testing.T) {
 	defer rmPriv()
 	defer rmPub()
 
-	key, err := ParseKeyFile(fPub, fPriv)
+	key, err := dnskey.ParseKeyFile(fPub, fPriv)
 	if err != nil {
 		t.Fatalf("failed to parse key: %v\n", err)
 	}
@@ -70,7 +71,7 @@ func TestSigningDifferentZone(t *testing.T) {
 	m := testMsgEx()
 	state := request.Request{Req: m}
 	cache, _ := lru.New(defaultCap)
-	d := New([]string{"example.org."}, []*DNSKEY{key}, nil, cache)
+	d := New([]string{"example.org."}, []*dnskey.Key{key}, nil, cache)
 	m = d.Sign(state, "example.org.", time.Now().UTC())
 	if !section(m.Answer, 1) {
 		t.Errorf("answer section should have 1 sig")
@@ -160,15 +161,15 @@ func testDelegationMsg() *dns.Msg {
 func newDnssec(t *testing.T, zones []string) (Dnssec, func(), func()) {
 	k, rm1, rm2 := newKey(t)
 	cache, _ := lru.New(defaultCap)
-	d := New(zones, []*DNSKEY{k}, nil, cache)
+	d := New(zones, []*dnskey.Key{k}, nil, cache)
 	return d, rm1, rm2
 }
 
-func newKey(t *testing.T) (*DNSKEY, func(), func()) {
+func newKey(t *testing.T) (*dnskey.Key, func(), func()) {
 	fPriv, rmPriv, _ := test.TempFile(".", privKey)
 	fPub, rmPub, _ := test.TempFile(".", pubKey)
 
-	key, err := ParseKeyFile(fPub, fPriv)
+	key, err := dnskey.ParseKeyFile(fPub, fPriv)
 	if err != nil {
 		t.Fatalf("failed to parse key: %v\n", err)
 	}
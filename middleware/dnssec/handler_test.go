@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/coredns/coredns/middleware/file"
+	"github.com/coredns/coredns/middleware/pkg/dnskey"
 	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
 	"github.com/coredns/coredns/middleware/test"
 
@@ -86,11 +87,11 @@ func TestLookupZone(t *testing.T) {
 		return
 	}
 	fm := file.File{Next: test.ErrorHandler(), Zones: file.Zones{Z: map[string]*file.Zone{"miek.nl.": zone}, Names: []string{"miek.nl."}}}
-	dnskey, rm1, rm2 := newKey(t)
+	dnsKey, rm1, rm2 := newKey(t)
 	defer rm1()
 	defer rm2()
 	cache, _ := lru.New(defaultCap)
-	dh := New([]string{"miek.nl."}, []*DNSKEY{dnskey}, fm, cache)
+	dh := New([]string{"miek.nl."}, []*dnskey.Key{dnsKey}, fm, cache)
 	ctx := context.TODO()
 
 	for _, tc := range dnsTestCases {
@@ -125,11 +126,11 @@ func TestLookupZone(t *testing.T) {
 }
 
 func TestLookupDNSKEY(t *testing.T) {
-	dnskey, rm1, rm2 := newKey(t)
+	dnsKey, rm1, rm2 := newKey(t)
 	defer rm1()
 	defer rm2()
 	cache, _ := lru.New(defaultCap)
-	dh := New([]string{"miek.nl."}, []*DNSKEY{dnskey}, test.ErrorHandler(), cache)
+	dh := New([]string{"miek.nl."}, []*dnskey.Key{dnsKey}, test.ErrorHandler(), cache)
 	ctx := context.TODO()
 
 	for _, tc := range dnssecTestCases {
@@ -2,10 +2,10 @@ package dnssec
 
 import (
 	"strconv"
-	"strings"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/dnskey"
 
 	"github.com/hashicorp/golang-lru"
 	"github.com/mholt/caddy"
@@ -38,10 +38,10 @@ func setup(c *caddy.Controller) error {
 	return nil
 }
 
-func dnssecParse(c *caddy.Controller) ([]string, []*DNSKEY, int, error) {
+func dnssecParse(c *caddy.Controller) ([]string, []*dnskey.Key, int, error) {
 	zones := []string{}
 
-	keys := []*DNSKEY{}
+	keys := []*dnskey.Key{}
 
 	capacity := defaultCap
 	for c.Next() {
@@ -57,7 +57,7 @@ func dnssecParse(c *caddy.Controller) ([]string, []*DNSKEY, int, error) {
 			for c.NextBlock() {
 				switch c.Val() {
 				case "key":
-					k, e := keyParse(c)
+					k, e := dnskey.Parse(c)
 					if e != nil {
 						return nil, nil, 0, e
 					}
@@ -82,31 +82,3 @@ func dnssecParse(c *caddy.Controller) ([]string, []*DNSKEY, int, error) {
 	}
 	return zones, keys, capacity, nil
 }
-
-func keyParse(c *caddy.Controller) ([]*DNSKEY, error) {
-	keys := []*DNSKEY{}
-
-	if !c.NextArg() {
-		return nil, c.ArgErr()
-	}
-	value := c.Val()
-	if value == "file" {
-		ks := c.RemainingArgs()
-		for _, k := range ks {
-			base := k
-			// Kmiek.nl.+013+26205.key, handle .private or without extension: Kmiek.nl.+013+26205
-			if strings.HasSuffix(k, ".key") {
-				base = k[:len(k)-4]
-			}
-			if strings.HasSuffix(k, ".private") {
-				base = k[:len(k)-8]
-			}
-			k, err := ParseKeyFile(base+".key", base+".private")
-			if err != nil {
-				return nil, err
-			}
-			keys = append(keys, k)
-		}
-	}
-	return keys, nil
-}
@@ -0,0 +1,71 @@
+// Package dnstap implements the dnstap middleware. It logs queries and responses in the dnstap
+// format (http://dnstap.info) to a collector listening on a unix socket or TCP address.
+package dnstap
+
+import (
+	"time"
+
+	"github.com/coredns/coredns/middleware"
+
+	tap "github.com/dnstap/golang-dnstap"
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Dnstap is the dnstap middleware.
+type Dnstap struct {
+	Next middleware.Handler
+	IO   IORoutine
+
+	// Full is true when the wire format of the messages should be included.
+	Full bool
+}
+
+// IORoutine sends dnstap messages to the collector.
+type IORoutine interface {
+	Dnstap(tap.Dnstap)
+}
+
+// Tapper is implemented by Dnstap, middleware can use it to log the messages they exchange with
+// other servers, see TapperFromContext.
+type Tapper interface {
+	TapMessage(*tap.Message)
+	Pack() bool
+}
+
+type contextKey struct{}
+
+// TapperFromContext returns the Tapper carried by ctx, or nil if the dnstap middleware is
+// not enabled.
+func TapperFromContext(ctx context.Context) Tapper {
+	t, _ := ctx.Value(contextKey{}).(Tapper)
+	return t
+}
+
+// TapMessage implements Tapper.
+func (h Dnstap) TapMessage(m *tap.Message) {
+	t := tap.Dnstap_MESSAGE
+	h.IO.Dnstap(tap.Dnstap{Type: &t, Message: m})
+}
+
+// Pack implements Tapper. It returns true when the wire format of the messages should be included.
+func (h Dnstap) Pack() bool { return h.Full }
+
+// ServeDNS implements the middleware.Handler interface.
+func (h Dnstap) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	now := time.Now()
+
+	b := ClientQuery(w.RemoteAddr(), now)
+	if h.Full {
+		b.Msg(r)
+	}
+	h.TapMessage(b.ToMessage())
+
+	rw := &ResponseWriter{ResponseWriter: w, Tapper: h, Query: now}
+	ctx = context.WithValue(ctx, contextKey{}, Tapper(h))
+
+	return middleware.NextOrFailure(h.Name(), h.Next, ctx, rw, r)
+}
+
+// Name implements the Handler interface.
+func (h Dnstap) Name() string { return "dnstap" }
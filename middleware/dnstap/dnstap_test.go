@@ -0,0 +1,99 @@
+package dnstap
+
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	tap "github.com/dnstap/golang-dnstap"
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+type testIO struct {
+	msgs []tap.Dnstap
+}
+
+func (t *testIO) Dnstap(p tap.Dnstap) { t.msgs = append(t.msgs, p) }
+
+func TestDnstap(t *testing.T) {
+	for _, full := range []bool{false, true} {
+		io := &testIO{}
+		var tapper Tapper
+		h := Dnstap{
+			Next: test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+				tapper = TapperFromContext(ctx)
+				m := new(dns.Msg)
+				m.SetReply(r)
+				m.Answer = []dns.RR{test.A("example.org. 3600 IN A 127.0.0.53")}
+				w.WriteMsg(m)
+				return 0, nil
+			}),
+			IO:   io,
+			Full: full,
+		}
+
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		if _, err := h.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		if tapper == nil {
+			t.Errorf("Expected a Tapper in the context")
+		}
+		if rec.Msg == nil || len(rec.Msg.Answer) != 1 {
+			t.Fatalf("Expected the response to be written to the client")
+		}
+		if len(io.msgs) != 2 {
+			t.Fatalf("Expected 2 dnstap messages, got %d", len(io.msgs))
+		}
+
+		q, r := io.msgs[0].Message, io.msgs[1].Message
+		if *q.Type != tap.Message_CLIENT_QUERY {
+			t.Errorf("Expected CLIENT_QUERY, got %s", q.Type)
+		}
+		if *r.Type != tap.Message_CLIENT_RESPONSE {
+			t.Errorf("Expected CLIENT_RESPONSE, got %s", r.Type)
+		}
+		if !net.IP(q.QueryAddress).Equal(net.ParseIP("10.240.0.1")) || *q.QueryPort != 40212 {
+			t.Errorf("Expected query address 10.240.0.1:40212, got %s:%d", net.IP(q.QueryAddress), *q.QueryPort)
+		}
+		if *q.SocketFamily != tap.SocketFamily_INET || *q.SocketProtocol != tap.SocketProtocol_UDP {
+			t.Errorf("Expected INET and UDP, got %s and %s", q.SocketFamily, q.SocketProtocol)
+		}
+		if r.ResponseTimeSec == nil {
+			t.Errorf("Expected the response time to be set")
+		}
+
+		if !full {
+			if q.QueryMessage != nil || r.ResponseMessage != nil {
+				t.Errorf("Expected no wire format messages")
+			}
+			continue
+		}
+		resp := new(dns.Msg)
+		if err := resp.Unpack(r.ResponseMessage); err != nil {
+			t.Fatalf("Expected a valid response message, got %s", err)
+		}
+		if len(resp.Answer) != 1 {
+			t.Errorf("Expected 1 answer in the response message, got %d", len(resp.Answer))
+		}
+		query := new(dns.Msg)
+		if err := query.Unpack(q.QueryMessage); err != nil {
+			t.Fatalf("Expected a valid query message, got %s", err)
+		}
+		if query.Question[0].Name != "example.org." {
+			t.Errorf("Expected query for example.org., got %s", query.Question[0].Name)
+		}
+	}
+}
+
+func TestTapperFromContext(t *testing.T) {
+	if TapperFromContext(context.TODO()) != nil {
+		t.Errorf("Expected no Tapper in an empty context")
+	}
+}
@@ -0,0 +1,179 @@
+package dnstap
+
+import (
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	tap "github.com/dnstap/golang-dnstap"
+	fs "github.com/farsightsec/golang-framestream"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	queueSize     = 10000           // messages queued before we start dropping them
+	flushInterval = 1 * time.Second // how often the encoder is flushed, and a reconnect is tried
+	dialTimeout   = 2 * time.Second
+)
+
+// dnstapIO sends dnstap messages to a collector using the frame streams protocol. Messages are
+// queued and written from a separate goroutine; when the queue is full, or the collector can't be
+// reached, messages are dropped.
+type dnstapIO struct {
+	proto    string // "unix" or "tcp"
+	endpoint string
+
+	conn  net.Conn
+	enc   *fs.Encoder
+	queue chan tap.Dnstap // never closed, queries may still be in flight when we shut down
+	quit  chan struct{}   // closed to stop the goroutine sending the messages
+	done  chan struct{}   // closed when that goroutine is done
+
+	mu      sync.Mutex // protects started and closed
+	started bool
+	closed  bool
+}
+
+func newIO(proto, endpoint string) *dnstapIO {
+	return &dnstapIO{
+		proto:    proto,
+		endpoint: endpoint,
+		queue:    make(chan tap.Dnstap, queueSize),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Connect connects to the collector and starts the goroutine sending the messages. If the collector
+// can't be reached we will keep trying to connect in the background.
+func (d *dnstapIO) Connect() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed || d.started {
+		return
+	}
+	d.started = true
+
+	if err := d.dial(); err != nil {
+		log.Printf("[ERROR] No connection to dnstap endpoint %s: %s", d.endpoint, err)
+	}
+	go d.serve()
+}
+
+// Dnstap implements the IORoutine interface. It never blocks.
+func (d *dnstapIO) Dnstap(p tap.Dnstap) {
+	select {
+	case d.queue <- p:
+	default:
+		droppedCount.Inc()
+	}
+}
+
+// Close flushes the queued messages and closes the connection. Messages sent after Close are
+// dropped.
+func (d *dnstapIO) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	started := d.started
+	close(d.quit)
+	d.mu.Unlock()
+
+	if started {
+		<-d.done
+	}
+}
+
+func (d *dnstapIO) dial() error {
+	conn, err := net.DialTimeout(d.proto, d.endpoint, dialTimeout)
+	if err != nil {
+		return err
+	}
+	enc, err := fs.NewEncoder(conn, &fs.EncoderOptions{
+		ContentType:   []byte("protobuf:dnstap.Dnstap"),
+		Bidirectional: true,
+	})
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	d.conn, d.enc = conn, enc
+	return nil
+}
+
+func (d *dnstapIO) close() {
+	if d.enc != nil {
+		d.enc.Close()
+	}
+	if d.conn != nil {
+		d.conn.Close()
+	}
+	d.conn, d.enc = nil, nil
+}
+
+func (d *dnstapIO) write(p *tap.Dnstap) {
+	if d.enc == nil {
+		droppedCount.Inc()
+		return
+	}
+	buf, err := proto.Marshal(p)
+	if err != nil {
+		droppedCount.Inc()
+		return
+	}
+	if _, err := d.enc.Write(buf); err != nil {
+		log.Printf("[ERROR] Failed to write to dnstap endpoint %s: %s", d.endpoint, err)
+		droppedCount.Inc()
+		d.close()
+	}
+}
+
+func (d *dnstapIO) serve() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case p := <-d.queue:
+			d.write(&p)
+
+		case <-d.quit:
+			d.drain()
+			if d.enc != nil {
+				d.enc.Flush()
+			}
+			d.close()
+			return
+
+		case <-ticker.C:
+			if d.enc == nil {
+				if err := d.dial(); err == nil {
+					log.Printf("[INFO] Connected to dnstap endpoint %s", d.endpoint)
+				}
+				continue
+			}
+			if err := d.enc.Flush(); err != nil {
+				log.Printf("[ERROR] Failed to flush dnstap endpoint %s: %s", d.endpoint, err)
+				d.close()
+			}
+		}
+	}
+}
+
+// drain writes the messages that are queued.
+func (d *dnstapIO) drain() {
+	for {
+		select {
+		case p := <-d.queue:
+			d.write(&p)
+		default:
+			return
+		}
+	}
+}
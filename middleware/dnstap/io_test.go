@@ -0,0 +1,96 @@
+package dnstap
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	tap "github.com/dnstap/golang-dnstap"
+	fs "github.com/farsightsec/golang-framestream"
+	"github.com/golang/protobuf/proto"
+)
+
+func TestIO(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer l.Close()
+
+	frames := make(chan []byte, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		dec, err := fs.NewDecoder(conn, &fs.DecoderOptions{ContentType: []byte("protobuf:dnstap.Dnstap"), Bidirectional: true})
+		if err != nil {
+			return
+		}
+		for {
+			f, err := dec.Decode()
+			if err != nil {
+				close(frames)
+				return
+			}
+			frames <- f
+		}
+	}()
+
+	d := newIO("tcp", l.Addr().String())
+	d.Connect()
+
+	typ := tap.Dnstap_MESSAGE
+	d.Dnstap(tap.Dnstap{Type: &typ, Message: ClientQuery(&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 53}, time.Now()).ToMessage()})
+	d.Close()
+
+	select {
+	case f := <-frames:
+		p := tap.Dnstap{}
+		if err := proto.Unmarshal(f, &p); err != nil {
+			t.Fatalf("Failed to unmarshal frame: %s", err)
+		}
+		if *p.Message.Type != tap.Message_CLIENT_QUERY {
+			t.Errorf("Expected CLIENT_QUERY, got %s", p.Message.Type)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a frame")
+	}
+}
+
+func TestIODrop(t *testing.T) {
+	// Never connected, nothing is written.
+	d := newIO("unix", "/nonexistent/dnstap.sock")
+	d.queue = make(chan tap.Dnstap, 1)
+
+	typ := tap.Dnstap_MESSAGE
+	d.Dnstap(tap.Dnstap{Type: &typ})
+	d.Dnstap(tap.Dnstap{Type: &typ}) // queue is full, must not block
+
+	if len(d.queue) != 1 {
+		t.Errorf("Expected 1 queued message, got %d", len(d.queue))
+	}
+}
+
+func TestIOClose(t *testing.T) {
+	d := newIO("unix", "/nonexistent/dnstap.sock")
+
+	// Close before Connect must not block.
+	closed := make(chan struct{})
+	go func() {
+		d.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Close before Connect blocked")
+	}
+
+	// Queries still in flight after Close must not panic.
+	typ := tap.Dnstap_MESSAGE
+	d.Dnstap(tap.Dnstap{Type: &typ})
+	d.Close()
+	d.Connect()
+}
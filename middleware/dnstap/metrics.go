@@ -0,0 +1,18 @@
+package dnstap
+
+import (
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var droppedCount = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: middleware.Namespace,
+	Subsystem: "dnstap",
+	Name:      "dropped_total",
+	Help:      "Counter of dnstap messages that were dropped because the collector was slow or unreachable.",
+})
+
+func init() {
+	prometheus.MustRegister(droppedCount)
+}
@@ -0,0 +1,134 @@
+package dnstap
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	tap "github.com/dnstap/golang-dnstap"
+	"github.com/miekg/dns"
+)
+
+// Builder builds a dnstap message.
+type Builder struct {
+	typ      tap.Message_Type
+	ip       net.IP
+	port     uint32
+	proto    tap.SocketProtocol
+	query    time.Time
+	response time.Time
+	msg      []byte
+}
+
+// ClientQuery returns a builder for a CLIENT_QUERY message received from remote at t.
+func ClientQuery(remote net.Addr, t time.Time) *Builder {
+	b := &Builder{typ: tap.Message_CLIENT_QUERY, query: t}
+	b.addr(remote)
+	return b
+}
+
+// ClientResponse returns a builder for a CLIENT_RESPONSE message sent to remote at t, for a query
+// received at query.
+func ClientResponse(remote net.Addr, query, t time.Time) *Builder {
+	b := &Builder{typ: tap.Message_CLIENT_RESPONSE, query: query, response: t}
+	b.addr(remote)
+	return b
+}
+
+// ForwarderQuery returns a builder for a FORWARDER_QUERY message sent at t to host, which is an
+// address as used by proxy. Proto is "udp" or "tcp".
+func ForwarderQuery(host, proto string, t time.Time) *Builder {
+	b := &Builder{typ: tap.Message_FORWARDER_QUERY, query: t}
+	b.hostPort(host, proto)
+	return b
+}
+
+// ForwarderResponse returns a builder for a FORWARDER_RESPONSE message received from host at t,
+// for a query sent at query.
+func ForwarderResponse(host, proto string, query, t time.Time) *Builder {
+	b := &Builder{typ: tap.Message_FORWARDER_RESPONSE, query: query, response: t}
+	b.hostPort(host, proto)
+	return b
+}
+
+// Msg adds the wire format of m to the message. If m can't be packed it is left out.
+func (b *Builder) Msg(m *dns.Msg) *Builder {
+	if m == nil {
+		return b
+	}
+	buf, err := m.Pack()
+	if err == nil {
+		b.msg = buf
+	}
+	return b
+}
+
+// ToMessage returns the dnstap message.
+func (b *Builder) ToMessage() *tap.Message {
+	m := &tap.Message{Type: &b.typ}
+
+	if b.ip != nil {
+		family := tap.SocketFamily_INET
+		ip := b.ip.To4()
+		if ip == nil {
+			family = tap.SocketFamily_INET6
+			ip = b.ip
+		}
+		m.SocketFamily = &family
+		m.SocketProtocol = &b.proto
+
+		// The address of the client for client messages, of the upstream for forwarder messages.
+		switch b.typ {
+		case tap.Message_CLIENT_QUERY, tap.Message_CLIENT_RESPONSE:
+			m.QueryAddress = ip
+			m.QueryPort = &b.port
+		default:
+			m.ResponseAddress = ip
+			m.ResponsePort = &b.port
+		}
+	}
+
+	sec, nsec := timestamp(b.query)
+	m.QueryTimeSec, m.QueryTimeNsec = &sec, &nsec
+	if !b.response.IsZero() {
+		sec, nsec := timestamp(b.response)
+		m.ResponseTimeSec, m.ResponseTimeNsec = &sec, &nsec
+	}
+
+	switch b.typ {
+	case tap.Message_CLIENT_QUERY, tap.Message_FORWARDER_QUERY:
+		m.QueryMessage = b.msg
+	default:
+		m.ResponseMessage = b.msg
+	}
+	return m
+}
+
+func (b *Builder) addr(a net.Addr) {
+	switch x := a.(type) {
+	case *net.UDPAddr:
+		b.ip, b.port, b.proto = x.IP, uint32(x.Port), tap.SocketProtocol_UDP
+	case *net.TCPAddr:
+		b.ip, b.port, b.proto = x.IP, uint32(x.Port), tap.SocketProtocol_TCP
+	}
+}
+
+func (b *Builder) hostPort(host, proto string) {
+	h, p, err := net.SplitHostPort(host)
+	if err != nil {
+		return
+	}
+	port, err := strconv.ParseUint(p, 10, 16)
+	if err != nil {
+		return
+	}
+	b.ip, b.port = net.ParseIP(h), uint32(port)
+	b.proto = tap.SocketProtocol_UDP
+	if proto == "tcp" {
+		b.proto = tap.SocketProtocol_TCP
+	}
+}
+
+func timestamp(t time.Time) (uint64, uint32) {
+	return uint64(t.Unix()), uint32(t.Nanosecond())
+}
@@ -0,0 +1,53 @@
+package dnstap
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	tap "github.com/dnstap/golang-dnstap"
+	"github.com/miekg/dns"
+)
+
+func TestForwarderMessages(t *testing.T) {
+	start := time.Unix(1500000000, 500)
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+
+	q := ForwarderQuery("[2001:db8::53]:53", "tcp", start).Msg(m).ToMessage()
+	if *q.Type != tap.Message_FORWARDER_QUERY {
+		t.Errorf("Expected FORWARDER_QUERY, got %s", q.Type)
+	}
+	if *q.SocketFamily != tap.SocketFamily_INET6 || *q.SocketProtocol != tap.SocketProtocol_TCP {
+		t.Errorf("Expected INET6 and TCP, got %s and %s", q.SocketFamily, q.SocketProtocol)
+	}
+	if !net.IP(q.ResponseAddress).Equal(net.ParseIP("2001:db8::53")) || *q.ResponsePort != 53 {
+		t.Errorf("Expected response address [2001:db8::53]:53, got %s:%d", net.IP(q.ResponseAddress), *q.ResponsePort)
+	}
+	if *q.QueryTimeSec != 1500000000 || *q.QueryTimeNsec != 500 {
+		t.Errorf("Expected query time 1500000000.500, got %d.%d", *q.QueryTimeSec, *q.QueryTimeNsec)
+	}
+	if q.QueryMessage == nil || q.ResponseMessage != nil {
+		t.Errorf("Expected only the query message to be set")
+	}
+
+	r := ForwarderResponse("8.8.8.8:53", "udp", start, start.Add(time.Second)).ToMessage()
+	if *r.Type != tap.Message_FORWARDER_RESPONSE {
+		t.Errorf("Expected FORWARDER_RESPONSE, got %s", r.Type)
+	}
+	if *r.SocketFamily != tap.SocketFamily_INET || len(r.ResponseAddress) != net.IPv4len {
+		t.Errorf("Expected an INET response address, got %s", net.IP(r.ResponseAddress))
+	}
+	if *r.ResponseTimeSec != 1500000001 {
+		t.Errorf("Expected response time 1500000001, got %d", *r.ResponseTimeSec)
+	}
+	if r.ResponseMessage != nil {
+		t.Errorf("Expected no response message")
+	}
+
+	// Not an address, i.e. https_google.
+	g := ForwarderQuery("dns.google.com", "tcp", start).ToMessage()
+	if g.SocketFamily != nil || g.ResponseAddress != nil {
+		t.Errorf("Expected no address for a host name")
+	}
+}
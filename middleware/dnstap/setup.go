@@ -0,0 +1,98 @@
+package dnstap
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("dnstap", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+type config struct {
+	proto    string
+	endpoint string
+	full     bool
+}
+
+func setup(c *caddy.Controller) error {
+	conf, err := parseConfig(c)
+	if err != nil {
+		return middleware.Error("dnstap", err)
+	}
+
+	dio := newIO(conf.proto, conf.endpoint)
+
+	c.OnStartup(func() error {
+		dio.Connect()
+		return nil
+	})
+	c.OnShutdown(func() error {
+		dio.Close()
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
+		return Dnstap{Next: next, IO: dio, Full: conf.full}
+	})
+
+	return nil
+}
+
+func parseConfig(c *caddy.Controller) (config, error) {
+	conf := config{}
+	i := 0
+	for c.Next() { // dnstap
+		if i > 0 {
+			return conf, c.Err("dnstap can only be specified once")
+		}
+		i++
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 2:
+			if args[1] != "full" {
+				return conf, c.ArgErr()
+			}
+			conf.full = true
+			fallthrough
+		case 1:
+			var err error
+			conf.proto, conf.endpoint, err = parseEndpoint(args[0])
+			if err != nil {
+				return conf, err
+			}
+		default:
+			return conf, c.ArgErr()
+		}
+	}
+	return conf, nil
+}
+
+// parseEndpoint parses the SOCKET argument, this is either a unix socket path, optionally
+// prefixed with unix://, or tcp://host:port.
+func parseEndpoint(s string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(s, "tcp://"):
+		addr := s[len("tcp://"):]
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return "", "", fmt.Errorf("invalid dnstap endpoint %q: %s", s, err)
+		}
+		return "tcp", addr, nil
+	case strings.HasPrefix(s, "unix://"):
+		s = s[len("unix://"):]
+	}
+	if s == "" {
+		return "", "", fmt.Errorf("invalid dnstap endpoint: empty socket path")
+	}
+	return "unix", s, nil
+}
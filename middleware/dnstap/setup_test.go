@@ -0,0 +1,45 @@
+package dnstap
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		input        string
+		shouldErr    bool
+		expectedProt string
+		expectedEP   string
+		expectedFull bool
+	}{
+		{"dnstap /tmp/dnstap.sock", false, "unix", "/tmp/dnstap.sock", false},
+		{"dnstap unix:///tmp/dnstap.sock full", false, "unix", "/tmp/dnstap.sock", true},
+		{"dnstap tcp://127.0.0.1:6000", false, "tcp", "127.0.0.1:6000", false},
+		{"dnstap tcp://[::1]:6000 full", false, "tcp", "[::1]:6000", true},
+		// fails
+		{"dnstap", true, "", "", false},
+		{"dnstap /tmp/dnstap.sock partial", true, "", "", false},
+		{"dnstap tcp://127.0.0.1", true, "", "", false},
+		{"dnstap unix://", true, "", "", false},
+		{"dnstap /tmp/a.sock\ndnstap /tmp/b.sock", true, "", "", false},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		conf, err := parseConfig(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if conf.proto != test.expectedProt || conf.endpoint != test.expectedEP || conf.full != test.expectedFull {
+			t.Errorf("Test %d: expected %s %s %t, got %s %s %t", i, test.expectedProt, test.expectedEP, test.expectedFull, conf.proto, conf.endpoint, conf.full)
+		}
+	}
+}
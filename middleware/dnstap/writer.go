@@ -0,0 +1,24 @@
+package dnstap
+
+import (
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ResponseWriter logs the response as a CLIENT_RESPONSE message before writing it to the client.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	Tapper Tapper
+	Query  time.Time // time the query was received
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(m *dns.Msg) error {
+	b := ClientResponse(w.RemoteAddr(), w.Query, time.Now())
+	if w.Tapper.Pack() {
+		b.Msg(m)
+	}
+	w.Tapper.TapMessage(b.ToMessage())
+	return w.ResponseWriter.WriteMsg(m)
+}
@@ -0,0 +1,116 @@
+// Package ecs implements a middleware that handles the EDNS0 client subnet option, see RFC 7871.
+package ecs
+
+import (
+	"errors"
+	"net"
+
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// ECS validates the client subnet option sent by clients and, when Add is true, adds one derived
+// from the client's address to queries that don't have one. The option is removed from responses
+// to clients that didn't send it.
+type ECS struct {
+	Next  middleware.Handler
+	Zones []string
+
+	Add     bool
+	Source4 uint8 // source prefix length for IPv4 clients
+	Source6 uint8 // source prefix length for IPv6 clients
+}
+
+// ServeDNS implements the middleware.Handler interface.
+func (e ECS) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if middleware.Zones(e.Zones).Matches(state.Name()) == "" {
+		return middleware.NextOrFailure(e.Name(), e.Next, ctx, w, r)
+	}
+
+	if subnet := state.ECS(); subnet != nil {
+		if err := valid(subnet); err != nil {
+			return dns.RcodeFormatError, middleware.Error(e.Name(), err)
+		}
+		return middleware.NextOrFailure(e.Name(), e.Next, ctx, w, r)
+	}
+
+	sw := &ResponseWriter{ResponseWriter: w}
+	if e.Add {
+		if subnet := e.subnet(state.IP()); subnet != nil {
+			// Add the option to a copy, the client's query is left alone.
+			r = r.Copy()
+			o := r.IsEdns0()
+			if o == nil {
+				r.SetEdns0(dns.MinMsgSize, false)
+				o = r.IsEdns0()
+				sw.removeOPT = true
+			}
+			o.Option = append(o.Option, subnet)
+		}
+	}
+	return middleware.NextOrFailure(e.Name(), e.Next, ctx, sw, r)
+}
+
+// Name implements the middleware.Handler interface.
+func (e ECS) Name() string { return "ecs" }
+
+// subnet returns the client subnet option for the client address ip, with the address truncated to
+// the source prefix length.
+func (e ECS) subnet(ip string) *dns.EDNS0_SUBNET {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil
+	}
+	subnet := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET}
+	if v4 := addr.To4(); v4 != nil {
+		subnet.Family = 1
+		subnet.SourceNetmask = e.Source4
+		subnet.Address = v4.Mask(net.CIDRMask(int(e.Source4), net.IPv4len*8))
+		return subnet
+	}
+	subnet.Family = 2
+	subnet.SourceNetmask = e.Source6
+	subnet.Address = addr.Mask(net.CIDRMask(int(e.Source6), net.IPv6len*8))
+	return subnet
+}
+
+// valid checks the client subnet option in a query, RFC 7871, Section 7.1.2.
+func valid(subnet *dns.EDNS0_SUBNET) error {
+	var (
+		addr net.IP
+		bits int
+	)
+	switch subnet.Family {
+	case 1:
+		addr, bits = subnet.Address.To4(), net.IPv4len*8
+	case 2:
+		addr, bits = subnet.Address.To16(), net.IPv6len*8
+	default:
+		return errUnknownFamily
+	}
+	if addr == nil {
+		return errAddress
+	}
+	if int(subnet.SourceNetmask) > bits {
+		return errSourcePrefix
+	}
+	if subnet.SourceScope != 0 {
+		return errScopePrefix
+	}
+	// The address may not have bits set beyond the source prefix length.
+	if !addr.Mask(net.CIDRMask(int(subnet.SourceNetmask), bits)).Equal(addr) {
+		return errAddress
+	}
+	return nil
+}
+
+var (
+	errUnknownFamily = errors.New("unknown client subnet family")
+	errAddress       = errors.New("client subnet address does not match its source prefix length")
+	errSourcePrefix  = errors.New("client subnet source prefix length too long")
+	errScopePrefix   = errors.New("client subnet scope prefix length set in query")
+)
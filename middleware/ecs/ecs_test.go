@@ -0,0 +1,135 @@
+package ecs
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// backend echoes the client subnet it receives, like an upstream that supports ECS does.
+type backend struct {
+	subnet string
+}
+
+func (b *backend) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Answer = []dns.RR{test.A("example.org. 3600 IN A 192.0.2.53")}
+
+	b.subnet = ""
+	if e := state.ECS(); e != nil {
+		b.subnet = e.Address.String() + "/" + strconv.Itoa(int(e.SourceNetmask))
+		m.SetEdns0(4096, false)
+		m.IsEdns0().Option = []dns.EDNS0{&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: e.Family,
+			SourceNetmask: e.SourceNetmask, SourceScope: e.SourceNetmask, Address: e.Address}}
+	} else {
+		state.SizeAndDo(m)
+	}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+func (b *backend) Name() string { return "backend" }
+
+// responseWriter6 is a test.ResponseWriter with an IPv6 remote address.
+type responseWriter6 struct {
+	test.ResponseWriter
+}
+
+func (w *responseWriter6) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.ParseIP("2001:db8:1234:5678::1"), Port: 40212}
+}
+
+func TestECS(t *testing.T) {
+	tests := []struct {
+		add    bool
+		edns   bool
+		subnet *dns.EDNS0_SUBNET // sent by the client
+		w      dns.ResponseWriter
+
+		rcode    int
+		upstream string // the subnet the backend sees
+		ecs      bool   // client subnet in the response
+	}{
+		// No EDNS0 from the client, the OPT record must be removed again.
+		{add: true, w: &test.ResponseWriter{}, upstream: "10.240.0.0/24"},
+		{add: true, edns: true, w: &test.ResponseWriter{}, upstream: "10.240.0.0/24"},
+		{add: true, edns: true, w: &responseWriter6{}, upstream: "2001:db8:1234:5600::/56"},
+		{edns: true, w: &test.ResponseWriter{}},
+		// The client's subnet is left alone.
+		{
+			add: true, edns: true, w: &test.ResponseWriter{},
+			subnet:   &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 16, Address: net.ParseIP("192.0.0.0").To4()},
+			upstream: "192.0.0.0/16", ecs: true,
+		},
+		// Host bits set beyond the source prefix length.
+		{
+			add: true, edns: true, w: &test.ResponseWriter{},
+			subnet: &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.1").To4()},
+			rcode:  dns.RcodeFormatError,
+		},
+		// Scope prefix length set in a query.
+		{
+			edns: true, w: &test.ResponseWriter{},
+			subnet: &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 24, Address: net.ParseIP("192.0.2.0").To4()},
+			rcode:  dns.RcodeFormatError,
+		},
+		{
+			edns: true, w: &test.ResponseWriter{},
+			subnet: &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 3, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()},
+			rcode:  dns.RcodeFormatError,
+		},
+	}
+
+	for i, tc := range tests {
+		b := &backend{}
+		e := ECS{Next: b, Zones: []string{"."}, Add: tc.add, Source4: defaultSource4, Source6: defaultSource6}
+
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		if tc.edns {
+			m.SetEdns0(4096, false)
+		}
+		if tc.subnet != nil {
+			m.IsEdns0().Option = append(m.IsEdns0().Option, tc.subnet)
+		}
+
+		before := m.String()
+
+		rec := dnsrecorder.New(tc.w)
+		rcode, err := e.ServeDNS(context.TODO(), rec, m)
+		if m.String() != before {
+			t.Errorf("Test %d: expected the client's query to be left alone", i)
+		}
+		if rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rcode)
+			continue
+		}
+		if tc.rcode != dns.RcodeSuccess {
+			if err == nil {
+				t.Errorf("Test %d: expected an error", i)
+			}
+			continue
+		}
+
+		if b.subnet != tc.upstream {
+			t.Errorf("Test %d: expected the backend to see subnet %q, got %q", i, tc.upstream, b.subnet)
+		}
+		if tc.edns != (rec.Msg.IsEdns0() != nil) {
+			t.Errorf("Test %d: expected OPT record in the response to be %t", i, tc.edns)
+		}
+		resp := request.Request{W: rec, Req: rec.Msg}
+		if (resp.ECS() != nil) != tc.ecs {
+			t.Errorf("Test %d: expected client subnet in the response to be %t", i, tc.ecs)
+		}
+	}
+}
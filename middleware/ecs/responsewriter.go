@@ -0,0 +1,45 @@
+package ecs
+
+import "github.com/miekg/dns"
+
+// ResponseWriter removes the client subnet option from responses to clients that didn't send one.
+// If the OPT record was added for the client subnet, the entire OPT record is removed.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	removeOPT bool
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	if w.removeOPT {
+		extra := res.Extra[:0]
+		for _, rr := range res.Extra {
+			if rr.Header().Rrtype != dns.TypeOPT {
+				extra = append(extra, rr)
+			}
+		}
+		res.Extra = extra
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	if o := res.IsEdns0(); o != nil {
+		options := o.Option[:0]
+		for _, e := range o.Option {
+			if e.Option() != dns.EDNS0SUBNET {
+				options = append(options, e)
+			}
+		}
+		o.Option = options
+	}
+	return w.ResponseWriter.WriteMsg(res)
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) Write(buf []byte) (int, error) {
+	return w.ResponseWriter.Write(buf)
+}
+
+// Hijack implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) Hijack() {
+	w.ResponseWriter.Hijack()
+}
@@ -0,0 +1,98 @@
+package ecs
+
+import (
+	"strconv"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("ecs", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	e, err := ecsParse(c)
+	if err != nil {
+		return middleware.Error("ecs", err)
+	}
+
+	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
+		e.Next = next
+		return e
+	})
+
+	return nil
+}
+
+func ecsParse(c *caddy.Controller) (ECS, error) {
+	e := ECS{Source4: defaultSource4, Source6: defaultSource6}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return e, c.Err("ecs can only be specified once per server block")
+		}
+		i++
+
+		e.Zones = c.RemainingArgs()
+		if len(e.Zones) == 0 {
+			e.Zones = make([]string, len(c.ServerBlockKeys))
+			copy(e.Zones, c.ServerBlockKeys)
+		}
+		for j := range e.Zones {
+			e.Zones[j] = middleware.Host(e.Zones[j]).Normalize()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "add":
+				args := c.RemainingArgs()
+				if len(args) > 2 {
+					return e, c.ArgErr()
+				}
+				e.Add = true
+				if len(args) > 0 {
+					n, err := parseSource(args[0], 32)
+					if err != nil {
+						return e, c.Errf("invalid IPv4 source prefix length %q", args[0])
+					}
+					e.Source4 = n
+				}
+				if len(args) > 1 {
+					n, err := parseSource(args[1], 128)
+					if err != nil {
+						return e, c.Errf("invalid IPv6 source prefix length %q", args[1])
+					}
+					e.Source6 = n
+				}
+			default:
+				return e, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	return e, nil
+}
+
+// parseSource parses s as a prefix length of at most max bits.
+func parseSource(s string, max int) (uint8, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > max {
+		return 0, errSourcePrefix
+	}
+	return uint8(n), nil
+}
+
+// The source prefix lengths recommended by RFC 7871, Section 11.1.
+const (
+	defaultSource4 = 24
+	defaultSource6 = 56
+)
@@ -0,0 +1,67 @@
+package ecs
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupECS(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		add       bool
+		source4   uint8
+		source6   uint8
+	}{
+		{`ecs`, false, false, 24, 56},
+		{`ecs {
+			add
+		}`, false, true, 24, 56},
+		{`ecs example.org {
+			add 20
+		}`, false, true, 20, 56},
+		{`ecs {
+			add 32 0
+		}`, false, true, 32, 0},
+		// fails
+		{`ecs {
+			add 33
+		}`, true, false, 0, 0},
+		{`ecs {
+			add 24 129
+		}`, true, false, 0, 0},
+		{`ecs {
+			add 24 56 64
+		}`, true, false, 0, 0},
+		{`ecs {
+			add foo
+		}`, true, false, 0, 0},
+		{`ecs {
+			unknown
+		}`, true, false, 0, 0},
+		{`ecs
+ecs`, true, false, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		e, err := ecsParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+
+		if e.Add != test.add {
+			t.Errorf("Test %d expected add to be %t, got %t", i, test.add, e.Add)
+		}
+		if e.Source4 != test.source4 || e.Source6 != test.source6 {
+			t.Errorf("Test %d expected source prefix lengths %d/%d, got %d/%d", i, test.source4, test.source6, e.Source4, e.Source6)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/roller"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -20,7 +21,8 @@ type errorHandler struct {
 	Next    middleware.Handler
 	LogFile string
 	Log     *log.Logger
-	Debug   bool // if true, errors are written out to client rather than to a log
+	Debug   bool           // if true, errors are written out to client rather than to a log
+	Roller  *roller.Roller // if not nil, LogFile is rotated
 }
 
 // ServeDNS implements the middleware.Handler interface.
@@ -7,6 +7,7 @@ import (
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/roller"
 
 	"github.com/hashicorp/go-syslog"
 	"github.com/mholt/caddy"
@@ -45,6 +46,11 @@ func setup(c *caddy.Controller) error {
 			break
 		}
 
+		if handler.Roller != nil {
+			writer = handler.Roller.Writer(handler.LogFile)
+			break
+		}
+
 		var file *os.File
 		file, err = os.OpenFile(handler.LogFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
 		if err != nil {
@@ -72,6 +78,15 @@ func errorsParse(c *caddy.Controller) (errorHandler, error) {
 			hadBlock = true
 
 			what := c.Val()
+			if roller.IsDirective(what) {
+				if handler.Roller == nil {
+					handler.Roller = roller.Default()
+				}
+				if err := handler.Roller.Parse(what, c.RemainingArgs()); err != nil {
+					return hadBlock, err
+				}
+				continue
+			}
 			if !c.NextArg() {
 				return hadBlock, c.ArgErr()
 			}
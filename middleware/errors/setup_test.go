@@ -1,8 +1,11 @@
 package errors
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/coredns/coredns/middleware/pkg/roller"
+
 	"github.com/mholt/caddy"
 )
 
@@ -26,6 +29,20 @@ func TestErrorsParse(t *testing.T) {
 			LogFile: "",
 			Debug:   true,
 		}},
+		{`errors {
+			log errors.txt
+			rotate_size 10
+			rotate_age 7
+		}`, false, errorHandler{
+			LogFile: "errors.txt",
+			Roller:  &roller.Roller{MaxSize: 10, MaxAge: 7, MaxBackups: 10},
+		}},
+		{`errors {
+			log errors.txt
+			rotate_keep
+		}`, true, errorHandler{
+			LogFile: "errors.txt",
+		}},
 	}
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.inputErrorsRules)
@@ -40,6 +57,10 @@ func TestErrorsParse(t *testing.T) {
 			t.Errorf("Test %d expected LogFile to be %s, but got %s",
 				i, test.expectedErrorHandler.LogFile, actualErrorsRule.LogFile)
 		}
+		if !test.shouldErr && !reflect.DeepEqual(actualErrorsRule.Roller, test.expectedErrorHandler.Roller) {
+			t.Errorf("Test %d expected Roller to be %v, but got %v",
+				i, test.expectedErrorHandler.Roller, actualErrorsRule.Roller)
+		}
 		if actualErrorsRule.Debug != test.expectedErrorHandler.Debug {
 			t.Errorf("Test %d expected Debug to be %v, but got %v",
 				i, test.expectedErrorHandler.Debug, actualErrorsRule.Debug)
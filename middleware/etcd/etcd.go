@@ -69,6 +69,12 @@ func (e *Etcd) Debug() string {
 	return e.PathPrefix
 }
 
+// Health implements the health.Healther interface. Etcd is healthy when etcd can be reached.
+func (e *Etcd) Health() bool {
+	_, err := e.get("/"+e.PathPrefix, false)
+	return err == nil || e.IsNameError(err)
+}
+
 // Records looks up records in etcd. If exact is true, it will lookup just this
 // name. This is used when find matches when completing SRV lookups for instance.
 func (e *Etcd) Records(name string, exact bool) ([]msg.Service, error) {
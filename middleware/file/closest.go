@@ -22,3 +22,35 @@ func (z *Zone) ClosestEncloser(qname string) (*tree.Elem, bool) {
 
 	return z.Tree.Search(z.origin)
 }
+
+// closestEncloserNSEC3 returns the closest encloser and the next closer name for qname as
+// defined in RFC 5155, section 7.2.1. The closest encloser is the longest ancestor of qname
+// that has a matching NSEC3 record; because empty-non-terminals have NSEC3 records as well,
+// they can be a closest encloser.
+func (z *Zone) closestEncloserNSEC3(qname string) (ce, nc string) {
+	nc = qname
+	offset, end := dns.NextLabel(qname, 0)
+	for !end {
+		name := qname[offset:]
+		if !dns.IsSubDomain(z.origin, name) {
+			break
+		}
+		if _, found := z.matchNSEC3(name); found {
+			return name, nc
+		}
+		nc = name
+
+		offset, end = dns.NextLabel(qname, offset)
+	}
+
+	return z.origin, nc
+}
+
+// closestEncloserProof returns the closest encloser for qname and the elements holding the NSEC3
+// records that make up the proof: one matching the closest encloser and one covering the next
+// closer name.
+func (z *Zone) closestEncloserProof(qname string) (string, []*tree.Elem) {
+	ce, nc := z.closestEncloserNSEC3(qname)
+	match, _ := z.matchNSEC3(ce)
+	return ce, []*tree.Elem{match, z.coverNSEC3(nc)}
+}
@@ -108,6 +108,27 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 // Name implements the Handler interface.
 func (f File) Name() string { return "file" }
 
+// Health implements the health.Healther interface. File is unhealthy when a secondary zone has
+// expired.
+func (f File) Health() bool {
+	for _, z := range f.Zones.Z {
+		if z.Expired != nil && *z.Expired {
+			return false
+		}
+	}
+	return true
+}
+
+// Ready implements the health.Readiness interface. File is ready when all its zones are loaded.
+func (f File) Ready() bool {
+	for _, z := range f.Zones.Z {
+		if !z.Loaded() {
+			return false
+		}
+	}
+	return true
+}
+
 // Parse parses the zone in filename and returns a new Zone or an error.
 func Parse(f io.Reader, origin, fileName string) (*Zone, error) {
 	tokens := dns.ParseZone(f, dns.Fqdn(origin), fileName)
@@ -10,3 +10,36 @@ func BenchmarkParseInsert(b *testing.B) {
 		Parse(strings.NewReader(dbMiekENTNL), testzone, "stdin")
 	}
 }
+
+func TestReady(t *testing.T) {
+	zone, err := Parse(strings.NewReader(dbMiekENTNL), testzone, "stdin")
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+	f := File{Zones: Zones{Z: map[string]*Zone{testzone: zone}, Names: []string{testzone}}}
+	if !f.Ready() {
+		t.Errorf("Expected file to be ready")
+	}
+
+	// A secondary zone that has not been transferred yet.
+	f.Zones.Z["example.org."] = NewZone("example.org.", "stdin")
+	f.Zones.Names = append(f.Zones.Names, "example.org.")
+	if f.Ready() {
+		t.Errorf("Expected file not to be ready")
+	}
+}
+
+func TestHealth(t *testing.T) {
+	zone, err := Parse(strings.NewReader(dbMiekENTNL), testzone, "stdin")
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+	f := File{Zones: Zones{Z: map[string]*Zone{testzone: zone}, Names: []string{testzone}}}
+	if !f.Health() {
+		t.Errorf("Expected file to be healthy")
+	}
+	*zone.Expired = true
+	if f.Health() {
+		t.Errorf("Expected file to be unhealthy with an expired zone")
+	}
+}
@@ -30,11 +30,11 @@ func (z *Zone) Lookup(state request.Request, qname string) ([]dns.RR, []dns.RR,
 	qtype := state.QType()
 	do := state.Do()
 
-	if !z.NoReload {
+	if !z.noLock() {
 		z.reloadMu.RLock()
 	}
 	defer func() {
-		if !z.NoReload {
+		if !z.noLock() {
 			z.reloadMu.RUnlock()
 		}
 	}()
@@ -105,6 +105,14 @@ func (z *Zone) Lookup(state request.Request, qname string) ([]dns.RR, []dns.RR,
 
 			if do {
 				dss := z.typeFromElem(elem, dns.TypeDS, do)
+				if len(dss) == 0 {
+					// Insecure delegation, prove the DS does not exist.
+					if z.isNSEC3() {
+						dss = z.nodataNSEC3(parts)
+					} else {
+						dss = z.typeFromElem(elem, dns.TypeNSEC, do)
+					}
+				}
 				nsrrs = append(nsrrs, dss...)
 			}
 
@@ -133,8 +141,12 @@ func (z *Zone) Lookup(state request.Request, qname string) ([]dns.RR, []dns.RR,
 		if len(rrs) == 0 {
 			ret := z.soa(do)
 			if do {
-				nsec := z.typeFromElem(elem, dns.TypeNSEC, do)
-				ret = append(ret, nsec...)
+				if z.isNSEC3() {
+					ret = append(ret, z.nodataNSEC3(qname)...)
+				} else {
+					nsec := z.typeFromElem(elem, dns.TypeNSEC, do)
+					ret = append(ret, nsec...)
+				}
 			}
 			return nil, ret, nil, NoData
 		}
@@ -169,17 +181,30 @@ func (z *Zone) Lookup(state request.Request, qname string) ([]dns.RR, []dns.RR,
 		if len(rrs) == 0 {
 			ret := z.soa(do)
 			if do {
-				nsec := z.typeFromElem(wildElem, dns.TypeNSEC, do)
-				ret = append(ret, nsec...)
+				if z.isNSEC3() {
+					// Closest encloser proof and the NSEC3 matching the wildcard, RFC 5155, section 7.2.5.
+					_, proof := z.closestEncloserProof(qname)
+					match, _ := z.matchNSEC3(wildElem.Name())
+					ret = append(ret, z.nsec3Records(append(proof, match)...)...)
+				} else {
+					nsec := z.typeFromElem(wildElem, dns.TypeNSEC, do)
+					ret = append(ret, nsec...)
+				}
 			}
 			return nil, ret, nil, Success
 		}
 
 		if do {
-			// An NSEC is needed to say no longer name exists under this wildcard.
-			if deny, found := z.Tree.Prev(qname); found {
-				nsec := z.typeFromElem(deny, dns.TypeNSEC, do)
-				auth = append(auth, nsec...)
+			if z.isNSEC3() {
+				// An NSEC3 covering the next closer name, RFC 5155, section 7.2.6.
+				_, nc := z.closestEncloserNSEC3(qname)
+				auth = append(auth, z.nsec3Records(z.coverNSEC3(nc))...)
+			} else {
+				// An NSEC is needed to say no longer name exists under this wildcard.
+				if deny, found := z.Tree.Prev(qname); found {
+					nsec := z.typeFromElem(deny, dns.TypeNSEC, do)
+					auth = append(auth, nsec...)
+				}
 			}
 
 			sigs := wildElem.Types(dns.TypeRRSIG, qname)
@@ -201,6 +226,17 @@ func (z *Zone) Lookup(state request.Request, qname string) ([]dns.RR, []dns.RR,
 	}
 
 	ret := z.soa(do)
+	if do && z.isNSEC3() {
+		if rcode != NameError {
+			ret = append(ret, z.nodataNSEC3(qname)...)
+			return nil, ret, nil, rcode
+		}
+
+		// Closest encloser proof and the NSEC3 covering the wildcard, RFC 5155, section 7.2.2.
+		ce, proof := z.closestEncloserProof(qname)
+		ret = append(ret, z.nsec3Records(append(proof, z.wildcardNSEC3(ce))...)...)
+		return nil, ret, nil, rcode
+	}
 	if do {
 		deny, found := z.Tree.Prev(qname)
 		nsec := z.typeFromElem(deny, dns.TypeNSEC, do)
@@ -0,0 +1,75 @@
+package file
+
+import (
+	"strings"
+
+	"github.com/coredns/coredns/middleware/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// isNSEC3 returns true when the zone holds an NSEC3 chain.
+func (z *Zone) isNSEC3() bool { return z.nsec3 != nil && z.nsec3.Len() > 0 }
+
+// hashName returns the NSEC3 owner name for name. The parameters from the NSEC3PARAM record are
+// used, if the zone doesn't have one we take them from the NSEC3 chain itself.
+func (z *Zone) hashName(name string) string {
+	var (
+		hash  uint8
+		iter  uint16
+		salt  string
+		param = z.Apex.NSEC3PARAM
+	)
+
+	if param != nil {
+		hash, iter, salt = param.Hash, param.Iterations, param.Salt
+	} else {
+		if e := z.nsec3.Min(); e != nil {
+			if rrs := e.Types(dns.TypeNSEC3); len(rrs) > 0 {
+				x := rrs[0].(*dns.NSEC3)
+				hash, iter, salt = x.Hash, x.Iterations, x.Salt
+			}
+		}
+	}
+	return strings.ToLower(dns.HashName(name, hash, iter, salt)) + "." + z.origin
+}
+
+// matchNSEC3 returns the element holding the NSEC3 record that matches name.
+func (z *Zone) matchNSEC3(name string) (*tree.Elem, bool) {
+	return z.nsec3.Search(z.hashName(name))
+}
+
+// coverNSEC3 returns the element holding the NSEC3 record that covers name. If the hash of name
+// sorts before the first NSEC3 record, the last one covers it.
+func (z *Zone) coverNSEC3(name string) *tree.Elem {
+	if e, found := z.nsec3.Prev(z.hashName(name)); found {
+		return e
+	}
+	return z.nsec3.Max()
+}
+
+// nsec3Records returns the NSEC3 records (and their signatures) from elems. Duplicates and nil
+// elements are skipped.
+func (z *Zone) nsec3Records(elems ...*tree.Elem) []dns.RR {
+	rrs := []dns.RR{}
+	seen := make(map[string]bool)
+	for _, e := range elems {
+		if e == nil || seen[e.Name()] {
+			continue
+		}
+		seen[e.Name()] = true
+		rrs = append(rrs, z.typeFromElem(e, dns.TypeNSEC3, true)...)
+	}
+	return rrs
+}
+
+// nodataNSEC3 returns the NSEC3 records proving qname has no data for the queried type, see
+// RFC 5155, section 7.2.3 and 7.2.4. When there is no matching NSEC3 (i.e. an opt-out span) the
+// closest encloser proof is returned instead.
+func (z *Zone) nodataNSEC3(qname string) []dns.RR {
+	if e, found := z.matchNSEC3(qname); found {
+		return z.nsec3Records(e)
+	}
+	_, proof := z.closestEncloserProof(qname)
+	return z.nsec3Records(proof...)
+}
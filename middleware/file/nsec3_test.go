@@ -1,28 +1,235 @@
 package file
 
 import (
+	"sort"
 	"strings"
 	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
 )
 
-func TestParseNSEC3PARAM(t *testing.T) {
-	_, err := Parse(strings.NewReader(nsec3paramTest), "miek.nl", "stdin")
-	if err == nil {
-		t.Fatalf("expected error when reading zone, got nothing")
+func TestParseNSEC3(t *testing.T) {
+	zone, err := Parse(strings.NewReader(dbExampleOrgNSEC3), "example.org.", "stdin")
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+	if !zone.isNSEC3() {
+		t.Fatalf("Expected zone to hold an NSEC3 chain")
+	}
+	if zone.Apex.NSEC3PARAM == nil {
+		t.Fatalf("Expected NSEC3PARAM to be set")
+	}
+	if x := zone.hashName("a.example.org."); x != "kqcvvkcpqombctogo83kfe7tag87p775.example.org." {
+		t.Errorf("Expected hashed name to be %s, got %s", "kqcvvkcpqombctogo83kfe7tag87p775.example.org.", x)
+	}
+	if l := len(zone.All()); l != 30 {
+		t.Errorf("Expected %d records in the zone, got %d", 30, l)
 	}
 }
 
-func TestParseNSEC3(t *testing.T) {
-	_, err := Parse(strings.NewReader(nsec3Test), "miek.nl", "stdin")
+func TestParseNSEC3NotBelowApex(t *testing.T) {
+	_, err := Parse(strings.NewReader(nsec3WrongOwner), "example.org.", "stdin")
 	if err == nil {
-		t.Fatalf("expected error when reading zone, got nothing")
+		t.Fatalf("Expected error when reading zone, got nothing")
+	}
+}
+
+var nsec3TestCases = []test.Case{
+	// NXDOMAIN: closest encloser is the apex, the wildcard is covered by the apex NSEC3 as well
+	{
+		Qname: "x.example.org.", Qtype: dns.TypeA, Do: true,
+		Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.RRSIG("example.org.	3600	IN	RRSIG	SOA 8 2 3600 20170419190224 20170320190224 31054 example.org. JmDC4rr9KOMGdkuvFRXB8ZD8="),
+			test.SOA("example.org.	3600	IN	SOA	ns1.example.org. hostmaster.example.org. 2017032001 7200 3600 1209600 3600"),
+			test.NSEC3("ood0a3dkjemftqsq27m7ukjusdfdv3lr.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD RKOF8QMFRB5F2V9EJHFBVB2JPVSA0DJD A RRSIG"),
+			test.RRSIG("ood0a3dkjemftqsq27m7ukjusdfdv3lr.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G="),
+			test.NSEC3("rkof8qmfrb5f2v9ejhfbvb2jpvsa0djd.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD 4FM5U53HMFU8TNU9MFE36OAHVSN8V51S NS SOA RRSIG DNSKEY NSEC3PARAM"),
+			test.RRSIG("rkof8qmfrb5f2v9ejhfbvb2jpvsa0djd.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G="),
+		},
+		Extra: []dns.RR{test.OPT(4096, true)},
+	},
+	// NXDOMAIN: closest encloser is a.example.org., next closer and wildcard covered by the same NSEC3
+	{
+		Qname: "z.y.a.example.org.", Qtype: dns.TypeA, Do: true,
+		Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.NSEC3("4mfuvd5c0siqu4upgpr0j5m3unu49okc.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD KFPEGRELP0U83A4E8L9A9K4VLJC5QLU6 TXT RRSIG"),
+			test.RRSIG("4mfuvd5c0siqu4upgpr0j5m3unu49okc.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G="),
+			test.RRSIG("example.org.	3600	IN	RRSIG	SOA 8 2 3600 20170419190224 20170320190224 31054 example.org. JmDC4rr9KOMGdkuvFRXB8ZD8="),
+			test.SOA("example.org.	3600	IN	SOA	ns1.example.org. hostmaster.example.org. 2017032001 7200 3600 1209600 3600"),
+			test.NSEC3("kqcvvkcpqombctogo83kfe7tag87p775.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD OOD0A3DKJEMFTQSQ27M7UKJUSDFDV3LR A RRSIG"),
+			test.RRSIG("kqcvvkcpqombctogo83kfe7tag87p775.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G="),
+		},
+		Extra: []dns.RR{test.OPT(4096, true)},
+	},
+	// NODATA
+	{
+		Qname: "a.example.org.", Qtype: dns.TypeTXT, Do: true,
+		Ns: []dns.RR{
+			test.RRSIG("example.org.	3600	IN	RRSIG	SOA 8 2 3600 20170419190224 20170320190224 31054 example.org. JmDC4rr9KOMGdkuvFRXB8ZD8="),
+			test.SOA("example.org.	3600	IN	SOA	ns1.example.org. hostmaster.example.org. 2017032001 7200 3600 1209600 3600"),
+			test.NSEC3("kqcvvkcpqombctogo83kfe7tag87p775.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD OOD0A3DKJEMFTQSQ27M7UKJUSDFDV3LR A RRSIG"),
+			test.RRSIG("kqcvvkcpqombctogo83kfe7tag87p775.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G="),
+		},
+		Extra: []dns.RR{test.OPT(4096, true)},
+	},
+	// NODATA for an empty-non-terminal
+	{
+		Qname: "c.example.org.", Qtype: dns.TypeA, Do: true,
+		Ns: []dns.RR{
+			test.NSEC3("4fm5u53hmfu8tnu9mfe36oahvsn8v51s.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD 4GKOIVBOF1N7MCNKOMFLUPJEA1UJRUBD"),
+			test.RRSIG("4fm5u53hmfu8tnu9mfe36oahvsn8v51s.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G="),
+			test.RRSIG("example.org.	3600	IN	RRSIG	SOA 8 2 3600 20170419190224 20170320190224 31054 example.org. JmDC4rr9KOMGdkuvFRXB8ZD8="),
+			test.SOA("example.org.	3600	IN	SOA	ns1.example.org. hostmaster.example.org. 2017032001 7200 3600 1209600 3600"),
+		},
+		Extra: []dns.RR{test.OPT(4096, true)},
+	},
+	// Wildcard expansion: next closer name covered
+	{
+		Qname: "y.w.example.org.", Qtype: dns.TypeTXT, Do: true,
+		Answer: []dns.RR{
+			test.RRSIG("y.w.example.org.	3600	IN	RRSIG	TXT 8 3 3600 20170419190224 20170320190224 31054 example.org. Glpp5ffQu+hq2EtrRjp="),
+			test.TXT(`y.w.example.org.	3600	IN	TXT	"wildcard"`),
+		},
+		Ns: []dns.RR{
+			test.NS("example.org.	3600	IN	NS	ns1.example.org."),
+			test.RRSIG("example.org.	3600	IN	RRSIG	NS 8 2 3600 20170419190224 20170320190224 31054 example.org. K4Q8cAgbmFhZ7+9BqhLu7="),
+			test.NSEC3("rkof8qmfrb5f2v9ejhfbvb2jpvsa0djd.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD 4FM5U53HMFU8TNU9MFE36OAHVSN8V51S NS SOA RRSIG DNSKEY NSEC3PARAM"),
+			test.RRSIG("rkof8qmfrb5f2v9ejhfbvb2jpvsa0djd.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G="),
+		},
+		Extra: []dns.RR{test.OPT(4096, true)},
+	},
+	// Wildcard NODATA: closest encloser proof and matching wildcard
+	{
+		Qname: "y.w.example.org.", Qtype: dns.TypeA, Do: true,
+		Ns: []dns.RR{
+			test.NSEC3("4mfuvd5c0siqu4upgpr0j5m3unu49okc.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD KFPEGRELP0U83A4E8L9A9K4VLJC5QLU6 TXT RRSIG"),
+			test.RRSIG("4mfuvd5c0siqu4upgpr0j5m3unu49okc.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G="),
+			test.RRSIG("example.org.	3600	IN	RRSIG	SOA 8 2 3600 20170419190224 20170320190224 31054 example.org. JmDC4rr9KOMGdkuvFRXB8ZD8="),
+			test.SOA("example.org.	3600	IN	SOA	ns1.example.org. hostmaster.example.org. 2017032001 7200 3600 1209600 3600"),
+			test.NSEC3("kfpegrelp0u83a4e8l9a9k4vljc5qlu6.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD KQCVVKCPQOMBCTOGO83KFE7TAG87P775"),
+			test.RRSIG("kfpegrelp0u83a4e8l9a9k4vljc5qlu6.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G="),
+			test.NSEC3("rkof8qmfrb5f2v9ejhfbvb2jpvsa0djd.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD 4FM5U53HMFU8TNU9MFE36OAHVSN8V51S NS SOA RRSIG DNSKEY NSEC3PARAM"),
+			test.RRSIG("rkof8qmfrb5f2v9ejhfbvb2jpvsa0djd.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G="),
+		},
+		Extra: []dns.RR{test.OPT(4096, true)},
+	},
+	// Insecure delegation: NSEC3 proving there is no DS
+	{
+		Qname: "sub.example.org.", Qtype: dns.TypeA, Do: true,
+		Ns: []dns.RR{
+			test.NSEC3("4gkoivbof1n7mcnkomflupjea1ujrubd.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD 4MFUVD5C0SIQU4UPGPR0J5M3UNU49OKC NS"),
+			test.RRSIG("4gkoivbof1n7mcnkomflupjea1ujrubd.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G="),
+			test.NS("sub.example.org.	3600	IN	NS	ns.sub.example.org."),
+		},
+		Extra: []dns.RR{
+			test.OPT(4096, true),
+			test.A("ns.sub.example.org.	3600	IN	A	192.0.2.53"),
+		},
+	},
+}
+
+func TestLookupNSEC3(t *testing.T) {
+	zone, err := Parse(strings.NewReader(dbExampleOrgNSEC3), "example.org.", "stdin")
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+
+	fm := File{Next: test.ErrorHandler(), Zones: Zones{Z: map[string]*Zone{"example.org.": zone}, Names: []string{"example.org."}}}
+	ctx := context.TODO()
+
+	for _, tc := range nsec3TestCases {
+		m := tc.Msg()
+
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		_, err := fm.ServeDNS(ctx, rec, m)
+		if err != nil {
+			t.Errorf("Expected no error, got %v\n", err)
+			return
+		}
+
+		resp := rec.Msg
+		sort.Sort(test.RRSet(resp.Answer))
+		sort.Sort(test.RRSet(resp.Ns))
+		sort.Sort(test.RRSet(resp.Extra))
+
+		if !test.Header(t, tc, resp) {
+			t.Logf("%v\n", resp)
+			continue
+		}
+		if !test.Section(t, tc, test.Answer, resp.Answer) {
+			t.Logf("%v\n", resp)
+		}
+		if !test.Section(t, tc, test.Ns, resp.Ns) {
+			t.Logf("%v\n", resp)
+		}
+		if !test.Section(t, tc, test.Extra, resp.Extra) {
+			t.Logf("%v\n", resp)
+		}
 	}
 }
 
-const nsec3paramTest = `miek.nl.	1800	IN	SOA	linode.atoom.net. miek.miek.nl. 1460175181 14400 3600 604800 14400
-miek.nl.		1800	IN	NS	omval.tednet.nl.
-miek.nl.		0	IN	NSEC3PARAM 1 0 5 A3DEBC9CC4F695C7`
+// Zone signed with: dnssec-signzone -3 AABBCCDD -H 10 -o example.org. (signatures shortened).
+const dbExampleOrgNSEC3 = `; File written on Mon Mar 20 19:02:24 2017
+; dnssec_signzone version 9.10.3-P4-Ubuntu
+example.org.		3600	IN SOA	ns1.example.org. hostmaster.example.org. (
+				2017032001 ; serial
+				7200       ; refresh (2 hours)
+				3600       ; retry (1 hour)
+				1209600    ; expire (2 weeks)
+				3600       ; minimum (1 hour)
+				)
+			3600	RRSIG	SOA 8 2 3600 (
+				20170419190224 20170320190224 31054 example.org.
+				JmDC4rr9KOMGdkuvFRXB8ZD8= )
+			3600	NS	ns1.example.org.
+			3600	RRSIG	NS 8 2 3600 (
+				20170419190224 20170320190224 31054 example.org.
+				K4Q8cAgbmFhZ7+9BqhLu7= )
+			3600	DNSKEY	257 3 8 (
+				AwEAAcNEU67LJI5GEgF9QLNqLO1SMq1EdoQ6E9f85ha0k0ew
+				) ; KSK; alg = RSASHA256; key id = 31054
+			3600	RRSIG	DNSKEY 8 2 3600 (
+				20170419190224 20170320190224 31054 example.org.
+				ZfJhBLaQz8LHSXXCzb1kcDVA= )
+			0	NSEC3PARAM 1 0 10 AABBCCDD
+			0	RRSIG	NSEC3PARAM 8 2 0 (
+				20170419190224 20170320190224 31054 example.org.
+				Rf8Wh4Z/7qbmvyO4dmNr3Q== )
+a.example.org.		3600	IN A	192.0.2.1
+			3600	RRSIG	A 8 3 3600 (
+				20170419190224 20170320190224 31054 example.org.
+				GqnF6cutipmSHEao= )
+b.c.example.org.	3600	IN A	192.0.2.2
+			3600	RRSIG	A 8 4 3600 (
+				20170419190224 20170320190224 31054 example.org.
+				V8b0uuDkLr0jn5M0PdLo= )
+sub.example.org.	3600	IN NS	ns.sub.example.org.
+ns.sub.example.org.	3600	IN A	192.0.2.53
+*.w.example.org.	3600	IN TXT	"wildcard"
+			3600	RRSIG	TXT 8 3 3600 (
+				20170419190224 20170320190224 31054 example.org.
+				Glpp5ffQu+hq2EtrRjp= )
+4fm5u53hmfu8tnu9mfe36oahvsn8v51s.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD 4GKOIVBOF1N7MCNKOMFLUPJEA1UJRUBD
+4fm5u53hmfu8tnu9mfe36oahvsn8v51s.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G=
+4gkoivbof1n7mcnkomflupjea1ujrubd.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD 4MFUVD5C0SIQU4UPGPR0J5M3UNU49OKC NS
+4gkoivbof1n7mcnkomflupjea1ujrubd.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G=
+4mfuvd5c0siqu4upgpr0j5m3unu49okc.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD KFPEGRELP0U83A4E8L9A9K4VLJC5QLU6 TXT RRSIG
+4mfuvd5c0siqu4upgpr0j5m3unu49okc.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G=
+kfpegrelp0u83a4e8l9a9k4vljc5qlu6.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD KQCVVKCPQOMBCTOGO83KFE7TAG87P775
+kfpegrelp0u83a4e8l9a9k4vljc5qlu6.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G=
+kqcvvkcpqombctogo83kfe7tag87p775.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD OOD0A3DKJEMFTQSQ27M7UKJUSDFDV3LR A RRSIG
+kqcvvkcpqombctogo83kfe7tag87p775.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G=
+ood0a3dkjemftqsq27m7ukjusdfdv3lr.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD RKOF8QMFRB5F2V9EJHFBVB2JPVSA0DJD A RRSIG
+ood0a3dkjemftqsq27m7ukjusdfdv3lr.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G=
+rkof8qmfrb5f2v9ejhfbvb2jpvsa0djd.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD 4FM5U53HMFU8TNU9MFE36OAHVSN8V51S NS SOA RRSIG DNSKEY NSEC3PARAM
+rkof8qmfrb5f2v9ejhfbvb2jpvsa0djd.example.org.	3600	IN	RRSIG	NSEC3 8 3 3600 20170419190224 20170320190224 31054 example.org. dTbJ0xZZcxFp+1Z0wz4G=
+`
 
-const nsec3Test = `example.org.		1800	IN	SOA	sns.dns.icann.org. noc.dns.icann.org. 2016082508 7200 3600 1209600 3600
-aub8v9ce95ie18spjubsr058h41n7pa5.example.org. 284 IN NSEC3 1 1 5 D0CBEAAF0AC77314 AUB95P93VPKP55G6U5S4SGS7LS61ND85 NS SOA TXT RRSIG DNSKEY NSEC3PARAM
-aub8v9ce95ie18spjubsr058h41n7pa5.example.org. 284 IN RRSIG NSEC3 8 2 600 20160910232502 20160827231002 14028 example.org. XBNpA7KAIjorPbXvTinOHrc1f630aHic2U716GHLHA4QMx9cl9ss4QjR Wj2UpDM9zBW/jNYb1xb0yjQoez/Jv200w0taSWjRci5aUnRpOi9bmcrz STHb6wIUjUsbJ+NstQsUwVkj6679UviF1FqNwr4GlJnWG3ZrhYhE+NI6 s0k=`
+const nsec3WrongOwner = `example.org.		3600	IN	SOA	ns1.example.org. hostmaster.example.org. 2017032001 7200 3600 1209600 3600
+kqcvvkcpqombctogo83kfe7tag87p775.a.example.org.	3600	IN	NSEC3	1 0 10 AABBCCDD OOD0A3DKJEMFTQSQ27M7UKJUSDFDV3LR A RRSIG`
@@ -53,6 +53,7 @@ Transfer:
 	}
 
 	z.Tree = z1.Tree
+	z.nsec3 = z1.nsec3
 	z.Apex = z1.Apex
 	*z.Expired = false
 	log.Printf("[INFO] Transferred: %s from %s", z.origin, tr)
@@ -1,12 +1,17 @@
 package file
 
 import (
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/dnskey"
 	"github.com/coredns/coredns/middleware/pkg/dnsutil"
 	"github.com/coredns/coredns/middleware/proxy"
 
@@ -35,9 +40,14 @@ func setup(c *caddy.Controller) error {
 					z.Notify()
 				}
 				z.Reload()
+				z.Resign()
 			})
 			return nil
 		})
+		c.OnShutdown(func() error {
+			z.StopResign()
+			return nil
+		})
 	}
 
 	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
@@ -92,15 +102,21 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 
 			noReload := false
 			prxy := proxy.Proxy{}
+			var signer *Signer
 			for c.NextBlock() {
-				t, _, e := TransferParse(c, false)
-				if e != nil {
-					return Zones{}, e
-				}
+				var t []string
 				switch c.Val() {
 				case "no_reload":
 					noReload = true
 
+				case "key", "nsec3":
+					if signer == nil {
+						signer = &Signer{}
+					}
+					if err := SignParse(c, signer); err != nil {
+						return Zones{}, err
+					}
+
 				case "upstream":
 					args := c.RemainingArgs()
 					if len(args) == 0 {
@@ -111,6 +127,13 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 						return Zones{}, err
 					}
 					prxy = proxy.NewLookup(ups)
+
+				default:
+					var e error
+					t, _, e = TransferParse(c, false)
+					if e != nil {
+						return Zones{}, e
+					}
 				}
 
 				for _, origin := range origins {
@@ -121,11 +144,57 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 					z[origin].Proxy = prxy
 				}
 			}
+
+			if signer != nil {
+				if len(signer.Keys) == 0 {
+					return Zones{}, fmt.Errorf("no keys configured to sign with")
+				}
+				for _, origin := range origins {
+					z[origin].Signer = signer
+					if err := z[origin].Sign(time.Now().UTC()); err != nil {
+						return Zones{}, err
+					}
+				}
+			}
 		}
 	}
 	return Zones{Z: z, Names: names}, nil
 }
 
+// SignParse parses the signing statements: 'key file KEY...' and 'nsec3 [SALT [ITERATIONS]]'
+// into s.
+func SignParse(c *caddy.Controller, s *Signer) error {
+	switch c.Val() {
+	case "key":
+		keys, err := dnskey.Parse(c)
+		if err != nil {
+			return err
+		}
+		s.Keys = append(s.Keys, keys...)
+
+	case "nsec3":
+		s.NSEC3 = true
+		args := c.RemainingArgs()
+		if len(args) > 2 {
+			return c.ArgErr()
+		}
+		if len(args) > 0 && args[0] != "-" {
+			if _, err := hex.DecodeString(args[0]); err != nil {
+				return fmt.Errorf("invalid NSEC3 salt %q: %s", args[0], err)
+			}
+			s.Salt = strings.ToUpper(args[0])
+		}
+		if len(args) > 1 {
+			i, err := strconv.ParseUint(args[1], 10, 16)
+			if err != nil {
+				return err
+			}
+			s.Iterations = uint16(i)
+		}
+	}
+	return nil
+}
+
 // TransferParse parses transfer statements: 'transfer to [address...]'.
 func TransferParse(c *caddy.Controller, secondary bool) (tos, froms []string, err error) {
 	what := c.Val()
@@ -48,6 +48,15 @@ func TestFileParse(t *testing.T) {
 			false,
 			Zones{Names: []string{"dnssex.nl."}},
 		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+				no_reload
+				upstream 8.8.8.8
+				transfer to 10.0.0.1
+			}`,
+			false,
+			Zones{Names: []string{"miek.nl."}},
+		},
 	}
 
 	for i, test := range tests {
@@ -70,3 +79,27 @@ func TestFileParse(t *testing.T) {
 		}
 	}
 }
+
+func TestFileParseBlock(t *testing.T) {
+	zoneFileName, rm, err := test.TempFile(".", dbMiekNL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	c := caddy.NewTestController("dns", `file `+zoneFileName+` miek.nl. {
+		no_reload
+		transfer to 10.0.0.1
+	}`)
+	zones, err := fileParse(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %q", err)
+	}
+	z := zones.Z["miek.nl."]
+	if !z.NoReload {
+		t.Errorf("Expected no_reload to be set")
+	}
+	if len(z.TransferTo) != 1 || z.TransferTo[0] != "10.0.0.1:53" {
+		t.Errorf("Expected transfer to 10.0.0.1:53, got %v", z.TransferTo)
+	}
+}
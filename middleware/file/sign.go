@@ -0,0 +1,364 @@
+package file
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/middleware/file/tree"
+	"github.com/coredns/coredns/middleware/pkg/dnskey"
+
+	"github.com/miekg/dns"
+)
+
+// Signer holds the keys and parameters used to sign a zone when it is loaded.
+type Signer struct {
+	Keys       []*dnskey.Key
+	NSEC3      bool   // Use NSEC3 instead of NSEC for authenticated denial of existence.
+	Salt       string // NSEC3 salt as a hex string, empty for no salt.
+	Iterations uint16 // NSEC3 extra hash iterations.
+}
+
+// Sign signs z with the keys from z.Signer, the zone's content is replaced by the signed version.
+func (z *Zone) Sign(now time.Time) error {
+	z.reloadMu.RLock()
+	soa := z.Apex.SOA
+	z.reloadMu.RUnlock()
+
+	if soa == nil {
+		return fmt.Errorf("no SOA record found for zone: %s", z.origin)
+	}
+	return z.sign(now, soa.Serial)
+}
+
+// sign signs z and sets the serial of the signed SOA record to serial.
+func (z *Zone) sign(now time.Time, serial uint32) error {
+	z1, err := z.Signer.sign(z, now, serial)
+	if err != nil {
+		return err
+	}
+
+	z.reloadMu.Lock()
+	z.Apex = z1.Apex
+	z.Tree = z1.Tree
+	z.nsec3 = z1.nsec3
+	z.expiration = now.Add(signatureValidity)
+	z.reloadMu.Unlock()
+	return nil
+}
+
+// signReload signs zone, the content of z freshly read from disk. The serial of the signed SOA is the
+// one from disk, unless re-signing already moved z past it: then the current serial plus one is used,
+// so secondaries see the reloaded zone as newer.
+func (z *Zone) signReload(zone *Zone, now time.Time) error {
+	if zone.Apex.SOA == nil {
+		return fmt.Errorf("no SOA record found for zone: %s", zone.origin)
+	}
+	serial := zone.Apex.SOA.Serial
+
+	z.reloadMu.RLock()
+	if z.Apex.SOA != nil && z.Apex.SOA.Serial+1 > serial {
+		serial = z.Apex.SOA.Serial + 1
+	}
+	z.reloadMu.RUnlock()
+
+	return zone.sign(now, serial)
+}
+
+// Resign starts a goroutine that re-signs z before its signatures expire. Each time the zone is
+// re-signed the serial is increased and notifies are sent. If z.Signer is nil this is a noop.
+func (z *Zone) Resign() {
+	if z.Signer == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(resignCheck)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				now := time.Now().UTC()
+
+				z.reloadMu.RLock()
+				expiration := z.expiration
+				serial := z.Apex.SOA.Serial
+				z.reloadMu.RUnlock()
+
+				if now.Add(signatureRefresh).Before(expiration) {
+					continue
+				}
+				if err := z.sign(now, serial+1); err != nil {
+					log.Printf("[ERROR] Failed to re-sign `%s': %v", z.origin, err)
+					continue
+				}
+
+				log.Printf("[INFO] Successfully re-signed zone `%s'", z.origin)
+				z.Notify()
+
+			case <-z.ResignShutdown:
+				return
+			}
+		}
+	}()
+}
+
+// StopResign stops the goroutine started by Resign. It is safe to call more than once.
+func (z *Zone) StopResign() {
+	if z.Signer == nil {
+		return
+	}
+	z.resignOnce.Do(func() { close(z.ResignShutdown) })
+}
+
+// sign returns a new zone with the content of z signed with the keys from s. Existing signatures
+// and NSEC(3) records in z are discarded; DNSKEYs are kept so pre-published keys survive.
+func (s *Signer) sign(z *Zone, now time.Time, serial uint32) (*Zone, error) {
+	incep := uint32(now.Add(-3 * time.Hour).Unix()) // -(2+1) hours, be sure to catch daylight saving time and such
+	expir := uint32(now.Add(signatureValidity).Unix())
+
+	z1 := NewZone(z.origin, z.file)
+	for _, r := range z.All() {
+		switch r.Header().Rrtype {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3, dns.TypeNSEC3PARAM:
+			continue
+		}
+		if err := z1.Insert(dns.Copy(r)); err != nil {
+			return nil, err
+		}
+	}
+	z1.Apex.SOA.Serial = serial
+
+	for _, k := range s.Keys {
+		key := dns.Copy(k.K)
+		key.Header().Name = z1.origin
+		key.Header().Ttl = z1.Apex.SOA.Header().Ttl
+		z1.Insert(key)
+	}
+	if s.NSEC3 {
+		param := &dns.NSEC3PARAM{Hash: dns.SHA1, Iterations: s.Iterations, SaltLength: uint8(len(s.Salt) / 2), Salt: s.Salt}
+		param.Hdr = dns.RR_Header{Name: z1.origin, Rrtype: dns.TypeNSEC3PARAM, Class: dns.ClassINET, Ttl: 0}
+		z1.Insert(param)
+	}
+
+	sigs := []dns.RR{}
+	add := func(rrs []dns.RR) error {
+		s1, err := s.signRRset(rrs, z1.origin, incep, expir)
+		if err != nil {
+			return err
+		}
+		sigs = append(sigs, s1...)
+		return nil
+	}
+
+	if err := add([]dns.RR{z1.Apex.SOA}); err != nil {
+		return nil, err
+	}
+	if err := add(z1.Apex.NS); err != nil {
+		return nil, err
+	}
+
+	// Collect the authoritative names and the types they hold, skipping glue.
+	names := []string{}
+	types := make(map[string][]uint16)
+	for _, e := range z1.Tree.All() {
+		name := e.Name()
+		if z1.occluded(name) {
+			continue
+		}
+
+		delegation := name != z1.origin && e.Types(dns.TypeNS) != nil
+		for t, rrs := range rrsetsFromElem(e) {
+			types[name] = append(types[name], t)
+			if delegation && t != dns.TypeDS {
+				continue
+			}
+			if err := add(rrs); err != nil {
+				return nil, err
+			}
+		}
+		names = append(names, name)
+	}
+	// The apex might not be in the tree when it only holds an SOA and NS records.
+	if len(names) == 0 || names[0] != z1.origin {
+		names = append([]string{z1.origin}, names...)
+	}
+	types[z1.origin] = append(types[z1.origin], dns.TypeSOA, dns.TypeNS)
+
+	var denial []dns.RR
+	if s.NSEC3 {
+		denial = s.nsec3Chain(z1, names, types)
+	} else {
+		denial = nsecChain(z1, names, types)
+	}
+	for _, d := range denial {
+		z1.Insert(d)
+		if err := add([]dns.RR{d}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, sig := range sigs {
+		z1.Insert(sig)
+	}
+	return z1, nil
+}
+
+// signRRset signs rrs with the keys from s. When both key signing keys (SEP flag set) and zone
+// signing keys are configured, the KSKs only sign the DNSKEY RRset.
+func (s *Signer) signRRset(rrs []dns.RR, signerName string, incep, expir uint32) ([]dns.RR, error) {
+	if len(rrs) == 0 {
+		return nil, nil
+	}
+
+	ksk, zsk := 0, 0
+	for _, k := range s.Keys {
+		if k.K.Flags&dns.SEP == dns.SEP {
+			ksk++
+		} else {
+			zsk++
+		}
+	}
+	split := ksk > 0 && zsk > 0
+	keySet := rrs[0].Header().Rrtype == dns.TypeDNSKEY
+
+	sigs := []dns.RR{}
+	for _, k := range s.Keys {
+		if split && (k.K.Flags&dns.SEP == dns.SEP) != keySet {
+			continue
+		}
+		ttl := rrs[0].Header().Ttl
+		sig, err := k.Sign(rrs, signerName, ttl, ttl, incep, expir)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs, nil
+}
+
+// nsecChain returns the NSEC records for names, which must be sorted in canonical order.
+func nsecChain(z *Zone, names []string, types map[string][]uint16) []dns.RR {
+	nsecs := make([]dns.RR, len(names))
+	for i, name := range names {
+		nsec := &dns.NSEC{NextDomain: names[(i+1)%len(names)]}
+		nsec.Hdr = dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: z.Apex.SOA.Minttl}
+		nsec.TypeBitMap = typeBitMap(append(types[name], dns.TypeRRSIG, dns.TypeNSEC))
+		nsecs[i] = nsec
+	}
+	return nsecs
+}
+
+// nsec3Chain returns the NSEC3 records for names and all empty-non-terminals between them and the apex.
+// Opt-out is not supported.
+func (s *Signer) nsec3Chain(z *Zone, names []string, types map[string][]uint16) []dns.RR {
+	bitmaps := make(map[string][]uint16)
+	for _, name := range names {
+		bitmap := types[name]
+		if !insecureDelegation(name, z.origin, bitmap) {
+			bitmap = append(bitmap, dns.TypeRRSIG)
+		}
+		bitmaps[dns.HashName(name, dns.SHA1, s.Iterations, s.Salt)] = typeBitMap(bitmap)
+
+		// empty-non-terminals
+		for off, end := dns.NextLabel(name, 0); !end; off, end = dns.NextLabel(name, off) {
+			parent := name[off:]
+			if !dns.IsSubDomain(z.origin, parent) || parent == z.origin {
+				break
+			}
+			if _, ok := types[parent]; ok {
+				continue
+			}
+			h := dns.HashName(parent, dns.SHA1, s.Iterations, s.Salt)
+			if _, ok := bitmaps[h]; !ok {
+				bitmaps[h] = nil
+			}
+		}
+	}
+
+	hashes := make([]string, 0, len(bitmaps))
+	for h := range bitmaps {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	nsec3s := make([]dns.RR, len(hashes))
+	for i, h := range hashes {
+		nsec3 := &dns.NSEC3{Hash: dns.SHA1, Iterations: s.Iterations, SaltLength: uint8(len(s.Salt) / 2), Salt: s.Salt}
+		nsec3.Hdr = dns.RR_Header{Name: strings.ToLower(h) + "." + z.origin, Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: z.Apex.SOA.Minttl}
+		nsec3.NextDomain = hashes[(i+1)%len(hashes)]
+		nsec3.HashLength = 20 // SHA1
+		nsec3.TypeBitMap = bitmaps[h]
+		nsec3s[i] = nsec3
+	}
+	return nsec3s
+}
+
+// occluded returns true when name is below a delegation point in z, i.e. it is glue.
+func (z *Zone) occluded(name string) bool {
+	for off, end := dns.NextLabel(name, 0); !end; off, end = dns.NextLabel(name, off) {
+		parent := name[off:]
+		if parent == z.origin || !dns.IsSubDomain(z.origin, parent) {
+			return false
+		}
+		if e, found := z.Tree.Search(parent); found && e.Types(dns.TypeNS) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// insecureDelegation returns true if name is a delegation without a DS record.
+func insecureDelegation(name, origin string, types []uint16) bool {
+	if name == origin {
+		return false
+	}
+	ns, ds := false, false
+	for _, t := range types {
+		switch t {
+		case dns.TypeNS:
+			ns = true
+		case dns.TypeDS:
+			ds = true
+		}
+	}
+	return ns && !ds
+}
+
+// rrsetsFromElem returns the RRsets from e, keyed by type.
+func rrsetsFromElem(e *tree.Elem) map[uint16][]dns.RR {
+	sets := make(map[uint16][]dns.RR)
+	for _, r := range e.All() {
+		t := r.Header().Rrtype
+		sets[t] = append(sets[t], r)
+	}
+	return sets
+}
+
+// typeBitMap returns the types sorted and without duplicates, as NSEC(3) records need them.
+func typeBitMap(types []uint16) []uint16 {
+	seen := make(map[uint16]bool)
+	bitmap := []uint16{}
+	for _, t := range types {
+		if !seen[t] {
+			seen[t] = true
+			bitmap = append(bitmap, t)
+		}
+	}
+	sort.Sort(uint16s(bitmap))
+	return bitmap
+}
+
+type uint16s []uint16
+
+func (u uint16s) Len() int           { return len(u) }
+func (u uint16s) Less(i, j int) bool { return u[i] < u[j] }
+func (u uint16s) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
+
+const (
+	signatureValidity = 4 * 7 * 24 * time.Hour // sign for 4 weeks
+	signatureRefresh  = 7 * 24 * time.Hour     // re-sign when signatures expire within a week
+	resignCheck       = 1 * time.Hour          // how often to check for expiring signatures
+)
@@ -0,0 +1,238 @@
+package file
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/middleware/pkg/dnskey"
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+func newSignedZone(t *testing.T, nsec3 bool) (*Zone, *dnskey.Key) {
+	fPriv, rmPriv, _ := test.TempFile(".", signPrivKey)
+	defer rmPriv()
+	fPub, rmPub, _ := test.TempFile(".", signPubKey)
+	defer rmPub()
+
+	key, err := dnskey.ParseKeyFile(fPub, fPriv)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+
+	zone, err := Parse(strings.NewReader(dbExampleOrgUnsigned), "example.org.", "stdin")
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+	zone.Signer = &Signer{Keys: []*dnskey.Key{key}, NSEC3: nsec3, Salt: "AABBCCDD", Iterations: 10}
+	if err := zone.Sign(time.Now().UTC()); err != nil {
+		t.Fatalf("Expected no error when signing zone, got %q", err)
+	}
+	return zone, key
+}
+
+func TestSignNSEC(t *testing.T) {
+	zone, key := newSignedZone(t, false)
+
+	if len(zone.Apex.SIGSOA) != 1 {
+		t.Fatalf("Expected 1 signature for the SOA, got %d", len(zone.Apex.SIGSOA))
+	}
+	if err := zone.Apex.SIGSOA[0].(*dns.RRSIG).Verify(key.K, []dns.RR{zone.Apex.SOA}); err != nil {
+		t.Errorf("Expected SOA signature to verify, got %s", err)
+	}
+	if len(zone.Apex.SIGNS) != 1 {
+		t.Fatalf("Expected 1 signature for the NS RRset, got %d", len(zone.Apex.SIGNS))
+	}
+
+	nsecs := []string{}
+	for _, rr := range zone.All() {
+		if x, ok := rr.(*dns.NSEC); ok {
+			nsecs = append(nsecs, x.Header().Name+" "+x.NextDomain)
+		}
+		// Glue is not signed.
+		if x, ok := rr.(*dns.RRSIG); ok && x.Header().Name == "ns.sub.example.org." {
+			t.Errorf("Expected no signatures for glue, got %s", x)
+		}
+	}
+	expected := []string{
+		"example.org. a.b.example.org.",
+		"a.b.example.org. ns1.example.org.",
+		"ns1.example.org. sub.example.org.",
+		"sub.example.org. example.org.",
+	}
+	if len(nsecs) != len(expected) {
+		t.Fatalf("Expected %d NSEC records, got %d: %v", len(expected), len(nsecs), nsecs)
+	}
+	for i := range expected {
+		if nsecs[i] != expected[i] {
+			t.Errorf("Expected NSEC %q, got %q", expected[i], nsecs[i])
+		}
+	}
+
+	elem, _ := zone.Tree.Search("sub.example.org.")
+	nsec := elem.Types(dns.TypeNSEC)[0].(*dns.NSEC)
+	if x := typeBitMap(nsec.TypeBitMap); len(x) != 3 || x[0] != dns.TypeNS || x[1] != dns.TypeRRSIG || x[2] != dns.TypeNSEC {
+		t.Errorf("Expected NS RRSIG NSEC in the type bitmap of the delegation, got %v", nsec.TypeBitMap)
+	}
+}
+
+func TestSignNSEC3(t *testing.T) {
+	zone, _ := newSignedZone(t, true)
+
+	if !zone.isNSEC3() {
+		t.Fatalf("Expected zone to hold an NSEC3 chain")
+	}
+	if zone.Apex.NSEC3PARAM == nil {
+		t.Fatalf("Expected NSEC3PARAM to be set")
+	}
+	// example.org, a.b.example.org, b.example.org (empty-non-terminal), ns1.example.org and sub.example.org.
+	if zone.nsec3.Len() != 5 {
+		t.Errorf("Expected 5 NSEC3 records, got %d", zone.nsec3.Len())
+	}
+	if _, found := zone.matchNSEC3("b.example.org."); !found {
+		t.Errorf("Expected NSEC3 for empty-non-terminal b.example.org.")
+	}
+
+	fm := File{Next: test.ErrorHandler(), Zones: Zones{Z: map[string]*Zone{"example.org.": zone}, Names: []string{"example.org."}}}
+
+	m := new(dns.Msg)
+	m.SetQuestion("nope.example.org.", dns.TypeA)
+	m.SetEdns0(4096, true)
+
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	if _, err := fm.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rec.Msg.Rcode != dns.RcodeNameError {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeNameError, rec.Msg.Rcode)
+	}
+	nsec3s, sigs := 0, 0
+	for _, rr := range rec.Msg.Ns {
+		switch x := rr.(type) {
+		case *dns.NSEC3:
+			nsec3s++
+		case *dns.RRSIG:
+			if x.TypeCovered == dns.TypeNSEC3 {
+				sigs++
+			}
+		}
+	}
+	if nsec3s == 0 || nsec3s != sigs {
+		t.Errorf("Expected signed NSEC3 records in the authority section, got %d NSEC3 and %d RRSIG", nsec3s, sigs)
+	}
+}
+
+func TestSignInsecureDelegationNSEC(t *testing.T) {
+	zone, _ := newSignedZone(t, false)
+	fm := File{Next: test.ErrorHandler(), Zones: Zones{Z: map[string]*Zone{"example.org.": zone}, Names: []string{"example.org."}}}
+
+	m := new(dns.Msg)
+	m.SetQuestion("sub.example.org.", dns.TypeDS)
+	m.SetEdns0(4096, true)
+
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	if _, err := fm.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// The referral must prove there is no DS record.
+	nsec, sig := false, false
+	for _, rr := range rec.Msg.Ns {
+		switch x := rr.(type) {
+		case *dns.NSEC:
+			nsec = x.Header().Name == "sub.example.org."
+		case *dns.RRSIG:
+			sig = sig || x.TypeCovered == dns.TypeNSEC
+		}
+	}
+	if !nsec || !sig {
+		t.Errorf("Expected a signed NSEC for sub.example.org. in the referral, got %v", rec.Msg.Ns)
+	}
+}
+
+func TestResign(t *testing.T) {
+	zone, _ := newSignedZone(t, false)
+	serial := zone.Apex.SOA.Serial
+
+	if err := zone.sign(time.Now().UTC().Add(signatureValidity), serial+1); err != nil {
+		t.Fatalf("Expected no error when re-signing zone, got %q", err)
+	}
+	if zone.Apex.SOA.Serial != serial+1 {
+		t.Errorf("Expected serial %d, got %d", serial+1, zone.Apex.SOA.Serial)
+	}
+	// Old signatures must be removed.
+	if len(zone.Apex.SIGSOA) != 1 {
+		t.Errorf("Expected 1 signature for the SOA, got %d", len(zone.Apex.SIGSOA))
+	}
+}
+
+func TestStopResign(t *testing.T) {
+	zone, _ := newSignedZone(t, false)
+	zone.Resign()
+
+	// Stopping twice, as on a restart of several server blocks, must not panic.
+	zone.StopResign()
+	zone.StopResign()
+}
+
+func TestResignReload(t *testing.T) {
+	zone, key := newSignedZone(t, false)
+	serial := zone.Apex.SOA.Serial
+
+	// A re-sign moved the serial past the one on disk.
+	if err := zone.sign(time.Now().UTC(), serial+1); err != nil {
+		t.Fatalf("Expected no error when re-signing zone, got %q", err)
+	}
+
+	reload, err := Parse(strings.NewReader(dbExampleOrgUnsigned), "example.org.", "stdin")
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+	reload.Signer = &Signer{Keys: []*dnskey.Key{key}}
+	if err := zone.signReload(reload, time.Now().UTC()); err != nil {
+		t.Fatalf("Expected no error when signing reloaded zone, got %q", err)
+	}
+	if reload.Apex.SOA.Serial != serial+2 {
+		t.Errorf("Expected serial %d, got %d", serial+2, reload.Apex.SOA.Serial)
+	}
+
+	// A serial on disk that is newer wins.
+	reload, _ = Parse(strings.NewReader(dbExampleOrgUnsigned), "example.org.", "stdin")
+	reload.Apex.SOA.Serial = 100
+	reload.Signer = &Signer{Keys: []*dnskey.Key{key}}
+	if err := zone.signReload(reload, time.Now().UTC()); err != nil {
+		t.Fatalf("Expected no error when signing reloaded zone, got %q", err)
+	}
+	if reload.Apex.SOA.Serial != 100 {
+		t.Errorf("Expected serial %d, got %d", 100, reload.Apex.SOA.Serial)
+	}
+}
+
+const dbExampleOrgUnsigned = `
+$TTL    30M
+$ORIGIN example.org.
+@       IN      SOA     ns1.example.org. hostmaster.example.org. (
+                             1 ; serial
+                        7200   ; refresh
+                        3600   ; retry
+                     1209600   ; expire
+                        3600 ) ; minimum
+                IN      NS      ns1
+ns1             IN      A       192.0.2.1
+a.b             IN      A       192.0.2.2
+sub             IN      NS      ns.sub
+ns.sub          IN      A       192.0.2.53`
+
+const (
+	signPubKey  = `example.org. IN DNSKEY 257 3 13 tVRWNSGpHZbCi7Pr7OmbADVUO3MxJ0Lb8Lk3o/HBHqCxf5K/J50lFqRa 98lkdAIiFOVRy8LyMvjwmxZKwB5MNw==`
+	signPrivKey = `Private-key-format: v1.3
+Algorithm: 13 (ECDSAP256SHA256)
+PrivateKey: i8j4OfDGT8CQt24SDwLz2hg9yx4qKOEOh1LvbAuSp1c=
+Created: 20160423211746
+Publish: 20160423211746
+Activate: 20160423211746
+`
+)
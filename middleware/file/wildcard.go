@@ -1,6 +1,10 @@
 package file
 
-import "github.com/miekg/dns"
+import (
+	"github.com/coredns/coredns/middleware/file/tree"
+
+	"github.com/miekg/dns"
+)
 
 // replaceWithWildcard replaces the left most label with '*'.
 func replaceWithAsteriskLabel(qname string) (wildcard string) {
@@ -11,3 +15,9 @@ func replaceWithAsteriskLabel(qname string) (wildcard string) {
 
 	return "*." + qname[i:]
 }
+
+// wildcardNSEC3 returns the element holding the NSEC3 record that covers the wildcard directly
+// below the closest encloser ce, proving that it doesn't exist.
+func (z *Zone) wildcardNSEC3(ce string) *tree.Elem {
+	return z.coverNSEC3("*." + ce)
+}
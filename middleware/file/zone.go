@@ -7,6 +7,7 @@ import (
 	"path"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/coredns/coredns/middleware/file/tree"
 	"github.com/coredns/coredns/middleware/proxy"
@@ -24,6 +25,8 @@ type Zone struct {
 	*tree.Tree
 	Apex Apex
 
+	nsec3 *tree.Tree // NSEC3 records and their signatures, indexed by hashed owner name.
+
 	TransferTo   []string
 	StartupOnce  sync.Once
 	TransferFrom []string
@@ -33,6 +36,11 @@ type Zone struct {
 	reloadMu       sync.RWMutex
 	ReloadShutdown chan bool
 	Proxy          proxy.Proxy // Proxy for looking up names during the resolution process
+
+	Signer         *Signer // When set the zone is signed when loaded and re-signed before the signatures expire.
+	ResignShutdown chan bool
+	resignOnce     sync.Once
+	expiration     time.Time // When the signatures made by Signer expire.
 }
 
 // Apex contains the apex records of a zone: SOA, NS and their potential signatures.
@@ -41,6 +49,8 @@ type Apex struct {
 	NS     []dns.RR
 	SIGSOA []dns.RR
 	SIGNS  []dns.RR
+
+	NSEC3PARAM *dns.NSEC3PARAM
 }
 
 // NewZone returns a new zone.
@@ -50,8 +60,10 @@ func NewZone(name, file string) *Zone {
 		origLen:        dns.CountLabel(dns.Fqdn(name)),
 		file:           path.Clean(file),
 		Tree:           &tree.Tree{},
+		nsec3:          &tree.Tree{},
 		Expired:        new(bool),
 		ReloadShutdown: make(chan bool),
+		ResignShutdown: make(chan bool),
 	}
 	*z.Expired = false
 
@@ -86,11 +98,23 @@ func (z *Zone) Insert(r dns.RR) error {
 
 		z.Apex.SOA = r.(*dns.SOA)
 		return nil
-	case dns.TypeNSEC3, dns.TypeNSEC3PARAM:
-		return fmt.Errorf("NSEC3 zone is not supported, dropping RR: %s for zone: %s", r.Header().Name, z.origin)
+	case dns.TypeNSEC3:
+		if !dns.IsSubDomain(z.origin, r.Header().Name) || dns.CountLabel(r.Header().Name) != z.origLen+1 {
+			return fmt.Errorf("NSEC3 record not directly below the apex, dropping RR: %s for zone: %s", r.Header().Name, z.origin)
+		}
+		z.nsec3.Insert(r)
+		return nil
+	case dns.TypeNSEC3PARAM:
+		if r.Header().Name != z.origin {
+			return fmt.Errorf("NSEC3PARAM record not at the apex, dropping RR: %s for zone: %s", r.Header().Name, z.origin)
+		}
+		z.Apex.NSEC3PARAM = r.(*dns.NSEC3PARAM)
 	case dns.TypeRRSIG:
 		x := r.(*dns.RRSIG)
 		switch x.TypeCovered {
+		case dns.TypeNSEC3:
+			z.nsec3.Insert(r)
+			return nil
 		case dns.TypeSOA:
 			z.Apex.SIGSOA = append(z.Apex.SIGSOA, x)
 			return nil
@@ -126,10 +150,17 @@ func (z *Zone) TransferAllowed(req request.Request) bool {
 	return false
 }
 
+// Loaded returns true when z has been loaded, i.e. it has an SOA record.
+func (z *Zone) Loaded() bool {
+	z.reloadMu.RLock()
+	defer z.reloadMu.RUnlock()
+	return z.Apex.SOA != nil
+}
+
 // All returns all records from the zone, the first record will be the SOA record,
 // otionally followed by all RRSIG(SOA)s.
 func (z *Zone) All() []dns.RR {
-	if !z.NoReload {
+	if !z.noLock() {
 		z.reloadMu.RLock()
 		defer z.reloadMu.RUnlock()
 	}
@@ -139,6 +170,9 @@ func (z *Zone) All() []dns.RR {
 	for _, a := range allNodes {
 		records = append(records, a.All()...)
 	}
+	for _, a := range z.nsec3.All() {
+		records = append(records, a.All()...)
+	}
 
 	if len(z.Apex.SIGNS) > 0 {
 		records = append(z.Apex.SIGNS, records...)
@@ -182,11 +216,20 @@ func (z *Zone) Reload() error {
 						log.Printf("[ERROR] Failed to parse `%s': %v", z.origin, err)
 						continue
 					}
+					if z.Signer != nil {
+						zone.Signer = z.Signer
+						if err := z.signReload(zone, time.Now().UTC()); err != nil {
+							log.Printf("[ERROR] Failed to sign `%s': %v", z.origin, err)
+							continue
+						}
+					}
 
 					// copy elements we need
 					z.reloadMu.Lock()
 					z.Apex = zone.Apex
 					z.Tree = zone.Tree
+					z.nsec3 = zone.nsec3
+					z.expiration = zone.expiration
 					z.reloadMu.Unlock()
 
 					log.Printf("[INFO] Successfully reloaded zone `%s'", z.origin)
@@ -201,6 +244,10 @@ func (z *Zone) Reload() error {
 	return nil
 }
 
+// noLock returns true when the zone's content never changes after it has been loaded, in which
+// case no locking is needed.
+func (z *Zone) noLock() bool { return z.NoReload && z.Signer == nil }
+
 // Print prints the zone's tree to stdout.
 func (z *Zone) Print() {
 	z.Tree.Print()
@@ -2,20 +2,63 @@
 package health
 
 import (
+	"encoding/json"
 	"io"
 	"log"
 	"net"
 	"net/http"
 	"sync"
+	"time"
 )
 
-var once sync.Once
+// Healther is implemented by middleware that can report on their health, for instance if a backend
+// they depend on can be reached. Health polls these every pollInterval.
+type Healther interface {
+	// Name returns the name of the middleware.
+	Name() string
+	// Health returns true when the middleware is healthy.
+	Health() bool
+}
+
+// Readiness is implemented by middleware that need some time before they can answer queries, for
+// instance because they need to load data first.
+type Readiness interface {
+	// Name returns the name of the middleware.
+	Name() string
+	// Ready returns true when the middleware is ready to answer queries.
+	Ready() bool
+}
 
 type health struct {
 	Addr string
 
-	ln  net.Listener
-	mux *http.ServeMux
+	ln net.Listener
+	l  *listener
+
+	// healthers are polled every pollInterval, the names of the unhealthy ones are stored in failing.
+	healthers []Healther
+	sync.RWMutex
+	failing []string
+	stop    chan bool
+
+	// readiness holds the middleware that report if they are ready, these are checked on each request
+	// to the ready path.
+	readiness []Readiness
+}
+
+// listener serves the health and ready paths for all the health instances that use its address,
+// i.e. those of different server blocks. It is closed when the last of them shuts down.
+type listener struct {
+	ln net.Listener
+
+	sync.RWMutex
+	hs []*health
+}
+
+// status is the JSON body returned when a health or readiness check fails.
+type status struct {
+	Status  string   `json:"status"`
+	Failing []string `json:"failing"`
 }
 
 func (h *health) Startup() error {
@@ -23,37 +66,158 @@ func (h *health) Startup() error {
 		h.Addr = defAddr
 	}
 
-	once.Do(func() {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+
+	l, ok := listeners[h.Addr]
+	if !ok {
 		ln, err := net.Listen("tcp", h.Addr)
 		if err != nil {
 			log.Printf("[ERROR] Failed to start health handler: %s", err)
-			return
+			return nil
 		}
+		l = &listener{ln: ln}
+		listeners[h.Addr] = l
 
-		h.ln = ln
-
-		h.mux = http.NewServeMux()
-
-		h.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-			io.WriteString(w, ok)
+		mux := http.NewServeMux()
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			write(w, "unhealthy", l.failing())
+		})
+		mux.HandleFunc(readyPath, func(w http.ResponseWriter, r *http.Request) {
+			write(w, "not ready", l.notReady())
 		})
 
-		go func() {
-			http.Serve(h.ln, h.mux)
-		}()
-	})
+		go func(ln net.Listener) {
+			http.Serve(ln, mux)
+		}(ln)
+	}
+	l.Lock()
+	l.hs = append(l.hs, h)
+	l.Unlock()
+	h.l = l
+	h.ln = l.ln
+
+	h.poll()
+	h.stop = make(chan bool)
+	go func(stop chan bool) {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.poll()
+			case <-stop:
+				return
+			}
+		}
+	}(h.stop)
 	return nil
 }
 
+// failing returns the names of the unhealthy middleware of all health instances using l.
+func (l *listener) failing() []string {
+	failing := []string{}
+	l.RLock()
+	for _, h := range l.hs {
+		h.RLock()
+		failing = append(failing, h.failing...)
+		h.RUnlock()
+	}
+	l.RUnlock()
+	return failing
+}
+
+// notReady returns the names of the middleware that are not ready of all health instances using l.
+func (l *listener) notReady() []string {
+	notReady := []string{}
+	l.RLock()
+	for _, h := range l.hs {
+		notReady = append(notReady, h.notReady()...)
+	}
+	l.RUnlock()
+	return notReady
+}
+
+// poll checks the health of all healthers and records the ones that fail.
+func (h *health) poll() {
+	failing := []string{}
+	for _, x := range h.healthers {
+		if !x.Health() {
+			failing = append(failing, x.Name())
+		}
+	}
+	h.Lock()
+	h.failing = failing
+	h.Unlock()
+}
+
+// notReady returns the names of the middleware that are not ready.
+func (h *health) notReady() []string {
+	notReady := []string{}
+	for _, r := range h.readiness {
+		if !r.Ready() {
+			notReady = append(notReady, r.Name())
+		}
+	}
+	return notReady
+}
+
+// write writes OK when nothing is failing, otherwise a 503 is returned with a JSON body that lists
+// the failing middleware.
+func write(w http.ResponseWriter, state string, failing []string) {
+	if len(failing) == 0 {
+		io.WriteString(w, ok)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(status{Status: state, Failing: failing})
+}
+
+// Shutdown stops polling and removes h from its listener, the last one to leave closes it.
 func (h *health) Shutdown() error {
-	if h.ln != nil {
-		return h.ln.Close()
+	if h.stop != nil {
+		close(h.stop)
+		h.stop = nil
 	}
-	return nil
+
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+
+	l := h.l
+	if l == nil {
+		return nil
+	}
+	h.l, h.ln = nil, nil
+
+	l.Lock()
+	for i := range l.hs {
+		if l.hs[i] == h {
+			l.hs = append(l.hs[:i], l.hs[i+1:]...)
+			break
+		}
+	}
+	left := len(l.hs)
+	l.Unlock()
+	if left > 0 {
+		return nil
+	}
+
+	if listeners[h.Addr] == l {
+		delete(listeners, h.Addr)
+	}
+	return l.ln.Close()
 }
 
+var (
+	listenersMu sync.Mutex
+	listeners   = make(map[string]*listener) // the health listeners, keyed by their configured address
+)
+
 const (
-	ok      = "OK"
-	defAddr = ":8080"
-	path    = "/health"
+	ok           = "OK"
+	defAddr      = ":8080"
+	path         = "/health"
+	readyPath    = "/ready"
+	pollInterval = 1 * time.Second
 )
@@ -7,10 +7,20 @@ import (
 	"testing"
 )
 
+type erratic struct {
+	healthy bool
+	ready   bool
+}
+
+func (e *erratic) Name() string { return "erratic" }
+func (e *erratic) Health() bool { return e.healthy }
+func (e *erratic) Ready() bool  { return e.ready }
+
 func TestHealth(t *testing.T) {
 	// We use a random port instead of a fixed port like 8080 that may have been
 	// occupied by some other process.
-	h := health{Addr: ":0"}
+	e := &erratic{healthy: true}
+	h := health{Addr: ":0", healthers: []Healther{e}, readiness: []Readiness{e}}
 	if err := h.Startup(); err != nil {
 		t.Fatalf("Unable to startup the health server: %v", err)
 	}
@@ -19,19 +29,105 @@ func TestHealth(t *testing.T) {
 	// Reconstruct the http address based on the port allocated by operating system.
 	address := fmt.Sprintf("http://%s%s", h.ln.Addr().String(), path)
 
+	code, content := get(t, address)
+	if code != 200 {
+		t.Errorf("Invalid status code: expecting '200', got '%d'", code)
+	}
+	if content != "OK" {
+		t.Errorf("Invalid response body: expecting 'OK', got '%s'", content)
+	}
+
+	e.healthy = false
+	h.poll()
+	code, content = get(t, address)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Invalid status code: expecting '%d', got '%d'", http.StatusServiceUnavailable, code)
+	}
+	if expected := `{"status":"unhealthy","failing":["erratic"]}` + "\n"; content != expected {
+		t.Errorf("Invalid response body: expecting '%s', got '%s'", expected, content)
+	}
+
+	address = fmt.Sprintf("http://%s%s", h.ln.Addr().String(), readyPath)
+
+	code, content = get(t, address)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Invalid status code: expecting '%d', got '%d'", http.StatusServiceUnavailable, code)
+	}
+	if expected := `{"status":"not ready","failing":["erratic"]}` + "\n"; content != expected {
+		t.Errorf("Invalid response body: expecting '%s', got '%s'", expected, content)
+	}
+
+	e.ready = true
+	code, content = get(t, address)
+	if code != 200 {
+		t.Errorf("Invalid status code: expecting '200', got '%d'", code)
+	}
+	if content != "OK" {
+		t.Errorf("Invalid response body: expecting 'OK', got '%s'", content)
+	}
+}
+
+func get(t *testing.T, address string) (int, string) {
 	response, err := http.Get(address)
 	if err != nil {
 		t.Fatalf("Unable to query %s: %v", address, err)
 	}
 	defer response.Body.Close()
-	if response.StatusCode != 200 {
-		t.Errorf("Invalid status code: expecting '200', got '%d'", response.StatusCode)
-	}
 	content, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		t.Fatalf("Unable to get response body from %s: %v", address, err)
 	}
-	if string(content) != "OK" {
-		t.Errorf("Invalid response body: expecting 'OK', got '%s'", string(content))
+	return response.StatusCode, string(content)
+}
+
+func TestHealthRestart(t *testing.T) {
+	e := &erratic{healthy: true}
+	h := &health{Addr: ":0", healthers: []Healther{e}}
+	if err := h.Startup(); err != nil {
+		t.Fatalf("Unable to startup the health server: %v", err)
+	}
+	addr := h.ln.Addr().String()
+	if err := h.Shutdown(); err != nil {
+		t.Fatalf("Unable to shutdown the health server: %v", err)
+	}
+
+	// A restart creates a new instance for the same address, it must serve again.
+	h = &health{Addr: addr, healthers: []Healther{e}}
+	if err := h.Startup(); err != nil {
+		t.Fatalf("Unable to startup the health server: %v", err)
+	}
+	defer h.Shutdown()
+	if h.ln == nil {
+		t.Fatalf("Expected the health server to listen on %s after a restart", addr)
+	}
+	if code, _ := get(t, "http://"+addr+path); code != 200 {
+		t.Errorf("Invalid status code: expecting '200', got '%d'", code)
+	}
+}
+
+func TestHealthShared(t *testing.T) {
+	e1, e2 := &erratic{healthy: true}, &erratic{healthy: false}
+	h1 := &health{Addr: ":0", healthers: []Healther{e1}}
+	if err := h1.Startup(); err != nil {
+		t.Fatalf("Unable to startup the health server: %v", err)
+	}
+	defer h1.Shutdown()
+	h2 := &health{Addr: ":0", healthers: []Healther{e2}}
+	if err := h2.Startup(); err != nil {
+		t.Fatalf("Unable to startup the health server: %v", err)
+	}
+	if h1.ln != h2.ln {
+		t.Fatalf("Expected the server blocks to share the health listener")
+	}
+
+	address := fmt.Sprintf("http://%s%s", h1.ln.Addr().String(), path)
+	if code, _ := get(t, address); code != http.StatusServiceUnavailable {
+		t.Errorf("Invalid status code: expecting '%d', got '%d'", http.StatusServiceUnavailable, code)
+	}
+
+	// After h2 is shut down, only h1's middleware are checked and the listener stays open.
+	h2.Shutdown()
+	if code, _ := get(t, address); code != 200 {
+		t.Errorf("Invalid status code: expecting '200', got '%d'", code)
 	}
 }
@@ -1,6 +1,7 @@
 package health
 
 import (
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/middleware"
 
 	"github.com/mholt/caddy"
@@ -20,6 +21,20 @@ func setup(c *caddy.Controller) error {
 	}
 
 	h := &health{Addr: addr}
+
+	// The middleware of this server are only known after the servers are made, collect the
+	// ones that report their health or readiness before starting the health server.
+	c.OnStartup(func() error {
+		for _, m := range dnsserver.GetConfig(c).Handlers() {
+			if x, ok := m.(Healther); ok {
+				h.healthers = append(h.healthers, x)
+			}
+			if r, ok := m.(Readiness); ok {
+				h.readiness = append(h.readiness, r)
+			}
+		}
+		return nil
+	})
 	c.OnStartup(h.Startup)
 	c.OnShutdown(h.Shutdown)
 
@@ -0,0 +1,121 @@
+// Package hosts implements a middleware that serves the content of /etc/hosts formatted files.
+package hosts
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/dnsutil"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Hosts is the middleware handler.
+type Hosts struct {
+	Next middleware.Handler
+	*Hostsfile
+
+	Origins     []string
+	TTL         uint32
+	Fallthrough bool
+}
+
+// ServeDNS implements the middleware.Handle interface.
+func (h Hosts) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := state.Name()
+
+	zone := middleware.Zones(h.Origins).Matches(qname)
+	if zone == "" || state.QClass() != dns.ClassINET {
+		return middleware.NextOrFailure(h.Name(), h.Next, ctx, w, r)
+	}
+
+	var answers []dns.RR
+	switch state.QType() {
+	case dns.TypePTR:
+		names := h.LookupStaticAddr(dnsutil.ExtractAddressFromReverse(qname))
+		answers = ptr(qname, h.TTL, names)
+	case dns.TypeA:
+		answers = a(qname, h.TTL, h.LookupStaticHostV4(qname))
+	case dns.TypeAAAA:
+		answers = aaaa(qname, h.TTL, h.LookupStaticHostV6(qname))
+	}
+
+	rcode := dns.RcodeSuccess
+	if len(answers) == 0 {
+		if h.Fallthrough {
+			return middleware.NextOrFailure(h.Name(), h.Next, ctx, w, r)
+		}
+		if !h.otherRecordsExist(state.QType(), qname) {
+			rcode = dns.RcodeNameError
+		}
+	}
+
+	m := new(dns.Msg)
+	m.SetRcode(r, rcode)
+	m.Authoritative, m.RecursionAvailable, m.Compress = true, true, true
+	m.Answer = answers
+
+	state.SizeAndDo(m)
+	m, _ = state.Scrub(m)
+	w.WriteMsg(m)
+	return rcode, nil
+}
+
+// otherRecordsExist returns true when qname has records of another type than qtype, the reply is
+// then a NODATA response instead of NXDOMAIN.
+func (h Hosts) otherRecordsExist(qtype uint16, qname string) bool {
+	if qtype != dns.TypeA && qtype != dns.TypeAAAA && h.Exists(qname) {
+		return true
+	}
+	switch qtype {
+	case dns.TypeA:
+		return len(h.LookupStaticHostV6(qname)) > 0
+	case dns.TypeAAAA:
+		return len(h.LookupStaticHostV4(qname)) > 0
+	case dns.TypePTR:
+		return false
+	}
+	return len(h.LookupStaticAddr(dnsutil.ExtractAddressFromReverse(qname))) > 0
+}
+
+// Name implements the middleware.Handle interface.
+func (h Hosts) Name() string { return "hosts" }
+
+// a takes a slice of net.IPs and returns a slice of A RRs.
+func a(zone string, ttl uint32, ips []net.IP) []dns.RR {
+	answers := []dns.RR{}
+	for _, ip := range ips {
+		r := new(dns.A)
+		r.Hdr = dns.RR_Header{Name: zone, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl}
+		r.A = ip
+		answers = append(answers, r)
+	}
+	return answers
+}
+
+// aaaa takes a slice of net.IPs and returns a slice of AAAA RRs.
+func aaaa(zone string, ttl uint32, ips []net.IP) []dns.RR {
+	answers := []dns.RR{}
+	for _, ip := range ips {
+		r := new(dns.AAAA)
+		r.Hdr = dns.RR_Header{Name: zone, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: ttl}
+		r.AAAA = ip
+		answers = append(answers, r)
+	}
+	return answers
+}
+
+// ptr takes a slice of host names and returns a slice of PTR RRs.
+func ptr(zone string, ttl uint32, names []string) []dns.RR {
+	answers := []dns.RR{}
+	for _, n := range names {
+		r := new(dns.PTR)
+		r.Hdr = dns.RR_Header{Name: zone, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: ttl}
+		r.Ptr = dns.Fqdn(n)
+		answers = append(answers, r)
+	}
+	return answers
+}
@@ -0,0 +1,108 @@
+package hosts
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+func TestLookupA(t *testing.T) {
+	h := Hosts{
+		Next:      test.ErrorHandler(),
+		Hostsfile: &Hostsfile{hmap: Parse(strings.NewReader(hostsExample))},
+		Origins:   []string{"."},
+		TTL:       300,
+	}
+	ctx := context.TODO()
+
+	for _, tc := range hostsTestCases {
+		m := tc.Msg()
+
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		_, err := h.ServeDNS(ctx, rec, m)
+		if err != nil {
+			t.Errorf("Expected no error, got %v\n", err)
+			return
+		}
+
+		resp := rec.Msg
+		sort.Sort(test.RRSet(resp.Answer))
+
+		if !test.Header(t, tc, resp) {
+			t.Logf("%v\n", resp)
+			continue
+		}
+		if !test.Section(t, tc, test.Answer, resp.Answer) {
+			t.Logf("%v\n", resp)
+		}
+	}
+}
+
+func TestLookupFallthrough(t *testing.T) {
+	h := Hosts{
+		Next:        test.NextHandler(dns.RcodeRefused, nil),
+		Hostsfile:   &Hostsfile{hmap: Parse(strings.NewReader(hostsExample))},
+		Origins:     []string{"."},
+		Fallthrough: true,
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("nope.example.org.", dns.TypeA)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	rc, _ := h.ServeDNS(context.TODO(), rec, m)
+	if rc != dns.RcodeRefused {
+		t.Errorf("Expected the query to fall through to the next middleware, got rcode %d", rc)
+	}
+}
+
+var hostsTestCases = []test.Case{
+	{
+		Qname: "www.example.org.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.A("www.example.org. 300 IN A 10.0.0.1"),
+		},
+	},
+	{
+		Qname: "example.org.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.A("example.org. 300 IN A 10.0.0.1"),
+			test.A("example.org. 300 IN A 10.0.0.2"),
+		},
+	},
+	{
+		Qname: "localhost.", Qtype: dns.TypeAAAA,
+		Answer: []dns.RR{
+			test.AAAA("localhost. 300 IN AAAA ::1"),
+		},
+	},
+	{
+		Qname: "1.0.0.10.in-addr.arpa.", Qtype: dns.TypePTR,
+		Answer: []dns.RR{
+			test.PTR("1.0.0.10.in-addr.arpa. 300 PTR example.org."),
+			test.PTR("1.0.0.10.in-addr.arpa. 300 PTR www.example.org."),
+		},
+	},
+	{
+		// NODATA
+		Qname: "example.org.", Qtype: dns.TypeAAAA,
+		Answer: []dns.RR{},
+	},
+	{
+		Qname: "example.org.", Qtype: dns.TypeMX,
+		Answer: []dns.RR{},
+	},
+	{
+		Qname: "nope.example.org.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+	},
+	{
+		Qname: "2.0.0.127.in-addr.arpa.", Qtype: dns.TypePTR,
+		Rcode: dns.RcodeNameError,
+	},
+}
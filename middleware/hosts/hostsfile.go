@@ -0,0 +1,212 @@
+package hosts
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Map holds the names and addresses from a hosts file.
+type Map struct {
+	// name4 and name6 map a lower cased, fully qualified, name to its addresses.
+	name4 map[string][]net.IP
+	name6 map[string][]net.IP
+
+	// addr maps an address, as returned by net.IP.String, to its names.
+	addr map[string][]string
+}
+
+func newMap() *Map {
+	return &Map{
+		name4: make(map[string][]net.IP),
+		name6: make(map[string][]net.IP),
+		addr:  make(map[string][]string),
+	}
+}
+
+// add adds the address ip for name to m.
+func (m *Map) add(name string, ip net.IP) {
+	name = absDomainName(name)
+	if ip4 := ip.To4(); ip4 != nil {
+		m.name4[name] = append(m.name4[name], ip4)
+	} else {
+		m.name6[name] = append(m.name6[name], ip)
+	}
+	key := ip.String()
+	m.addr[key] = append(m.addr[key], name)
+}
+
+// merge adds the content of m1 to m.
+func (m *Map) merge(m1 *Map) {
+	for name, ips := range m1.name4 {
+		m.name4[name] = append(m.name4[name], ips...)
+	}
+	for name, ips := range m1.name6 {
+		m.name6[name] = append(m.name6[name], ips...)
+	}
+	for addr, names := range m1.addr {
+		m.addr[addr] = append(m.addr[addr], names...)
+	}
+}
+
+// Parse reads a hosts file from r and returns its content. Lines that can't be parsed are skipped.
+func Parse(r io.Reader) *Map {
+	m := newMap()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parseLine(m, scanner.Bytes())
+	}
+	return m
+}
+
+// parseLine parses a single hosts file line, "ADDRESS NAME [NAME...]", and adds it to m.
+func parseLine(m *Map, line []byte) {
+	if i := bytes.Index(line, []byte{'#'}); i >= 0 {
+		// Discard comments.
+		line = line[:i]
+	}
+	f := bytes.Fields(line)
+	if len(f) < 2 {
+		return
+	}
+	ip := parseIP(string(f[0]))
+	if ip == nil {
+		return
+	}
+	for _, name := range f[1:] {
+		m.add(string(name), ip)
+	}
+}
+
+// parseIP parses an address, discarding an IPv6 zone.
+func parseIP(addr string) net.IP {
+	if i := strings.Index(addr, "%"); i >= 0 {
+		addr = addr[0:i]
+	}
+	return net.ParseIP(addr)
+}
+
+// absDomainName returns the lower cased, fully qualified, version of name.
+func absDomainName(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// Hostsfile holds the content of the hosts files and the inline entries.
+type Hostsfile struct {
+	sync.RWMutex
+
+	files  []string
+	inline *Map // entries from the Corefile, never reloaded
+
+	hmap  *Map                 // merged content of the files and the inline entries
+	stamp map[string]fileStamp // modification time and size of the files when they were read
+}
+
+type fileStamp struct {
+	mtime time.Time
+	size  int64
+}
+
+// NewHostsfile returns a Hostsfile for files and the inline entries. It is empty until ReadHosts
+// is called.
+func NewHostsfile(files []string, inline *Map) *Hostsfile {
+	if inline == nil {
+		inline = newMap()
+	}
+	h := &Hostsfile{files: files, inline: inline, hmap: newMap()}
+	h.hmap.merge(inline)
+	return h
+}
+
+// ReadHosts (re)reads the hosts files when one of them has changed since the last time they were
+// read, a file that can't be read is treated as empty.
+func (h *Hostsfile) ReadHosts() {
+	stamp := make(map[string]fileStamp)
+	for _, f := range h.files {
+		if fi, err := os.Stat(f); err == nil {
+			stamp[f] = fileStamp{mtime: fi.ModTime(), size: fi.Size()}
+		}
+	}
+
+	h.RLock()
+	changed := h.stamp == nil || !sameStamps(h.stamp, stamp)
+	h.RUnlock()
+	if !changed {
+		return
+	}
+
+	m := newMap()
+	m.merge(h.inline)
+	for _, f := range h.files {
+		file, err := os.Open(f)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("[WARNING] Failed to read hosts file %s: %s", f, err)
+			}
+			continue
+		}
+		m.merge(Parse(file))
+		file.Close()
+	}
+
+	h.Lock()
+	h.hmap = m
+	h.stamp = stamp
+	h.Unlock()
+}
+
+func sameStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for f, s := range a {
+		s1, ok := b[f]
+		if !ok || s1.size != s.size || !s1.mtime.Equal(s.mtime) {
+			return false
+		}
+	}
+	return true
+}
+
+// LookupStaticHostV4 returns the IPv4 addresses for name.
+func (h *Hostsfile) LookupStaticHostV4(name string) []net.IP {
+	h.RLock()
+	defer h.RUnlock()
+	return h.hmap.name4[absDomainName(name)]
+}
+
+// LookupStaticHostV6 returns the IPv6 addresses for name.
+func (h *Hostsfile) LookupStaticHostV6(name string) []net.IP {
+	h.RLock()
+	defer h.RUnlock()
+	return h.hmap.name6[absDomainName(name)]
+}
+
+// LookupStaticAddr returns the names for addr.
+func (h *Hostsfile) LookupStaticAddr(addr string) []string {
+	ip := parseIP(addr)
+	if ip == nil {
+		return nil
+	}
+	h.RLock()
+	defer h.RUnlock()
+	return h.hmap.addr[ip.String()]
+}
+
+// Exists returns true if name has addresses, of any family.
+func (h *Hostsfile) Exists(name string) bool {
+	name = absDomainName(name)
+	h.RLock()
+	defer h.RUnlock()
+	return len(h.hmap.name4[name]) > 0 || len(h.hmap.name6[name]) > 0
+}
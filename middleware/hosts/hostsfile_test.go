@@ -0,0 +1,97 @@
+package hosts
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	m := Parse(strings.NewReader(hostsExample))
+
+	tests := []struct {
+		name string
+		v4   []string
+		v6   []string
+	}{
+		{"localhost.", []string{"127.0.0.1"}, []string{"::1"}},
+		{"example.org.", []string{"10.0.0.1", "10.0.0.2"}, nil},
+		{"www.example.org.", []string{"10.0.0.1"}, nil},
+		{"ipv6.example.org.", nil, []string{"fe80::1"}},
+		{"commented.example.org.", nil, nil},
+		{"broken.example.org.", nil, nil},
+	}
+	for _, tc := range tests {
+		if got := ipStrings(m.name4[tc.name]); strings.Join(got, ",") != strings.Join(tc.v4, ",") {
+			t.Errorf("Expected IPv4 addresses %v for %s, got %v", tc.v4, tc.name, got)
+		}
+		if got := ipStrings(m.name6[tc.name]); strings.Join(got, ",") != strings.Join(tc.v6, ",") {
+			t.Errorf("Expected IPv6 addresses %v for %s, got %v", tc.v6, tc.name, got)
+		}
+	}
+
+	if names := m.addr["10.0.0.1"]; len(names) != 2 || names[0] != "example.org." || names[1] != "www.example.org." {
+		t.Errorf("Expected example.org. and www.example.org. for 10.0.0.1, got %v", names)
+	}
+}
+
+func TestReadHosts(t *testing.T) {
+	f, err := ioutil.TempFile("", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("10.0.0.1 example.org\n")
+	f.Close()
+
+	inline := newMap()
+	parseLine(inline, []byte("10.0.0.10 inline.example.org"))
+
+	h := NewHostsfile([]string{f.Name(), "/nonexistent/hosts"}, inline)
+	h.ReadHosts()
+
+	if ips := h.LookupStaticHostV4("Example.ORG"); len(ips) != 1 || !ips[0].Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("Expected 10.0.0.1 for example.org, got %v", ips)
+	}
+	if ips := h.LookupStaticHostV4("inline.example.org."); len(ips) != 1 {
+		t.Errorf("Expected the inline entry to be present, got %v", ips)
+	}
+
+	// Change the file, the modification time must differ.
+	ioutil.WriteFile(f.Name(), []byte("10.0.0.3 example.org\n10.0.0.4 new.example.org\n"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(f.Name(), later, later)
+
+	h.ReadHosts()
+	if ips := h.LookupStaticHostV4("example.org."); len(ips) != 1 || !ips[0].Equal(net.ParseIP("10.0.0.3")) {
+		t.Errorf("Expected 10.0.0.3 for example.org after reload, got %v", ips)
+	}
+	if names := h.LookupStaticAddr("10.0.0.4"); len(names) != 1 || names[0] != "new.example.org." {
+		t.Errorf("Expected new.example.org. for 10.0.0.4, got %v", names)
+	}
+	if ips := h.LookupStaticHostV4("inline.example.org."); len(ips) != 1 {
+		t.Errorf("Expected the inline entry to survive a reload, got %v", ips)
+	}
+}
+
+func ipStrings(ips []net.IP) []string {
+	s := []string{}
+	for _, ip := range ips {
+		s = append(s, ip.String())
+	}
+	return s
+}
+
+const hostsExample = `
+127.0.0.1       localhost
+::1             localhost
+10.0.0.1        example.org www.example.org # trailing comment
+10.0.0.2        example.org
+fe80::1%lo0     ipv6.example.org
+# 10.0.0.3      commented.example.org
+not-an-address  broken.example.org
+10.0.0.4
+`
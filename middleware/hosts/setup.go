@@ -0,0 +1,158 @@
+package hosts
+
+import (
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("hosts", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	h, reload, err := hostsParse(c)
+	if err != nil {
+		return middleware.Error("hosts", err)
+	}
+
+	parseChan := make(chan bool)
+
+	c.OnStartup(func() error {
+		h.ReadHosts()
+		if reload == 0 {
+			return nil
+		}
+
+		go func() {
+			ticker := time.NewTicker(reload)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-parseChan:
+					return
+				case <-ticker.C:
+					h.ReadHosts()
+				}
+			}
+		}()
+		return nil
+	})
+
+	c.OnShutdown(func() error {
+		close(parseChan)
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
+		h.Next = next
+		return h
+	})
+
+	return nil
+}
+
+func hostsParse(c *caddy.Controller) (Hosts, time.Duration, error) {
+	var (
+		h      = Hosts{TTL: defaultTTL}
+		files  []string
+		inline = newMap()
+		reload = defaultReload
+
+		hasInline bool
+	)
+
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return h, 0, c.Err("hosts can only be specified once per server block")
+		}
+		i++
+
+		h.Origins = make([]string, len(c.ServerBlockKeys))
+		for i := range c.ServerBlockKeys {
+			h.Origins[i] = middleware.Host(c.ServerBlockKeys[i]).Normalize()
+		}
+
+		for _, f := range c.RemainingArgs() {
+			if !path.IsAbs(f) && config.Root != "" {
+				f = path.Join(config.Root, f)
+			}
+			if _, err := os.Stat(f); err != nil {
+				if !os.IsNotExist(err) {
+					return h, 0, c.Errf("unable to access hosts file '%s': %v", f, err)
+				}
+				log.Printf("[WARNING] File does not exist: %s", f)
+			}
+			files = append(files, f)
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "fallthrough":
+				if len(c.RemainingArgs()) != 0 {
+					return h, 0, c.ArgErr()
+				}
+				h.Fallthrough = true
+			case "ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return h, 0, c.ArgErr()
+				}
+				ttl, err := strconv.ParseUint(args[0], 10, 32)
+				if err != nil {
+					return h, 0, c.Errf("ttl must be a positive integer: %q", args[0])
+				}
+				h.TTL = uint32(ttl)
+			case "reload":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return h, 0, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil || d < 0 {
+					return h, 0, c.Errf("invalid reload duration: %q", args[0])
+				}
+				reload = d
+			default:
+				// An inline entry: ADDRESS NAME [NAME...]
+				addr := c.Val()
+				if parseIP(addr) == nil {
+					return h, 0, c.Errf("unknown property '%s'", addr)
+				}
+				names := c.RemainingArgs()
+				if len(names) == 0 {
+					return h, 0, c.Errf("inline entry for '%s' needs at least one name", addr)
+				}
+				parseLine(inline, []byte(addr+" "+strings.Join(names, " ")))
+				hasInline = true
+			}
+		}
+	}
+
+	// Without files or inline entries we serve the system's hosts file.
+	if len(files) == 0 && !hasInline {
+		files = []string{defaultFile}
+	}
+	h.Hostsfile = NewHostsfile(files, inline)
+	return h, reload, nil
+}
+
+const (
+	defaultFile   = "/etc/hosts"
+	defaultTTL    = 3600
+	defaultReload = 5 * time.Second
+)
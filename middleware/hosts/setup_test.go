@@ -0,0 +1,104 @@
+package hosts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+)
+
+func TestHostsParse(t *testing.T) {
+	tests := []struct {
+		input               string
+		shouldErr           bool
+		expectedFiles       []string
+		expectedOrigins     []string
+		expectedFallthrough bool
+		expectedTTL         uint32
+		expectedReload      time.Duration
+	}{
+		{`hosts`, false, []string{"/etc/hosts"}, nil, false, defaultTTL, defaultReload},
+		{`hosts /tmp/hosts`, false, []string{"/tmp/hosts"}, nil, false, defaultTTL, defaultReload},
+		{`hosts /tmp/hosts /tmp/more-hosts`, false, []string{"/tmp/hosts", "/tmp/more-hosts"}, nil, false, defaultTTL, defaultReload},
+		{`hosts {
+			fallthrough
+		}`, false, []string{"/etc/hosts"}, nil, true, defaultTTL, defaultReload},
+		{`hosts /tmp/hosts {
+			ttl 60
+			reload 30s
+		}`, false, []string{"/tmp/hosts"}, nil, false, 60, 30 * time.Second},
+		{`hosts {
+			reload 0
+		}`, false, []string{"/etc/hosts"}, nil, false, defaultTTL, 0},
+		{`hosts {
+			10.0.0.1 example.org
+		}`, false, nil, nil, false, defaultTTL, defaultReload},
+		// fails
+		{`hosts {
+			ttl
+		}`, true, nil, nil, false, 0, 0},
+		{`hosts {
+			ttl -1
+		}`, true, nil, nil, false, 0, 0},
+		{`hosts {
+			reload forever
+		}`, true, nil, nil, false, 0, 0},
+		{`hosts {
+			10.0.0.1
+		}`, true, nil, nil, false, 0, 0},
+		{`hosts {
+			example.org 10.0.0.1
+		}`, true, nil, nil, false, 0, 0},
+		{`hosts
+hosts`, true, nil, nil, false, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		h, reload, err := hostsParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+
+		if len(h.files) != len(test.expectedFiles) {
+			t.Fatalf("Test %d expected files %v, got %v", i, test.expectedFiles, h.files)
+		}
+		for j := range h.files {
+			if h.files[j] != test.expectedFiles[j] {
+				t.Errorf("Test %d expected file %s, got %s", i, test.expectedFiles[j], h.files[j])
+			}
+		}
+		if h.Fallthrough != test.expectedFallthrough {
+			t.Errorf("Test %d expected fallthrough of %v, got %v", i, test.expectedFallthrough, h.Fallthrough)
+		}
+		if h.TTL != test.expectedTTL {
+			t.Errorf("Test %d expected TTL of %d, got %d", i, test.expectedTTL, h.TTL)
+		}
+		if reload != test.expectedReload {
+			t.Errorf("Test %d expected reload of %s, got %s", i, test.expectedReload, reload)
+		}
+	}
+}
+
+func TestHostsInlineParse(t *testing.T) {
+	c := caddy.NewTestController("dns", `hosts {
+		10.0.0.1 example.org www.example.org
+		::1 localhost
+	}`)
+	h, _, err := hostsParse(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if ips := h.LookupStaticHostV4("www.example.org."); len(ips) != 1 || ips[0].String() != "10.0.0.1" {
+		t.Errorf("Expected 10.0.0.1 for www.example.org., got %v", ips)
+	}
+	if ips := h.LookupStaticHostV6("localhost."); len(ips) != 1 || ips[0].String() != "::1" {
+		t.Errorf("Expected ::1 for localhost., got %v", ips)
+	}
+}
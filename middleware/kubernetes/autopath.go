@@ -0,0 +1,119 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/nonwriter"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+	"k8s.io/client-go/1.5/pkg/api"
+)
+
+// AutoPath holds the configuration of server side search path completion. When enabled the
+// search path of the querying pod is walked by CoreDNS instead of by the client.
+type AutoPath struct {
+	Enabled        bool
+	NDots          int      // Only names with at least this many dots are autopathed.
+	OnNXDOMAIN     int      // Rcode returned when all searches fail.
+	HostSearchPath []string // Search domains of the host, appended to the pod's search path.
+}
+
+// autoPath handles a query that is the first search of a pod's search path, i.e. a query for
+// <name>.<namespace>.svc.<zone>. It returns false when the query is not handled by autopath.
+func (k Kubernetes) autoPath(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, zone string) (int, bool) {
+	state := request.Request{W: w, Req: r}
+
+	namespace := k.podNamespace(state.IP())
+	if namespace == "" {
+		return dns.RcodeSuccess, false
+	}
+	search := k.searchPath(namespace, zone)
+
+	qname := state.Name()
+	if !strings.HasSuffix(qname, "."+search[0]) {
+		return dns.RcodeSuccess, false
+	}
+	base := strings.TrimSuffix(qname, "."+search[0])
+	if strings.Count(base, ".") < k.AutoPath.NDots {
+		return dns.RcodeSuccess, false
+	}
+
+	// The internal lookups must not be autopathed again.
+	k1 := k
+	k1.AutoPath.Enabled = false
+
+	names := make([]string, 0, len(search)+1)
+	for _, s := range search {
+		names = append(names, base+"."+s)
+	}
+	names = append(names, dns.Fqdn(base))
+
+	for _, name := range names {
+		r1 := r.Copy()
+		r1.Question[0].Name = name
+		nw := nonwriter.New(w)
+
+		if middleware.Zones(k.Zones).Matches(name) != "" {
+			k1.ServeDNS(ctx, nw, r1)
+		} else {
+			middleware.NextOrFailure(k.Name(), k.Next, ctx, nw, r1)
+		}
+
+		if nw.Msg == nil || nw.Msg.Rcode != dns.RcodeSuccess || len(nw.Msg.Answer) == 0 {
+			continue
+		}
+
+		m := nw.Msg
+		m.Id = r.Id
+		m.Question = r.Question
+		if name != qname {
+			cname := &dns.CNAME{Target: name}
+			cname.Hdr = dns.RR_Header{Name: qname, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: m.Answer[0].Header().Ttl}
+			m.Answer = append([]dns.RR{cname}, m.Answer...)
+		}
+
+		state.SizeAndDo(m)
+		m, _ = state.Scrub(m)
+		w.WriteMsg(m)
+		return m.Rcode, true
+	}
+
+	m := new(dns.Msg)
+	m.SetRcode(r, k.AutoPath.OnNXDOMAIN)
+	m.Authoritative, m.RecursionAvailable = true, true
+	state.SizeAndDo(m)
+	w.WriteMsg(m)
+	return k.AutoPath.OnNXDOMAIN, true
+}
+
+// searchPath returns the search path of a pod in namespace: <namespace>.svc.<zone>, svc.<zone>,
+// <zone> and the search domains of the host.
+func (k Kubernetes) searchPath(namespace, zone string) []string {
+	search := []string{namespace + ".svc." + zone, "svc." + zone, zone}
+	return append(search, k.AutoPath.HostSearchPath...)
+}
+
+// podNamespace returns the namespace of the pod with address ip. If no such pod is known the empty
+// string is returned.
+func (k Kubernetes) podNamespace(ip string) string {
+	if k.APIConn == nil || k.APIConn.podLister.Indexer == nil {
+		return ""
+	}
+	objList, err := k.APIConn.podLister.Indexer.ByIndex(podIPIndex, ip)
+	if err != nil {
+		return ""
+	}
+	for _, o := range objList {
+		p, ok := o.(*api.Pod)
+		if !ok {
+			continue
+		}
+		if p.Status.PodIP == ip {
+			return p.Namespace
+		}
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+	"k8s.io/client-go/1.5/pkg/api"
+)
+
+var autoPathCases = []test.Case{
+	{
+		// First search hits, no CNAME needed.
+		Qname: "svc1.podns.svc.cluster.local.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.A("svc1.podns.svc.cluster.local.	0	IN	A	10.0.0.1"),
+		},
+	},
+	{
+		// svc1.podns.svc.cluster.local. is found by the second search.
+		Qname: "svc1.podns.podns.svc.cluster.local.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.CNAME("svc1.podns.podns.svc.cluster.local.	0	IN	CNAME	svc1.podns.svc.cluster.local."),
+			test.A("svc1.podns.svc.cluster.local.	0	IN	A	10.0.0.1"),
+		},
+	},
+	{
+		// The bare name is resolved by the next middleware.
+		Qname: "example.com.podns.svc.cluster.local.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.CNAME("example.com.podns.svc.cluster.local.	3600	IN	CNAME	example.com."),
+			test.A("example.com.	3600	IN	A	192.0.2.1"),
+		},
+	},
+	{
+		// Nothing found anywhere.
+		Qname: "nothere.podns.svc.cluster.local.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeServerFailure,
+	},
+}
+
+func TestAutoPath(t *testing.T) {
+	k := newAutoPathKubernetes()
+
+	ctx := context.TODO()
+	for _, tc := range autoPathCases {
+		m := tc.Msg()
+
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		if _, err := k.ServeDNS(ctx, rec, m); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+			continue
+		}
+
+		resp := rec.Msg
+		if !test.Header(t, tc, resp) {
+			t.Logf("%v\n", resp)
+			continue
+		}
+		if !test.Section(t, tc, test.Answer, resp.Answer) {
+			t.Logf("%v\n", resp)
+		}
+	}
+}
+
+func TestAutoPathUnknownPod(t *testing.T) {
+	k := newAutoPathKubernetes()
+	// Remove the pod, the query is then answered without autopath.
+	for _, o := range k.APIConn.podLister.Indexer.List() {
+		k.APIConn.podLister.Indexer.Delete(o)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.podns.svc.cluster.local.", dns.TypeA)
+
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	k.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg.Rcode != dns.RcodeNameError {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeNameError, rec.Msg.Rcode)
+	}
+}
+
+func TestSearchPath(t *testing.T) {
+	k := Kubernetes{AutoPath: AutoPath{HostSearchPath: []string{"example.org."}}}
+	search := k.searchPath("podns", "cluster.local.")
+	expected := []string{"podns.svc.cluster.local.", "svc.cluster.local.", "cluster.local.", "example.org."}
+	if len(search) != len(expected) {
+		t.Fatalf("Expected %d search domains, got %d: %v", len(expected), len(search), search)
+	}
+	for i := range expected {
+		if search[i] != expected[i] {
+			t.Errorf("Expected search domain %q, got %q", expected[i], search[i])
+		}
+	}
+}
+
+// newAutoPathKubernetes returns a Kubernetes with autopath enabled, a single service svc1 in
+// namespace podns and a pod with the address of test.ResponseWriter in the same namespace.
+func newAutoPathKubernetes() Kubernetes {
+	c := newTestController(&api.Service{
+		ObjectMeta: api.ObjectMeta{Name: "svc1", Namespace: "podns"},
+		Spec: api.ServiceSpec{
+			ClusterIP: "10.0.0.1",
+			Ports:     []api.ServicePort{{Name: "http", Protocol: "tcp", Port: 80}},
+		},
+	})
+	c.podLister.Indexer.Add(&api.Pod{
+		ObjectMeta: api.ObjectMeta{Name: "client", Namespace: "podns"},
+		Status:     api.PodStatus{PodIP: "10.240.0.1"},
+	})
+
+	return Kubernetes{
+		Zones:    []string{"cluster.local."},
+		APIConn:  c,
+		PodMode:  PodModeVerified,
+		AutoPath: AutoPath{Enabled: true, OnNXDOMAIN: dns.RcodeServerFailure},
+		Next:     test.HandlerFunc(nextExampleCom),
+	}
+}
+
+// nextExampleCom answers example.com. A queries and returns NXDOMAIN for everything else.
+func nextExampleCom(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	m := new(dns.Msg)
+	if r.Question[0].Name != "example.com." {
+		m.SetRcode(r, dns.RcodeNameError)
+		w.WriteMsg(m)
+		return dns.RcodeNameError, nil
+	}
+	m.SetReply(r)
+	m.Answer = []dns.RR{test.A("example.com.	3600	IN	A	192.0.2.1")}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
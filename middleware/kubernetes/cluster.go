@@ -0,0 +1,111 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Cluster is a kubernetes cluster whose services are served under Zone. When multiple clusters
+// share a zone, their records are merged.
+type Cluster struct {
+	Zone          string // Zone this cluster is served under, empty means all zones.
+	APIEndpoint   string
+	APICertAuth   string
+	APIClientCert string
+	APIClientKey  string
+	APIConn       *dnsController
+}
+
+// name returns the name of the cluster used in logging and metrics.
+func (c *Cluster) name() string {
+	endpoint := c.APIEndpoint
+	if endpoint == "" {
+		endpoint = "in-cluster"
+	}
+	return strings.TrimSpace(c.Zone + " " + endpoint)
+}
+
+// clustersForZone returns the clusters that serve zone.
+func (k *Kubernetes) clustersForZone(zone string) []*Cluster {
+	clusters := []*Cluster{}
+	for _, c := range k.Clusters {
+		if zone == "" || c.Zone == "" || c.Zone == zone {
+			clusters = append(clusters, c)
+		}
+	}
+	if len(clusters) == 0 && k.APIConn != nil {
+		// No clusters configured, this happens when Kubernetes is created without InitKubeCache.
+		clusters = append(clusters, &Cluster{APIConn: k.APIConn})
+	}
+	return clusters
+}
+
+// defaultClusters fills in the connection details of clusters that only specify a zone, they use
+// the endpoint and TLS options of the kubernetes block. When clusters are configured every
+// forward zone must be served by at least one of them.
+func (k *Kubernetes) defaultClusters() error {
+	if len(k.Clusters) == 0 {
+		return nil
+	}
+	for _, c := range k.Clusters {
+		if c.APIEndpoint == "" {
+			c.APIEndpoint, c.APIClientCert, c.APIClientKey, c.APICertAuth = k.APIEndpoint, k.APIClientCert, k.APIClientKey, k.APICertAuth
+		}
+	}
+	for _, z := range k.Zones {
+		if strings.HasSuffix(z, "in-addr.arpa.") || strings.HasSuffix(z, "ip6.arpa.") {
+			continue
+		}
+		found := false
+		for _, c := range k.Clusters {
+			if c.Zone == z {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no cluster configured for zone %s", z)
+		}
+	}
+	return nil
+}
+
+// Health implements the health.Healther interface. Kubernetes is healthy when the API of all
+// clusters can be reached.
+func (k *Kubernetes) Health() bool {
+	for _, c := range k.clustersForZone("") {
+		if !c.APIConn.Connected() {
+			return false
+		}
+	}
+	return true
+}
+
+// Ready implements the health.Readiness interface. Kubernetes is ready when the caches of all
+// clusters have synced.
+func (k *Kubernetes) Ready() bool {
+	for _, c := range k.clustersForZone("") {
+		if !c.APIConn.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+var clusterConnected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: middleware.Namespace,
+	Subsystem: "kubernetes",
+	Name:      "cluster_connected",
+	Help:      "Set to 1 when the kubernetes API of the cluster can be reached.",
+}, []string{"cluster"})
+
+const connectionCheckInterval = 10 * time.Second
+
+func init() {
+	prometheus.MustRegister(clusterConnected)
+}
@@ -0,0 +1,146 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/middleware/etcd/msg"
+
+	"k8s.io/client-go/1.5/pkg/api"
+	"k8s.io/client-go/1.5/tools/cache"
+)
+
+func TestClustersForZone(t *testing.T) {
+	a := &Cluster{Zone: "a.local."}
+	b := &Cluster{Zone: "b.local."}
+	b1 := &Cluster{Zone: "b.local."}
+	k := Kubernetes{Zones: []string{"a.local.", "b.local."}, Clusters: []*Cluster{a, b, b1}}
+
+	if x := k.clustersForZone("a.local."); len(x) != 1 || x[0] != a {
+		t.Errorf("Expected cluster a for a.local., got %v", x)
+	}
+	if x := k.clustersForZone("b.local."); len(x) != 2 || x[0] != b || x[1] != b1 {
+		t.Errorf("Expected clusters b and b1 for b.local., got %v", x)
+	}
+	if x := k.clustersForZone(""); len(x) != 3 {
+		t.Errorf("Expected all clusters, got %v", x)
+	}
+}
+
+func TestDefaultClusters(t *testing.T) {
+	k := Kubernetes{
+		Zones:       []string{"a.local.", "b.local.", "10.in-addr.arpa."},
+		APIEndpoint: "https://default:443",
+		Clusters:    []*Cluster{{Zone: "a.local."}, {Zone: "b.local.", APIEndpoint: "https://b:443"}},
+	}
+	if err := k.defaultClusters(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if k.Clusters[0].APIEndpoint != "https://default:443" {
+		t.Errorf("Expected default endpoint for cluster a, got %s", k.Clusters[0].APIEndpoint)
+	}
+	if k.Clusters[1].APIEndpoint != "https://b:443" {
+		t.Errorf("Expected endpoint https://b:443 for cluster b, got %s", k.Clusters[1].APIEndpoint)
+	}
+
+	k.Clusters = k.Clusters[:1]
+	if err := k.defaultClusters(); err == nil {
+		t.Errorf("Expected error for zone without a cluster, got none")
+	}
+}
+
+func TestRecordsMultiCluster(t *testing.T) {
+	svc := func(ip string) *api.Service {
+		return &api.Service{
+			ObjectMeta: api.ObjectMeta{Name: "svc1", Namespace: "testns"},
+			Spec:       api.ServiceSpec{ClusterIP: ip, Ports: []api.ServicePort{{Name: "http", Protocol: "tcp", Port: 80}}},
+		}
+	}
+	a, b := newTestController(svc("10.0.0.1")), newTestController(svc("10.0.1.1"))
+	k := Kubernetes{
+		Zones:    []string{"cluster.local."},
+		Clusters: []*Cluster{{Zone: "cluster.local.", APIConn: a}, {Zone: "cluster.local.", APIConn: b}},
+	}
+	r := recordRequest{service: "svc1", namespace: "testns", typeName: "svc", zone: "cluster.local."}
+
+	records, err := k.Records(r)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !hasHost(records, "10.0.0.1") || !hasHost(records, "10.0.1.1") {
+		t.Errorf("Expected records from both clusters, got %v", records)
+	}
+
+	// Cluster b is disconnected, only records from a are returned.
+	b.connected = 0
+	records, err = k.Records(r)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !hasHost(records, "10.0.0.1") || hasHost(records, "10.0.1.1") {
+		t.Errorf("Expected records from cluster a only, got %v", records)
+	}
+
+	// Both are disconnected, fail instead of returning an empty answer.
+	a.connected = 0
+	if _, err := k.Records(r); err != errNotConnected {
+		t.Errorf("Expected error %v, got %v", errNotConnected, err)
+	}
+}
+
+func hasHost(records []msg.Service, host string) bool {
+	for _, r := range records {
+		if r.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
+// newTestController returns a connected dnsController that holds services.
+func newTestController(services ...*api.Service) *dnsController {
+	c := &dnsController{
+		svcLister: cache.StoreToServiceLister{Indexer: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})},
+		podLister: cache.StoreToPodLister{Indexer: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{podIPIndex: podIPIndexFunc})},
+		nsLister:  storeToNamespaceLister{cache.NewStore(cache.MetaNamespaceKeyFunc)},
+		epLister:  cache.StoreToEndpointsLister{Store: cache.NewStore(cache.MetaNamespaceKeyFunc)},
+		connected: 1,
+		synced:    make(chan struct{}),
+	}
+	close(c.synced)
+	for _, s := range services {
+		c.svcLister.Indexer.Add(s)
+	}
+	return c
+}
+
+func TestReady(t *testing.T) {
+	a, b := newTestController(), newTestController()
+	k := Kubernetes{
+		Zones:    []string{"cluster.local."},
+		Clusters: []*Cluster{{Zone: "cluster.local.", APIConn: a}, {Zone: "cluster.local.", APIConn: b}},
+	}
+	if !k.Ready() {
+		t.Errorf("Expected kubernetes to be ready")
+	}
+
+	// Cluster b has not synced and the deadline has passed, queries fail immediately.
+	b.synced = make(chan struct{})
+	if k.Ready() {
+		t.Errorf("Expected kubernetes not to be ready")
+	}
+	if b.waitSynced() {
+		t.Errorf("Expected waitSynced to return false")
+	}
+}
+
+func TestHealth(t *testing.T) {
+	a := newTestController()
+	k := Kubernetes{Zones: []string{"cluster.local."}, Clusters: []*Cluster{{Zone: "cluster.local.", APIConn: a}}}
+	if !k.Health() {
+		t.Errorf("Expected kubernetes to be healthy")
+	}
+	a.connected = 0
+	if k.Health() {
+		t.Errorf("Expected kubernetes not to be healthy")
+	}
+}
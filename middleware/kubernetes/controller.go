@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"k8s.io/client-go/1.5/kubernetes"
@@ -37,18 +38,21 @@ func (s *storeToNamespaceLister) List() (ns api.NamespaceList, err error) {
 
 type dnsController struct {
 	client *kubernetes.Clientset
+	name   string // Name of the cluster, used in logging and metrics.
 
 	selector *labels.Selector
 
-	svcController *cache.Controller
-	podController *cache.Controller
-	nsController  *cache.Controller
-	epController  *cache.Controller
+	svcController  *cache.Controller
+	podController  *cache.Controller
+	nsController   *cache.Controller
+	epController   *cache.Controller
+	nodeController *cache.Controller
 
 	svcLister cache.StoreToServiceLister
 	podLister cache.StoreToPodLister
 	nsLister  storeToNamespaceLister
 	epLister  cache.StoreToEndpointsLister
+	nodeStore cache.Store // Only set when nodes are watched, i.e. when federations are configured.
 
 	// stopLock is used to enforce only a single call to Stop is active.
 	// Needed because we allow stopping through an http endpoint and
@@ -56,14 +60,22 @@ type dnsController struct {
 	stopLock sync.Mutex
 	shutdown bool
 	stopCh   chan struct{}
+
+	connected int32 // Set to 1 when the API server can be reached, use atomic.
+
+	synced       chan struct{} // Closed when the caches have synced for the first time.
+	syncDeadline time.Time     // Until when queries wait for the caches to sync.
 }
 
 // newDNSController creates a controller for CoreDNS.
-func newdnsController(kubeClient *kubernetes.Clientset, resyncPeriod time.Duration, lselector *labels.Selector, initPodCache bool) *dnsController {
+func newdnsController(kubeClient *kubernetes.Clientset, name string, resyncPeriod, syncTimeout time.Duration, lselector *labels.Selector, initPodCache, initNodeCache bool) *dnsController {
 	dns := dnsController{
-		client:   kubeClient,
-		selector: lselector,
-		stopCh:   make(chan struct{}),
+		client:       kubeClient,
+		name:         name,
+		selector:     lselector,
+		stopCh:       make(chan struct{}),
+		synced:       make(chan struct{}),
+		syncDeadline: time.Now().Add(syncTimeout),
 	}
 
 	dns.svcLister.Indexer, dns.svcController = cache.NewIndexerInformer(
@@ -102,6 +114,16 @@ func newdnsController(kubeClient *kubernetes.Clientset, resyncPeriod time.Durati
 		},
 		&api.Endpoints{}, resyncPeriod, cache.ResourceEventHandlerFuncs{})
 
+	if initNodeCache {
+		// Nodes are not filtered with the label selector, that applies to the records served.
+		dns.nodeStore, dns.nodeController = cache.NewInformer(
+			&cache.ListWatch{
+				ListFunc:  nodeListFunc(dns.client),
+				WatchFunc: nodeWatchFunc(dns.client),
+			},
+			&api.Node{}, resyncPeriod, cache.ResourceEventHandlerFuncs{})
+	}
+
 	return &dns
 }
 
@@ -190,6 +212,14 @@ func v1ToAPIFilter(in watch.Event) (out watch.Event, keep bool) {
 			return in, true
 		}
 		return watch.Event{Type: in.Type, Object: &apiObj}, true
+	case *v1.Node:
+		var apiObj api.Node
+		err := v1.Convert_v1_Node_To_api_Node(v1Obj, &apiObj, nil)
+		if err != nil {
+			log.Printf("[ERROR] Could not convert v1.Node: %s", err)
+			return in, true
+		}
+		return watch.Event{Type: in.Type, Object: &apiObj}, true
 	}
 
 	log.Printf("[WARN] Unhandled v1 type in event: %v", in)
@@ -286,8 +316,76 @@ func endpointsWatchFunc(c *kubernetes.Clientset, ns string, s *labels.Selector)
 	}
 }
 
+func nodeListFunc(c *kubernetes.Clientset) func(api.ListOptions) (runtime.Object, error) {
+	return func(opts api.ListOptions) (runtime.Object, error) {
+		listV1, err := c.Core().Nodes().List(opts)
+		if err != nil {
+			return nil, err
+		}
+		var listAPI api.NodeList
+		err = v1.Convert_v1_NodeList_To_api_NodeList(listV1, &listAPI, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &listAPI, err
+	}
+}
+
+func nodeWatchFunc(c *kubernetes.Clientset) func(options api.ListOptions) (watch.Interface, error) {
+	return func(options api.ListOptions) (watch.Interface, error) {
+		w, err := c.Core().Nodes().Watch(options)
+		if err != nil {
+			return nil, err
+		}
+		return watch.Filter(w, v1ToAPIFilter), nil
+	}
+}
+
 func (dns *dnsController) controllersInSync() bool {
-	return dns.svcController.HasSynced()
+	synced := dns.svcController.HasSynced() && dns.nsController.HasSynced() && dns.epController.HasSynced()
+	if dns.podController != nil {
+		synced = synced && dns.podController.HasSynced()
+	}
+	if dns.nodeController != nil {
+		synced = synced && dns.nodeController.HasSynced()
+	}
+	return synced
+}
+
+// waitForSync closes dns.synced once all caches have synced.
+func (dns *dnsController) waitForSync() {
+	if cache.WaitForCacheSync(dns.stopCh, dns.controllersInSync) {
+		log.Printf("[INFO] Kubernetes caches of cluster %q synced", dns.name)
+		close(dns.synced)
+	}
+}
+
+// HasSynced returns true when the caches have synced with the API.
+func (dns *dnsController) HasSynced() bool {
+	select {
+	case <-dns.synced:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitSynced waits until the caches have synced or the sync deadline has passed. It returns true
+// if the caches have synced.
+func (dns *dnsController) waitSynced() bool {
+	if dns.HasSynced() {
+		return true
+	}
+	wait := dns.syncDeadline.Sub(time.Now())
+	if wait <= 0 {
+		return false
+	}
+	select {
+	case <-dns.synced:
+		return true
+	case <-time.After(wait):
+		return false
+	}
 }
 
 // Stop stops the  controller.
@@ -314,9 +412,52 @@ func (dns *dnsController) Run() {
 	if dns.podController != nil {
 		go dns.podController.Run(dns.stopCh)
 	}
+	if dns.nodeController != nil {
+		go dns.nodeController.Run(dns.stopCh)
+	}
+	go dns.checkConnection()
+	go dns.waitForSync()
 	<-dns.stopCh
 }
 
+// Connected returns true if the API server could be reached during the last check.
+func (dns *dnsController) Connected() bool { return atomic.LoadInt32(&dns.connected) == 1 }
+
+// setConnected sets the connection state and reports changes to it.
+func (dns *dnsController) setConnected(up bool) {
+	var v int32
+	if up {
+		v = 1
+	}
+	old := atomic.SwapInt32(&dns.connected, v)
+	clusterConnected.WithLabelValues(dns.name).Set(float64(v))
+	if old == v {
+		return
+	}
+	if up {
+		log.Printf("[INFO] Connected to kubernetes API of cluster %q", dns.name)
+		return
+	}
+	log.Printf("[ERROR] Lost connection to kubernetes API of cluster %q", dns.name)
+}
+
+// checkConnection checks if the API server can be reached every connectionCheckInterval, until
+// the controller is stopped.
+func (dns *dnsController) checkConnection() {
+	ticker := time.NewTicker(connectionCheckInterval)
+	defer ticker.Stop()
+	for {
+		_, err := dns.client.Discovery().ServerVersion()
+		dns.setConnected(err == nil)
+
+		select {
+		case <-ticker.C:
+		case <-dns.stopCh:
+			return
+		}
+	}
+}
+
 func (dns *dnsController) NamespaceList() *api.NamespaceList {
 	nsList, err := dns.nsLister.List()
 	if err != nil {
@@ -358,3 +499,20 @@ func (dns *dnsController) ServiceInNamespace(namespace, servicename string) *api
 	}
 	return svcObj
 }
+
+// GetNodeByName returns the node with name from the node cache.
+func (dns *dnsController) GetNodeByName(name string) (*api.Node, error) {
+	if dns.nodeStore == nil {
+		return nil, errNoNodeCache
+	}
+	obj, exists, err := dns.nodeStore.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("node %q not found", name)
+	}
+	return obj.(*api.Node), nil
+}
+
+var errNoNodeCache = errors.New("nodes are not watched")
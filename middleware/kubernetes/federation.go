@@ -0,0 +1,134 @@
+package kubernetes
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/middleware/etcd/msg"
+
+	"k8s.io/client-go/1.5/pkg/api"
+	unversionedapi "k8s.io/client-go/1.5/pkg/api/unversioned"
+)
+
+// Federation is a kubernetes federation this cluster is a member of.
+type Federation struct {
+	name string // Name of the federation, used as a label in the query name.
+	zone string // DNS domain the federation's services are published under.
+}
+
+var errNoFederationLabels = errors.New("local node has no zone or region labels")
+
+// stripFederation checks if the second to last label in segs is the name of a configured
+// federation. If so the federation's name and segs without that label are returned.
+func (k *Kubernetes) stripFederation(segs []string) (string, []string) {
+	if len(segs) < 3 {
+		return "", segs
+	}
+	l := len(segs)
+	for _, f := range k.Federations {
+		if f.name == segs[l-2] {
+			return f.name, append(segs[:l-2:l-2], segs[l-1])
+		}
+	}
+	return "", segs
+}
+
+// federationRecords returns a service that is a CNAME to the name r is known under in the
+// federation, in the availability zone and region of the node CoreDNS runs on.
+func (k *Kubernetes) federationRecords(r recordRequest) ([]msg.Service, error) {
+	node, err := k.localNode()
+	if err != nil {
+		return nil, err
+	}
+	return k.federationCNAME(r, node.Labels[unversionedapi.LabelZoneFailureDomain], node.Labels[unversionedapi.LabelZoneRegion])
+}
+
+// federationCNAME returns a service for r that is a CNAME to
+// [endpoint.]service.namespace.federation.svc.<az>.<region>.<federation zone>.
+func (k *Kubernetes) federationCNAME(r recordRequest, az, region string) ([]msg.Service, error) {
+	if az == "" || region == "" {
+		return nil, errNoFederationLabels
+	}
+	var zone string
+	for _, f := range k.Federations {
+		if f.name == r.federation {
+			zone = f.zone
+			break
+		}
+	}
+
+	name := []string{r.service, r.namespace, r.federation, r.typeName}
+	if r.endpoint != "" {
+		name = append([]string{r.endpoint}, name...)
+	}
+	key := strings.Join(append(name, r.zone), ".")
+	target := strings.Join(append(name, az, region, zone), ".")
+
+	return []msg.Service{{Key: msg.Path(key, "coredns"), Host: target}}, nil
+}
+
+// hasEndpoints returns true if any of the services has a ready endpoint in the local cluster.
+func (k *Kubernetes) hasEndpoints(services []service) bool {
+	for _, svc := range services {
+		if len(svc.endpoints) > 0 {
+			return true
+		}
+	}
+	endpointsList, err := k.APIConn.epLister.List()
+	if err != nil {
+		return false
+	}
+	for _, svc := range services {
+		for _, ep := range endpointsList.Items {
+			if ep.ObjectMeta.Name != svc.name || ep.ObjectMeta.Namespace != svc.namespace {
+				continue
+			}
+			for _, eps := range ep.Subsets {
+				if len(eps.Addresses) > 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// localNode returns the node CoreDNS runs on. It is found by looking for the endpoint that has
+// the address of this instance.
+func (k *Kubernetes) localNode() (*api.Node, error) {
+	ip := k.interfaceAddrsFunc()
+	if ip == nil {
+		return nil, errors.New("no local address found")
+	}
+	endpointsList, err := k.APIConn.epLister.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, ep := range endpointsList.Items {
+		for _, eps := range ep.Subsets {
+			for _, addr := range eps.Addresses {
+				if addr.NodeName != nil && ip.Equal(net.ParseIP(addr.IP)) {
+					return k.APIConn.GetNodeByName(*addr.NodeName)
+				}
+			}
+		}
+	}
+	return nil, errors.New("no endpoint found for local address " + ip.String())
+}
+
+// localPodIP returns the first non-loopback address of this host.
+func localPodIP() net.IP {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		ip, _, _ := net.ParseCIDR(addr.String())
+		if ip == nil || ip.IsLoopback() {
+			continue
+		}
+		return ip
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package kubernetes
+
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/etcd/msg"
+
+	"k8s.io/client-go/1.5/pkg/api"
+	unversionedapi "k8s.io/client-go/1.5/pkg/api/unversioned"
+	"k8s.io/client-go/1.5/tools/cache"
+)
+
+func TestParseFederationRequest(t *testing.T) {
+	k := Kubernetes{
+		Zones:       []string{"interwebs.test."},
+		Federations: []Federation{{name: "fed", zone: "era.tion.com."}},
+	}
+	f := "parseRequest"
+
+	query := "svc1.testns.fed.svc.interwebs.test."
+	r, e := k.parseRequest(query, "A")
+	if e != nil {
+		t.Errorf("Expected no error from parseRequest(%v, \"A\"). Instead got '%v'.", query, e)
+	}
+	tcs := map[string]string{
+		"endpoint":   "",
+		"service":    "svc1",
+		"namespace":  "testns",
+		"federation": "fed",
+		"typeName":   "svc",
+		"zone":       "interwebs.test.",
+	}
+	for field, expected := range tcs {
+		expectString(t, f, "A", query, &r, field, expected)
+	}
+
+	query = "_http._tcp.svc1.testns.fed.svc.interwebs.test."
+	r, e = k.parseRequest(query, "SRV")
+	if e != nil {
+		t.Errorf("Expected no error from parseRequest(%v, \"SRV\"). Instead got '%v'.", query, e)
+	}
+	expectString(t, f, "SRV", query, &r, "federation", "fed")
+	expectString(t, f, "SRV", query, &r, "port", "http")
+
+	// Not a configured federation.
+	query = "svc1.testns.nofed.svc.interwebs.test."
+	r, e = k.parseRequest(query, "A")
+	if e != nil {
+		t.Errorf("Expected no error from parseRequest(%v, \"A\"). Instead got '%v'.", query, e)
+	}
+	expectString(t, f, "A", query, &r, "federation", "")
+	expectString(t, f, "A", query, &r, "endpoint", "svc1")
+}
+
+func TestFederationCNAME(t *testing.T) {
+	k := Kubernetes{
+		Zones:       []string{"interwebs.test."},
+		Federations: []Federation{{name: "fed", zone: "era.tion.com."}},
+	}
+
+	tests := []struct {
+		r        recordRequest
+		az       string
+		region   string
+		expected msg.Service
+		err      error
+	}{
+		{
+			r:        recordRequest{service: "svc1", namespace: "testns", federation: "fed", typeName: "svc", zone: "interwebs.test."},
+			az:       "fd-az",
+			region:   "fd-r",
+			expected: msg.Service{Key: "/coredns/test/interwebs/svc/fed/testns/svc1", Host: "svc1.testns.fed.svc.fd-az.fd-r.era.tion.com."},
+		},
+		{
+			r:        recordRequest{endpoint: "ep1", service: "svc1", namespace: "testns", federation: "fed", typeName: "svc", zone: "interwebs.test."},
+			az:       "fd-az",
+			region:   "fd-r",
+			expected: msg.Service{Key: "/coredns/test/interwebs/svc/fed/testns/svc1/ep1", Host: "ep1.svc1.testns.fed.svc.fd-az.fd-r.era.tion.com."},
+		},
+		{
+			r:   recordRequest{service: "svc1", namespace: "testns", federation: "fed", typeName: "svc", zone: "interwebs.test."},
+			az:  "fd-az",
+			err: errNoFederationLabels,
+		},
+	}
+
+	for i, tc := range tests {
+		s, err := k.federationCNAME(tc.r, tc.az, tc.region)
+		if err != tc.err {
+			t.Errorf("Test %d: Expected error %v, got %v", i, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(s) != 1 {
+			t.Fatalf("Test %d: Expected 1 service, got %d", i, len(s))
+		}
+		if s[0].Key != tc.expected.Key || s[0].Host != tc.expected.Host {
+			t.Errorf("Test %d: Expected %s -> %s, got %s -> %s", i, tc.expected.Key, tc.expected.Host, s[0].Key, s[0].Host)
+		}
+	}
+}
+
+func TestLocalNode(t *testing.T) {
+	c := newTestController()
+	c.nodeStore = cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	node := "node1"
+	c.epLister.Store.Add(&api.Endpoints{
+		ObjectMeta: api.ObjectMeta{Name: "coredns", Namespace: "kube-system"},
+		Subsets:    []api.EndpointSubset{{Addresses: []api.EndpointAddress{{IP: "10.0.0.10", NodeName: &node}}}},
+	})
+	c.nodeStore.Add(&api.Node{ObjectMeta: api.ObjectMeta{
+		Name:   node,
+		Labels: map[string]string{unversionedapi.LabelZoneFailureDomain: "fd-az", unversionedapi.LabelZoneRegion: "fd-r"},
+	}})
+
+	k := Kubernetes{APIConn: c, interfaceAddrsFunc: func() net.IP { return net.ParseIP("10.0.0.10") }}
+	n, err := k.localNode()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if n.Labels[unversionedapi.LabelZoneFailureDomain] != "fd-az" || n.Labels[unversionedapi.LabelZoneRegion] != "fd-r" {
+		t.Errorf("Expected zone fd-az and region fd-r, got %v", n.Labels)
+	}
+
+	// Without the node cache the node can't be found.
+	c.nodeStore = nil
+	if _, err := k.localNode(); err != errNoNodeCache {
+		t.Errorf("Expected error %v, got %v", errNoNodeCache, err)
+	}
+}
@@ -39,6 +39,12 @@ func (k Kubernetes) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.M
 		zone = state.Name()
 	}
 
+	if k.AutoPath.Enabled {
+		if rcode, ok := k.autoPath(ctx, w, r, zone); ok {
+			return rcode, nil
+		}
+	}
+
 	var (
 		records, extra []dns.RR
 		err            error
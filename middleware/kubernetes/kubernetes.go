@@ -38,11 +38,17 @@ type Kubernetes struct {
 	APIClientKey  string
 	APIConn       *dnsController
 	ResyncPeriod  time.Duration
+	SyncTimeout   time.Duration // How long queries are held while waiting for the caches to sync.
 	Namespaces    []string
 	LabelSelector *unversionedapi.LabelSelector
 	Selector      *labels.Selector
 	PodMode       string
 	ReverseCidrs  []net.IPNet
+	AutoPath      AutoPath
+	Federations   []Federation
+	Clusters      []*Cluster
+
+	interfaceAddrsFunc func() net.IP
 }
 
 const (
@@ -62,11 +68,12 @@ type endpoint struct {
 }
 
 type service struct {
-	name      string
-	namespace string
-	addr      string
-	ports     []api.ServicePort
-	endpoints []endpoint
+	name         string
+	namespace    string
+	addr         string
+	externalName string
+	ports        []api.ServicePort
+	endpoints    []endpoint
 }
 
 type pod struct {
@@ -76,12 +83,13 @@ type pod struct {
 }
 
 type recordRequest struct {
-	port, protocol, endpoint, service, namespace, typeName, zone string
+	port, protocol, endpoint, service, namespace, typeName, zone, federation string
 }
 
 var errNoItems = errors.New("no items found")
 var errNsNotExposed = errors.New("namespace is not exposed")
 var errInvalidRequest = errors.New("invalid query name")
+var errNotConnected = errors.New("not connected to the kubernetes API or not synced")
 
 // Services implements the ServiceBackend interface.
 func (k *Kubernetes) Services(state request.Request, exact bool, opt middleware.Options) ([]msg.Service, []msg.Service, error) {
@@ -92,7 +100,7 @@ func (k *Kubernetes) Services(state request.Request, exact bool, opt middleware.
 	}
 
 	switch state.Type() {
-	case "A", "SRV":
+	case "A", "AAAA", "CNAME", "SRV":
 		s, e := k.Records(r)
 		return s, nil, e // Haven't implemented debug queries yet.
 	case "TXT":
@@ -126,8 +134,21 @@ func (k *Kubernetes) Reverse(state request.Request, exact bool, opt middleware.O
 		return nil, nil, nil
 	}
 
-	records := k.getServiceRecordForIP(ip, state.Name())
-	return records, nil, nil
+	for _, c := range k.clustersForZone("") {
+		if !c.APIConn.waitSynced() || !c.APIConn.Connected() {
+			continue
+		}
+		k1 := *k
+		k1.APIConn = c.APIConn
+		zone := c.Zone
+		if zone == "" {
+			zone = k.PrimaryZone()
+		}
+		if records := k1.getServiceRecordForIP(ip, zone); records != nil {
+			return records, nil, nil
+		}
+	}
+	return nil, nil, nil
 }
 
 func (k *Kubernetes) isRequestInReverseRange(state request.Request) bool {
@@ -153,15 +174,15 @@ func (k *Kubernetes) IsNameError(err error) bool {
 // Debug implements the ServiceBackend interface.
 func (k *Kubernetes) Debug() string { return "debug" }
 
-func (k *Kubernetes) getClientConfig() (*rest.Config, error) {
+func getClientConfig(c *Cluster) (*rest.Config, error) {
 	// For a custom api server or running outside a k8s cluster
 	// set URL in env.KUBERNETES_MASTER or set endpoint in Corefile
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
 	overrides := &clientcmd.ConfigOverrides{}
 	clusterinfo := clientcmdapi.Cluster{}
 	authinfo := clientcmdapi.AuthInfo{}
-	if len(k.APIEndpoint) > 0 {
-		clusterinfo.Server = k.APIEndpoint
+	if len(c.APIEndpoint) > 0 {
+		clusterinfo.Server = c.APIEndpoint
 	} else {
 		cc, err := rest.InClusterConfig()
 		if err != nil {
@@ -169,14 +190,14 @@ func (k *Kubernetes) getClientConfig() (*rest.Config, error) {
 		}
 		return cc, err
 	}
-	if len(k.APICertAuth) > 0 {
-		clusterinfo.CertificateAuthority = k.APICertAuth
+	if len(c.APICertAuth) > 0 {
+		clusterinfo.CertificateAuthority = c.APICertAuth
 	}
-	if len(k.APIClientCert) > 0 {
-		authinfo.ClientCertificate = k.APIClientCert
+	if len(c.APIClientCert) > 0 {
+		authinfo.ClientCertificate = c.APIClientCert
 	}
-	if len(k.APIClientKey) > 0 {
-		authinfo.ClientKey = k.APIClientKey
+	if len(c.APIClientKey) > 0 {
+		authinfo.ClientKey = c.APIClientKey
 	}
 	overrides.ClusterInfo = clusterinfo
 	overrides.AuthInfo = authinfo
@@ -184,22 +205,11 @@ func (k *Kubernetes) getClientConfig() (*rest.Config, error) {
 	return clientConfig.ClientConfig()
 }
 
-// InitKubeCache initializes a new Kubernetes cache.
+// InitKubeCache initializes a new Kubernetes cache for each cluster. Without configured clusters
+// a single connection, made with APIEndpoint and the TLS options, is used for all zones.
 func (k *Kubernetes) InitKubeCache() error {
-
-	config, err := k.getClientConfig()
-	if err != nil {
-		return err
-	}
-
-	kubeClient, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return fmt.Errorf("Failed to create kubernetes notification controller: %v", err)
-	}
-
 	if k.LabelSelector != nil {
-		var selector labels.Selector
-		selector, err = unversionedapi.LabelSelectorAsSelector(k.LabelSelector)
+		selector, err := unversionedapi.LabelSelectorAsSelector(k.LabelSelector)
 		k.Selector = &selector
 		if err != nil {
 			return fmt.Errorf("Unable to create Selector for LabelSelector '%s'.Error was: %s", k.LabelSelector, err)
@@ -212,16 +222,34 @@ func (k *Kubernetes) InitKubeCache() error {
 		log.Printf("[INFO] Kubernetes middleware configured with the label selector '%s'. Only kubernetes objects matching this label selector will be exposed.", unversionedapi.FormatLabelSelector(k.LabelSelector))
 	}
 
-	k.APIConn = newdnsController(kubeClient, k.ResyncPeriod, k.Selector, k.PodMode == PodModeVerified)
+	if len(k.Clusters) == 0 {
+		k.Clusters = []*Cluster{{APIEndpoint: k.APIEndpoint, APICertAuth: k.APICertAuth, APIClientCert: k.APIClientCert, APIClientKey: k.APIClientKey}}
+	}
+	for i, c := range k.Clusters {
+		config, err := getClientConfig(c)
+		if err != nil {
+			return err
+		}
+
+		kubeClient, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return fmt.Errorf("Failed to create kubernetes notification controller: %v", err)
+		}
+		// Only the nodes of the cluster CoreDNS runs in are needed, to find the zone and region for federation.
+		initNodeCache := i == 0 && len(k.Federations) > 0
+		c.APIConn = newdnsController(kubeClient, c.name(), k.ResyncPeriod, k.SyncTimeout, k.Selector, k.PodMode == PodModeVerified, initNodeCache)
+	}
+	// APIConn is the cluster CoreDNS runs in, used for autopath and federation.
+	k.APIConn = k.Clusters[0].APIConn
 
-	return err
+	return nil
 }
 
 func (k *Kubernetes) parseRequest(lowerCasedName, qtype string) (r recordRequest, err error) {
 	// 3 Possible cases
-	//   SRV Request: _port._protocol.service.namespace.type.zone
-	//   A Request (endpoint): endpoint.service.namespace.type.zone
-	//   A Request (service): service.namespace.type.zone
+	//   SRV Request: _port._protocol.service.namespace.[federation.]type.zone
+	//   A Request (endpoint): endpoint.service.namespace.[federation.]type.zone
+	//   A Request (service): service.namespace.[federation.]type.zone
 
 	// separate zone from rest of lowerCasedName
 	var segs []string
@@ -238,6 +266,8 @@ func (k *Kubernetes) parseRequest(lowerCasedName, qtype string) (r recordRequest
 		return r, errors.New("zone not found")
 	}
 
+	r.federation, segs = k.stripFederation(segs)
+
 	offset := 0
 	if qtype == "SRV" {
 		if len(segs) != 5 {
@@ -269,8 +299,8 @@ func (k *Kubernetes) parseRequest(lowerCasedName, qtype string) (r recordRequest
 		}
 		offset = 2
 	}
-	if qtype == "A" && len(segs) == 4 {
-		// This is an endpoint A record request. Get first element as endpoint.
+	if (qtype == "A" || qtype == "AAAA") && len(segs) == 4 {
+		// This is an endpoint A/AAAA record request. Get first element as endpoint.
 		r.endpoint = segs[0]
 		offset = 1
 	}
@@ -303,9 +333,38 @@ func (k *Kubernetes) Records(r recordRequest) ([]msg.Service, error) {
 		return nil, errNsNotExposed
 	}
 
-	services, pods, err := k.get(r)
-	if err != nil {
-		return nil, err
+	var (
+		services  []service
+		pods      []pod
+		endpoints bool
+		connected bool
+	)
+	for _, c := range k.clustersForZone(r.zone) {
+		// Hold the query until the caches have synced, an unsynced cache would give
+		// NXDOMAIN for names that do exist.
+		if !c.APIConn.waitSynced() || !c.APIConn.Connected() {
+			continue
+		}
+		connected = true
+
+		k1 := *k
+		k1.APIConn = c.APIConn
+		s, p, err := k1.get(r)
+		if err != nil {
+			return nil, err
+		}
+		if r.federation != "" && k1.hasEndpoints(s) {
+			endpoints = true
+		}
+		services = append(services, s...)
+		pods = append(pods, p...)
+	}
+	if !connected {
+		return nil, errNotConnected
+	}
+	if r.federation != "" && !endpoints {
+		// No healthy local endpoints, point to the federated service elsewhere.
+		return k.federationRecords(r)
 	}
 	if len(services) == 0 && len(pods) == 0 {
 		// Did not find item in k8s
@@ -336,6 +395,13 @@ func (k *Kubernetes) getRecordsForK8sItems(services []service, pods []pod, zone
 
 		key := svc.name + "." + svc.namespace + ".svc." + zone
 
+		if svc.externalName != "" {
+			// ExternalName service, this becomes a CNAME to the external name.
+			s := msg.Service{Key: msg.Path(strings.ToLower(key), "coredns"), Host: svc.externalName}
+			records = append(records, s)
+			continue
+		}
+
 		if svc.addr == api.ClusterIPNone {
 			// This is a headless service, create records for each endpoint
 			for _, ep := range svc.endpoints {
@@ -451,6 +517,15 @@ func (k *Kubernetes) findServices(r recordRequest) ([]service, error) {
 			continue
 		}
 		s := service{name: svc.Name, namespace: svc.Namespace, addr: svc.Spec.ClusterIP}
+		if svc.Spec.Type == api.ServiceTypeExternalName {
+			// ExternalName services don't have ports or endpoints, so no SRV or endpoint records.
+			if r.endpoint != "" || r.port != "" {
+				continue
+			}
+			s.externalName = svc.Spec.ExternalName
+			resultItems = append(resultItems, s)
+			continue
+		}
 		if s.addr != api.ClusterIPNone {
 			for _, p := range svc.Spec.Ports {
 				if !(symbolMatches(r.port, strings.ToLower(p.Name), portWildcard) && symbolMatches(r.protocol, strings.ToLower(string(p.Protocol)), protocolWildcard)) {
@@ -472,11 +547,16 @@ func (k *Kubernetes) findServices(r recordRequest) ([]service, error) {
 			}
 			for _, eps := range ep.Subsets {
 				for _, addr := range eps.Addresses {
+					ephostname := endpointHostname(addr)
+					if r.endpoint != "" && r.endpoint != ephostname {
+						continue
+					}
+					// A headless service without ports still has A/AAAA records for its endpoints.
+					if len(eps.Ports) == 0 && r.port == "" {
+						s.endpoints = append(s.endpoints, endpoint{addr: addr})
+						continue
+					}
 					for _, p := range eps.Ports {
-						ephostname := endpointHostname(addr)
-						if r.endpoint != "" && r.endpoint != ephostname {
-							continue
-						}
 						if !(symbolMatches(r.port, strings.ToLower(p.Name), portWildcard) && symbolMatches(r.protocol, strings.ToLower(string(p.Protocol)), protocolWildcard)) {
 							continue
 						}
@@ -485,11 +565,39 @@ func (k *Kubernetes) findServices(r recordRequest) ([]service, error) {
 				}
 			}
 		}
+		if r.endpoint != "" && len(s.endpoints) == 0 {
+			s.endpoints = k.findPodHostnames(r, svc.Name, svc.Namespace)
+		}
 		resultItems = append(resultItems, s)
 	}
 	return resultItems, nil
 }
 
+// findPodHostnames returns endpoints for the pods in namespace that have their hostname set to
+// r.endpoint and their subdomain set to subdomain, i.e. hostname.subdomain.namespace.svc.zone.
+// This is only possible when the pod cache is enabled ("pods verified").
+func (k *Kubernetes) findPodHostnames(r recordRequest, subdomain, namespace string) []endpoint {
+	if k.APIConn.podLister.Indexer == nil {
+		return nil
+	}
+
+	var eps []endpoint
+	for _, o := range k.APIConn.podLister.Indexer.List() {
+		p, ok := o.(*api.Pod)
+		if !ok {
+			continue
+		}
+		if p.Namespace != namespace || p.Status.PodIP == "" {
+			continue
+		}
+		if strings.ToLower(p.Spec.Hostname) != r.endpoint || p.Spec.Subdomain != subdomain {
+			continue
+		}
+		eps = append(eps, endpoint{addr: api.EndpointAddress{IP: p.Status.PodIP, Hostname: p.Spec.Hostname}})
+	}
+	return eps
+}
+
 func symbolMatches(queryString, candidateString string, wildcard bool) bool {
 	if wildcard {
 		return true
@@ -498,8 +606,8 @@ func symbolMatches(queryString, candidateString string, wildcard bool) bool {
 }
 
 // getServiceRecordForIP: Gets a service record with a cluster ip matching the ip argument
-// If a service cluster ip does not match, it checks all endpoints
-func (k *Kubernetes) getServiceRecordForIP(ip, name string) []msg.Service {
+// If a service cluster ip does not match, it checks all endpoints. Names are created in zone.
+func (k *Kubernetes) getServiceRecordForIP(ip, zone string) []msg.Service {
 	// First check services with cluster ips
 	svcList, err := k.APIConn.svcLister.List(labels.Everything())
 	if err != nil {
@@ -510,7 +618,7 @@ func (k *Kubernetes) getServiceRecordForIP(ip, name string) []msg.Service {
 			continue
 		}
 		if service.Spec.ClusterIP == ip {
-			domain := service.Name + "." + service.Namespace + ".svc." + k.PrimaryZone()
+			domain := service.Name + "." + service.Namespace + ".svc." + zone
 			return []msg.Service{{Host: domain}}
 		}
 	}
@@ -526,7 +634,7 @@ func (k *Kubernetes) getServiceRecordForIP(ip, name string) []msg.Service {
 		for _, eps := range ep.Subsets {
 			for _, addr := range eps.Addresses {
 				if addr.IP == ip {
-					domain := endpointHostname(addr) + "." + ep.ObjectMeta.Name + "." + ep.ObjectMeta.Namespace + ".svc." + k.PrimaryZone()
+					domain := endpointHostname(addr) + "." + ep.ObjectMeta.Name + "." + ep.ObjectMeta.Namespace + ".svc." + zone
 					return []msg.Service{{Host: domain}}
 				}
 			}
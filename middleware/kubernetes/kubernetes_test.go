@@ -1,7 +1,13 @@
 package kubernetes
 
-import "testing"
-import "reflect"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/etcd/msg"
+
+	"k8s.io/client-go/1.5/pkg/api"
+)
 
 // Test data for TestSymbolContainsWildcard cases.
 var testdataSymbolContainsWildcard = []struct {
@@ -102,6 +108,14 @@ func TestParseRequest(t *testing.T) {
 		expectString(t, f, "A", query, &r, field, expected)
 	}
 
+	// Test AAAA request of endpoint
+	query = "1-2-3-4.webs.mynamespace.svc.inter.webs.test."
+	r, e = k.parseRequest(query, "AAAA")
+	if e != nil {
+		t.Errorf("Expected no error from parseRequest(\"%v\", \"AAAA\"). Instead got '%v'.", query, e)
+	}
+	expectString(t, f, "AAAA", query, &r, "endpoint", "1-2-3-4")
+
 	// Invalid query tests
 	invalidAQueries := []string{
 		"_http._tcp.webs.mynamespace.svc.inter.webs.test.", // A requests cannot have port or protocol
@@ -132,3 +146,32 @@ func TestParseRequest(t *testing.T) {
 		}
 	}
 }
+
+func TestGetRecordsForK8sItems(t *testing.T) {
+	k := Kubernetes{Zones: []string{"cluster.local."}}
+
+	services := []service{
+		{name: "ext", namespace: "testns", externalName: "example.net"},
+		{name: "headless", namespace: "testns", addr: api.ClusterIPNone, endpoints: []endpoint{
+			{addr: api.EndpointAddress{IP: "172.0.0.1", Hostname: "pod-1"}},
+			{addr: api.EndpointAddress{IP: "fd00::1"}},
+		}},
+		{name: "v6", namespace: "testns", addr: "fd00::100", ports: []api.ServicePort{{Name: "http", Port: 80}}},
+	}
+	expected := []msg.Service{
+		{Key: msg.Path("ext.testns.svc.cluster.local.", "coredns"), Host: "example.net"},
+		{Key: msg.Path("pod-1.headless.testns.svc.cluster.local.", "coredns"), Host: "172.0.0.1"},
+		{Key: msg.Path("fd00--1.headless.testns.svc.cluster.local.", "coredns"), Host: "fd00::1"},
+		{Key: msg.Path("v6.testns.svc.cluster.local.", "coredns"), Host: "fd00::100", Port: 80},
+	}
+
+	records := k.getRecordsForK8sItems(services, nil, "cluster.local.")
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %d", len(expected), len(records))
+	}
+	for i := range expected {
+		if records[i].Key != expected[i].Key || records[i].Host != expected[i].Host || records[i].Port != expected[i].Port {
+			t.Errorf("Expected record %d to be %v, got %v", i, expected[i], records[i])
+		}
+	}
+}
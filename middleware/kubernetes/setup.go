@@ -4,13 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/dnsutil"
+	dnsstrings "github.com/coredns/coredns/middleware/pkg/strings"
+	"github.com/coredns/coredns/middleware/proxy"
 
 	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
 	unversionedapi "k8s.io/client-go/1.5/pkg/api/unversioned"
 )
 
@@ -34,12 +39,19 @@ func setup(c *caddy.Controller) error {
 
 	// Register KubeCache start and stop functions with Caddy
 	c.OnStartup(func() error {
-		go kubernetes.APIConn.Run()
+		for _, cl := range kubernetes.Clusters {
+			go cl.APIConn.Run()
+		}
 		return nil
 	})
 
 	c.OnShutdown(func() error {
-		return kubernetes.APIConn.Stop()
+		for _, cl := range kubernetes.Clusters {
+			if err := cl.APIConn.Stop(); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 
 	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
@@ -51,7 +63,7 @@ func setup(c *caddy.Controller) error {
 }
 
 func kubernetesParse(c *caddy.Controller) (*Kubernetes, error) {
-	k8s := &Kubernetes{ResyncPeriod: defaultResyncPeriod}
+	k8s := &Kubernetes{ResyncPeriod: defaultResyncPeriod, SyncTimeout: defaultSyncTimeout, interfaceAddrsFunc: localPodIP}
 	k8s.PodMode = PodModeDisabled
 
 	for c.Next() {
@@ -143,6 +155,17 @@ func kubernetesParse(c *caddy.Controller) (*Kubernetes, error) {
 						continue
 					}
 					return nil, c.ArgErr()
+				case "synctimeout":
+					args := c.RemainingArgs()
+					if len(args) > 0 {
+						st, err := time.ParseDuration(args[0])
+						if err != nil {
+							return nil, fmt.Errorf("Unable to parse sync timeout value. Value provided was '%v'. Example valid values: '5s', '1m'. Error was: %v", args[0], err)
+						}
+						k8s.SyncTimeout = st
+						continue
+					}
+					return nil, c.ArgErr()
 				case "labels":
 					args := c.RemainingArgs()
 					if len(args) > 0 {
@@ -155,8 +178,86 @@ func kubernetesParse(c *caddy.Controller) (*Kubernetes, error) {
 						continue
 					}
 					return nil, c.ArgErr()
+				case "upstream":
+					args := c.RemainingArgs()
+					if len(args) > 0 {
+						ups, err := dnsutil.ParseHostPortOrFile(args...)
+						if err != nil {
+							return nil, err
+						}
+						k8s.Proxy = proxy.NewLookup(ups)
+						continue
+					}
+					return nil, c.ArgErr()
+				case "cluster": // zone [endpoint [cert key cacertfile]]
+					args := c.RemainingArgs()
+					if len(args) != 1 && len(args) != 2 && len(args) != 5 {
+						return nil, c.ArgErr()
+					}
+					cl := &Cluster{Zone: middleware.Host(args[0]).Normalize()}
+					if !dnsstrings.StringInSlice(cl.Zone, k8s.Zones) {
+						return nil, fmt.Errorf("zone %s of cluster is not a zone of kubernetes", cl.Zone)
+					}
+					if len(args) > 1 {
+						cl.APIEndpoint = args[1]
+					}
+					if len(args) == 5 {
+						cl.APIClientCert, cl.APIClientKey, cl.APICertAuth = args[2], args[3], args[4]
+					}
+					k8s.Clusters = append(k8s.Clusters, cl)
+					continue
+				case "federation": // name zone
+					args := c.RemainingArgs()
+					if len(args) == 2 {
+						k8s.Federations = append(k8s.Federations, Federation{name: args[0], zone: dns.Fqdn(args[1])})
+						continue
+					}
+					return nil, c.ArgErr()
+				case "autopath": // [NDOTS [RESPONSE [RESOLV-CONF]]]
+					args := c.RemainingArgs()
+					if len(args) > 3 {
+						return nil, c.ArgErr()
+					}
+					k8s.AutoPath = AutoPath{Enabled: true, NDots: defaultAutoPathNDots, OnNXDOMAIN: defaultOnNXDOMAIN}
+					if len(args) > 0 {
+						ndots, err := strconv.Atoi(args[0])
+						if err != nil || ndots < 0 {
+							return nil, fmt.Errorf("invalid NDOTS argument for autopath, got '%v', expected a positive integer", args[0])
+						}
+						k8s.AutoPath.NDots = ndots
+					}
+					if len(args) > 1 {
+						switch rcode := strings.ToUpper(args[1]); rcode {
+						case "NXDOMAIN", "SERVFAIL", "NOERROR":
+							k8s.AutoPath.OnNXDOMAIN = dns.StringToRcode[rcode]
+						default:
+							return nil, fmt.Errorf("invalid RESPONSE argument for autopath, got '%v', expected SERVFAIL, NXDOMAIN, or NOERROR", args[1])
+						}
+					}
+					resolvConf := defaultResolvConfFile
+					if len(args) > 2 {
+						resolvConf = args[2]
+					}
+					rc, err := dns.ClientConfigFromFile(resolvConf)
+					if err != nil {
+						// A missing default resolv.conf is not fatal, there is just no host search path.
+						if len(args) > 2 {
+							return nil, fmt.Errorf("error when parsing %s for autopath: %v", resolvConf, err)
+						}
+						continue
+					}
+					for _, s := range rc.Search {
+						k8s.AutoPath.HostSearchPath = append(k8s.AutoPath.HostSearchPath, dns.Fqdn(s))
+					}
+					continue
 				}
 			}
+			if err := k8s.defaultClusters(); err != nil {
+				return nil, err
+			}
+			if k8s.AutoPath.Enabled && k8s.PodMode != PodModeVerified {
+				return nil, errors.New("autopath requires pods to be set to verified")
+			}
 			return k8s, nil
 		}
 	}
@@ -165,5 +266,10 @@ func kubernetesParse(c *caddy.Controller) (*Kubernetes, error) {
 
 const (
 	defaultResyncPeriod = 5 * time.Minute
+	defaultSyncTimeout  = 5 * time.Second
 	defaultPodMode      = PodModeDisabled
+
+	defaultAutoPathNDots  = 0
+	defaultOnNXDOMAIN     = dns.RcodeServerFailure
+	defaultResolvConfFile = "/etc/resolv.conf"
 )
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
 	unversionedapi "k8s.io/client-go/1.5/pkg/api/unversioned"
 )
 
@@ -379,6 +380,139 @@ func TestKubernetesParse(t *testing.T) {
 			defaultPodMode,
 			[]net.IPNet{parseCidr("10.0.0.0/24"), parseCidr("10.0.1.0/24")},
 		},
+		// synctimeout
+		{
+			"synctimeout",
+			`kubernetes coredns.local {
+	synctimeout 10s
+}`,
+			false,
+			"",
+			1,
+			0,
+			defaultResyncPeriod,
+			"",
+			defaultPodMode,
+			nil,
+		},
+		{
+			"invalid synctimeout",
+			`kubernetes coredns.local {
+	synctimeout ten
+}`,
+			true,
+			"Unable to parse sync timeout value",
+			-1,
+			0,
+			defaultResyncPeriod,
+			"",
+			defaultPodMode,
+			nil,
+		},
+		// clusters
+		{
+			"clusters",
+			`kubernetes a.local b.local {
+	cluster a.local
+	cluster b.local https://b:443
+	cluster b.local https://b2:443 cert key cacert
+}`,
+			false,
+			"",
+			2,
+			0,
+			defaultResyncPeriod,
+			"",
+			defaultPodMode,
+			nil,
+		},
+		{
+			"cluster for unknown zone",
+			`kubernetes a.local {
+	cluster c.local
+}`,
+			true,
+			"zone c.local. of cluster is not a zone of kubernetes",
+			-1,
+			0,
+			defaultResyncPeriod,
+			"",
+			defaultPodMode,
+			nil,
+		},
+		{
+			"zone without cluster",
+			`kubernetes a.local b.local {
+	cluster a.local
+}`,
+			true,
+			"no cluster configured for zone b.local.",
+			-1,
+			0,
+			defaultResyncPeriod,
+			"",
+			defaultPodMode,
+			nil,
+		},
+		// federation
+		{
+			"federation",
+			`kubernetes coredns.local {
+	federation prod prod.feddomain.com
+}`,
+			false,
+			"",
+			1,
+			0,
+			defaultResyncPeriod,
+			"",
+			defaultPodMode,
+			nil,
+		},
+		{
+			"federation without domain",
+			`kubernetes coredns.local {
+	federation prod
+}`,
+			true,
+			"Wrong argument count",
+			-1,
+			0,
+			defaultResyncPeriod,
+			"",
+			defaultPodMode,
+			nil,
+		},
+		// autopath
+		{
+			"autopath with pods verified",
+			`kubernetes coredns.local {
+	pods verified
+	autopath
+}`,
+			false,
+			"",
+			1,
+			0,
+			defaultResyncPeriod,
+			"",
+			PodModeVerified,
+			nil,
+		},
+		{
+			"autopath without pods verified",
+			`kubernetes coredns.local {
+	autopath
+}`,
+			true,
+			"autopath requires pods to be set to verified",
+			-1,
+			0,
+			defaultResyncPeriod,
+			"",
+			defaultPodMode,
+			nil,
+		},
 		// cidrs ok
 		{
 			"Invalid cidr: hard",
@@ -469,3 +603,43 @@ func TestKubernetesParse(t *testing.T) {
 
 	}
 }
+
+func TestKubernetesParseAutoPath(t *testing.T) {
+	tests := []struct {
+		input         string
+		shouldErr     bool
+		expectedNDots int
+		expectedRcode int
+	}{
+		{"kubernetes coredns.local {\n pods verified\n autopath\n}", false, defaultAutoPathNDots, defaultOnNXDOMAIN},
+		{"kubernetes coredns.local {\n pods verified\n autopath 1 NXDOMAIN\n}", false, 1, dns.RcodeNameError},
+		{"kubernetes coredns.local {\n pods verified\n autopath 0 noerror\n}", false, 0, dns.RcodeSuccess},
+		{"kubernetes coredns.local {\n pods verified\n autopath -1\n}", true, 0, 0},
+		{"kubernetes coredns.local {\n pods verified\n autopath 1 REFUSED\n}", true, 0, 0},
+		{"kubernetes coredns.local {\n pods verified\n autopath 1 NXDOMAIN /does/not/exist\n}", true, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		k, err := kubernetesParse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error, got none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, got %v for input %s", i, err, test.input)
+			continue
+		}
+		if !k.AutoPath.Enabled {
+			t.Errorf("Test %d: Expected autopath to be enabled", i)
+		}
+		if k.AutoPath.NDots != test.expectedNDots {
+			t.Errorf("Test %d: Expected ndots %d, got %d", i, test.expectedNDots, k.AutoPath.NDots)
+		}
+		if k.AutoPath.OnNXDOMAIN != test.expectedRcode {
+			t.Errorf("Test %d: Expected rcode %d, got %d", i, test.expectedRcode, k.AutoPath.OnNXDOMAIN)
+		}
+	}
+}
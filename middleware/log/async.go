@@ -0,0 +1,72 @@
+package log
+
+import (
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// asyncWriter is an io.Writer that writes to an underlying writer from a separate goroutine, so
+// that a slow log destination doesn't block the query path. When the buffer is full, lines are
+// dropped.
+type asyncWriter struct {
+	w     io.Writer
+	lines chan []byte
+	done  chan struct{}
+
+	mu     sync.RWMutex // protects closed
+	closed bool
+
+	dropped uint64
+}
+
+func newAsyncWriter(w io.Writer, size int) *asyncWriter {
+	a := &asyncWriter{w: w, lines: make(chan []byte, size), done: make(chan struct{})}
+	go a.run()
+	return a
+}
+
+// Write queues p for writing. It never blocks and always succeeds.
+func (a *asyncWriter) Write(p []byte) (int, error) {
+	// The log.Logger reuses p, so we need our own copy.
+	b := make([]byte, len(p))
+	copy(b, p)
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return len(p), nil
+	}
+	select {
+	case a.lines <- b:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+	return len(p), nil
+}
+
+func (a *asyncWriter) run() {
+	for b := range a.lines {
+		a.w.Write(b)
+	}
+	close(a.done)
+}
+
+// Close writes the queued lines and stops the writer. The underlying writer is not closed.
+func (a *asyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.lines)
+	a.mu.Unlock()
+
+	<-a.done
+	if n := atomic.LoadUint64(&a.dropped); n > 0 {
+		log.Printf("[WARNING] Dropped %d query log lines because the buffer was full", n)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package log
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestAsyncWriter(t *testing.T) {
+	var f bytes.Buffer
+	a := newAsyncWriter(&f, 10)
+	l := log.New(a, "", 0)
+
+	l.Println("one")
+	l.Println("two")
+	a.Close()
+
+	if x := f.String(); x != "one\ntwo\n" {
+		t.Errorf("Expected lines to be written, got %q", x)
+	}
+
+	// Writing after close doesn't panic and is discarded.
+	l.Println("three")
+	if x := f.String(); x != "one\ntwo\n" {
+		t.Errorf("Expected no lines to be written after close, got %q", x)
+	}
+}
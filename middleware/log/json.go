@@ -0,0 +1,55 @@
+package log
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/pkg/replacer"
+
+	"github.com/miekg/dns"
+)
+
+// entry is a log entry in the JSON log format. Fields are never omitted, so the schema is the same
+// for every query.
+type entry struct {
+	Timestamp   string  `json:"timestamp"`
+	ClientIP    string  `json:"client_ip"`
+	ClientPort  int     `json:"client_port"`
+	Proto       string  `json:"proto"`
+	QName       string  `json:"qname"`
+	QType       string  `json:"qtype"`
+	QClass      string  `json:"qclass"`
+	Rcode       string  `json:"rcode"`
+	AnswerCount int     `json:"answer_count"`
+	Flags       string  `json:"flags"`
+	Duration    float64 `json:"duration"` // in seconds
+	ServerZone  string  `json:"server_zone"`
+	Upstream    string  `json:"upstream"`
+}
+
+// jsonEntry returns the JSON log entry for query r and the response recorded in rr.
+func jsonEntry(r *dns.Msg, rr *dnsrecorder.Recorder, zone, upstream string) string {
+	rep := replacer.New(r, rr, "")
+	port, _ := strconv.Atoi(rep.Replace("{port}"))
+	ancount, _ := strconv.Atoi(rep.Replace("{ancount}"))
+
+	e := entry{
+		Timestamp:   time.Now().UTC().Format(time.RFC3339Nano),
+		ClientIP:    rep.Replace("{remote}"),
+		ClientPort:  port,
+		Proto:       rep.Replace("{proto}"),
+		QName:       rep.Replace("{name}"),
+		QType:       rep.Replace("{type}"),
+		QClass:      rep.Replace("{class}"),
+		Rcode:       rep.Replace("{rcode}"),
+		AnswerCount: ancount,
+		Flags:       rep.Replace("{rflags}"),
+		Duration:    time.Since(rr.Start).Seconds(),
+		ServerZone:  zone,
+		Upstream:    upstream,
+	}
+	b, _ := json.Marshal(e)
+	return string(b)
+}
@@ -2,15 +2,19 @@
 package log
 
 import (
+	"io"
 	"log"
+	"math/rand"
 	"time"
 
 	"github.com/coredns/coredns/middleware"
 	"github.com/coredns/coredns/middleware/metrics/vars"
 	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/pkg/meta"
 	"github.com/coredns/coredns/middleware/pkg/rcode"
 	"github.com/coredns/coredns/middleware/pkg/replacer"
 	"github.com/coredns/coredns/middleware/pkg/response"
+	"github.com/coredns/coredns/middleware/pkg/roller"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -21,7 +25,8 @@ import (
 type Logger struct {
 	Next      middleware.Handler
 	Rules     []Rule
-	ErrorFunc func(dns.ResponseWriter, *dns.Msg, int) // failover error handler
+	ErrorFunc func(context.Context, dns.ResponseWriter, *dns.Msg, int) // failover error handler
+	Zone      string                                                   // zone of the server block, used in the JSON log format
 }
 
 // ServeDNS implements the middleware.Handler interface.
@@ -32,6 +37,7 @@ func (l Logger) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 			continue
 		}
 
+		ctx, m := meta.New(ctx)
 		rrw := dnsrecorder.New(w)
 		rc, err := middleware.NextOrFailure(l.Name(), l.Next, ctx, rrw, r)
 
@@ -39,23 +45,27 @@ func (l Logger) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 			// There was an error up the chain, but no response has been written yet.
 			// The error must be handled here so the log entry will record the response size.
 			if l.ErrorFunc != nil {
-				l.ErrorFunc(rrw, r, rc)
+				l.ErrorFunc(ctx, rrw, r, rc)
 			} else {
 				answer := new(dns.Msg)
 				answer.SetRcode(r, rc)
 				state.SizeAndDo(answer)
 
-				vars.Report(state, vars.Dropped, rcode.ToString(rc), answer.Len(), time.Now())
+				vars.Report(ctx, state, vars.Dropped, rcode.ToString(rc), answer.Len(), time.Now())
 
 				w.WriteMsg(answer)
 			}
 			rc = 0
 		}
 
-		class, _ := response.Classify(rrw.Msg)
-		if rule.Class == response.All || rule.Class == class {
-			rep := replacer.New(r, rrw, CommonLogEmptyValue)
-			rule.Log.Println(rep.Replace(rule.Format))
+		if rule.match(rrw.Msg) && rule.sample() {
+			if rule.Format == JSONLogFormat {
+				rule.Log.Println(jsonEntry(r, rrw, l.Zone, m.Get(meta.Upstream)))
+			} else {
+				rep := replacer.New(r, rrw, CommonLogEmptyValue)
+				rep.Set("upstream", m.Get(meta.Upstream))
+				rule.Log.Println(rep.Replace(rule.Format))
+			}
 		}
 
 		return rc, err
@@ -70,10 +80,40 @@ func (l Logger) Name() string { return "log" }
 // Rule configures the logging middleware.
 type Rule struct {
 	NameScope  string
-	Class      response.Class
+	Class      map[response.Class]bool // Only log responses of these classes, all when empty.
+	Rcode      map[int]bool            // Only log responses with these rcodes, all when empty.
+	Sample     int                     // When larger than one, only 1 in Sample responses is logged.
 	OutputFile string
 	Format     string
+	Async      int            // When larger than zero, log lines are written asynchronously with a buffer of this size.
+	Roller     *roller.Roller // When not nil, OutputFile is rotated.
 	Log        *log.Logger
+
+	closers []io.Closer // closed on shutdown, in order
+}
+
+// match returns true when the response m passes the class and rcode filters of r.
+func (r Rule) match(m *dns.Msg) bool {
+	if len(r.Class) > 0 && !r.Class[response.All] {
+		class, _ := response.Classify(m)
+		if !r.Class[class] {
+			return false
+		}
+	}
+	if len(r.Rcode) > 0 {
+		if m == nil || !r.Rcode[m.Rcode] {
+			return false
+		}
+	}
+	return true
+}
+
+// sample returns true when this response should be logged according to the sample rate of r.
+func (r Rule) sample() bool {
+	if r.Sample <= 1 {
+		return true
+	}
+	return rand.Intn(r.Sample) == 0
 }
 
 const (
@@ -85,6 +125,8 @@ const (
 	CommonLogEmptyValue = "-"
 	// CombinedLogFormat is the combined log format.
 	CombinedLogFormat = CommonLogFormat + ` "{>opcode}"`
+	// JSONLogFormat logs each query as a JSON object.
+	JSONLogFormat = "{json}"
 	// DefaultLogFormat is the default log format.
 	DefaultLogFormat = CommonLogFormat
 )
@@ -2,11 +2,13 @@ package log
 
 import (
 	"bytes"
+	"encoding/json"
 	"log"
 	"strings"
 	"testing"
 
 	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/pkg/meta"
 	"github.com/coredns/coredns/middleware/pkg/response"
 	"github.com/coredns/coredns/middleware/test"
 
@@ -50,7 +52,7 @@ func TestLoggedClassDenial(t *testing.T) {
 		NameScope: ".",
 		Format:    DefaultLogFormat,
 		Log:       log.New(&f, "", 0),
-		Class:     response.Denial,
+		Class:     map[response.Class]bool{response.Denial: true},
 	}
 
 	logger := Logger{
@@ -78,7 +80,7 @@ func TestLoggedClassError(t *testing.T) {
 		NameScope: ".",
 		Format:    DefaultLogFormat,
 		Log:       log.New(&f, "", 0),
-		Class:     response.Error,
+		Class:     map[response.Class]bool{response.Error: true},
 	}
 
 	logger := Logger{
@@ -99,3 +101,139 @@ func TestLoggedClassError(t *testing.T) {
 		t.Errorf("Expected it to be logged. Logged string: %s", logged)
 	}
 }
+
+func TestLoggedRcode(t *testing.T) {
+	tests := []struct {
+		class    map[response.Class]bool
+		rcode    map[int]bool
+		expected bool
+	}{
+		{nil, map[int]bool{dns.RcodeServerFailure: true}, true},
+		{nil, map[int]bool{dns.RcodeNameError: true}, false},
+		{map[response.Class]bool{response.Error: true}, map[int]bool{dns.RcodeServerFailure: true}, true},
+		{map[response.Class]bool{response.Denial: true}, map[int]bool{dns.RcodeServerFailure: true}, false},
+		{map[response.Class]bool{response.Denial: true, response.Error: true}, nil, true},
+	}
+
+	for i, tc := range tests {
+		var f bytes.Buffer
+		rule := Rule{
+			NameScope: ".",
+			Format:    DefaultLogFormat,
+			Log:       log.New(&f, "", 0),
+			Class:     tc.class,
+			Rcode:     tc.rcode,
+		}
+		logger := Logger{Rules: []Rule{rule}, Next: test.ErrorHandler()}
+
+		r := new(dns.Msg)
+		r.SetQuestion("example.org.", dns.TypeA)
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		logger.ServeDNS(context.TODO(), rec, r)
+
+		if logged := f.Len() > 0; logged != tc.expected {
+			t.Errorf("Test %d: expected logged to be %t, got %t", i, tc.expected, logged)
+		}
+	}
+}
+
+func TestLoggedSample(t *testing.T) {
+	var f bytes.Buffer
+	rule := Rule{
+		NameScope: ".",
+		Format:    "{name}",
+		Log:       log.New(&f, "", 0),
+		Sample:    10,
+	}
+	logger := Logger{Rules: []Rule{rule}, Next: test.ErrorHandler()}
+
+	const queries = 1000
+	for i := 0; i < queries; i++ {
+		r := new(dns.Msg)
+		r.SetQuestion("example.org.", dns.TypeA)
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		logger.ServeDNS(context.TODO(), rec, r)
+	}
+
+	// With 1 in 10 we expect about 100 lines, allow for a lot of randomness.
+	lines := strings.Count(f.String(), "\n")
+	if lines == 0 || lines >= queries/2 {
+		t.Errorf("Expected about %d lines to be logged, got %d", queries/10, lines)
+	}
+}
+
+func TestLoggedJSON(t *testing.T) {
+	var f bytes.Buffer
+	rule := Rule{
+		NameScope: ".",
+		Format:    JSONLogFormat,
+		Log:       log.New(&f, "", 0),
+	}
+
+	logger := Logger{
+		Rules: []Rule{rule},
+		Next:  upstreamHandler(),
+		Zone:  "example.org.",
+	}
+
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+
+	logger.ServeDNS(context.TODO(), rec, r)
+
+	e := entry{}
+	if err := json.Unmarshal(f.Bytes(), &e); err != nil {
+		t.Fatalf("Expected a JSON log entry, got %q: %s", f.String(), err)
+	}
+	if e.ClientIP != "10.240.0.1" || e.ClientPort != 40212 || e.Proto != "udp" {
+		t.Errorf("Unexpected client in log entry: %+v", e)
+	}
+	if e.QName != "example.org." || e.QType != "A" || e.QClass != "IN" {
+		t.Errorf("Unexpected question in log entry: %+v", e)
+	}
+	if e.Rcode != "NOERROR" || e.AnswerCount != 1 || e.Flags != "qr,rd,ra" {
+		t.Errorf("Unexpected response in log entry: %+v", e)
+	}
+	if e.ServerZone != "example.org." || e.Upstream != "192.0.2.53:53" {
+		t.Errorf("Unexpected server zone or upstream in log entry: %+v", e)
+	}
+}
+
+func TestLoggedUpstream(t *testing.T) {
+	var f bytes.Buffer
+	rule := Rule{
+		NameScope: ".",
+		Format:    "{name} {upstream} {rflags} {ancount}",
+		Log:       log.New(&f, "", 0),
+	}
+
+	logger := Logger{
+		Rules: []Rule{rule},
+		Next:  upstreamHandler(),
+	}
+
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+
+	logger.ServeDNS(context.TODO(), rec, r)
+
+	if logged, expected := f.String(), "example.org. 192.0.2.53:53 qr,rd,ra 1\n"; logged != expected {
+		t.Errorf("Expected %q to be logged, got %q", expected, logged)
+	}
+}
+
+// upstreamHandler returns a handler that answers like proxy does: it records the upstream used in the
+// context's meta.
+func upstreamHandler() test.Handler {
+	return test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.RecursionAvailable = true
+		m.Answer = []dns.RR{test.A("example.org. 300 IN A 192.0.2.1")}
+		meta.FromContext(ctx).Set(meta.Upstream, "192.0.2.53:53")
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
@@ -1,13 +1,17 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/middleware"
 	"github.com/coredns/coredns/middleware/pkg/response"
+	"github.com/coredns/coredns/middleware/pkg/roller"
 
 	"github.com/hashicorp/go-syslog"
 	"github.com/mholt/caddy"
@@ -33,6 +37,7 @@ func setup(c *caddy.Controller) error {
 			var err error
 			var writer io.Writer
 
+			rules[i].closers = nil
 			if rules[i].OutputFile == "stdout" {
 				writer = os.Stdout
 			} else if rules[i].OutputFile == "stderr" {
@@ -42,6 +47,10 @@ func setup(c *caddy.Controller) error {
 				if err != nil {
 					return middleware.Error("log", err)
 				}
+			} else if rules[i].Roller != nil {
+				file := rules[i].Roller.Writer(rules[i].OutputFile)
+				writer = file
+				rules[i].closers = append(rules[i].closers, file)
 			} else {
 				var file *os.File
 				file, err = os.OpenFile(rules[i].OutputFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
@@ -49,6 +58,14 @@ func setup(c *caddy.Controller) error {
 					return middleware.Error("log", err)
 				}
 				writer = file
+				rules[i].closers = append(rules[i].closers, file)
+			}
+
+			if rules[i].Async > 0 {
+				a := newAsyncWriter(writer, rules[i].Async)
+				writer = a
+				// The async writer must be flushed before the file is closed.
+				rules[i].closers = append([]io.Closer{a}, rules[i].closers...)
 			}
 
 			rules[i].Log = log.New(writer, "", 0)
@@ -57,8 +74,17 @@ func setup(c *caddy.Controller) error {
 		return nil
 	})
 
+	c.OnShutdown(func() error {
+		for i := 0; i < len(rules); i++ {
+			for _, cl := range rules[i].closers {
+				cl.Close()
+			}
+		}
+		return nil
+	})
+
 	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
-		return Logger{Next: next, Rules: rules, ErrorFunc: dnsserver.DefaultErrorFunc}
+		return Logger{Next: next, Rules: rules, ErrorFunc: dnsserver.DefaultErrorFunc, Zone: dnsserver.GetConfig(c).Zone}
 	})
 
 	return nil
@@ -90,14 +116,7 @@ func logParse(c *caddy.Controller) ([]Rule, error) {
 			format := DefaultLogFormat
 
 			if len(args) > 2 {
-				switch args[2] {
-				case "{common}":
-					format = CommonLogFormat
-				case "{combined}":
-					format = CombinedLogFormat
-				default:
-					format = args[2]
-				}
+				format = logFormat(args[2])
 			}
 
 			rules = append(rules, Rule{
@@ -109,24 +128,114 @@ func logParse(c *caddy.Controller) ([]Rule, error) {
 
 		// Class refinements in an extra block.
 		for c.NextBlock() {
+			rule := &rules[len(rules)-1]
 			switch c.Val() {
-			// class followed by all, denial, error or success.
+			// class followed by one or more of all, denial, error or success.
 			case "class":
 				classes := c.RemainingArgs()
 				if len(classes) == 0 {
 					return nil, c.ArgErr()
 				}
-				cls, err := response.ClassFromString(classes[0])
+				if rule.Class == nil {
+					rule.Class = make(map[response.Class]bool)
+				}
+				for _, cl := range classes {
+					cls, err := response.ClassFromString(cl)
+					if err != nil {
+						return nil, err
+					}
+					rule.Class[cls] = true
+				}
+			// rcode followed by one or more rcodes, i.e. NOERROR NXDOMAIN.
+			case "rcode":
+				rcodes := c.RemainingArgs()
+				if len(rcodes) == 0 {
+					return nil, c.ArgErr()
+				}
+				if rule.Rcode == nil {
+					rule.Rcode = make(map[int]bool)
+				}
+				for _, rc := range rcodes {
+					code, ok := dns.StringToRcode[strings.ToUpper(rc)]
+					if !ok {
+						return nil, fmt.Errorf("invalid rcode: %q", rc)
+					}
+					rule.Rcode[code] = true
+				}
+			// sample followed by 1/N.
+			case "sample":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				n, err := parseSample(args[0])
 				if err != nil {
 					return nil, err
 				}
-				// update class and the last added Rule (bit icky)
-				rules[len(rules)-1].Class = cls
+				rule.Sample = n
+			// format followed by json, common, combined or a custom format.
+			case "format":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				rule.Format = logFormat(args[0])
+			// async followed by an optional buffer size.
+			case "async":
+				args := c.RemainingArgs()
+				if len(args) > 1 {
+					return nil, c.ArgErr()
+				}
+				size := defaultAsyncSize
+				if len(args) == 1 {
+					var err error
+					size, err = strconv.Atoi(args[0])
+					if err != nil || size <= 0 {
+						return nil, fmt.Errorf("async buffer size must be a positive integer: %q", args[0])
+					}
+				}
+				rule.Async = size
 			default:
-				return nil, c.ArgErr()
+				if !roller.IsDirective(c.Val()) {
+					return nil, c.ArgErr()
+				}
+				if rule.Roller == nil {
+					rule.Roller = roller.Default()
+				}
+				if err := rule.Roller.Parse(c.Val(), c.RemainingArgs()); err != nil {
+					return nil, err
+				}
 			}
 		}
 	}
 
 	return rules, nil
 }
+
+// logFormat returns the format for the format names json, common and combined (optionally written
+// as {json}, {common} and {combined}), any other value is returned as is.
+func logFormat(f string) string {
+	switch f {
+	case "json", JSONLogFormat:
+		return JSONLogFormat
+	case "common", "{common}":
+		return CommonLogFormat
+	case "combined", "{combined}":
+		return CombinedLogFormat
+	}
+	return f
+}
+
+// parseSample parses a sample rate written as 1/N and returns N.
+func parseSample(s string) (int, error) {
+	if !strings.HasPrefix(s, "1/") {
+		return 0, fmt.Errorf("sample rate must be written as 1/N: %q", s)
+	}
+	n, err := strconv.Atoi(s[2:])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("sample rate must be written as 1/N with N a positive integer: %q", s)
+	}
+	return n, nil
+}
+
+const defaultAsyncSize = 1000
@@ -1,11 +1,14 @@
 package log
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/coredns/coredns/middleware/pkg/response"
+	"github.com/coredns/coredns/middleware/pkg/roller"
 
 	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
 )
 
 func TestLogParse(t *testing.T) {
@@ -71,7 +74,7 @@ func TestLogParse(t *testing.T) {
 			NameScope:  "example.org.",
 			OutputFile: "log.txt",
 			Format:     CommonLogFormat,
-			Class:      response.All,
+			Class:      map[response.Class]bool{response.All: true},
 		}}},
 		{`log example.org log.txt {
 			class denial
@@ -79,7 +82,7 @@ func TestLogParse(t *testing.T) {
 			NameScope:  "example.org.",
 			OutputFile: "log.txt",
 			Format:     CommonLogFormat,
-			Class:      response.Denial,
+			Class:      map[response.Class]bool{response.Denial: true},
 		}}},
 		{`log {
 			class denial
@@ -87,8 +90,74 @@ func TestLogParse(t *testing.T) {
 			NameScope:  ".",
 			OutputFile: DefaultLogFilename,
 			Format:     CommonLogFormat,
-			Class:      response.Denial,
+			Class:      map[response.Class]bool{response.Denial: true},
 		}}},
+		{`log example.org log.txt {json}`, false, []Rule{{
+			NameScope:  "example.org.",
+			OutputFile: "log.txt",
+			Format:     JSONLogFormat,
+		}}},
+		{`log stdout {
+			format json
+			async 100
+		}`, false, []Rule{{
+			NameScope:  ".",
+			OutputFile: "stdout",
+			Format:     JSONLogFormat,
+			Async:      100,
+		}}},
+		{`log {
+			format combined
+			async
+		}`, false, []Rule{{
+			NameScope:  ".",
+			OutputFile: DefaultLogFilename,
+			Format:     CombinedLogFormat,
+			Async:      defaultAsyncSize,
+		}}},
+		{`log {
+			class denial error
+			rcode NXDOMAIN servfail
+			sample 1/100
+		}`, false, []Rule{{
+			NameScope:  ".",
+			OutputFile: DefaultLogFilename,
+			Format:     CommonLogFormat,
+			Class:      map[response.Class]bool{response.Denial: true, response.Error: true},
+			Rcode:      map[int]bool{dns.RcodeNameError: true, dns.RcodeServerFailure: true},
+			Sample:     100,
+		}}},
+		{`log query.log {
+			rotate_size 50
+			rotate_keep 5
+			rotate_compress
+		}`, false, []Rule{{
+			NameScope:  ".",
+			OutputFile: "query.log",
+			Format:     CommonLogFormat,
+			Roller:     &roller.Roller{MaxSize: 50, MaxAge: 14, MaxBackups: 5, Compress: true},
+		}}},
+		{`log {
+			async -1
+		}`, true, nil},
+		{`log {
+			class
+		}`, true, nil},
+		{`log {
+			rcode NOPE
+		}`, true, nil},
+		{`log {
+			sample 100
+		}`, true, nil},
+		{`log {
+			sample 1/0
+		}`, true, nil},
+		{`log {
+			rotate_size big
+		}`, true, nil},
+		{`log {
+			format
+		}`, true, nil},
 	}
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.inputLogRules)
@@ -120,10 +189,30 @@ func TestLogParse(t *testing.T) {
 					i, j, test.expectedLogRules[j].Format, actualLogRule.Format)
 			}
 
-			if actualLogRule.Class != test.expectedLogRules[j].Class {
-				t.Errorf("Test %d expected %dth LogRule Class to be  %s  , but got %s",
+			if actualLogRule.Async != test.expectedLogRules[j].Async {
+				t.Errorf("Test %d expected %dth LogRule Async to be  %d  , but got %d",
+					i, j, test.expectedLogRules[j].Async, actualLogRule.Async)
+			}
+
+			if !reflect.DeepEqual(actualLogRule.Class, test.expectedLogRules[j].Class) {
+				t.Errorf("Test %d expected %dth LogRule Class to be  %v  , but got %v",
 					i, j, test.expectedLogRules[j].Class, actualLogRule.Class)
 			}
+
+			if !reflect.DeepEqual(actualLogRule.Rcode, test.expectedLogRules[j].Rcode) {
+				t.Errorf("Test %d expected %dth LogRule Rcode to be  %v  , but got %v",
+					i, j, test.expectedLogRules[j].Rcode, actualLogRule.Rcode)
+			}
+
+			if actualLogRule.Sample != test.expectedLogRules[j].Sample {
+				t.Errorf("Test %d expected %dth LogRule Sample to be  %d  , but got %d",
+					i, j, test.expectedLogRules[j].Sample, actualLogRule.Sample)
+			}
+
+			if !reflect.DeepEqual(actualLogRule.Roller, test.expectedLogRules[j].Roller) {
+				t.Errorf("Test %d expected %dth LogRule Roller to be  %v  , but got %v",
+					i, j, test.expectedLogRules[j].Roller, actualLogRule.Roller)
+			}
 		}
 	}
 
@@ -0,0 +1,164 @@
+// Package loop implements a middleware that detects forwarding loops.
+package loop
+
+import (
+	"log"
+	"net"
+	"sync"
+
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Loop detects forwarding loops. At startup a probe query is sent to the server itself, if it
+// arrives back more than twice the server is forwarding to itself and exits. At runtime queries
+// that arrive again from the upstream while the identical query is still being forwarded to that
+// upstream are counted, when that happens more than twice the query is answered with SERVFAIL to
+// break the loop.
+type Loop struct {
+	Next middleware.Handler
+
+	zone  string
+	qname string // name of the probe query
+
+	mu       sync.Mutex
+	inflight map[inflightKey]*inflight
+	probing  bool   // the probe query is being sent
+	probeID  uint16 // message ID of the probe query
+}
+
+// inflightKey identifies a query. Forwarders keep the message ID, so a query that arrives from
+// the upstream with the same ID and question as a query that is still being forwarded to that
+// upstream is the same query.
+type inflightKey struct {
+	id     uint16
+	name   string
+	qtype  uint16
+	qclass uint16
+}
+
+// inflight is a query that is being handled.
+type inflight struct {
+	count    int    // number of times the query arrived and is still being handled
+	repeats  int    // number of times the query arrived after it was forwarded
+	upstream string // upstream the query has been forwarded to, if any
+}
+
+// New returns a new Loop for zone, with a random probe name.
+func New(zone string) *Loop {
+	return &Loop{
+		zone:     zone,
+		qname:    qname(zone),
+		inflight: make(map[inflightKey]*inflight),
+	}
+}
+
+// ServeDNS implements the middleware.Handler interface.
+func (l *Loop) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	k := inflightKey{id: r.Id, name: state.Name(), qtype: state.QType(), qclass: state.QClass()}
+	e, repeats, upstream := l.enter(k, state.IP())
+	defer l.leave(k)
+
+	if repeats > maxRepeats {
+		if l.isProbe(state) {
+			fatal("[FATAL] Forwarding loop detected in \"%s\" zone. Exiting. Probe query \"HINFO %s\" was forwarded to %s and came back from %s.",
+				l.zone, l.qname, upstream, state.IP())
+		}
+		if repeats == maxRepeats+1 {
+			log.Printf("[ERROR] Forwarding loop detected for \"%s %s\": forwarded to %s and came back from %s",
+				state.Type(), state.Name(), upstream, state.IP())
+		}
+		LoopCount.WithLabelValues(upstream).Inc()
+		return dns.RcodeServerFailure, nil
+	}
+
+	ctx = context.WithValue(ctx, key{}, &forwarded{l: l, e: e})
+	return middleware.NextOrFailure(l.Name(), l.Next, ctx, w, r)
+}
+
+// Name implements the middleware.Handler interface.
+func (l *Loop) Name() string { return "loop" }
+
+// enter records that the query k arrived from ip. It returns the number of times k came back from
+// the upstream after it was forwarded and the upstream it was forwarded to.
+func (l *Loop) enter(k inflightKey, ip string) (e *inflight, repeats int, upstream string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.inflight[k]
+	if !ok {
+		e = &inflight{}
+		l.inflight[k] = e
+	}
+	e.count++
+	// An identical query that arrives before the first one is forwarded, or from another
+	// address than the upstream, comes from a client; e.g. a retransmission.
+	if e.upstream != "" && fromUpstream(e.upstream, ip) {
+		e.repeats++
+	}
+	return e, e.repeats, e.upstream
+}
+
+// leave records that the handling of query k is done.
+func (l *Loop) leave(k inflightKey) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.inflight[k]
+	if !ok {
+		return
+	}
+	e.count--
+	if e.count <= 0 {
+		delete(l.inflight, k)
+	}
+}
+
+// isProbe returns true if state is the probe query sent at startup, while it is being sent.
+func (l *Loop) isProbe(state request.Request) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.probing && state.Req.Id == l.probeID &&
+		state.QType() == dns.TypeHINFO && state.Name() == l.qname
+}
+
+// fromUpstream returns true if ip is the address of upstream, which is a host:port.
+func fromUpstream(upstream, ip string) bool {
+	host, _, err := net.SplitHostPort(upstream)
+	if err != nil {
+		host = upstream
+	}
+	return host == ip
+}
+
+type key struct{}
+
+type forwarded struct {
+	l *Loop
+	e *inflight
+}
+
+// Forwarded records that the query being handled in ctx is forwarded to upstream. It is called
+// by middleware that forwards queries, like proxy. When there is no loop middleware Forwarded
+// is a noop.
+func Forwarded(ctx context.Context, upstream string) {
+	f, ok := ctx.Value(key{}).(*forwarded)
+	if !ok {
+		return
+	}
+	f.l.mu.Lock()
+	f.e.upstream = upstream
+	f.l.mu.Unlock()
+}
+
+// maxRepeats is the number of times a query may come back before it is considered a loop.
+const maxRepeats = 2
+
+// fatal is log.Fatalf, tests overwrite it.
+var fatal = log.Fatalf
@@ -0,0 +1,145 @@
+package loop
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// forwarder returns a handler that forwards every query to upstream, which is l itself. The query
+// comes back from the address of test.ResponseWriter.
+func forwarder(l *Loop, calls *int) test.HandlerFunc {
+	return func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		*calls++
+		Forwarded(ctx, "10.240.0.1:53")
+		// The query arrives back at the server, with a fresh context.
+		return l.ServeDNS(context.TODO(), w, r)
+	}
+}
+
+func TestLoop(t *testing.T) {
+	l := New(".")
+	calls := 0
+	l.Next = forwarder(l, &calls)
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+
+	rcode, err := l.ServeDNS(context.TODO(), rec, m)
+	if err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeServerFailure, rcode)
+	}
+	if calls != maxRepeats+1 {
+		t.Errorf("Expected query to be forwarded %d times, got %d", maxRepeats+1, calls)
+	}
+	if len(l.inflight) != 0 {
+		t.Errorf("Expected no queries in flight, got %d", len(l.inflight))
+	}
+}
+
+func TestLoopProbe(t *testing.T) {
+	var msg string
+	old := fatal
+	fatal = func(format string, v ...interface{}) { msg = fmt.Sprintf(format, v...) }
+	defer func() { fatal = old }()
+
+	l := New("example.org.")
+	calls := 0
+	l.Next = forwarder(l, &calls)
+
+	m := new(dns.Msg)
+	m.SetQuestion(l.qname, dns.TypeHINFO)
+
+	// Outside of the probe window the probe name is just another query.
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	if rcode, _ := l.ServeDNS(context.TODO(), rec, m); rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeServerFailure, rcode)
+	}
+	if msg != "" {
+		t.Fatalf("Expected no fatal error outside of the probe window, got %s", msg)
+	}
+
+	// The probe name with another message ID, while probing.
+	l.probing, l.probeID = true, m.Id+1
+	l.ServeDNS(context.TODO(), rec, m)
+	if msg != "" {
+		t.Fatalf("Expected no fatal error for another message ID, got %s", msg)
+	}
+
+	l.probeID = m.Id
+	l.ServeDNS(context.TODO(), rec, m)
+	if msg == "" {
+		t.Fatalf("Expected loop to be fatal for the probe query")
+	}
+	t.Logf("%s", msg)
+}
+
+func TestLoopRetransmit(t *testing.T) {
+	l := New(".")
+	calls := 0
+	// The identical query arrives again while it is forwarded, but from the client and not from the
+	// upstream: a retransmission or an ID collision.
+	l.Next = test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		calls++
+		Forwarded(ctx, "10.0.0.1:53")
+		if calls <= maxRepeats+1 {
+			return l.ServeDNS(context.TODO(), w, r)
+		}
+		w.WriteMsg(r)
+		return dns.RcodeSuccess, nil
+	})
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+
+	rcode, _ := l.ServeDNS(context.TODO(), rec, m)
+	if rcode != dns.RcodeSuccess {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeSuccess, rcode)
+	}
+	if calls != maxRepeats+2 {
+		t.Errorf("Expected %d calls, got %d", maxRepeats+2, calls)
+	}
+}
+
+func TestNoLoop(t *testing.T) {
+	l := New(".")
+	calls := 0
+	// Identical queries from clients that arrive while the first one is handled, but before it is
+	// forwarded, are not a loop.
+	l.Next = test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		calls++
+		if calls <= maxRepeats+1 {
+			l.ServeDNS(context.TODO(), w, r)
+		}
+		Forwarded(ctx, "10.0.0.1:53")
+		w.WriteMsg(r)
+		return dns.RcodeSuccess, nil
+	})
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+
+	rcode, _ := l.ServeDNS(context.TODO(), rec, m)
+	if rcode != dns.RcodeSuccess {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeSuccess, rcode)
+	}
+	if calls != maxRepeats+2 {
+		t.Errorf("Expected %d calls, got %d", maxRepeats+2, calls)
+	}
+}
+
+func TestForwardedWithoutLoop(t *testing.T) {
+	// Must not panic.
+	Forwarded(context.TODO(), "10.0.0.1:53")
+}
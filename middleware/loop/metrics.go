@@ -0,0 +1,19 @@
+package loop
+
+import (
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LoopCount is the counter of queries that were answered with SERVFAIL because of a loop.
+var LoopCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: middleware.Namespace,
+	Subsystem: "loop",
+	Name:      "detected_total",
+	Help:      "Counter of queries that were answered with SERVFAIL because they were in a forwarding loop, per upstream.",
+}, []string{"upstream"})
+
+func init() {
+	prometheus.MustRegister(LoopCount)
+}
@@ -0,0 +1,127 @@
+package loop
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func init() {
+	caddy.RegisterPlugin("loop", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	zone, err := loopParse(c)
+	if err != nil {
+		return middleware.Error("loop", err)
+	}
+
+	l := New(zone)
+	config := dnsserver.GetConfig(c)
+
+	c.OnStartup(func() error {
+		go func() {
+			addr := net.JoinHostPort(probeHost(config.ListenHost), config.Port)
+			if err := l.probe(addr); err != nil {
+				log.Printf("[WARNING] Loop detection probe to %s failed: %s", addr, err)
+			}
+		}()
+		return nil
+	})
+
+	config.AddMiddleware(func(next middleware.Handler) middleware.Handler {
+		l.Next = next
+		return l
+	})
+
+	return nil
+}
+
+func loopParse(c *caddy.Controller) (string, error) {
+	zone := "."
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return "", c.Err("loop can only be specified once per server block")
+		}
+		i++
+
+		if c.NextArg() {
+			return "", c.ArgErr()
+		}
+		if len(c.ServerBlockKeys) > 0 {
+			zone = middleware.Host(c.ServerBlockKeys[0]).Normalize()
+		}
+	}
+	return zone, nil
+}
+
+// probe sends the probe query to the server at addr. When the server forwards the probe back to
+// itself, ServeDNS exits before probe returns. Only while probe runs can ServeDNS exit.
+func (l *Loop) probe(addr string) error {
+	m := new(dns.Msg)
+	m.SetQuestion(l.qname, dns.TypeHINFO)
+
+	l.mu.Lock()
+	l.probing, l.probeID = true, m.Id
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.probing = false
+		l.mu.Unlock()
+	}()
+
+	c := new(dns.Client)
+	c.Timeout = probeTimeout
+
+	var err error
+	for i := 0; i < probeTries; i++ {
+		if _, _, err = c.Exchange(m, addr); err == nil {
+			return nil
+		}
+		time.Sleep(probeTimeout)
+	}
+	return err
+}
+
+// qname returns a random name in zone to use as the probe. The name must not be guessable, so it
+// comes from crypto/rand.
+func qname(zone string) string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	name := hex.EncodeToString(buf[:8]) + "." + hex.EncodeToString(buf[8:]) + "."
+	if zone == "." {
+		return name
+	}
+	return name + zone
+}
+
+// probeHost returns the address to send the probe to for a server listening on host.
+func probeHost(host string) string {
+	switch host {
+	case "", "0.0.0.0":
+		return "127.0.0.1"
+	case "::":
+		return "::1"
+	}
+	return host
+}
+
+const (
+	probeTimeout = 2 * time.Second
+	probeTries   = 3
+)
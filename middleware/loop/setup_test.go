@@ -0,0 +1,58 @@
+package loop
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupLoop(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+	}{
+		{`loop`, false},
+		{`loop fail`, true},
+		{`loop
+loop`, true},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		_, err := loopParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d expected no errors, but got '%v'", i, err)
+		}
+	}
+}
+
+func TestProbeName(t *testing.T) {
+	if q := qname("."); strings.Count(q, ".") != 2 {
+		t.Errorf("Expected two labels in probe name, got %s", q)
+	}
+	if q := qname("example.org."); !strings.HasSuffix(q, ".example.org.") || strings.Count(q, ".") != 4 {
+		t.Errorf("Expected probe name in example.org., got %s", q)
+	}
+	if qname(".") == qname(".") {
+		t.Errorf("Expected random probe names")
+	}
+}
+
+func TestProbeHost(t *testing.T) {
+	tests := map[string]string{
+		"":          "127.0.0.1",
+		"0.0.0.0":   "127.0.0.1",
+		"::":        "::1",
+		"10.0.0.1":  "10.0.0.1",
+		"127.0.0.1": "127.0.0.1",
+	}
+	for host, expected := range tests {
+		if x := probeHost(host); x != expected {
+			t.Errorf("Expected %s for %q, got %s", expected, host, x)
+		}
+	}
+}
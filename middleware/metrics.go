@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+// Metrics for the middleware chain. These are registered by the metrics middleware.
+var (
+	HandlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "middleware",
+		Name:      "request_duration_milliseconds",
+		Buckets:   DurationBuckets,
+		Help:      "Histogram of the time (in milliseconds) spent in each middleware, including the middleware it called.",
+	}, []string{"middleware"})
+
+	HandlerHandledCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "middleware",
+		Name:      "handled_count_total",
+		Help:      "Counter of requests handled by each middleware, i.e. the middleware that was called last.",
+	}, []string{"middleware"})
+)
+
+// DurationBuckets are the buckets of the histograms that record a duration in milliseconds, use
+// Milliseconds to get the value to observe.
+var DurationBuckets = []float64{.1, .25, .5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// Milliseconds returns d in milliseconds, including the fraction.
+func Milliseconds(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+type handledKey struct{}
+
+// handled holds the name of the last middleware called for a request.
+type handled struct{ name string }
+
+// Serve calls h.ServeDNS and records how long it took. It is used by the server to call the first
+// middleware of the chain; NextOrFailure uses it to call the others. When the first middleware
+// returns, the middleware that handled the request is recorded.
+func Serve(ctx context.Context, h Handler, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	hd, ok := ctx.Value(handledKey{}).(*handled)
+	first := !ok
+	if first {
+		hd = &handled{}
+		ctx = context.WithValue(ctx, handledKey{}, hd)
+	}
+	hd.name = h.Name()
+
+	start := time.Now()
+	rc, err := h.ServeDNS(ctx, w, r)
+	HandlerDuration.WithLabelValues(h.Name()).Observe(Milliseconds(time.Since(start)))
+
+	if first {
+		HandlerHandledCount.WithLabelValues(hd.name).Inc()
+	}
+	return rc, err
+}
@@ -21,7 +21,13 @@ func init() {
 	prometheus.MustRegister(vars.RequestType)
 
 	prometheus.MustRegister(vars.ResponseSize)
+	prometheus.MustRegister(vars.ResponseSizeType)
 	prometheus.MustRegister(vars.ResponseRcode)
+	prometheus.MustRegister(vars.CookieCount)
+	prometheus.MustRegister(vars.Panic)
+
+	prometheus.MustRegister(middleware.HandlerDuration)
+	prometheus.MustRegister(middleware.HandlerHandledCount)
 }
 
 // Metrics holds the prometheus configuration. The metrics' path is fixed to be /metrics
@@ -60,8 +66,16 @@ func (m *Metrics) ZoneNames() []string {
 	return s
 }
 
-// OnStartup sets up the metrics on startup.
+// OnStartup sets up the metrics on startup. If another server block already exports the metrics on
+// the same address, that listener is used.
 func (m *Metrics) OnStartup() error {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+
+	if _, ok := listeners[m.Addr]; ok {
+		return nil
+	}
+
 	ln, err := net.Listen("tcp", m.Addr)
 	if err != nil {
 		log.Printf("[ERROR] Failed to start metrics handler: %s", err)
@@ -69,23 +83,34 @@ func (m *Metrics) OnStartup() error {
 	}
 
 	m.ln = ln
+	listeners[m.Addr] = ln
 	ListenAddr = m.ln.Addr().String()
 
 	m.mux = http.NewServeMux()
 	m.mux.Handle("/metrics", prometheus.Handler())
 
-	go func() {
-		http.Serve(m.ln, m.mux)
-	}()
+	go func(ln net.Listener) {
+		http.Serve(ln, m.mux)
+	}(ln)
 	return nil
 }
 
-// OnShutdown tears down the metrics on shutdown and restart.
+// OnShutdown tears down the metrics on shutdown and restart. Only the server block that started
+// the listener closes it.
 func (m *Metrics) OnShutdown() error {
-	if m.ln != nil {
-		return m.ln.Close()
+	if m.ln == nil {
+		return nil
 	}
-	return nil
+
+	listenersMu.Lock()
+	if listeners[m.Addr] == m.ln {
+		delete(listeners, m.Addr)
+	}
+	listenersMu.Unlock()
+
+	err := m.ln.Close()
+	m.ln = nil
+	return err
 }
 
 func keys(m map[string]bool) []string {
@@ -96,6 +121,11 @@ func keys(m map[string]bool) []string {
 	return sx
 }
 
+var (
+	listenersMu sync.Mutex
+	listeners   = make(map[string]net.Listener) // the metrics listeners, keyed by their configured address
+)
+
 // ListenAddr is assigned the address of the prometheus listener. Its use is mainly in tests where
 // we listen on "localhost:0" and need to retrieve the actual address.
 var ListenAddr string
@@ -5,10 +5,13 @@ import (
 
 	"github.com/coredns/coredns/middleware"
 	mtest "github.com/coredns/coredns/middleware/metrics/test"
+	"github.com/coredns/coredns/middleware/metrics/vars"
 	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
 	"github.com/coredns/coredns/middleware/test"
 
 	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"golang.org/x/net/context"
 )
 
@@ -81,3 +84,74 @@ func TestMetrics(t *testing.T) {
 		}
 	}
 }
+
+func TestMetricsListeners(t *testing.T) {
+	m1 := &Metrics{Addr: "localhost:0", zoneMap: make(map[string]bool)}
+	m2 := &Metrics{Addr: "localhost:0", zoneMap: make(map[string]bool)}
+	m3 := &Metrics{Addr: "127.0.0.1:0", zoneMap: make(map[string]bool)}
+
+	for _, m := range []*Metrics{m1, m2, m3} {
+		if err := m.OnStartup(); err != nil {
+			t.Fatalf("Failed to start metrics handler: %s", err)
+		}
+	}
+	if m1.ln == nil {
+		t.Errorf("Expected the first server block to listen")
+	}
+	if m2.ln != nil {
+		t.Errorf("Expected the second server block to share the listener of the first")
+	}
+	if m3.ln == nil {
+		t.Errorf("Expected a server block with another address to have its own listener")
+	}
+
+	for _, m := range []*Metrics{m1, m2, m3} {
+		if err := m.OnShutdown(); err != nil {
+			t.Errorf("Expected no error on shutdown, got %s", err)
+		}
+	}
+	if len(listeners) != 0 {
+		t.Errorf("Expected all listeners to be removed, got %d", len(listeners))
+	}
+
+	// After a restart the listener must be started again.
+	if err := m2.OnStartup(); err != nil {
+		t.Fatalf("Failed to restart metrics handler: %s", err)
+	}
+	defer m2.OnShutdown()
+	if m2.ln == nil {
+		t.Errorf("Expected the listener to be started again")
+	}
+}
+
+func TestMetricsServerLabel(t *testing.T) {
+	met := &Metrics{Addr: "localhost:0", zoneMap: make(map[string]bool)}
+	if err := met.OnStartup(); err != nil {
+		t.Fatalf("Failed to start metrics handler: %s", err)
+	}
+	defer met.OnShutdown()
+
+	met.AddZone("example.net.")
+	met.Next = test.NextHandler(dns.RcodeSuccess, nil)
+
+	ctx := context.WithValue(context.TODO(), middleware.ServerCtx{}, "dns://:1053")
+	req := new(dns.Msg)
+	req.SetQuestion("example.net.", dns.TypeMX)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	if _, err := met.ServeDNS(ctx, rec, req); err != nil {
+		t.Fatalf("Expected no error, but got %s", err)
+	}
+
+	result := mtest.Scrape(t, "http://"+ListenAddr+"/metrics")
+	_, labels := mtest.MetricValueLabel("coredns_dns_request_count_total", "dns://:1053", result)
+	if labels["server"] != "dns://:1053" || labels["zone"] != "example.net." {
+		t.Errorf("Expected server dns://:1053 and zone example.net., got %v", labels)
+	}
+
+	h := vars.ResponseSizeType.WithLabelValues("dns://:1053", "example.net.", "MX").(prometheus.Histogram)
+	m := &dto.Metric{}
+	h.Write(m)
+	if x := m.GetHistogram().GetSampleCount(); x != 1 {
+		t.Errorf("Expected 1 response size observation for type MX, got %d", x)
+	}
+}
@@ -2,7 +2,6 @@ package metrics
 
 import (
 	"net"
-	"sync"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/middleware"
@@ -28,11 +27,11 @@ func setup(c *caddy.Controller) error {
 		return m
 	})
 
-	// During restarts we will keep this handler running.
-	metricsOnce.Do(func() {
-		c.OncePerServerBlock(m.OnStartup)
-		c.OnFinalShutdown(m.OnShutdown)
-	})
+	// Each server block can export the metrics on its own address, blocks using the same address
+	// share the listener. On restart the listeners are closed, so changed addresses are picked up.
+	c.OncePerServerBlock(m.OnStartup)
+	c.OnRestart(m.OnShutdown)
+	c.OnFinalShutdown(m.OnShutdown)
 
 	return nil
 }
@@ -86,7 +85,5 @@ func prometheusParse(c *caddy.Controller) (*Metrics, error) {
 	return met, err
 }
 
-var metricsOnce sync.Once
-
 // Addr is the address the where the metrics are exported by default.
 const addr = "localhost:9153"
@@ -3,13 +3,18 @@ package vars
 import (
 	"time"
 
+	"github.com/coredns/coredns/middleware"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
+	"golang.org/x/net/context"
 )
 
-// Report reports the metrics data associcated with request.
-func Report(req request.Request, zone, rcode string, size int, start time.Time) {
+// Report reports the metrics data associcated with request. The server label is taken from ctx,
+// see ServerFromContext.
+func Report(ctx context.Context, req request.Request, zone, rcode string, size int, start time.Time) {
+	server := ServerFromContext(ctx)
+
 	// Proto and Family.
 	net := req.Proto()
 	fam := "1"
@@ -17,25 +22,32 @@ func Report(req request.Request, zone, rcode string, size int, start time.Time)
 		fam = "2"
 	}
 
-	typ := req.QType()
+	typ := other
+	if _, known := monitorType[req.QType()]; known {
+		typ = dns.Type(req.QType()).String()
+	}
 
-	RequestCount.WithLabelValues(zone, net, fam).Inc()
-	RequestDuration.WithLabelValues(zone).Observe(float64(time.Since(start) / time.Millisecond))
+	RequestCount.WithLabelValues(server, zone, net, fam).Inc()
+	RequestDuration.WithLabelValues(server, zone).Observe(float64(time.Since(start) / time.Millisecond))
 
 	if req.Do() {
-		RequestDo.WithLabelValues(zone).Inc()
+		RequestDo.WithLabelValues(server, zone).Inc()
 	}
 
-	if _, known := monitorType[typ]; known {
-		RequestType.WithLabelValues(zone, dns.Type(typ).String()).Inc()
-	} else {
-		RequestType.WithLabelValues(zone, other).Inc()
-	}
+	RequestType.WithLabelValues(server, zone, typ).Inc()
 
-	ResponseSize.WithLabelValues(zone, net).Observe(float64(size))
-	RequestSize.WithLabelValues(zone, net).Observe(float64(req.Len()))
+	ResponseSize.WithLabelValues(server, zone, net).Observe(float64(size))
+	ResponseSizeType.WithLabelValues(server, zone, typ).Observe(float64(size))
+	RequestSize.WithLabelValues(server, zone, net).Observe(float64(req.Len()))
+
+	ResponseRcode.WithLabelValues(server, zone, rcode).Inc()
+}
 
-	ResponseRcode.WithLabelValues(zone, rcode).Inc()
+// ServerFromContext returns the address of the server handling the request, as stored in ctx by
+// the server. If there is none, the empty string is returned.
+func ServerFromContext(ctx context.Context) string {
+	srv, _ := ctx.Value(middleware.ServerCtx{}).(string)
+	return srv
 }
 
 var monitorType = map[uint16]bool{
@@ -13,7 +13,7 @@ var (
 		Subsystem: subsystem,
 		Name:      "request_count_total",
 		Help:      "Counter of DNS requests made per zone, protocol and family.",
-	}, []string{"zone", "proto", "family"})
+	}, []string{"server", "zone", "proto", "family"})
 
 	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Namespace: middleware.Namespace,
@@ -21,7 +21,7 @@ var (
 		Name:      "request_duration_milliseconds",
 		Buckets:   append(prometheus.DefBuckets, []float64{50, 100, 200, 500, 1000, 2000, 3000, 4000, 5000, 10000}...),
 		Help:      "Histogram of the time (in milliseconds) each request took.",
-	}, []string{"zone"})
+	}, []string{"server", "zone"})
 
 	RequestSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Namespace: middleware.Namespace,
@@ -29,21 +29,21 @@ var (
 		Name:      "request_size_bytes",
 		Help:      "Size of the EDNS0 UDP buffer in bytes (64K for TCP).",
 		Buckets:   []float64{0, 100, 200, 300, 400, 511, 1023, 2047, 4095, 8291, 16e3, 32e3, 48e3, 64e3},
-	}, []string{"zone", "proto"})
+	}, []string{"server", "zone", "proto"})
 
 	RequestDo = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: middleware.Namespace,
 		Subsystem: subsystem,
 		Name:      "request_do_count_total",
 		Help:      "Counter of DNS requests with DO bit set per zone.",
-	}, []string{"zone"})
+	}, []string{"server", "zone"})
 
 	RequestType = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: middleware.Namespace,
 		Subsystem: subsystem,
 		Name:      "request_type_count_total",
 		Help:      "Counter of DNS requests per type, per zone.",
-	}, []string{"zone", "type"})
+	}, []string{"server", "zone", "type"})
 
 	ResponseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Namespace: middleware.Namespace,
@@ -51,14 +51,35 @@ var (
 		Name:      "response_size_bytes",
 		Help:      "Size of the returned response in bytes.",
 		Buckets:   []float64{0, 100, 200, 300, 400, 511, 1023, 2047, 4095, 8291, 16e3, 32e3, 48e3, 64e3},
-	}, []string{"zone", "proto"})
+	}, []string{"server", "zone", "proto"})
+
+	ResponseSizeType = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: middleware.Namespace,
+		Subsystem: subsystem,
+		Name:      "response_size_type_bytes",
+		Help:      "Size of the returned response in bytes, per query type.",
+		Buckets:   []float64{0, 100, 200, 300, 400, 511, 1023, 2047, 4095, 8291, 16e3, 32e3, 48e3, 64e3},
+	}, []string{"server", "zone", "type"})
 
 	ResponseRcode = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: middleware.Namespace,
 		Subsystem: subsystem,
 		Name:      "response_rcode_count_total",
 		Help:      "Counter of response status codes.",
-	}, []string{"zone", "rcode"})
+	}, []string{"server", "zone", "rcode"})
+
+	CookieCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: middleware.Namespace,
+		Subsystem: subsystem,
+		Name:      "cookie_count_total",
+		Help:      "Counter of DNS Cookies seen in requests, per result of the validation.",
+	}, []string{"server", "result"})
+
+	Panic = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: middleware.Namespace,
+		Name:      "panic_count_total",
+		Help:      "Counter of panics recovered while handling a request.",
+	})
 )
 
 const (
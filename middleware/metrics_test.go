@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+type testHandler struct {
+	name string
+	next Handler
+}
+
+func (t testHandler) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	if t.next == nil {
+		return dns.RcodeSuccess, nil
+	}
+	return NextOrFailure(t.name, t.next, ctx, w, r)
+}
+
+func (t testHandler) Name() string { return t.name }
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	if err := c.Write(m); err != nil {
+		t.Fatalf("Failed to read counter: %s", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestServeHandled(t *testing.T) {
+	chain := testHandler{name: "test-first", next: testHandler{name: "test-second", next: testHandler{name: "test-last"}}}
+
+	first := counterValue(t, HandlerHandledCount.WithLabelValues("test-first"))
+	last := counterValue(t, HandlerHandledCount.WithLabelValues("test-last"))
+
+	if _, err := Serve(context.TODO(), chain, nil, new(dns.Msg)); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if x := counterValue(t, HandlerHandledCount.WithLabelValues("test-last")); x != last+1 {
+		t.Errorf("Expected test-last to have handled 1 request, got %f", x-last)
+	}
+	if x := counterValue(t, HandlerHandledCount.WithLabelValues("test-first")); x != first {
+		t.Errorf("Expected test-first to have handled no requests, got %f", x-first)
+	}
+	if x := counterValue(t, HandlerHandledCount.WithLabelValues("test-second")); x != 0 {
+		t.Errorf("Expected test-second to have handled no requests, got %f", x)
+	}
+}
+
+func TestMilliseconds(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected float64
+	}{
+		{250 * time.Microsecond, 0.25},
+		{3 * time.Millisecond, 3},
+		{2 * time.Second, 2000},
+	}
+	for i, test := range tests {
+		if x := Milliseconds(test.d); x != test.expected {
+			t.Errorf("Test %d: expected %s to be %f ms, got %f", i, test.d, test.expected, x)
+		}
+	}
+}
@@ -76,11 +76,15 @@ func NextOrFailure(name string, next Handler, ctx context.Context, w dns.Respons
 			defer child.Finish()
 			ctx = ot.ContextWithSpan(ctx, child)
 		}
-		return next.ServeDNS(ctx, w, r)
+		return Serve(ctx, next, w, r)
 	}
 
 	return dns.RcodeServerFailure, Error(name, errors.New("no next middleware found"))
 }
 
+// ServerCtx is the context key under which the server stores its address, so middleware can see
+// which server is handling the request.
+type ServerCtx struct{}
+
 // Namespace is the namespace used for the metrics.
 const Namespace = "coredns"
@@ -0,0 +1,24 @@
+// Package minimal implements minimal responses, it strips the authority and additional sections
+// from responses where they are not required.
+package minimal
+
+import (
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Minimal is middleware that strips the responses from the next middleware.
+type Minimal struct {
+	Next middleware.Handler
+}
+
+// ServeDNS implements the middleware.Handler interface.
+func (m Minimal) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	mw := &ResponseWriter{w}
+	return middleware.NextOrFailure(m.Name(), m.Next, ctx, mw, r)
+}
+
+// Name implements the Handler interface.
+func (m Minimal) Name() string { return "minimal" }
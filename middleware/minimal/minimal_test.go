@@ -0,0 +1,104 @@
+package minimal
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+func TestMinimal(t *testing.T) {
+	tests := []struct {
+		rcode  int
+		answer []dns.RR
+		ns     []dns.RR
+		extra  []dns.RR
+
+		expectedNs    []uint16 // types in the authority section
+		expectedExtra []uint16 // types in the additional section
+	}{
+		// A positive response loses the NS records and glue.
+		{
+			answer: []dns.RR{test.A("example.org. 3600 IN A 192.0.2.53")},
+			ns:     []dns.RR{test.NS("example.org. 3600 IN NS ns.example.org.")},
+			extra:  []dns.RR{test.A("ns.example.org. 3600 IN A 192.0.2.1"), test.OPT(4096, true)},
+
+			expectedExtra: []uint16{dns.TypeOPT},
+		},
+		// A wildcard expansion keeps its denial of existence proof.
+		{
+			answer: []dns.RR{test.A("a.example.org. 3600 IN A 192.0.2.53")},
+			ns: []dns.RR{
+				test.NS("example.org. 3600 IN NS ns.example.org."),
+				test.NSEC("example.org. 3600 IN NSEC www.example.org. A NS SOA RRSIG NSEC"),
+				test.RRSIG("example.org. 3600 IN RRSIG NSEC 13 2 3600 20170702091734 20170624061734 18512 example.org. ZOeI"),
+				test.RRSIG("example.org. 3600 IN RRSIG NS 13 2 3600 20170702091734 20170624061734 18512 example.org. ZOeI"),
+			},
+
+			expectedNs: []uint16{dns.TypeNSEC, dns.TypeRRSIG},
+		},
+		// A negative response keeps the SOA.
+		{
+			rcode: dns.RcodeNameError,
+			ns: []dns.RR{
+				test.SOA("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 300"),
+				test.RRSIG("example.org. 3600 IN RRSIG SOA 13 2 3600 20170702091734 20170624061734 18512 example.org. ZOeI"),
+			},
+			extra: []dns.RR{test.OPT(4096, true)},
+
+			expectedNs:    []uint16{dns.TypeSOA, dns.TypeRRSIG},
+			expectedExtra: []uint16{dns.TypeOPT},
+		},
+		// A referral is left alone.
+		{
+			ns:    []dns.RR{test.NS("sub.example.org. 3600 IN NS ns.sub.example.org.")},
+			extra: []dns.RR{test.A("ns.sub.example.org. 3600 IN A 192.0.2.1")},
+
+			expectedNs:    []uint16{dns.TypeNS},
+			expectedExtra: []uint16{dns.TypeA},
+		},
+	}
+
+	for i, tc := range tests {
+		backend := test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			m := new(dns.Msg)
+			m.SetRcode(r, tc.rcode)
+			m.Answer, m.Ns, m.Extra = tc.answer, tc.ns, tc.extra
+			w.WriteMsg(m)
+			return tc.rcode, nil
+		})
+		mi := Minimal{Next: backend}
+
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		if _, err := mi.ServeDNS(context.TODO(), rec, req); err != nil {
+			t.Fatalf("Test %d: expected no error, got %v", i, err)
+		}
+
+		if len(rec.Msg.Answer) != len(tc.answer) {
+			t.Errorf("Test %d: expected %d answers, got %d", i, len(tc.answer), len(rec.Msg.Answer))
+		}
+		if !types(rec.Msg.Ns, tc.expectedNs) {
+			t.Errorf("Test %d: expected authority section %v, got %v", i, tc.expectedNs, rec.Msg.Ns)
+		}
+		if !types(rec.Msg.Extra, tc.expectedExtra) {
+			t.Errorf("Test %d: expected additional section %v, got %v", i, tc.expectedExtra, rec.Msg.Extra)
+		}
+	}
+}
+
+func types(rrs []dns.RR, expected []uint16) bool {
+	if len(rrs) != len(expected) {
+		return false
+	}
+	for i, rr := range rrs {
+		if rr.Header().Rrtype != expected[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package minimal
+
+import "github.com/miekg/dns"
+
+// ResponseWriter is a response writer that removes the records from the authority and additional
+// sections that are not needed to answer the query.
+type ResponseWriter struct {
+	dns.ResponseWriter
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	if referral(res) {
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	res.Ns = authority(res.Ns, len(res.Answer) == 0)
+	res.Extra = additional(res.Extra)
+	return w.ResponseWriter.WriteMsg(res)
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) Write(buf []byte) (int, error) {
+	return w.ResponseWriter.Write(buf)
+}
+
+// Hijack implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) Hijack() {
+	w.ResponseWriter.Hijack()
+}
+
+// referral returns true if res delegates to other name servers, in which case the NS records and
+// the glue are the answer.
+func referral(res *dns.Msg) bool {
+	if res.Rcode != dns.RcodeSuccess || len(res.Answer) > 0 {
+		return false
+	}
+	ns := false
+	for _, rr := range res.Ns {
+		switch rr.Header().Rrtype {
+		case dns.TypeSOA:
+			return false
+		case dns.TypeNS:
+			ns = true
+		}
+	}
+	return ns
+}
+
+// authority returns the records from ns that are required: the denial of existence proofs and, for
+// negative responses, the SOA record and its signatures.
+func authority(ns []dns.RR, negative bool) []dns.RR {
+	var keep []dns.RR
+	for _, rr := range ns {
+		t := rr.Header().Rrtype
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			t = sig.TypeCovered
+		}
+		switch t {
+		case dns.TypeNSEC, dns.TypeNSEC3:
+			keep = append(keep, rr)
+		case dns.TypeSOA:
+			if negative {
+				keep = append(keep, rr)
+			}
+		}
+	}
+	return keep
+}
+
+// additional returns the OPT and TSIG records from extra.
+func additional(extra []dns.RR) []dns.RR {
+	var keep []dns.RR
+	for _, rr := range extra {
+		switch rr.Header().Rrtype {
+		case dns.TypeOPT, dns.TypeTSIG:
+			keep = append(keep, rr)
+		}
+	}
+	return keep
+}
@@ -0,0 +1,45 @@
+package minimal
+
+import (
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("minimal", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	if err := minimalParse(c); err != nil {
+		return middleware.Error("minimal", err)
+	}
+
+	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
+		return Minimal{Next: next}
+	})
+
+	return nil
+}
+
+func minimalParse(c *caddy.Controller) error {
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return c.Err("minimal can only be specified once per server block")
+		}
+		i++
+
+		if c.NextArg() {
+			return c.ArgErr()
+		}
+		if c.NextBlock() {
+			return c.Errf("unknown property '%s'", c.Val())
+		}
+	}
+	return nil
+}
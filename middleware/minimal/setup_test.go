@@ -0,0 +1,34 @@
+package minimal
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupMinimal(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+	}{
+		{`minimal`, false},
+		// fails
+		{`minimal yes`, true},
+		{`minimal {
+			any
+		}`, true},
+		{`minimal
+minimal`, true},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		err := minimalParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d expected no errors, but got '%v'", i, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package nsid implements a middleware that returns the name server identifier (NSID) in responses,
+// see RFC 5001.
+package nsid
+
+import (
+	"encoding/hex"
+
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Nsid adds the NSID option with Data to the responses for queries that ask for it.
+type Nsid struct {
+	Next middleware.Handler
+	Data string
+}
+
+// ServeDNS implements the middleware.Handler interface.
+func (n Nsid) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	if o := r.IsEdns0(); o != nil {
+		for _, e := range o.Option {
+			if e.Option() == dns.EDNS0NSID {
+				nw := &ResponseWriter{ResponseWriter: w, req: r, nsid: hex.EncodeToString([]byte(n.Data))}
+				return middleware.NextOrFailure(n.Name(), n.Next, ctx, nw, r)
+			}
+		}
+	}
+	return middleware.NextOrFailure(n.Name(), n.Next, ctx, w, r)
+}
+
+// Name implements the middleware.Handler interface.
+func (n Nsid) Name() string { return "nsid" }
+
+// ResponseWriter adds the NSID option to the response.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	req  *dns.Msg
+	nsid string // hex encoded
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	if res.IsEdns0() == nil {
+		state := request.Request{W: w.ResponseWriter, Req: w.req}
+		state.SizeAndDo(res)
+	}
+
+	// The OPT record may be shared with the request, which holds the (empty) NSID option of the
+	// client, so replace it with a copy.
+	extra := make([]dns.RR, len(res.Extra))
+	for i, rr := range res.Extra {
+		o, ok := rr.(*dns.OPT)
+		if !ok {
+			extra[i] = rr
+			continue
+		}
+		opt := dns.Copy(o).(*dns.OPT)
+		opt.Option = make([]dns.EDNS0, 0, len(o.Option)+1)
+		for _, e := range o.Option {
+			if e.Option() != dns.EDNS0NSID {
+				opt.Option = append(opt.Option, e)
+			}
+		}
+		opt.Option = append(opt.Option, &dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: w.nsid})
+		extra[i] = opt
+	}
+	res.Extra = extra
+	return w.ResponseWriter.WriteMsg(res)
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) Write(buf []byte) (int, error) {
+	return w.ResponseWriter.Write(buf)
+}
+
+// Hijack implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) Hijack() {
+	w.ResponseWriter.Hijack()
+}
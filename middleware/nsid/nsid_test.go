@@ -0,0 +1,74 @@
+package nsid
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// backend answers the query, it echoes the OPT record of the query like most middleware does.
+func backend(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Answer = []dns.RR{test.A("example.org. 3600 IN A 192.0.2.53")}
+	state.SizeAndDo(m)
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+func TestNsid(t *testing.T) {
+	n := Nsid{Next: test.HandlerFunc(backend), Data: "ns1.example.org"}
+
+	tests := []struct {
+		edns     bool
+		nsid     bool
+		expected string // the NSID in the response
+	}{
+		{edns: true, nsid: true, expected: "ns1.example.org"},
+		{edns: true},
+		{},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		if tc.edns {
+			m.SetEdns0(4096, false)
+		}
+		if tc.nsid {
+			m.IsEdns0().Option = []dns.EDNS0{&dns.EDNS0_NSID{Code: dns.EDNS0NSID}}
+		}
+
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		if _, err := n.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %v", i, err)
+		}
+
+		nsid, count := "", 0
+		if o := rec.Msg.IsEdns0(); o != nil {
+			for _, e := range o.Option {
+				if x, ok := e.(*dns.EDNS0_NSID); ok {
+					buf, _ := hex.DecodeString(x.Nsid)
+					nsid = string(buf)
+					count++
+				}
+			}
+		}
+		if nsid != tc.expected {
+			t.Errorf("Test %d: expected NSID %q, got %q", i, tc.expected, nsid)
+		}
+		if tc.nsid && count != 1 {
+			t.Errorf("Test %d: expected 1 NSID option, got %d", i, count)
+		}
+		if tc.nsid && m.IsEdns0().Option[0].(*dns.EDNS0_NSID).Nsid != "" {
+			t.Errorf("Test %d: expected the query to be left alone", i)
+		}
+	}
+}
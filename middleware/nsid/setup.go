@@ -0,0 +1,52 @@
+package nsid
+
+import (
+	"strings"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/identity"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("nsid", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	data, err := nsidParse(c)
+	if err != nil {
+		return middleware.Error("nsid", err)
+	}
+
+	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
+		return Nsid{Next: next, Data: data}
+	})
+
+	return nil
+}
+
+func nsidParse(c *caddy.Controller) (string, error) {
+	data := ""
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return "", c.Err("nsid can only be specified once per server block")
+		}
+		i++
+
+		data = strings.Join(c.RemainingArgs(), " ")
+		if c.NextBlock() {
+			return "", c.Errf("unknown property '%s'", c.Val())
+		}
+	}
+	if data == "" {
+		return identity.Hostname(), nil
+	}
+	return identity.Expand(data), nil
+}
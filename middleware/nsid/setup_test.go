@@ -0,0 +1,47 @@
+package nsid
+
+import (
+	"os"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/identity"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupNsid(t *testing.T) {
+	os.Setenv("POD_NAME", "coredns-1234")
+	defer os.Unsetenv("POD_NAME")
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  string
+	}{
+		{`nsid`, false, identity.Hostname()},
+		{`nsid ns1.example.org`, false, "ns1.example.org"},
+		{`nsid "ns1 in ams"`, false, "ns1 in ams"},
+		{`nsid ns1 ams`, false, "ns1 ams"},
+		{`nsid {pod}`, false, "coredns-1234"},
+		// fails
+		{`nsid ns1 {
+			unknown
+		}`, true, ""},
+		{`nsid
+nsid`, true, ""},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		data, err := nsidParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if !test.shouldErr && data != test.expected {
+			t.Errorf("Test %d expected NSID %q, got %q", i, test.expected, data)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package cookie implements DNS Cookies, see RFC 7873.
+package cookie
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// Config is the DNS Cookie configuration of a server.
+type Config struct {
+	Secret *Secret
+	// Require a valid server cookie for queries over UDP. Queries without a cookie get a truncated
+	// response, queries without a valid server cookie get BADCOOKIE.
+	Require bool
+}
+
+// Secret holds the secret used to create server cookies. After Rotate the previous secret is still
+// used to check server cookies, so cookies handed out just before stay valid.
+type Secret struct {
+	mu       sync.RWMutex
+	current  []byte
+	previous []byte
+}
+
+// NewSecret returns a Secret that uses secret. If secret is nil a random secret is used.
+func NewSecret(secret []byte) *Secret {
+	if secret == nil {
+		secret = random(SecretLen)
+	}
+	return &Secret{current: secret}
+}
+
+// Rotate replaces the secret with a new random one.
+func (s *Secret) Rotate() {
+	secret := random(SecretLen)
+	s.mu.Lock()
+	s.previous, s.current = s.current, secret
+	s.mu.Unlock()
+}
+
+// Server returns the server cookie for client, the client cookie, sent from ip.
+func (s *Secret) Server(client []byte, ip net.IP) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return serverCookie(s.current, client, ip)
+}
+
+// Valid returns true if server is a server cookie we handed out to ip for client.
+func (s *Secret) Valid(client, server []byte, ip net.IP) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if hmac.Equal(server, serverCookie(s.current, client, ip)) {
+		return true
+	}
+	return s.previous != nil && hmac.Equal(server, serverCookie(s.previous, client, ip))
+}
+
+// Client returns the client cookie to use for the server at addr. A different client cookie is used
+// for every server, RFC 7873, Section 5.1.
+func (s *Secret) Client(addr string) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h := hmac.New(sha256.New, s.current)
+	h.Write([]byte(addr))
+	return h.Sum(nil)[:ClientLen]
+}
+
+// serverCookie returns an HMAC-SHA256 over client and ip, truncated to ServerLen bytes, RFC 7873,
+// Appendix B.2.
+func serverCookie(secret, client []byte, ip net.IP) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(client)
+	h.Write(ip.To16())
+	return h.Sum(nil)[:ServerLen]
+}
+
+// Parse returns the client and server cookie of the COOKIE option in m. If m has no COOKIE option
+// both are nil. An error is returned for a malformed option, RFC 7873, Section 5.2.2.
+func Parse(m *dns.Msg) (client, server []byte, err error) {
+	o := m.IsEdns0()
+	if o == nil {
+		return nil, nil, nil
+	}
+	for _, e := range o.Option {
+		c, ok := e.(*dns.EDNS0_COOKIE)
+		if !ok {
+			continue
+		}
+		buf, err := hex.DecodeString(c.Cookie)
+		if err != nil {
+			return nil, nil, errMalformed
+		}
+		switch l := len(buf); {
+		case l == ClientLen:
+			return buf, nil, nil
+		case l >= ClientLen+8 && l <= ClientLen+32:
+			return buf[:ClientLen], buf[ClientLen:], nil
+		}
+		return nil, nil, errMalformed
+	}
+	return nil, nil, nil
+}
+
+// Set sets the COOKIE option in m to client and server. Any existing COOKIE option is replaced. Set
+// is a noop if m has no OPT record. The OPT record of m is copied and not modified, as it may be shared
+// with another message.
+func Set(m *dns.Msg, client, server []byte) {
+	o := replaceOPT(m)
+	if o == nil {
+		return
+	}
+	o.Option = append(o.Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: hex.EncodeToString(append(append([]byte{}, client...), server...))})
+}
+
+// Remove removes the COOKIE option from m.
+func Remove(m *dns.Msg) { replaceOPT(m) }
+
+// replaceOPT replaces the OPT record of m with a copy without the COOKIE option, and returns it.
+func replaceOPT(m *dns.Msg) *dns.OPT {
+	var opt *dns.OPT
+	extra := make([]dns.RR, len(m.Extra))
+	for i, rr := range m.Extra {
+		o, ok := rr.(*dns.OPT)
+		if !ok {
+			extra[i] = rr
+			continue
+		}
+		opt = dns.Copy(o).(*dns.OPT)
+		opt.Option = make([]dns.EDNS0, 0, len(o.Option)+1)
+		for _, e := range o.Option {
+			if e.Option() != dns.EDNS0COOKIE {
+				opt.Option = append(opt.Option, e)
+			}
+		}
+		extra[i] = opt
+	}
+	m.Extra = extra
+	return opt
+}
+
+// SetBadCookie sets the rcode of m to BADCOOKIE. This is an extended rcode, so m must have an OPT
+// record.
+func SetBadCookie(m *dns.Msg) {
+	o := m.IsEdns0()
+	if o == nil {
+		return
+	}
+	m.Rcode = dns.RcodeBadCookie & 0xF
+	o.Hdr.Ttl = o.Hdr.Ttl&0x00FFFFFF | uint32(dns.RcodeBadCookie>>4)<<24
+}
+
+// IsBadCookie returns true if the rcode of m is BADCOOKIE.
+func IsBadCookie(m *dns.Msg) bool {
+	o := m.IsEdns0()
+	if o == nil {
+		return false
+	}
+	return m.Rcode&0xF == dns.RcodeBadCookie&0xF && o.Hdr.Ttl>>24 == dns.RcodeBadCookie>>4
+}
+
+// random returns n random bytes.
+func random(n int) []byte {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return buf
+}
+
+const (
+	// ClientLen is the length of a client cookie.
+	ClientLen = 8
+	// ServerLen is the length of the server cookies we create.
+	ServerLen = 8
+	// SecretLen is the length of a random secret.
+	SecretLen = 16
+)
+
+var errMalformed = errors.New("malformed cookie")
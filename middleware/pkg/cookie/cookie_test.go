@@ -0,0 +1,132 @@
+package cookie
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestSecret(t *testing.T) {
+	s := NewSecret(nil)
+	client := []byte("abcdefgh")
+	ip := net.ParseIP("192.0.2.1")
+
+	server := s.Server(client, ip)
+	if len(server) != ServerLen {
+		t.Fatalf("Expected server cookie of %d bytes, got %d", ServerLen, len(server))
+	}
+	if !s.Valid(client, server, ip) {
+		t.Errorf("Expected server cookie to be valid")
+	}
+	if s.Valid(client, server, net.ParseIP("192.0.2.2")) {
+		t.Errorf("Expected server cookie to be invalid for another address")
+	}
+	if s.Valid([]byte("hgfedcba"), server, ip) {
+		t.Errorf("Expected server cookie to be invalid for another client cookie")
+	}
+
+	s.Rotate()
+	if !s.Valid(client, server, ip) {
+		t.Errorf("Expected server cookie to be valid after one rotation")
+	}
+	if bytes.Equal(server, s.Server(client, ip)) {
+		t.Errorf("Expected a new server cookie after rotation")
+	}
+	s.Rotate()
+	if s.Valid(client, server, ip) {
+		t.Errorf("Expected server cookie to be invalid after two rotations")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		cookie    string
+		client    string
+		server    string
+		shouldErr bool
+	}{
+		{"", "", "", false},
+		{"0102030405060708", "0102030405060708", "", false},
+		{"0102030405060708a1a2a3a4a5a6a7a8", "0102030405060708", "a1a2a3a4a5a6a7a8", false},
+		// fails
+		{"01020304", "", "", true},
+		{"0102030405060708a1a2a3a4", "", "", true},
+		{"zz02030405060708", "", "", true},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		m.SetEdns0(4096, false)
+		if tc.cookie != "" {
+			m.IsEdns0().Option = []dns.EDNS0{&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: tc.cookie}}
+		}
+
+		client, server, err := Parse(m)
+		if (err != nil) != tc.shouldErr {
+			t.Errorf("Test %d: expected error to be %t, got %v", i, tc.shouldErr, err)
+			continue
+		}
+		if x := hex.EncodeToString(client); x != tc.client {
+			t.Errorf("Test %d: expected client cookie %q, got %q", i, tc.client, x)
+		}
+		if x := hex.EncodeToString(server); x != tc.server {
+			t.Errorf("Test %d: expected server cookie %q, got %q", i, tc.server, x)
+		}
+	}
+}
+
+func TestSetRemove(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	opt := m.IsEdns0()
+	opt.Option = []dns.EDNS0{&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: "0102030405060708"}}
+
+	client, server := []byte("abcdefgh"), []byte("12345678")
+	Set(m, client, server)
+	c, s, err := Parse(m)
+	if err != nil || !bytes.Equal(c, client) || !bytes.Equal(s, server) {
+		t.Errorf("Expected cookie %x%x, got %x%x (%v)", client, server, c, s, err)
+	}
+	if len(m.IsEdns0().Option) != 1 {
+		t.Errorf("Expected 1 option, got %d", len(m.IsEdns0().Option))
+	}
+	// The original OPT record must not be modified.
+	if opt.Option[0].(*dns.EDNS0_COOKIE).Cookie != "0102030405060708" {
+		t.Errorf("Expected the original OPT record to be left alone, got %s", opt)
+	}
+
+	Remove(m)
+	if c, _, _ := Parse(m); c != nil {
+		t.Errorf("Expected no cookie after Remove, got %x", c)
+	}
+}
+
+func TestBadCookie(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if IsBadCookie(m) {
+		t.Fatalf("Expected no BADCOOKIE")
+	}
+	m.SetEdns0(4096, false)
+	SetBadCookie(m)
+	if !IsBadCookie(m) {
+		t.Errorf("Expected BADCOOKIE")
+	}
+
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatalf("Expected no error packing, got %v", err)
+	}
+	m1 := new(dns.Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatalf("Expected no error unpacking, got %v", err)
+	}
+	if !IsBadCookie(m1) {
+		t.Errorf("Expected BADCOOKIE after unpacking")
+	}
+}
@@ -0,0 +1,99 @@
+// Package dnskey implements reading DNSSEC keys from disk and signing RRsets with them.
+package dnskey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+// Key holds a DNSSEC public and private key.
+type Key struct {
+	K      *dns.DNSKEY
+	s      crypto.Signer
+	keytag uint16
+}
+
+// ParseKeyFile read a DNSSEC keyfile as generated by dnssec-keygen or other
+// utilities. It adds ".key" for the public key and ".private" for the private key.
+func ParseKeyFile(pubFile, privFile string) (*Key, error) {
+	f, e := os.Open(pubFile)
+	if e != nil {
+		return nil, e
+	}
+	k, e := dns.ReadRR(f, pubFile)
+	if e != nil {
+		return nil, e
+	}
+
+	f, e = os.Open(privFile)
+	if e != nil {
+		return nil, e
+	}
+	p, e := k.(*dns.DNSKEY).ReadPrivateKey(f, privFile)
+	if e != nil {
+		return nil, e
+	}
+
+	if v, ok := p.(*rsa.PrivateKey); ok {
+		return &Key{k.(*dns.DNSKEY), v, k.(*dns.DNSKEY).KeyTag()}, nil
+	}
+	if v, ok := p.(*ecdsa.PrivateKey); ok {
+		return &Key{k.(*dns.DNSKEY), v, k.(*dns.DNSKEY).KeyTag()}, nil
+	}
+	return &Key{k.(*dns.DNSKEY), nil, 0}, errors.New("no known? private key found")
+}
+
+// Parse parses the arguments of a 'key file KEY...' statement and returns the keys read from disk.
+func Parse(c *caddy.Controller) ([]*Key, error) {
+	keys := []*Key{}
+
+	if !c.NextArg() {
+		return nil, c.ArgErr()
+	}
+	value := c.Val()
+	if value == "file" {
+		ks := c.RemainingArgs()
+		for _, k := range ks {
+			base := k
+			// Kmiek.nl.+013+26205.key, handle .private or without extension: Kmiek.nl.+013+26205
+			if strings.HasSuffix(k, ".key") {
+				base = k[:len(k)-4]
+			}
+			if strings.HasSuffix(k, ".private") {
+				base = k[:len(k)-8]
+			}
+			k, err := ParseKeyFile(base+".key", base+".private")
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+// Sign signs rrs with k and returns the RRSIG. The TTL of the RRSIG is set to ttl and its original
+// TTL to origTTL.
+func (k *Key) Sign(rrs []dns.RR, signerName string, ttl, origTTL, incep, expir uint32) (*dns.RRSIG, error) {
+	sig := new(dns.RRSIG)
+
+	sig.Hdr.Rrtype = dns.TypeRRSIG
+	sig.Algorithm = k.K.Algorithm
+	sig.KeyTag = k.keytag
+	sig.SignerName = signerName
+	sig.Hdr.Ttl = ttl
+	sig.OrigTtl = origTTL
+
+	sig.Inception = incep
+	sig.Expiration = expir
+
+	err := sig.Sign(k.s, rrs)
+	return sig, err
+}
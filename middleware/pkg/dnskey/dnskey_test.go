@@ -0,0 +1,51 @@
+package dnskey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestSign(t *testing.T) {
+	fPub, rmPub, _ := test.TempFile(".", pubKey)
+	defer rmPub()
+	fPriv, rmPriv, _ := test.TempFile(".", privKey)
+	defer rmPriv()
+
+	k, err := ParseKeyFile(fPub, fPriv)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+
+	now := time.Now().UTC()
+	incep, expir := uint32(now.Add(-time.Hour).Unix()), uint32(now.Add(time.Hour).Unix())
+	rrs := []dns.RR{test.A("www.miek.nl. 1800 IN A 192.0.2.1")}
+
+	sig, err := k.Sign(rrs, "miek.nl.", 1800, 3600, incep, expir)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if sig.Hdr.Ttl != 1800 || sig.OrigTtl != 3600 {
+		t.Errorf("Expected TTL 1800 and original TTL 3600, got %d and %d", sig.Hdr.Ttl, sig.OrigTtl)
+	}
+	if sig.KeyTag != k.K.KeyTag() {
+		t.Errorf("Expected key tag %d, got %d", k.K.KeyTag(), sig.KeyTag)
+	}
+	if err := sig.Verify(k.K, rrs); err != nil {
+		t.Errorf("Expected the signature to verify, got %v", err)
+	}
+}
+
+const (
+	pubKey  = `miek.nl. IN DNSKEY 257 3 13 0J8u0XJ9GNGFEBXuAmLu04taHG4BXPP3gwhetiOUMnGA+x09nqzgF5IY OyjWB7N3rXqQbnOSILhH1hnuyh7mmA==`
+	privKey = `Private-key-format: v1.3
+Algorithm: 13 (ECDSAP256SHA256)
+PrivateKey: /4BZk8AFvyW5hL3cOLSVxIp1RTqHSAEloWUxj86p3gs=
+Created: 20160423195532
+Publish: 20160423195532
+Activate: 20160423195532
+`
+)
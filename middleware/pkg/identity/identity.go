@@ -0,0 +1,32 @@
+// Package identity expands placeholders that identify the server, i.e. the hostname, or the pod and
+// node names when running in Kubernetes.
+package identity
+
+import (
+	"os"
+	"strings"
+)
+
+// Hostname returns the hostname of the server, or "localhost" when it can't be determined.
+func Hostname() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "localhost"
+	}
+	return hostname
+}
+
+// Expand replaces the placeholders in s: {hostname} with the hostname, {pod} with the POD_NAME
+// environment variable and {node} with the NODE_NAME environment variable. These are the names
+// commonly used to expose the pod and node name through the Kubernetes downward API.
+func Expand(s string) string {
+	if !strings.Contains(s, "{") {
+		return s
+	}
+	r := strings.NewReplacer(
+		"{hostname}", Hostname(),
+		"{pod}", os.Getenv("POD_NAME"),
+		"{node}", os.Getenv("NODE_NAME"),
+	)
+	return r.Replace(s)
+}
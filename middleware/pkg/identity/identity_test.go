@@ -0,0 +1,27 @@
+package identity
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	os.Setenv("POD_NAME", "coredns-1234")
+	defer os.Unsetenv("POD_NAME")
+	os.Unsetenv("NODE_NAME")
+
+	tests := []struct {
+		in, expected string
+	}{
+		{"ns1", "ns1"},
+		{"{pod}", "coredns-1234"},
+		{"{hostname}/{pod}", Hostname() + "/coredns-1234"},
+		{"node={node}", "node="},
+		{"{unknown}", "{unknown}"},
+	}
+	for i, tc := range tests {
+		if x := Expand(tc.in); x != tc.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, tc.expected, x)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Package meta allows middleware to record information about how a query was handled, so that
+// middleware earlier in the chain, like log, can use it after the query has been answered.
+package meta
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// Meta holds key value pairs recorded while handling a query.
+type Meta struct {
+	mu sync.RWMutex
+	m  map[string]string
+}
+
+type key struct{}
+
+// Upstream is the key used by proxy to record the upstream host that answered the query.
+const Upstream = "upstream"
+
+// New returns a context derived from ctx that carries a new, empty, Meta.
+func New(ctx context.Context) (context.Context, *Meta) {
+	m := &Meta{m: make(map[string]string)}
+	return context.WithValue(ctx, key{}, m), m
+}
+
+// FromContext returns the Meta carried by ctx, or nil if there is none.
+func FromContext(ctx context.Context) *Meta {
+	m, _ := ctx.Value(key{}).(*Meta)
+	return m
+}
+
+// Set records value under key. Set is a noop when m is nil, so middleware can call
+// FromContext(ctx).Set without checking if something is interested in the value.
+func (m *Meta) Set(key, value string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.m[key] = value
+	m.mu.Unlock()
+}
+
+// Get returns the value recorded under key, or the empty string if there is none.
+func (m *Meta) Get(key string) string {
+	if m == nil {
+		return ""
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m[key]
+}
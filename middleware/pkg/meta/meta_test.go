@@ -0,0 +1,24 @@
+package meta
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestMeta(t *testing.T) {
+	if m := FromContext(context.TODO()); m != nil {
+		t.Errorf("Expected no meta in context, got %v", m)
+	}
+	// Set on a nil Meta is a noop.
+	FromContext(context.TODO()).Set(Upstream, "127.0.0.1:53")
+
+	ctx, m := New(context.TODO())
+	FromContext(ctx).Set(Upstream, "127.0.0.1:53")
+	if x := m.Get(Upstream); x != "127.0.0.1:53" {
+		t.Errorf("Expected upstream %q, got %q", "127.0.0.1:53", x)
+	}
+	if x := m.Get("nope"); x != "" {
+		t.Errorf("Expected empty value, got %q", x)
+	}
+}
@@ -0,0 +1,25 @@
+// Package nonwriter implements a dns.ResponseWriter that does not write to the client.
+package nonwriter
+
+import (
+	"github.com/miekg/dns"
+)
+
+// Writer is a type of ResponseWriter that captures the message, but never writes to the client.
+// It is used by middleware that need to perform internal lookups before answering a query.
+type Writer struct {
+	dns.ResponseWriter
+	Msg *dns.Msg
+}
+
+// New makes and returns a new Writer.
+func New(w dns.ResponseWriter) *Writer { return &Writer{ResponseWriter: w} }
+
+// WriteMsg records the message, but doesn't write it to the client.
+func (w *Writer) WriteMsg(res *dns.Msg) error {
+	w.Msg = res
+	return nil
+}
+
+// Write implements the dns.ResponseWriter interface. The bytes are discarded.
+func (w *Writer) Write(buf []byte) (int, error) { return len(buf), nil }
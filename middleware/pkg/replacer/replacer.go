@@ -29,26 +29,29 @@ type replacer struct {
 // Do not create a new replacer until r and rr have all
 // the needed values, because this function copies those
 // values into the replacer. rr may be nil if it is not
-// available. emptyValue should be the string that is used
-// in place of empty string (can still be empty string).
+// available, the placeholders that need the client's
+// transport are then not replaced. emptyValue should be the
+// string that is used in place of empty string (can still
+// be empty string).
 func New(r *dns.Msg, rr *dnsrecorder.Recorder, emptyValue string) Replacer {
-	req := request.Request{W: rr, Req: r}
+	req := request.Request{Req: r}
 	rep := replacer{
 		replacements: map[string]string{
 			"{type}":  req.Type(),
 			"{name}":  req.Name(),
 			"{class}": req.Class(),
-			"{proto}": req.Proto(),
 			"{when}": func() string {
 				return time.Now().Format(timeFormat)
 			}(),
-			"{size}":   strconv.Itoa(req.Len()),
-			"{remote}": req.IP(),
-			"{port}":   req.Port(),
+			"{size}": strconv.Itoa(req.Len()),
 		},
 		emptyValue: emptyValue,
 	}
 	if rr != nil {
+		req.W = rr
+		rep.replacements["{proto}"] = req.Proto()
+		rep.replacements["{remote}"] = req.IP()
+		rep.replacements["{port}"] = req.Port()
 		rcode := dns.RcodeToString[rr.Rcode]
 		if rcode == "" {
 			rcode = strconv.Itoa(rr.Rcode)
@@ -56,13 +59,26 @@ func New(r *dns.Msg, rr *dnsrecorder.Recorder, emptyValue string) Replacer {
 		rep.replacements["{rcode}"] = rcode
 		rep.replacements["{rsize}"] = strconv.Itoa(rr.Len)
 		rep.replacements["{duration}"] = time.Since(rr.Start).String()
+		if rr.Msg != nil {
+			rep.replacements["{rflags}"] = flagsToString(rr.Msg.MsgHdr)
+			rep.replacements["{ancount}"] = strconv.Itoa(len(rr.Msg.Answer))
+			rep.replacements["{answer}"] = answerToString(rr.Msg.Answer)
+			if opt := rr.Msg.IsEdns0(); opt != nil {
+				rep.replacements["{rbufsize}"] = strconv.Itoa(int(opt.UDPSize()))
+			}
+		}
 	}
 
 	// Header placeholders (case-insensitive)
 	rep.replacements[headerReplacer+"id}"] = strconv.Itoa(int(r.Id))
 	rep.replacements[headerReplacer+"opcode}"] = strconv.Itoa(int(r.Opcode))
 	rep.replacements[headerReplacer+"do}"] = boolToString(req.Do())
-	rep.replacements[headerReplacer+"bufsize}"] = strconv.Itoa(req.Size())
+	if req.W != nil {
+		rep.replacements[headerReplacer+"bufsize}"] = strconv.Itoa(req.Size())
+	} else if opt := r.IsEdns0(); opt != nil {
+		rep.replacements[headerReplacer+"bufsize}"] = strconv.Itoa(int(opt.UDPSize()))
+	}
+	rep.replacements[headerReplacer+"ecs}"] = ecsToString(req.ECS())
 
 	return rep
 }
@@ -103,6 +119,41 @@ func (r replacer) Set(key, value string) {
 	r.replacements["{"+key+"}"] = value
 }
 
+// flagsToString returns the flags set in h as a comma separated list, i.e. "qr,aa,rd".
+func flagsToString(h dns.MsgHdr) string {
+	flags := make([]string, 0, 7)
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{h.Response, "qr"}, {h.Authoritative, "aa"}, {h.Truncated, "tc"}, {h.RecursionDesired, "rd"},
+		{h.RecursionAvailable, "ra"}, {h.AuthenticatedData, "ad"}, {h.CheckingDisabled, "cd"},
+	} {
+		if f.set {
+			flags = append(flags, f.name)
+		}
+	}
+	return strings.Join(flags, ",")
+}
+
+// answerToString returns the records in rrs in presentation format, separated by " | ".
+func answerToString(rrs []dns.RR) string {
+	s := make([]string, len(rrs))
+	for i, rr := range rrs {
+		s[i] = strings.Replace(rr.String(), "\t", " ", -1)
+	}
+	return strings.Join(s, " | ")
+}
+
+// ecsToString returns the EDNS0 client subnet e as address/source-netmask, or the empty string if
+// e is nil.
+func ecsToString(e *dns.EDNS0_SUBNET) string {
+	if e == nil {
+		return ""
+	}
+	return e.Address.String() + "/" + strconv.Itoa(int(e.SourceNetmask))
+}
+
 func boolToString(b bool) string {
 	if b {
 		return "true"
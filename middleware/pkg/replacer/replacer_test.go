@@ -1,5 +1,72 @@
 package replacer
 
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestResponsePlaceholders(t *testing.T) {
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	r.SetEdns0(4096, false)
+	ecs := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()}
+	opt := r.IsEdns0()
+	opt.Option = append(opt.Option, ecs)
+
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative, m.RecursionAvailable = true, true
+	m.Answer = []dns.RR{test.A("example.org. 300 IN A 192.0.2.1"), test.A("example.org. 300 IN A 192.0.2.2")}
+	m.SetEdns0(1232, false)
+	rec.WriteMsg(m)
+
+	rep := New(r, rec, "-")
+	tests := map[string]string{
+		"{rflags}":   "qr,aa,rd,ra",
+		"{ancount}":  "2",
+		"{answer}":   "example.org. 300 IN A 192.0.2.1 | example.org. 300 IN A 192.0.2.2",
+		"{rbufsize}": "1232",
+		"{>bufsize}": "4096",
+		"{>ecs}":     "192.0.2.0/24",
+		"{rcode}":    "NOERROR",
+	}
+	for placeholder, expected := range tests {
+		if x := rep.Replace(placeholder); x != expected {
+			t.Errorf("Expected %s to be replaced with %q, got %q", placeholder, expected, x)
+		}
+	}
+
+	// Without a client subnet the empty value is used.
+	r.IsEdns0().Option = nil
+	if x := New(r, rec, "-").Replace("{>ecs}"); x != "-" {
+		t.Errorf("Expected {>ecs} to be replaced with %q, got %q", "-", x)
+	}
+}
+
+func TestNoRecorder(t *testing.T) {
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	r.SetEdns0(4096, false)
+
+	rep := New(r, nil, "-")
+	tests := map[string]string{
+		"{name}":     "example.org.",
+		"{>bufsize}": "4096",
+		"{remote}":   "{remote}",
+	}
+	for placeholder, expected := range tests {
+		if x := rep.Replace(placeholder); x != expected {
+			t.Errorf("Expected %s to be replaced with %q, got %q", placeholder, expected, x)
+		}
+	}
+}
+
 /*
 func TestNewReplacer(t *testing.T) {
 	w := httptest.NewRecorder()
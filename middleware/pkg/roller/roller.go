@@ -0,0 +1,113 @@
+// Package roller rotates log files when they grow too large or too old.
+package roller
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Roller holds the settings for rotating a log file.
+type Roller struct {
+	MaxSize    int  // Rotate the file when it is larger than this, in megabytes.
+	MaxAge     int  // Remove rotated files that are older than this, in days. Zero keeps them.
+	MaxBackups int  // Keep at most this many rotated files. Zero keeps them all.
+	Compress   bool // Gzip the rotated files.
+}
+
+// Default returns a Roller with the default settings.
+func Default() *Roller {
+	return &Roller{MaxSize: defaultMaxSize, MaxAge: defaultMaxAge, MaxBackups: defaultMaxBackups}
+}
+
+// IsDirective returns true if name is one of the roller directives: rotate_size, rotate_age,
+// rotate_keep and rotate_compress.
+func IsDirective(name string) bool {
+	switch name {
+	case "rotate_size", "rotate_age", "rotate_keep", "rotate_compress":
+		return true
+	}
+	return false
+}
+
+// Parse parses the roller directive name and its arguments and updates r.
+func (r *Roller) Parse(name string, args []string) error {
+	if name == "rotate_compress" {
+		if len(args) != 0 {
+			return fmt.Errorf("%s takes no arguments", name)
+		}
+		r.Compress = true
+		return nil
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("%s takes exactly one argument", name)
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 {
+		return fmt.Errorf("%s must be a positive integer: %q", name, args[0])
+	}
+
+	switch name {
+	case "rotate_size":
+		if n == 0 {
+			return fmt.Errorf("%s must be larger than zero", name)
+		}
+		r.MaxSize = n
+	case "rotate_age":
+		r.MaxAge = n
+	case "rotate_keep":
+		r.MaxBackups = n
+	default:
+		return fmt.Errorf("unknown roller directive: %s", name)
+	}
+	return nil
+}
+
+// Writer returns a writer that appends to filename and rotates it according to r. Writers are
+// shared between calls for the same file with the same settings, so that a file used in multiple
+// server blocks, or kept across a reload, is rotated only once.
+func (r *Roller) Writer(filename string) io.WriteCloser {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		path = filename
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if w, ok := writers[path]; ok && w.roller == *r {
+		return w.Logger
+	}
+
+	l := &lumberjack.Logger{
+		Filename:   filename,
+		MaxSize:    r.MaxSize,
+		MaxAge:     r.MaxAge,
+		MaxBackups: r.MaxBackups,
+		Compress:   r.Compress,
+		LocalTime:  true,
+	}
+	writers[path] = writer{Logger: l, roller: *r}
+	return l
+}
+
+type writer struct {
+	*lumberjack.Logger
+	roller Roller
+}
+
+var (
+	mu      sync.Mutex
+	writers = make(map[string]writer)
+)
+
+const (
+	defaultMaxSize    = 100 // megabytes
+	defaultMaxAge     = 14  // days
+	defaultMaxBackups = 10
+)
@@ -0,0 +1,88 @@
+package roller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		shouldErr bool
+		expected  Roller
+	}{
+		{"rotate_size", []string{"10"}, false, Roller{MaxSize: 10, MaxAge: defaultMaxAge, MaxBackups: defaultMaxBackups}},
+		{"rotate_age", []string{"0"}, false, Roller{MaxSize: defaultMaxSize, MaxAge: 0, MaxBackups: defaultMaxBackups}},
+		{"rotate_keep", []string{"3"}, false, Roller{MaxSize: defaultMaxSize, MaxAge: defaultMaxAge, MaxBackups: 3}},
+		{"rotate_compress", nil, false, Roller{MaxSize: defaultMaxSize, MaxAge: defaultMaxAge, MaxBackups: defaultMaxBackups, Compress: true}},
+		// fails
+		{"rotate_size", []string{"0"}, true, Roller{}},
+		{"rotate_size", []string{"-1"}, true, Roller{}},
+		{"rotate_age", []string{"many"}, true, Roller{}},
+		{"rotate_keep", nil, true, Roller{}},
+		{"rotate_compress", []string{"yes"}, true, Roller{}},
+		{"rotate", []string{"1"}, true, Roller{}},
+	}
+
+	for i, test := range tests {
+		r := Default()
+		err := r.Parse(test.name, test.args)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %s %v, got none", i, test.name, test.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error for %s %v, got %s", i, test.name, test.args, err)
+			continue
+		}
+		if *r != test.expected {
+			t.Errorf("Test %d: expected %+v, got %+v", i, test.expected, *r)
+		}
+	}
+}
+
+func TestIsDirective(t *testing.T) {
+	if !IsDirective("rotate_size") {
+		t.Errorf("Expected rotate_size to be a directive")
+	}
+	if IsDirective("class") {
+		t.Errorf("Expected class not to be a directive")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "roller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "query.log")
+	r := Default()
+	w := r.Writer(file)
+	defer w.Close()
+
+	if w1 := r.Writer(file); w1 != w {
+		t.Errorf("Expected the writer to be shared for the same file and settings")
+	}
+	r1 := Default()
+	r1.Compress = true
+	w1 := r1.Writer(file)
+	defer w1.Close()
+	if w1 == w {
+		t.Errorf("Expected a new writer for different settings")
+	}
+
+	if _, err := w.Write([]byte("line\n")); err != nil {
+		t.Fatalf("Expected no error writing, got %s", err)
+	}
+	buf, err := ioutil.ReadFile(file)
+	if err != nil || string(buf) != "line\n" {
+		t.Errorf("Expected the line to be written, got %q (%v)", buf, err)
+	}
+}
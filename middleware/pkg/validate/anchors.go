@@ -0,0 +1,56 @@
+package validate
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/miekg/dns"
+)
+
+// rootAnchors are the DS records of the root zone's key signing keys, as published by IANA.
+var rootAnchors = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D", // KSK-2017
+	". IN DS 19036 8 2 49AAC11D7B6F6446702E54A1607371607A1A41855200FD2CE1CDDE32F24E8FB5", // KSK-2010
+}
+
+// RootAnchors returns the trust anchors for the root zone.
+func RootAnchors() []dns.RR {
+	rrs := make([]dns.RR, len(rootAnchors))
+	for i, s := range rootAnchors {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			panic(err)
+		}
+		rrs[i] = rr
+	}
+	return rrs
+}
+
+// ParseAnchors reads DS and DNSKEY records in zone file format from r. Other records are ignored.
+func ParseAnchors(r io.Reader, file string) ([]dns.RR, error) {
+	anchors := []dns.RR{}
+	for x := range dns.ParseZone(r, ".", file) {
+		if x.Error != nil {
+			return nil, x.Error
+		}
+		switch x.RR.Header().Rrtype {
+		case dns.TypeDS, dns.TypeDNSKEY:
+			anchors = append(anchors, x.RR)
+		}
+	}
+	if len(anchors) == 0 {
+		return nil, fmt.Errorf("no DS or DNSKEY records found in %s", file)
+	}
+	return anchors, nil
+}
+
+// ReadAnchors reads the trust anchors from file, see ParseAnchors.
+func ReadAnchors(file string) ([]dns.RR, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseAnchors(f, file)
+}
@@ -0,0 +1,222 @@
+package validate
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// rrset is an RRset together with the signatures covering it.
+type rrset struct {
+	name  string // lower cased owner name
+	qtype uint16
+	rrs   []dns.RR
+	sigs  []dns.RR
+}
+
+// rrsets groups the records in rrs into RRsets, RRSIGs are added to the RRset they cover. The
+// order of the first appearance of each RRset is kept.
+func rrsets(rrs []dns.RR) []*rrset {
+	type key struct {
+		name  string
+		qtype uint16
+	}
+	sets := []*rrset{}
+	index := make(map[key]*rrset)
+	get := func(k key) *rrset {
+		s, ok := index[k]
+		if !ok {
+			s = &rrset{name: k.name, qtype: k.qtype}
+			index[k] = s
+			sets = append(sets, s)
+		}
+		return s
+	}
+	for _, rr := range rrs {
+		name := strings.ToLower(rr.Header().Name)
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			s := get(key{name, sig.TypeCovered})
+			s.sigs = append(s.sigs, rr)
+			continue
+		}
+		if rr.Header().Rrtype == dns.TypeOPT {
+			continue
+		}
+		s := get(key{name, rr.Header().Rrtype})
+		s.rrs = append(s.rrs, rr)
+	}
+
+	// Signatures without data are of no use.
+	ret := sets[:0]
+	for _, s := range sets {
+		if len(s.rrs) > 0 {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// hasType returns true if t is in the type bitmap.
+func hasType(bitmap []uint16, t uint16) bool {
+	for _, b := range bitmap {
+		if b == t {
+			return true
+		}
+	}
+	return false
+}
+
+// compare compares a and b in canonical DNS name order, see RFC 4034, Section 6.1.
+func compare(a, b string) int {
+	la := dns.SplitDomainName(strings.ToLower(a))
+	lb := dns.SplitDomainName(strings.ToLower(b))
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(la[i], lb[j]); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// between returns true if x sorts after owner and before next, taking into account that the last
+// record in a chain points back to the first one.
+func between(owner, next, x string, cmp func(a, b string) int) bool {
+	if cmp(owner, next) < 0 {
+		return cmp(owner, x) < 0 && cmp(x, next) < 0
+	}
+	return cmp(owner, x) < 0 || cmp(x, next) < 0
+}
+
+// nsecCover returns true if nsec proves name doesn't exist.
+func nsecCover(nsec *dns.NSEC, name string) bool {
+	return between(nsec.Hdr.Name, nsec.NextDomain, name, compare)
+}
+
+// nsec3Hash returns the hash of name with the parameters of nsec3, and the hash nsec3 is for. It
+// returns false if name isn't in the zone of nsec3.
+func nsec3Hash(nsec3 *dns.NSEC3, name string) (hash, owner string, ok bool) {
+	off, end := dns.NextLabel(nsec3.Hdr.Name, 0)
+	if end || !dns.IsSubDomain(nsec3.Hdr.Name[off:], name) {
+		return "", "", false
+	}
+	hash = dns.HashName(name, nsec3.Hash, nsec3.Iterations, nsec3.Salt)
+	owner = strings.ToUpper(nsec3.Hdr.Name[:off-1])
+	return hash, owner, hash != ""
+}
+
+// nsec3Match returns true if nsec3 is the NSEC3 record for name.
+func nsec3Match(nsec3 *dns.NSEC3, name string) bool {
+	hash, owner, ok := nsec3Hash(nsec3, name)
+	return ok && hash == owner
+}
+
+// nsec3Cover returns true if nsec3 proves name doesn't exist.
+func nsec3Cover(nsec3 *dns.NSEC3, name string) bool {
+	hash, owner, ok := nsec3Hash(nsec3, name)
+	if !ok {
+		return false
+	}
+	return between(owner, strings.ToUpper(nsec3.NextDomain), hash, strings.Compare)
+}
+
+// nsec3ClosestEncloser finds the closest encloser proof for name in nsecs, see RFC 5155, Section
+// 7.2.1. It returns the closest encloser and the NSEC3 that covers the next closer name.
+func nsec3ClosestEncloser(nsecs []dns.RR, name string) (string, *dns.NSEC3, bool) {
+	labels := dns.SplitDomainName(name)
+	for i := 1; i <= len(labels); i++ {
+		ce := dns.Fqdn(strings.Join(labels[i:], "."))
+		nc := dns.Fqdn(strings.Join(labels[i-1:], "."))
+		if findNSEC3(nsecs, ce, nsec3Match) == nil {
+			continue
+		}
+		if cover := findNSEC3(nsecs, nc, nsec3Cover); cover != nil {
+			return ce, cover, true
+		}
+		return "", nil, false
+	}
+	return "", nil, false
+}
+
+// findNSEC3 returns the first NSEC3 in nsecs for which f returns true.
+func findNSEC3(nsecs []dns.RR, name string, f func(*dns.NSEC3, string) bool) *dns.NSEC3 {
+	for _, rr := range nsecs {
+		if x, ok := rr.(*dns.NSEC3); ok && f(x, name) {
+			return x
+		}
+	}
+	return nil
+}
+
+// findNSEC returns the first NSEC in nsecs for which f returns true.
+func findNSEC(nsecs []dns.RR, name string, f func(*dns.NSEC, string) bool) *dns.NSEC {
+	for _, rr := range nsecs {
+		if x, ok := rr.(*dns.NSEC); ok && f(x, name) {
+			return x
+		}
+	}
+	return nil
+}
+
+func nsecMatch(nsec *dns.NSEC, name string) bool { return strings.EqualFold(nsec.Hdr.Name, name) }
+
+// nsecClosestEncloser returns the closest encloser of name, derived from the NSEC that covers it.
+func nsecClosestEncloser(nsec *dns.NSEC, name string) string {
+	n := dns.CompareDomainName(nsec.Hdr.Name, name)
+	if m := dns.CompareDomainName(nsec.NextDomain, name); m > n {
+		n = m
+	}
+	labels := dns.SplitDomainName(name)
+	return dns.Fqdn(strings.Join(labels[len(labels)-n:], "."))
+}
+
+// provesNXDOMAIN returns true if nsecs prove that name and the wildcard that could have
+// matched it don't exist.
+func provesNXDOMAIN(nsecs []dns.RR, name string) bool {
+	if nsec := findNSEC(nsecs, name, nsecCover); nsec != nil {
+		ce := nsecClosestEncloser(nsec, name)
+		return findNSEC(nsecs, "*."+ce, nsecCover) != nil
+	}
+	if ce, _, ok := nsec3ClosestEncloser(nsecs, name); ok {
+		return findNSEC3(nsecs, "*."+ce, nsec3Cover) != nil
+	}
+	return false
+}
+
+// provesNODATA returns true if nsecs prove that name exists but has no records of type qtype.
+func provesNODATA(nsecs []dns.RR, name string, qtype uint16) bool {
+	noType := func(bitmap []uint16) bool {
+		return !hasType(bitmap, qtype) && !hasType(bitmap, dns.TypeCNAME)
+	}
+
+	if nsec := findNSEC(nsecs, name, nsecMatch); nsec != nil {
+		return noType(nsec.TypeBitMap)
+	}
+	if nsec := findNSEC(nsecs, name, nsecCover); nsec != nil {
+		// Empty non-terminal: name has no records, but a name below it does.
+		if dns.IsSubDomain(name, nsec.NextDomain) {
+			return true
+		}
+		// Wildcard NODATA.
+		ce := nsecClosestEncloser(nsec, name)
+		if wild := findNSEC(nsecs, "*."+ce, nsecMatch); wild != nil {
+			return noType(wild.TypeBitMap)
+		}
+		return false
+	}
+
+	if nsec3 := findNSEC3(nsecs, name, nsec3Match); nsec3 != nil {
+		return noType(nsec3.TypeBitMap)
+	}
+	if ce, cover, ok := nsec3ClosestEncloser(nsecs, name); ok {
+		// A DS query for a name in an opt-out span, RFC 5155, Section 8.6.
+		if qtype == dns.TypeDS && cover.Flags&optOut == optOut {
+			return true
+		}
+		if wild := findNSEC3(nsecs, "*."+ce, nsec3Match); wild != nil {
+			return noType(wild.TypeBitMap)
+		}
+	}
+	return false
+}
+
+const optOut = 1 // NSEC3 opt-out flag
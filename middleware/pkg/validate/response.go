@@ -0,0 +1,28 @@
+package validate
+
+import "github.com/miekg/dns"
+
+// AuthenticatedData returns the AD bit for a response with security sec to the query req. AD is
+// only set for clients that show they understand it, RFC 6840, Section 5.8.
+func AuthenticatedData(sec Security, req *dns.Msg) bool {
+	if sec != Secure {
+		return false
+	}
+	if o := req.IsEdns0(); o != nil && o.Do() {
+		return true
+	}
+	return req.AuthenticatedData
+}
+
+// StripDNSSEC returns rrs without the DNSSEC records, for clients that didn't ask for them.
+func StripDNSSEC(rrs []dns.RR) []dns.RR {
+	ret := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		switch rr.Header().Rrtype {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+			continue
+		}
+		ret = append(ret, rr)
+	}
+	return ret
+}
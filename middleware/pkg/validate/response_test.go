@@ -0,0 +1,50 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestAuthenticatedData(t *testing.T) {
+	do := new(dns.Msg)
+	do.SetQuestion("example.org.", dns.TypeA)
+	do.SetEdns0(4096, true)
+
+	ad := new(dns.Msg)
+	ad.SetQuestion("example.org.", dns.TypeA)
+	ad.AuthenticatedData = true
+
+	plain := new(dns.Msg)
+	plain.SetQuestion("example.org.", dns.TypeA)
+
+	tests := []struct {
+		sec      Security
+		req      *dns.Msg
+		expected bool
+	}{
+		{Secure, do, true},
+		{Secure, ad, true},
+		{Secure, plain, false},
+		{Insecure, do, false},
+		{Indeterminate, ad, false},
+	}
+	for i, tc := range tests {
+		if x := AuthenticatedData(tc.sec, tc.req); x != tc.expected {
+			t.Errorf("Test %d: expected AD %t, got %t", i, tc.expected, x)
+		}
+	}
+}
+
+func TestStripDNSSEC(t *testing.T) {
+	rrs := []dns.RR{
+		test.A("example.org. 3600 IN A 192.0.2.53"),
+		test.RRSIG("example.org. 3600 IN RRSIG A 13 2 3600 20170702091734 20170624061734 18512 example.org. ZOeI"),
+		test.NSEC("example.org. 3600 IN NSEC www.example.org. A RRSIG NSEC"),
+	}
+	if x := StripDNSSEC(rrs); len(x) != 1 || x[0].Header().Rrtype != dns.TypeA {
+		t.Errorf("Expected only the A record, got %v", x)
+	}
+}
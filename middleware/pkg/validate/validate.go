@@ -0,0 +1,580 @@
+// Package validate implements DNSSEC validation of responses, see RFC 4033, 4034 and 4035.
+//
+// The chain of trust is built from the trust anchors down to the zone that signed the data, the
+// DNSKEY and DS records needed for that are retrieved with a Lookup function, so the validator can
+// be used both by a recursive resolver and by a forwarder.
+package validate
+
+import (
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Security is the result of validating a response.
+type Security int
+
+const (
+	// Indeterminate means there is no trust anchor for the data.
+	Indeterminate Security = iota
+	// Insecure means the data is in a zone that is proven to be unsigned.
+	Insecure
+	// Secure means all signatures in the response validated up to a trust anchor.
+	Secure
+	// Bogus means the response should have been signed, but validation failed.
+	Bogus
+)
+
+var securityNames = map[Security]string{
+	Indeterminate: "indeterminate",
+	Insecure:      "insecure",
+	Secure:        "secure",
+	Bogus:         "bogus",
+}
+
+func (s Security) String() string { return securityNames[s] }
+
+// worst returns the least secure of a and b, where Bogus is worse than Insecure, which is worse
+// than Secure.
+func worst(a, b Security) Security {
+	rank := map[Security]int{Secure: 0, Indeterminate: 1, Insecure: 2, Bogus: 3}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// Lookup looks up name and qtype. The response must contain the DNSSEC records, i.e. the query
+// must be sent with the DO bit set.
+type Lookup func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error)
+
+// Validator validates responses.
+type Validator struct {
+	anchors map[string][]dns.RR // DS or DNSKEY records per zone
+	id      string              // identifies the anchors in the key cache
+	lookup  Lookup
+
+	cache *lru.Cache // validated DNSKEYs per zone, as *keyEntry
+
+	// Now returns the current time, it is used to check the validity period of signatures.
+	Now func() time.Time
+}
+
+// keyEntry holds the validated keys of a zone, or the reason there are none.
+type keyEntry struct {
+	keys   []*dns.DNSKEY
+	sec    Security
+	expire time.Time
+}
+
+// New returns a Validator that trusts anchors, which are DS or DNSKEY records, and looks up
+// the records it needs with lookup. Validated keys are stored in cache, which may be shared by
+// Validators with different anchors. If cache is nil a new one with DefaultCapacity is used.
+func New(anchors []dns.RR, lookup Lookup, cache *lru.Cache) *Validator {
+	if cache == nil {
+		cache, _ = lru.New(DefaultCapacity)
+	}
+	v := &Validator{
+		anchors: make(map[string][]dns.RR),
+		id:      anchorsID(anchors),
+		lookup:  lookup,
+		cache:   cache,
+		Now:     time.Now,
+	}
+	for _, a := range anchors {
+		switch a.Header().Rrtype {
+		case dns.TypeDS, dns.TypeDNSKEY:
+			name := strings.ToLower(a.Header().Name)
+			v.anchors[name] = append(v.anchors[name], a)
+		}
+	}
+	return v
+}
+
+// Validate validates the response m. For Bogus responses the error describes why validation
+// failed.
+func (v *Validator) Validate(ctx context.Context, m *dns.Msg) (Security, error) {
+	if len(m.Question) == 0 {
+		return Indeterminate, errNoQuestion
+	}
+	if len(v.anchors) == 0 {
+		return Indeterminate, nil
+	}
+	if m.Rcode != dns.RcodeSuccess && m.Rcode != dns.RcodeNameError {
+		return Indeterminate, nil
+	}
+	q := m.Question[0]
+	qname := strings.ToLower(q.Name)
+
+	sec := Secure
+	name := qname
+	answered := false
+
+	for _, set := range rrsets(m.Answer) {
+		s, sig, err := v.verify(ctx, set)
+		if s == Bogus {
+			return Bogus, err
+		}
+		sec = worst(sec, s)
+
+		// A wildcard expansion must come with a proof that the name itself doesn't exist.
+		if sig != nil && int(sig.Labels) < dns.CountLabel(set.name) {
+			if !v.provesWildcard(ctx, m.Ns, set.name, sig.Labels) {
+				return Bogus, errNoWildcardProof
+			}
+		}
+
+		if set.name == name {
+			if set.qtype == dns.TypeCNAME && q.Qtype != dns.TypeCNAME {
+				name = strings.ToLower(set.rrs[0].(*dns.CNAME).Target)
+				continue
+			}
+			if set.qtype == q.Qtype || q.Qtype == dns.TypeANY {
+				answered = true
+			}
+		}
+	}
+
+	if answered {
+		return sec, nil
+	}
+
+	// A negative response, for name, which is qname or the end of a CNAME chain.
+	s, err := v.denial(ctx, m, name, q.Qtype)
+	if s == Bogus {
+		return Bogus, err
+	}
+	return worst(sec, s), nil
+}
+
+// verify verifies the signatures of set. If set is Secure the signature that validated is returned.
+func (v *Validator) verify(ctx context.Context, set *rrset) (Security, *dns.RRSIG, error) {
+	if len(set.sigs) == 0 {
+		// Unsigned data must be in an insecure zone.
+		s, err := v.zoneSecurity(ctx, set.name)
+		if s == Secure || (s == Indeterminate && v.Covers(set.name)) {
+			return Bogus, nil, errUnsigned
+		}
+		return s, nil, err
+	}
+
+	var lastErr error = errNoValidSignature
+	for _, rr := range set.sigs {
+		sig := rr.(*dns.RRSIG)
+		signer := strings.ToLower(sig.SignerName)
+		if !dns.IsSubDomain(signer, set.name) {
+			lastErr = errSignerName
+			continue
+		}
+		keys, s, err := v.zoneKeys(ctx, signer)
+		if s != Secure {
+			return s, nil, err
+		}
+		if err := v.verifySig(sig, keys, set.rrs); err != nil {
+			lastErr = err
+			continue
+		}
+		return Secure, sig, nil
+	}
+	return Bogus, nil, lastErr
+}
+
+// verifySig verifies sig over rrs with one of keys.
+func (v *Validator) verifySig(sig *dns.RRSIG, keys []*dns.DNSKEY, rrs []dns.RR) error {
+	if !sig.ValidityPeriod(v.Now()) {
+		return errSignatureExpired
+	}
+	for _, k := range keys {
+		if k.KeyTag() != sig.KeyTag || k.Algorithm != sig.Algorithm {
+			continue
+		}
+		if err := sig.Verify(k, rrs); err == nil {
+			return nil
+		}
+	}
+	return errNoValidSignature
+}
+
+// zoneKeys returns the validated DNSKEYs of zone.
+func (v *Validator) zoneKeys(ctx context.Context, zone string) ([]*dns.DNSKEY, Security, error) {
+	if e, ok := v.cache.Get(v.id + zone); ok {
+		if e := e.(*keyEntry); v.Now().Before(e.expire) {
+			return e.keys, e.sec, nil
+		}
+	}
+
+	keys, sec, ttl, err := v.fetchKeys(ctx, zone)
+	if _, ok := err.(*lookupError); ok || (err != nil && sec != Bogus) {
+		// Don't cache lookup failures.
+		return nil, sec, err
+	}
+	if ttl > maxKeyTTL || ttl == 0 {
+		ttl = maxKeyTTL
+	}
+	if sec == Bogus {
+		ttl = bogusTTL
+	}
+	v.cache.Add(v.id+zone, &keyEntry{keys: keys, sec: sec, expire: v.Now().Add(time.Duration(ttl) * time.Second)})
+	return keys, sec, err
+}
+
+// fetchKeys retrieves the DNSKEYs of zone and validates them with the DS records from the parent
+// or with the trust anchors.
+func (v *Validator) fetchKeys(ctx context.Context, zone string) ([]*dns.DNSKEY, Security, uint32, error) {
+	var (
+		ds  []dns.RR
+		ttl uint32
+	)
+	if anchors, ok := v.anchors[zone]; ok {
+		ds = anchors
+	} else {
+		if zone == "." {
+			return nil, Indeterminate, 0, nil
+		}
+		var (
+			sec Security
+			err error
+		)
+		ds, sec, ttl, err = v.fetchDS(ctx, zone)
+		if sec != Secure {
+			return nil, sec, ttl, err
+		}
+	}
+
+	m, err := v.lookup(ctx, zone, dns.TypeDNSKEY)
+	if err != nil {
+		s, err := v.lookupFailed(zone, dns.TypeDNSKEY, err)
+		return nil, s, 0, err
+	}
+	var (
+		keys []*dns.DNSKEY
+		set  *rrset
+	)
+	for _, s := range rrsets(m.Answer) {
+		if s.name == zone && s.qtype == dns.TypeDNSKEY {
+			set = s
+		}
+	}
+	if set == nil {
+		return nil, Bogus, 0, errNoDNSKEY
+	}
+	for _, rr := range set.rrs {
+		keys = append(keys, rr.(*dns.DNSKEY))
+	}
+
+	// The DNSKEY RRset must be signed by a key that is trusted: one that matches a DS record or
+	// that is a trust anchor itself.
+	trusted := []*dns.DNSKEY{}
+	for _, k := range keys {
+		for _, d := range ds {
+			switch x := d.(type) {
+			case *dns.DS:
+				if kds := k.ToDS(x.DigestType); kds != nil && x.KeyTag == kds.KeyTag && x.Algorithm == kds.Algorithm &&
+					strings.EqualFold(x.Digest, kds.Digest) {
+					trusted = append(trusted, k)
+				}
+			case *dns.DNSKEY:
+				if x.Algorithm == k.Algorithm && x.PublicKey == k.PublicKey {
+					trusted = append(trusted, k)
+				}
+			}
+		}
+	}
+	if len(trusted) == 0 {
+		return nil, Bogus, 0, errNoTrustedKey
+	}
+	for _, rr := range set.sigs {
+		if err := v.verifySig(rr.(*dns.RRSIG), trusted, set.rrs); err == nil {
+			return keys, Secure, set.rrs[0].Header().Ttl, nil
+		}
+	}
+	return nil, Bogus, 0, errNoValidSignature
+}
+
+// fetchDS retrieves and validates the DS records for zone from its parent. When the parent
+// proves there are none, zone is Insecure.
+func (v *Validator) fetchDS(ctx context.Context, zone string) ([]dns.RR, Security, uint32, error) {
+	m, err := v.lookup(ctx, zone, dns.TypeDS)
+	if err != nil {
+		s, err := v.lookupFailed(zone, dns.TypeDS, err)
+		return nil, s, 0, err
+	}
+	// The DS records live in the parent, a response signed by zone itself can't be used.
+	if signedBy(m.Answer, zone) || signedBy(m.Ns, zone) {
+		return nil, Bogus, 0, errChildDS
+	}
+
+	// Referrals from the parent side carry the DS records in the authority section.
+	for _, section := range [][]dns.RR{m.Answer, m.Ns} {
+		for _, set := range rrsets(section) {
+			if set.name != zone || set.qtype != dns.TypeDS {
+				continue
+			}
+			s, _, err := v.verify(ctx, set)
+			if s != Secure {
+				return nil, s, 0, err
+			}
+			return set.rrs, Secure, set.rrs[0].Header().Ttl, nil
+		}
+	}
+
+	// No DS records, the parent must prove that.
+	s, err := v.proveNoDS(ctx, m, zone)
+	return nil, s, 0, err
+}
+
+// Covers returns true if name is at or below one of the trust anchors. Data for such names must
+// validate as Secure or Insecure; anything else is Bogus.
+func (v *Validator) Covers(name string) bool {
+	name = strings.ToLower(name)
+	for zone := range v.anchors {
+		if dns.IsSubDomain(zone, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupFailed returns the security for name when the lookup of its qtype records failed. Below a
+// trust anchor the data can then not be proven to be secure or insecure, so it is Bogus. Otherwise
+// dropping a single DS or DNSKEY query would be enough to get forged, unsigned data accepted.
+func (v *Validator) lookupFailed(name string, qtype uint16, err error) (Security, error) {
+	if v.Covers(name) {
+		return Bogus, &lookupError{name: name, qtype: qtype, err: err}
+	}
+	return Indeterminate, err
+}
+
+// lookupError is the error for a failed lookup of a name that is covered by a trust anchor.
+type lookupError struct {
+	name  string
+	qtype uint16
+	err   error
+}
+
+func (e *lookupError) Error() string {
+	return "lookup of " + e.name + " " + dns.TypeToString[e.qtype] + " failed: " + e.err.Error()
+}
+
+// anchorsID returns an identifier for anchors, used to keep the keys validated with different
+// anchors apart in the cache.
+func anchorsID(anchors []dns.RR) string {
+	s := make([]string, len(anchors))
+	for i, a := range anchors {
+		s[i] = strings.ToLower(a.String())
+	}
+	sort.Strings(s)
+
+	h := fnv.New64()
+	for _, x := range s {
+		h.Write([]byte(x))
+	}
+	return strconv.FormatUint(h.Sum64(), 10) + " "
+}
+
+// signedBy returns true if one of the signatures in rrs is made by zone.
+func signedBy(rrs []dns.RR, zone string) bool {
+	for _, rr := range rrs {
+		if sig, ok := rr.(*dns.RRSIG); ok && strings.EqualFold(sig.SignerName, zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// zoneSecurity returns the security of the zone that is authoritative for name. It does this by
+// looking up the DS records for name, which either proves name is a (signed or unsigned) zone cut,
+// or that name is part of a signed zone.
+func (v *Validator) zoneSecurity(ctx context.Context, name string) (Security, error) {
+	if _, ok := v.anchors[name]; ok || name == "." {
+		_, s, err := v.zoneKeys(ctx, name)
+		return s, err
+	}
+	_, s, _, err := v.fetchDS(ctx, name)
+	if s == Bogus && err == errNotACut {
+		return Secure, nil
+	}
+	return s, err
+}
+
+// proveNoDS checks the response m to a DS query for zone for a proof that there are no DS records.
+func (v *Validator) proveNoDS(ctx context.Context, m *dns.Msg, zone string) (Security, error) {
+	nsecs, s, err := v.denialRecords(ctx, m)
+	if s != Secure {
+		if len(nsecs) == 0 && s != Bogus {
+			return v.unsigned(ctx, m, zone)
+		}
+		return s, err
+	}
+
+	for _, rr := range nsecs {
+		switch x := rr.(type) {
+		case *dns.NSEC:
+			if strings.ToLower(x.Hdr.Name) == zone && !hasType(x.TypeBitMap, dns.TypeDS) {
+				if hasType(x.TypeBitMap, dns.TypeNS) && !hasType(x.TypeBitMap, dns.TypeSOA) {
+					return Insecure, nil
+				}
+				// Not a zone cut, zone is part of its (secure) parent.
+				return Bogus, errNotACut
+			}
+		case *dns.NSEC3:
+			if nsec3Match(x, zone) && !hasType(x.TypeBitMap, dns.TypeDS) {
+				if hasType(x.TypeBitMap, dns.TypeNS) && !hasType(x.TypeBitMap, dns.TypeSOA) {
+					return Insecure, nil
+				}
+				return Bogus, errNotACut
+			}
+		}
+	}
+
+	// NSEC3 opt-out: the next closer name is covered by an opt-out NSEC3, RFC 5155, Section 6.
+	if _, cover, ok := nsec3ClosestEncloser(nsecs, zone); ok && cover.Flags&optOut == optOut {
+		return Insecure, nil
+	}
+	return Bogus, errNoDenial
+}
+
+// unsigned returns the security of the unsigned negative response m for a query for name. This is
+// only acceptable when the zone that sent it is not signed. That zone is taken from the SOA record,
+// as long as it is a parent of name, otherwise we look at the parent of name.
+func (v *Validator) unsigned(ctx context.Context, m *dns.Msg, name string) (Security, error) {
+	if name == "." {
+		_, s, err := v.zoneKeys(ctx, ".")
+		if s == Secure {
+			return Bogus, errUnsigned
+		}
+		return s, err
+	}
+
+	off, _ := dns.NextLabel(name, 0)
+	zone := name[off:]
+	for _, rr := range m.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			owner := strings.ToLower(soa.Hdr.Name)
+			if owner != name && dns.IsSubDomain(owner, name) {
+				zone = owner
+			}
+		}
+	}
+
+	s, err := v.zoneSecurity(ctx, zone)
+	switch {
+	case s == Secure:
+		return Bogus, errUnsigned
+	case s == Indeterminate && v.Covers(name):
+		// The SOA may be from a zone above the trust anchor, that doesn't make name insecure.
+		return Bogus, errUnsigned
+	}
+	return s, err
+}
+
+// denial validates the negative response m for name and qtype.
+func (v *Validator) denial(ctx context.Context, m *dns.Msg, name string, qtype uint16) (Security, error) {
+	nsecs, s, err := v.denialRecords(ctx, m)
+	if s != Secure {
+		if len(nsecs) == 0 && s != Bogus {
+			return v.unsigned(ctx, m, name)
+		}
+		return s, err
+	}
+
+	if m.Rcode == dns.RcodeNameError {
+		if provesNXDOMAIN(nsecs, name) {
+			return Secure, nil
+		}
+		return Bogus, errNoDenial
+	}
+	if provesNODATA(nsecs, name, qtype) {
+		return Secure, nil
+	}
+	return Bogus, errNoDenial
+}
+
+// denialRecords validates the SOA, NSEC and NSEC3 records in the authority section of m and
+// returns the NSEC and NSEC3 records.
+func (v *Validator) denialRecords(ctx context.Context, m *dns.Msg) ([]dns.RR, Security, error) {
+	var (
+		nsecs []dns.RR
+		sec   = Secure
+		seen  = false
+	)
+	for _, set := range rrsets(m.Ns) {
+		switch set.qtype {
+		case dns.TypeSOA, dns.TypeNSEC, dns.TypeNSEC3:
+		default:
+			continue
+		}
+		if len(set.sigs) == 0 {
+			continue
+		}
+		seen = true
+		s, _, err := v.verify(ctx, set)
+		if s == Bogus {
+			return nil, Bogus, err
+		}
+		sec = worst(sec, s)
+		if set.qtype != dns.TypeSOA {
+			nsecs = append(nsecs, set.rrs...)
+		}
+	}
+	if !seen {
+		return nil, Insecure, nil
+	}
+	return nsecs, sec, nil
+}
+
+// provesWildcard checks if the records in ns prove that name doesn't exist, as needed for an
+// answer expanded from a wildcard with labels labels.
+func (v *Validator) provesWildcard(ctx context.Context, ns []dns.RR, name string, labels uint8) bool {
+	nsecs, s, _ := v.denialRecords(ctx, &dns.Msg{Ns: ns})
+	if s != Secure {
+		return false
+	}
+	// The next closer name is one label longer than the wildcard's parent.
+	nextCloser := name
+	if off, end := dns.PrevLabel(name, int(labels)+1); !end {
+		nextCloser = name[off:]
+	}
+	for _, rr := range nsecs {
+		switch x := rr.(type) {
+		case *dns.NSEC:
+			if nsecCover(x, name) {
+				return true
+			}
+		case *dns.NSEC3:
+			if nsec3Cover(x, nextCloser) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DefaultCapacity is the default number of zones the key cache holds.
+const DefaultCapacity = 10000
+
+const (
+	maxKeyTTL = 3600 // seconds
+	bogusTTL  = 60   // seconds, how long to remember a zone's keys are bogus
+)
+
+var (
+	errNoQuestion       = errors.New("no question in message")
+	errUnsigned         = errors.New("unsigned data in a signed zone")
+	errSignerName       = errors.New("signer name is not a parent of the owner name")
+	errNoValidSignature = errors.New("no valid signature")
+	errSignatureExpired = errors.New("signature expired or not yet valid")
+	errNoDNSKEY         = errors.New("no DNSKEY records")
+	errNoTrustedKey     = errors.New("no DNSKEY matches a DS record or trust anchor")
+	errNoDenial         = errors.New("no proof of non-existence")
+	errNotACut          = errors.New("DS requested for a name that is not a zone cut")
+	errChildDS          = errors.New("DS response from the child side of a zone cut")
+	errNoWildcardProof  = errors.New("wildcard answer without proof of non-existence")
+)
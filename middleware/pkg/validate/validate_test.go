@@ -0,0 +1,20 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAnchors(t *testing.T) {
+	anchors, err := ParseAnchors(strings.NewReader(strings.Join(rootAnchors, "\n")+"\n. IN NS a.root-servers.net.\n"), "stdin")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(anchors) != 2 {
+		t.Errorf("Expected 2 anchors, got %d", len(anchors))
+	}
+
+	if _, err := ParseAnchors(strings.NewReader(". IN NS a.root-servers.net.\n"), "stdin"); err == nil {
+		t.Errorf("Expected error for a file without anchors")
+	}
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/coredns/coredns/middleware/pkg/cookie"
+
+	"github.com/miekg/dns"
+)
+
+// cookieJar holds our client cookies and the server cookies we got back from the upstreams, see
+// RFC 7873, Section 5.3.
+type cookieJar struct {
+	secret *cookie.Secret
+
+	mu      sync.RWMutex
+	servers map[string][]byte // server cookie per upstream address
+}
+
+func newCookieJar() *cookieJar {
+	return &cookieJar{secret: cookie.NewSecret(nil), servers: make(map[string][]byte)}
+}
+
+// prepare returns a copy of m that carries our cookie for the upstream at addr, instead of the
+// cookie the client sent. Messages without an OPT record are returned as is.
+func (j *cookieJar) prepare(m *dns.Msg, addr string) *dns.Msg {
+	if m.IsEdns0() == nil {
+		return m
+	}
+	j.mu.RLock()
+	server := j.servers[addr]
+	j.mu.RUnlock()
+
+	m1 := m.Copy()
+	cookie.Set(m1, j.secret.Client(addr), server)
+	return m1
+}
+
+// update remembers the server cookie in reply from the upstream at addr and removes the cookie
+// from reply.
+func (j *cookieJar) update(reply *dns.Msg, addr string) {
+	client, server, err := cookie.Parse(reply)
+	if client == nil && err == nil {
+		return
+	}
+	cookie.Remove(reply)
+	if err != nil || server == nil || !bytes.Equal(client, j.secret.Client(addr)) {
+		return
+	}
+	j.mu.Lock()
+	j.servers[addr] = server
+	j.mu.Unlock()
+}
@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sync"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/cookie"
+	"github.com/coredns/coredns/middleware/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestProxyCookie(t *testing.T) {
+	serverCookie, _ := hex.DecodeString("a1a2a3a4a5a6a7a8")
+	clientCookie, _ := hex.DecodeString("0102030405060708")
+
+	var (
+		mu      sync.Mutex
+		queries int
+		clients [][]byte
+	)
+
+	// The upstream requires a server cookie and answers BADCOOKIE when it is missing.
+	server, addr, err := test.UDPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create a UDP server: %s", err)
+	}
+	defer server.Shutdown()
+	dns.HandleFunc("example.org.", func(w dns.ResponseWriter, r *dns.Msg) {
+		client, srv, _ := cookie.Parse(r)
+		mu.Lock()
+		queries++
+		clients = append(clients, client)
+		mu.Unlock()
+
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.SetEdns0(4096, false)
+		cookie.Set(m, client, serverCookie)
+		if !bytes.Equal(srv, serverCookie) {
+			cookie.SetBadCookie(m)
+			w.WriteMsg(m)
+			return
+		}
+		m.Answer = []dns.RR{test.A("example.org. 3600 IN A 192.0.2.53")}
+		w.WriteMsg(m)
+	})
+	defer dns.HandleRemove("example.org.")
+
+	p := NewLookup([]string{addr})
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	cookie.Set(m, clientCookie, nil)
+
+	reply, err := p.Forward(request.Request{W: &test.ResponseWriter{}, Req: m})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if reply.Rcode != dns.RcodeSuccess || len(reply.Answer) != 1 {
+		t.Fatalf("Expected an answer after retrying with the server cookie, got %s", reply)
+	}
+	if c, _, _ := cookie.Parse(reply); c != nil {
+		t.Errorf("Expected the upstream's cookie to be removed from the reply, got %x", c)
+	}
+
+	mu.Lock()
+	if queries != 2 {
+		t.Errorf("Expected 2 queries to the upstream, got %d", queries)
+	}
+	for i, c := range clients {
+		if c == nil || bytes.Equal(c, clientCookie) {
+			t.Errorf("Query %d: expected our own client cookie, got %x", i, c)
+		}
+	}
+	queries = 0
+	mu.Unlock()
+
+	// The server cookie is remembered for the next query.
+	if _, err := p.Forward(request.Request{W: &test.ResponseWriter{}, Req: m}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if queries != 1 {
+		t.Errorf("Expected 1 query to the upstream, got %d", queries)
+	}
+}
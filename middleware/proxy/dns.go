@@ -5,19 +5,23 @@ import (
 	"net"
 	"time"
 
+	"github.com/coredns/coredns/middleware/pkg/cookie"
 	"github.com/coredns/coredns/middleware/pkg/singleflight"
+	"github.com/coredns/coredns/middleware/trace"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
+	ot "github.com/opentracing/opentracing-go"
 )
 
 type dnsEx struct {
 	Timeout time.Duration
 	group   *singleflight.Group
+	cookies *cookieJar
 }
 
 func newDNSEx() *dnsEx {
-	return &dnsEx{group: new(singleflight.Group), Timeout: defaultTimeout * time.Second}
+	return &dnsEx{group: new(singleflight.Group), Timeout: defaultTimeout * time.Second, cookies: newCookieJar()}
 }
 
 func (d *dnsEx) Protocol() string          { return "dns" }
@@ -26,12 +30,31 @@ func (d *dnsEx) OnStartup(p *Proxy) error  { return nil }
 
 // Exchange implements the Exchanger interface.
 func (d *dnsEx) Exchange(ctx context.Context, addr string, state request.Request) (*dns.Msg, error) {
+	reply, err := d.exchangeOnce(ctx, addr, state)
+	if err == nil && cookie.IsBadCookie(reply) {
+		// The reply carries a fresh server cookie, retry once with it, RFC 7873, Section 5.3.
+		reply, err = d.exchangeOnce(ctx, addr, state)
+	}
+	return reply, err
+}
+
+func (d *dnsEx) exchangeOnce(ctx context.Context, addr string, state request.Request) (*dns.Msg, error) {
 	co, err := net.DialTimeout(state.Proto(), addr, d.Timeout)
 	if err != nil {
 		return nil, err
 	}
 
-	reply, _, err := d.ExchangeConn(state.Req, co)
+	// Send our own client cookie, the client's cookie is meant for us, not for the upstream.
+	m := d.cookies.prepare(state.Req, addr)
+
+	// Propagate the trace context to the upstream, so its spans join our trace. Only when configured,
+	// as this exposes our trace context to the upstream.
+	addedOPT := false
+	if span := ot.SpanFromContext(ctx); span != nil && trace.Propagate(ctx) {
+		m, addedOPT = trace.InjectEDNS0(span, m)
+	}
+
+	reply, _, err := d.ExchangeConn(m, co)
 
 	co.Close()
 
@@ -46,6 +69,10 @@ func (d *dnsEx) Exchange(ctx context.Context, addr string, state request.Request
 
 	reply.Compress = true
 	reply.Id = state.Req.Id
+	d.cookies.update(reply, addr)
+	if addedOPT {
+		trace.RemoveEDNS0(reply)
+	}
 
 	return reply, nil
 }
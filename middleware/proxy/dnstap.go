@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/coredns/coredns/middleware/dnstap"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// toDnstap logs the exchange with host as FORWARDER_QUERY and FORWARDER_RESPONSE messages, if the
+// dnstap middleware is enabled. The query was sent at start, reply may be nil if the exchange failed.
+func toDnstap(ctx context.Context, host string, ex Exchanger, state request.Request, reply *dns.Msg, start time.Time) {
+	tapper := dnstap.TapperFromContext(ctx)
+	if tapper == nil {
+		return
+	}
+
+	// Only the dns exchanger uses the protocol of the client, the others use TCP.
+	proto := "tcp"
+	if ex.Protocol() == "dns" {
+		proto = state.Proto()
+	}
+
+	b := dnstap.ForwarderQuery(host, proto, start)
+	if tapper.Pack() {
+		b.Msg(state.Req)
+	}
+	tapper.TapMessage(b.ToMessage())
+
+	if reply == nil {
+		return
+	}
+	b = dnstap.ForwarderResponse(host, proto, start, time.Now())
+	if tapper.Pack() {
+		b.Msg(reply)
+	}
+	tapper.TapMessage(b.ToMessage())
+}
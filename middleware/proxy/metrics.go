@@ -2,8 +2,10 @@ package proxy
 
 import (
 	"sync"
+	"time"
 
 	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/rcode"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -17,14 +19,85 @@ var (
 		Buckets:   append(prometheus.DefBuckets, []float64{50, 100, 200, 500, 1000, 2000, 3000, 4000, 5000, 10000}...),
 		Help:      "Histogram of the time (in milliseconds) each request took.",
 	}, []string{"proto", "proxy_proto", "from"})
+
+	HostRequestCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: middleware.Namespace,
+		Subsystem: "proxy",
+		Name:      "host_request_count_total",
+		Help:      "Counter of requests made to each upstream host.",
+	}, []string{"from", "to"})
+
+	HostRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: middleware.Namespace,
+		Subsystem: "proxy",
+		Name:      "host_request_duration_milliseconds",
+		Buckets:   middleware.DurationBuckets,
+		Help:      "Histogram of the time (in milliseconds) each request to an upstream host took.",
+	}, []string{"from", "to"})
+
+	HostResponseRcode = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: middleware.Namespace,
+		Subsystem: "proxy",
+		Name:      "host_response_rcode_count_total",
+		Help:      "Counter of the rcodes of the responses from each upstream host.",
+	}, []string{"from", "to", "rcode"})
+
+	HostFailureCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: middleware.Namespace,
+		Subsystem: "proxy",
+		Name:      "host_failure_count_total",
+		Help:      "Counter of failed requests to each upstream host.",
+	}, []string{"from", "to"})
+
+	HostHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: middleware.Namespace,
+		Subsystem: "proxy",
+		Name:      "host_healthy",
+		Help:      "Gauge that is 1 when an upstream host is considered healthy and 0 when it is down.",
+	}, []string{"from", "to"})
+
+	ValidationCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: middleware.Namespace,
+		Subsystem: "proxy",
+		Name:      "validation_total",
+		Help:      "Counter of DNSSEC validation results of the responses from each upstream.",
+	}, []string{"from", "result"})
 )
 
 // OnStartupMetrics sets up the metrics on startup. This is done for all proxy protocols.
 func OnStartupMetrics() error {
 	metricsOnce.Do(func() {
 		prometheus.MustRegister(RequestDuration)
+		prometheus.MustRegister(HostRequestCount)
+		prometheus.MustRegister(HostRequestDuration)
+		prometheus.MustRegister(HostResponseRcode)
+		prometheus.MustRegister(HostFailureCount)
+		prometheus.MustRegister(HostHealthy)
+		prometheus.MustRegister(ValidationCount)
 	})
 	return nil
 }
 
+// reportHost records the metrics for an exchange with host that started at start. Rc is the rcode
+// of the reply, or -1 when the exchange failed.
+func reportHost(from string, host *UpstreamHost, rc int, start time.Time) {
+	HostRequestCount.WithLabelValues(from, host.Name).Inc()
+	HostRequestDuration.WithLabelValues(from, host.Name).Observe(middleware.Milliseconds(time.Since(start)))
+	if rc < 0 {
+		HostFailureCount.WithLabelValues(from, host.Name).Inc()
+	} else {
+		HostResponseRcode.WithLabelValues(from, host.Name, rcode.ToString(rc)).Inc()
+	}
+	reportHealth(from, host)
+}
+
+// reportHealth records the health state of host.
+func reportHealth(from string, host *UpstreamHost) {
+	healthy := 1.0
+	if host.Down() {
+		healthy = 0.0
+	}
+	HostHealthy.WithLabelValues(from, host.Name).Set(healthy)
+}
+
 var metricsOnce sync.Once
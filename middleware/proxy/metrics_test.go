@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestReportHost(t *testing.T) {
+	host := &UpstreamHost{Name: "192.0.2.1:53"}
+
+	reportHost("example.org.", host, dns.RcodeNameError, time.Now())
+	if x := counterValue(t, HostRequestCount.WithLabelValues("example.org.", host.Name)); x != 1 {
+		t.Errorf("Expected 1 request, got %f", x)
+	}
+	if x := counterValue(t, HostResponseRcode.WithLabelValues("example.org.", host.Name, "NXDOMAIN")); x != 1 {
+		t.Errorf("Expected 1 NXDOMAIN response, got %f", x)
+	}
+	if x := gaugeValue(t, HostHealthy.WithLabelValues("example.org.", host.Name)); x != 1 {
+		t.Errorf("Expected host to be healthy, got %f", x)
+	}
+
+	host.Fails = 1
+	reportHost("example.org.", host, -1, time.Now())
+	if x := counterValue(t, HostFailureCount.WithLabelValues("example.org.", host.Name)); x != 1 {
+		t.Errorf("Expected 1 failure, got %f", x)
+	}
+	if x := counterValue(t, HostRequestCount.WithLabelValues("example.org.", host.Name)); x != 2 {
+		t.Errorf("Expected 2 requests, got %f", x)
+	}
+	if x := gaugeValue(t, HostHealthy.WithLabelValues("example.org.", host.Name)); x != 0 {
+		t.Errorf("Expected host to be down, got %f", x)
+	}
+}
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	if err := c.Write(m); err != nil {
+		t.Fatalf("Failed to read counter: %s", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func gaugeValue(t *testing.T, g prometheus.Gauge) float64 {
+	m := &dto.Metric{}
+	if err := g.Write(m); err != nil {
+		t.Fatalf("Failed to read gauge: %s", err)
+	}
+	return m.GetGauge().GetValue()
+}
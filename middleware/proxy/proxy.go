@@ -7,10 +7,16 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/loop"
+	"github.com/coredns/coredns/middleware/pkg/meta"
+	"github.com/coredns/coredns/middleware/pkg/rcode"
+	"github.com/coredns/coredns/middleware/trace"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
 	ot "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
 	"golang.org/x/net/context"
 )
 
@@ -18,6 +24,7 @@ var (
 	errUnreachable     = errors.New("unreachable backend")
 	errInvalidProtocol = errors.New("invalid protocol")
 	errInvalidDomain   = errors.New("invalid path for proxy")
+	errNoState         = errors.New("no query to validate")
 )
 
 // Proxy represents a middleware instance that can proxy requests to another (DNS) server.
@@ -45,6 +52,8 @@ type Upstream interface {
 	IsAllowedDomain(string) bool
 	// Exchanger returns the exchanger to be used for this upstream.
 	Exchanger() Exchanger
+	// Healthy returns true if at least one upstream host is not down.
+	Healthy() bool
 }
 
 // UpstreamHostDownFunc can be used to customize how Down behaves.
@@ -73,6 +82,24 @@ func (uh *UpstreamHost) Down() bool {
 	return uh.CheckDown(uh)
 }
 
+// Health implements the health.Healther interface. Proxy is healthy when each upstream has at least
+// one host that is not down.
+func (p Proxy) Health() bool { return p.Ready() }
+
+// Ready implements the health.Readiness interface. Proxy is ready when each upstream has at least
+// one host that is not down.
+func (p Proxy) Ready() bool {
+	if p.Upstreams == nil {
+		return true
+	}
+	for _, u := range *p.Upstreams {
+		if !u.Healthy() {
+			return false
+		}
+	}
+	return true
+}
+
 // tryDuration is how long to try upstream hosts; failures result in
 // immediate retries until this duration ends or we get a nil host.
 var tryDuration = 60 * time.Second
@@ -88,6 +115,16 @@ func (p Proxy) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 		return middleware.NextOrFailure(p.Name(), p.Next, ctx, w, r)
 	}
 
+	// When validating we need the DNSSEC records, even if the client didn't ask for them.
+	fwd := state
+	v := validator(upstream)
+	if r.CheckingDisabled {
+		v = nil
+	}
+	if v != nil {
+		fwd = request.Request{W: w, Req: withDo(r)}
+	}
+
 	for {
 		start := time.Now()
 
@@ -104,20 +141,43 @@ func (p Proxy) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 
 			if span != nil {
 				child = span.Tracer().StartSpan("exchange", ot.ChildOf(span.Context()))
+				child.SetTag(trace.TagUpstream, host.Name)
 				ctx = ot.ContextWithSpan(ctx, child)
 			}
 
+			loop.Forwarded(ctx, host.Name)
+
 			atomic.AddInt64(&host.Conns, 1)
+			qt := time.Now()
 
-			reply, backendErr := upstream.Exchanger().Exchange(ctx, host.Name, state)
+			reply, backendErr := upstream.Exchanger().Exchange(ctx, host.Name, fwd)
 
 			atomic.AddInt64(&host.Conns, -1)
 
+			toDnstap(ctx, host.Name, upstream.Exchanger(), state, reply, qt)
+
 			if child != nil {
+				if backendErr != nil {
+					ext.Error.Set(child, true)
+					child.LogFields(otlog.Error(backendErr))
+				} else {
+					child.SetTag(trace.TagRcode, rcode.ToString(reply.Rcode))
+				}
 				child.Finish()
 			}
 
 			if backendErr == nil {
+				reportHost(upstream.From(), host, reply.Rcode, qt)
+				meta.FromContext(ctx).Set(meta.Upstream, host.Name)
+
+				if v != nil {
+					if err := validateReply(ctx, v, upstream, state, reply); err != nil {
+						RequestDuration.WithLabelValues(state.Proto(), upstream.Exchanger().Protocol(), upstream.From()).Observe(float64(time.Since(start) / time.Millisecond))
+						return dns.RcodeServerFailure, err
+					}
+					// The query was sent with a larger buffer than the client may have asked for.
+					reply, _ = state.Scrub(reply)
+				}
 				w.WriteMsg(reply)
 
 				RequestDuration.WithLabelValues(state.Proto(), upstream.Exchanger().Protocol(), upstream.From()).Observe(float64(time.Since(start) / time.Millisecond))
@@ -129,10 +189,12 @@ func (p Proxy) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 				timeout = 10 * time.Second
 			}
 			atomic.AddInt32(&host.Fails, 1)
-			go func(host *UpstreamHost, timeout time.Duration) {
+			reportHost(upstream.From(), host, -1, qt)
+			go func(from string, host *UpstreamHost, timeout time.Duration) {
 				time.Sleep(timeout)
 				atomic.AddInt32(&host.Fails, -1)
-			}(host, timeout)
+				reportHealth(from, host)
+			}(upstream.From(), host, timeout)
 		}
 
 		RequestDuration.WithLabelValues(state.Proto(), upstream.Exchanger().Protocol(), upstream.From()).Observe(float64(time.Since(start) / time.Millisecond))
@@ -14,6 +14,7 @@ import (
 	"github.com/coredns/coredns/middleware"
 	"github.com/coredns/coredns/middleware/pkg/dnsutil"
 	"github.com/coredns/coredns/middleware/pkg/tls"
+	"github.com/coredns/coredns/middleware/pkg/validate"
 
 	"github.com/mholt/caddy/caddyfile"
 	"github.com/miekg/dns"
@@ -39,6 +40,8 @@ type staticUpstream struct {
 	WithoutPathPrefix string
 	IgnoredSubDomains []string
 	ex                Exchanger
+	anchors           []dns.RR
+	validator         *validate.Validator
 }
 
 // NewStaticUpstreams parses the configuration input and sets up
@@ -75,6 +78,9 @@ func NewStaticUpstreams(c *caddyfile.Dispenser) ([]Upstream, error) {
 				return upstreams, err
 			}
 		}
+		if len(upstream.anchors) > 0 {
+			upstream.validator = validate.New(upstream.anchors, validationLookup(upstream), keyCache)
+		}
 
 		upstream.Hosts = make([]*UpstreamHost, len(toHosts))
 		for i, host := range toHosts {
@@ -183,6 +189,20 @@ func parseBlock(c *caddyfile.Dispenser, u *staticUpstream) error {
 		u.IgnoredSubDomains = ignoredDomains
 	case "spray":
 		u.Spray = &Spray{}
+	case "validate":
+		files := c.RemainingArgs()
+		if len(files) == 0 {
+			u.anchors = validate.RootAnchors()
+			return nil
+		}
+		u.anchors = nil
+		for _, f := range files {
+			anchors, err := validate.ReadAnchors(f)
+			if err != nil {
+				return err
+			}
+			u.anchors = append(u.anchors, anchors...)
+		}
 	case "protocol":
 		encArgs := c.RemainingArgs()
 		if len(encArgs) == 0 {
@@ -232,6 +252,7 @@ func (u *staticUpstream) healthCheck() {
 		} else {
 			host.Unhealthy = true
 		}
+		reportHealth(u.From(), host)
 	}
 }
 
@@ -305,3 +326,15 @@ func (u *staticUpstream) IsAllowedDomain(name string) bool {
 }
 
 func (u *staticUpstream) Exchanger() Exchanger { return u.ex }
+
+// Validator implements the Validating interface.
+func (u *staticUpstream) Validator() *validate.Validator { return u.validator }
+
+func (u *staticUpstream) Healthy() bool {
+	for _, host := range u.Hosts {
+		if !host.Down() {
+			return true
+		}
+	}
+	return false
+}
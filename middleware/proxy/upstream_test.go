@@ -51,6 +51,26 @@ func TestSelect(t *testing.T) {
 	}
 }
 
+func TestReady(t *testing.T) {
+	upstream := &staticUpstream{
+		from:        ".",
+		Hosts:       testPool()[:2],
+		Policy:      &Random{},
+		FailTimeout: 10 * time.Second,
+		MaxFails:    1,
+	}
+	p := Proxy{Upstreams: &[]Upstream{upstream}}
+
+	upstream.Hosts[0].Unhealthy = true
+	if !p.Ready() {
+		t.Error("Expected proxy to be ready with one healthy host")
+	}
+	upstream.Hosts[1].Unhealthy = true
+	if p.Ready() {
+		t.Error("Expected proxy not to be ready as all hosts are down")
+	}
+}
+
 func TestRegisterPolicy(t *testing.T) {
 	name := "custom"
 	customPolicy := &customPolicy{}
@@ -165,6 +185,20 @@ proxy . 8.8.8.8:53 {
 		},
 		{
 			`
+proxy . 8.8.8.8:53 {
+    validate
+}`,
+			false,
+		},
+		{
+			`
+proxy . 8.8.8.8:53 {
+    validate /does/not/exist
+}`,
+			true,
+		},
+		{
+			`
 proxy . 8.8.8.8:53 {
     error_option
 }`,
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"fmt"
+
+	"github.com/coredns/coredns/middleware/pkg/validate"
+	"github.com/coredns/coredns/request"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Validating is implemented by an Upstream whose responses are DNSSEC validated. It is a separate
+// interface so Upstreams that don't validate don't need to implement it.
+type Validating interface {
+	// Validator returns the validator for the responses, or nil if they are not validated.
+	Validator() *validate.Validator
+}
+
+// keyCache holds the validated DNSKEYs, it is shared by all proxies that validate responses.
+var keyCache, _ = lru.New(validate.DefaultCapacity)
+
+// validator returns the validator of upstream, or nil if its responses are not validated.
+func validator(upstream Upstream) *validate.Validator {
+	if v, ok := upstream.(Validating); ok {
+		return v.Validator()
+	}
+	return nil
+}
+
+// validateReply validates reply, the response to the query in state, with v. The DNSKEY and DS
+// records needed for that are retrieved through upstream. An error is returned for bogus responses,
+// and for responses that can't be validated while a trust anchor covers them. The AD bit is set for
+// secure responses, and the DNSSEC records are removed when the client didn't ask for them.
+func validateReply(ctx context.Context, v *validate.Validator, upstream Upstream, state request.Request, reply *dns.Msg) error {
+	ctx = context.WithValue(ctx, stateKey{}, state)
+	sec, err := v.Validate(ctx, reply)
+	ValidationCount.WithLabelValues(upstream.From(), sec.String()).Inc()
+	if sec == validate.Bogus {
+		return fmt.Errorf("bogus response for %s from %s: %v", state.Name(), upstream.From(), err)
+	}
+	if sec == validate.Indeterminate && v.Covers(state.Name()) {
+		return fmt.Errorf("unable to validate response for %s from %s: %v", state.Name(), upstream.From(), err)
+	}
+
+	reply.AuthenticatedData = validate.AuthenticatedData(sec, state.Req)
+
+	if !state.Do() {
+		reply.Answer, reply.Ns = validate.StripDNSSEC(reply.Answer), validate.StripDNSSEC(reply.Ns)
+		if state.Req.IsEdns0() == nil {
+			reply.Extra = removeOPT(reply.Extra)
+		}
+		state.SizeAndDo(reply)
+	}
+	return nil
+}
+
+// stateKey is the context key for the query that is being validated.
+type stateKey struct{}
+
+// validationLookup returns a validate.Lookup that sends its queries to upstream, with the protocol
+// of the query that is being validated.
+func validationLookup(upstream Upstream) validate.Lookup {
+	return func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+		state, ok := ctx.Value(stateKey{}).(request.Request)
+		if !ok {
+			return nil, errNoState
+		}
+		host := upstream.Select()
+		if host == nil {
+			return nil, errUnreachable
+		}
+		req := new(dns.Msg)
+		req.SetQuestion(name, qtype)
+		req.SetEdns0(4096, true)
+		req.CheckingDisabled = true
+		return upstream.Exchanger().Exchange(ctx, host.Name, request.Request{W: state.W, Req: req})
+	}
+}
+
+// withDo returns a copy of r with the DO bit set.
+func withDo(r *dns.Msg) *dns.Msg {
+	r1 := r.Copy()
+	if o := r1.IsEdns0(); o != nil {
+		o.SetDo()
+		return r1
+	}
+	r1.SetEdns0(4096, true)
+	return r1
+}
+
+func removeOPT(rrs []dns.RR) []dns.RR {
+	ret := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,185 @@
+package proxy
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/pkg/validate"
+	"github.com/coredns/coredns/middleware/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// signer holds a zone's key, used to sign the records served by the upstream in the tests.
+type signer struct {
+	key  *dns.DNSKEY
+	priv crypto.Signer
+}
+
+func newSigner(t *testing.T, zone string) *signer {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatalf("Failed to generate key for %s: %v", zone, err)
+	}
+	return &signer{key: key, priv: priv.(crypto.Signer)}
+}
+
+func (s *signer) sign(t *testing.T, rrs ...dns.RR) []dns.RR {
+	now := time.Now().UTC()
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: rrs[0].Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 3600},
+		Algorithm:  s.key.Algorithm,
+		KeyTag:     s.key.KeyTag(),
+		SignerName: s.key.Header().Name,
+		Inception:  uint32(now.Add(-time.Hour).Unix()),
+		Expiration: uint32(now.Add(time.Hour).Unix()),
+	}
+	if err := sig.Sign(s.priv, rrs); err != nil {
+		t.Fatalf("Failed to sign %s: %v", rrs[0].Header().Name, err)
+	}
+	return append(rrs, sig)
+}
+
+// signedUpstream starts an upstream that serves a signed root and example. zone. It returns the
+// address of the upstream and the trust anchor for the root.
+func signedUpstream(t *testing.T) (*dns.Server, string, dns.RR) {
+	root, example := newSigner(t, "."), newSigner(t, "example.")
+
+	a := test.A("a.example. 3600 IN A 192.0.2.1")
+	bogus := test.A("bogus.example. 3600 IN A 192.0.2.2")
+	signedBogus := example.sign(t, bogus)
+	signedBogus[0] = test.A("bogus.example. 3600 IN A 192.0.2.66")
+
+	answers := map[dns.Question][]dns.RR{
+		{Name: ".", Qtype: dns.TypeDNSKEY, Qclass: dns.ClassINET}:         root.sign(t, root.key),
+		{Name: "example.", Qtype: dns.TypeDS, Qclass: dns.ClassINET}:      root.sign(t, example.key.ToDS(dns.SHA256)),
+		{Name: "example.", Qtype: dns.TypeDNSKEY, Qclass: dns.ClassINET}:  example.sign(t, example.key),
+		{Name: "a.example.", Qtype: dns.TypeA, Qclass: dns.ClassINET}:     example.sign(t, a),
+		{Name: "bogus.example.", Qtype: dns.TypeA, Qclass: dns.ClassINET}: signedBogus,
+	}
+
+	server, addr, err := test.UDPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create a UDP server: %s", err)
+	}
+	dns.HandleFunc(".", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		do := false
+		if o := r.IsEdns0(); o != nil {
+			do = o.Do()
+			m.SetEdns0(4096, do)
+		}
+		for _, rr := range answers[r.Question[0]] {
+			if rr.Header().Rrtype == dns.TypeRRSIG && !do {
+				continue
+			}
+			m.Answer = append(m.Answer, rr)
+		}
+		w.WriteMsg(m)
+	})
+
+	return server, addr, root.key.ToDS(dns.SHA256)
+}
+
+func TestProxyValidate(t *testing.T) {
+	server, addr, anchor := signedUpstream(t)
+	defer server.Shutdown()
+	defer dns.HandleRemove(".")
+
+	p := NewLookup([]string{addr})
+	u := (*p.Upstreams)[0].(*staticUpstream)
+	u.validator = validate.New([]dns.RR{anchor}, validationLookup(u), keyCache)
+
+	tests := []struct {
+		qname      string
+		do, cd     bool
+		rcode      int
+		ad         bool
+		signatures bool
+	}{
+		{qname: "a.example.", do: true, ad: true, signatures: true},
+		{qname: "a.example."},
+		{qname: "bogus.example.", rcode: dns.RcodeServerFailure},
+		// Checking disabled, the client gets the bogus answer.
+		{qname: "bogus.example.", do: true, cd: true, signatures: true},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		m.CheckingDisabled = tc.cd
+		if tc.do {
+			m.SetEdns0(4096, true)
+		}
+
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		rcode, err := p.ServeDNS(context.TODO(), rec, m)
+		if rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d (%v)", i, tc.rcode, rcode, err)
+			continue
+		}
+		if tc.rcode == dns.RcodeServerFailure {
+			if err == nil {
+				t.Errorf("Test %d: expected an error for a bogus response", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+
+		if rec.Msg.AuthenticatedData != tc.ad {
+			t.Errorf("Test %d: expected AD to be %t, got %t", i, tc.ad, rec.Msg.AuthenticatedData)
+		}
+		signatures := false
+		for _, rr := range rec.Msg.Answer {
+			if rr.Header().Rrtype == dns.TypeRRSIG {
+				signatures = true
+			}
+		}
+		if signatures != tc.signatures {
+			t.Errorf("Test %d: expected signatures to be %t, got %t", i, tc.signatures, signatures)
+		}
+		if !tc.do && rec.Msg.IsEdns0() != nil {
+			t.Errorf("Test %d: expected no OPT record in the reply to a query without EDNS0", i)
+		}
+	}
+}
+
+func TestProxyValidateLookupError(t *testing.T) {
+	anchor := test.DS(". 3600 IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D")
+	// All the DS and DNSKEY lookups to the upstream fail.
+	lookup := func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+		return nil, errors.New("timeout")
+	}
+	v := validate.New([]dns.RR{anchor}, lookup, nil)
+
+	p := NewLookup([]string{"127.0.0.1:53"})
+	u := (*p.Upstreams)[0]
+
+	m := new(dns.Msg)
+	m.SetQuestion("a.example.", dns.TypeA)
+	state := request.Request{W: &test.ResponseWriter{}, Req: m}
+
+	// A forged answer with the signatures stripped.
+	reply := new(dns.Msg)
+	reply.SetReply(m)
+	reply.Answer = []dns.RR{test.A("a.example. 3600 IN A 192.0.2.66")}
+
+	if err := validateReply(context.TODO(), v, u, state, reply); err == nil {
+		t.Errorf("Expected an error when the response can't be validated")
+	}
+}
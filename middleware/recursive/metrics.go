@@ -0,0 +1,19 @@
+package recursive
+
+import (
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ValidationCount is the counter of DNSSEC validation results.
+var ValidationCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: middleware.Namespace,
+	Subsystem: "recursive",
+	Name:      "validation_total",
+	Help:      "Counter of DNSSEC validation results, per result (secure, insecure, bogus or indeterminate).",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(ValidationCount)
+}
@@ -0,0 +1,77 @@
+// Package recursive implements a recursive resolver that validates DNSSEC.
+package recursive
+
+import (
+	"fmt"
+
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/validate"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Recursive is middleware that resolves queries by iterating from the root servers.
+type Recursive struct {
+	Next  middleware.Handler
+	Zones []string
+
+	resolver  *Resolver
+	validator *validate.Validator // nil when DNSSEC validation is disabled
+}
+
+// New returns a Recursive for zones that uses resolver. When anchors is not empty responses are
+// validated with those trust anchors.
+func New(zones []string, resolver *Resolver, anchors []dns.RR) *Recursive {
+	rc := &Recursive{Zones: zones, resolver: resolver}
+	if len(anchors) > 0 {
+		rc.validator = validate.New(anchors, resolver.Resolve, nil)
+	}
+	return rc
+}
+
+// ServeDNS implements the middleware.Handler interface.
+func (rc *Recursive) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	zone := middleware.Zones(rc.Zones).Matches(state.Name())
+	if zone == "" {
+		return middleware.NextOrFailure(rc.Name(), rc.Next, ctx, w, r)
+	}
+	if state.QClass() != dns.ClassINET {
+		return dns.RcodeNotImplemented, nil
+	}
+
+	resp, err := rc.resolver.Resolve(ctx, state.Name(), state.QType())
+	if err != nil {
+		return dns.RcodeServerFailure, middleware.Error(rc.Name(), err)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.RecursionAvailable, m.Compress = true, true
+	m.Rcode = resp.Rcode
+	m.Answer, m.Ns = resp.Answer, resp.Ns
+
+	if rc.validator != nil && !r.CheckingDisabled {
+		sec, err := rc.validator.Validate(ctx, resp)
+		ValidationCount.WithLabelValues(sec.String()).Inc()
+		if sec == validate.Bogus {
+			return dns.RcodeServerFailure, middleware.Error(rc.Name(), fmt.Errorf("bogus response for %s: %v", state.Name(), err))
+		}
+		m.AuthenticatedData = validate.AuthenticatedData(sec, r)
+	}
+
+	if !state.Do() {
+		m.Answer, m.Ns = validate.StripDNSSEC(m.Answer), validate.StripDNSSEC(m.Ns)
+	}
+
+	state.SizeAndDo(m)
+	m, _ = state.Scrub(m)
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the Handler interface.
+func (rc *Recursive) Name() string { return "recursive" }
@@ -0,0 +1,339 @@
+package recursive
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/file"
+	"github.com/coredns/coredns/middleware/pkg/dnskey"
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// network routes queries to the authoritative servers of the tests, by address.
+type network struct {
+	servers map[string]middleware.Handler
+
+	mu      sync.Mutex
+	queries map[string][]string // address -> "qname qtype" of the queries it received
+}
+
+func (n *network) exchange(m *dns.Msg, addr string) (*dns.Msg, error) {
+	n.mu.Lock()
+	n.queries[addr] = append(n.queries[addr], m.Question[0].Name+" "+dns.TypeToString[m.Question[0].Qtype])
+	n.mu.Unlock()
+
+	h, ok := n.servers[addr]
+	if !ok {
+		return nil, errNoServers
+	}
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	rcode, _ := h.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg == nil {
+		ret := new(dns.Msg)
+		ret.SetRcode(m, rcode)
+		return ret, nil
+	}
+	return rec.Msg, nil
+}
+
+func (n *network) received(addr string) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.queries[addr]
+}
+
+// signZone parses content as the zone origin and signs it with a newly generated key. It returns
+// the zone and the DS record for the key.
+func signZone(t *testing.T, origin, content string) (*file.Zone, *dns.DS) {
+	z := parseZone(t, origin, content)
+
+	k := &dns.DNSKEY{Flags: 257, Protocol: 3, Algorithm: dns.ECDSAP256SHA256}
+	k.Hdr = dns.RR_Header{Name: origin, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600}
+	priv, err := k.Generate(256)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+	fPub, rmPub, _ := test.TempFile(".", k.String()+"\n")
+	defer rmPub()
+	fPriv, rmPriv, _ := test.TempFile(".", k.PrivateKeyString(priv))
+	defer rmPriv()
+	key, err := dnskey.ParseKeyFile(fPub, fPriv)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %s", err)
+	}
+
+	z.Signer = &file.Signer{Keys: []*dnskey.Key{key}}
+	if err := z.Sign(time.Now().UTC()); err != nil {
+		t.Fatalf("Failed to sign zone %s: %s", origin, err)
+	}
+	return z, key.K.ToDS(dns.SHA256)
+}
+
+func parseZone(t *testing.T, origin, content string) *file.Zone {
+	z, err := file.Parse(strings.NewReader(content), origin, "stdin")
+	if err != nil {
+		t.Fatalf("Failed to parse zone %s: %s", origin, err)
+	}
+	return z
+}
+
+func serve(zones ...*file.Zone) middleware.Handler {
+	f := file.File{Zones: file.Zones{Z: make(map[string]*file.Zone)}}
+	for _, z := range zones {
+		origin := z.Apex.SOA.Header().Name
+		f.Zones.Z[origin] = z
+		f.Zones.Names = append(f.Zones.Names, origin)
+	}
+	return f
+}
+
+// newNetwork sets up the servers for the root, the signed example. zone and the unsigned
+// insecure.example. and other. zones. It returns the network and the trust anchor for the root.
+func newNetwork(t *testing.T) (*network, *dns.DS) {
+	example, ds := signZone(t, "example.", dbExample)
+	// Unsigned data in a signed zone.
+	example.Insert(test.A("bogus.example. 3600 IN A 192.0.2.66"))
+
+	root, rootDS := signZone(t, ".", dbRoot+ds.String()+"\n")
+
+	n := &network{
+		servers: map[string]middleware.Handler{
+			"127.0.0.1:53": serve(root),
+			"127.0.0.2:53": serve(example),
+			"127.0.0.3:53": serve(parseZone(t, "insecure.example.", dbInsecure), parseZone(t, "other.", dbOther)),
+			"127.0.0.9:53": test.NextHandler(dns.RcodeRefused, nil),
+		},
+		queries: make(map[string][]string),
+	}
+	return n, rootDS
+}
+
+func newRecursive(t *testing.T, minimise bool) (*Recursive, *network) {
+	n, ds := newNetwork(t)
+	r := NewResolver([]string{"127.0.0.1"})
+	r.exchange = n.exchange
+	r.minimise = minimise
+	return New([]string{"."}, r, []dns.RR{ds}), n
+}
+
+func TestRecursive(t *testing.T) {
+	rc, _ := newRecursive(t, true)
+
+	tests := []test.Case{
+		{
+			Qname: "www.example.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("www.example. 3600 IN A 192.0.2.1")},
+			Ns:     []dns.RR{test.NS("example. 3600 IN NS lame.example."), test.NS("example. 3600 IN NS ns.example.")},
+		},
+		{
+			Qname: "nx.example.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{test.SOA("example. 3600 IN SOA ns.example. hostmaster.example. 1 7200 3600 1209600 3600")},
+		},
+		{
+			Qname: "www.insecure.example.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("www.insecure.example. 3600 IN A 192.0.2.3")},
+			Ns:     []dns.RR{test.NS("insecure.example. 3600 IN NS ns.insecure.example.")},
+		},
+		{
+			// CNAME to another zone.
+			Qname: "cross.example.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.CNAME("cross.example. 3600 IN CNAME www.insecure.example."),
+				test.A("www.insecure.example. 3600 IN A 192.0.2.3"),
+			},
+			Ns: []dns.RR{test.NS("insecure.example. 3600 IN NS ns.insecure.example.")},
+		},
+		{
+			// Name server without glue.
+			Qname: "www.other.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("www.other. 3600 IN A 192.0.2.4")},
+			Ns:     []dns.RR{test.NS("other. 3600 IN NS ns.insecure.example.")},
+		},
+	}
+
+	for _, tc := range tests {
+		m := tc.Msg()
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		if _, err := rc.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Errorf("Test %s: expected no error, got %s", tc.Qname, err)
+			continue
+		}
+		resp := rec.Msg
+		if !resp.RecursionAvailable {
+			t.Errorf("Test %s: expected RA to be set", tc.Qname)
+		}
+		if resp.AuthenticatedData {
+			t.Errorf("Test %s: expected no AD for a query without DO", tc.Qname)
+		}
+		if !test.Header(t, tc, resp) {
+			t.Logf("%v\n", resp)
+			continue
+		}
+		if !test.Section(t, tc, test.Answer, resp.Answer) {
+			t.Logf("%v\n", resp)
+		}
+		if !test.Section(t, tc, test.Ns, resp.Ns) {
+			t.Logf("%v\n", resp)
+		}
+	}
+}
+
+func TestRecursiveDNSSEC(t *testing.T) {
+	rc, _ := newRecursive(t, true)
+
+	tests := []struct {
+		qname string
+		cd    bool
+		rcode int
+		ad    bool
+	}{
+		{"www.example.", false, dns.RcodeSuccess, true},
+		{"a.wild.example.", false, dns.RcodeSuccess, true},
+		{"nx.example.", false, dns.RcodeNameError, true},
+		{"www.insecure.example.", false, dns.RcodeSuccess, false},
+		{"cross.example.", false, dns.RcodeSuccess, false},
+		{"www.other.", false, dns.RcodeSuccess, false},
+		{"bogus.example.", false, dns.RcodeServerFailure, false},
+		{"bogus.example.", true, dns.RcodeSuccess, false},
+	}
+
+	for _, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		m.SetEdns0(4096, true)
+		m.CheckingDisabled = tc.cd
+
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		rcode, _ := rc.ServeDNS(context.TODO(), rec, m)
+		if rcode == dns.RcodeServerFailure {
+			if tc.rcode != dns.RcodeServerFailure {
+				t.Errorf("Test %s: expected rcode %d, got SERVFAIL", tc.qname, tc.rcode)
+			}
+			continue
+		}
+		resp := rec.Msg
+		if resp.Rcode != tc.rcode {
+			t.Errorf("Test %s: expected rcode %d, got %d", tc.qname, tc.rcode, resp.Rcode)
+		}
+		if resp.AuthenticatedData != tc.ad {
+			t.Errorf("Test %s: expected AD %t, got %t", tc.qname, tc.ad, resp.AuthenticatedData)
+		}
+		if tc.ad {
+			sigs := 0
+			for _, rr := range append(resp.Answer, resp.Ns...) {
+				if rr.Header().Rrtype == dns.TypeRRSIG {
+					sigs++
+				}
+			}
+			if sigs == 0 {
+				t.Errorf("Test %s: expected signatures for a query with DO", tc.qname)
+			}
+		}
+	}
+}
+
+func TestRecursiveMinimise(t *testing.T) {
+	for _, minimise := range []bool{true, false} {
+		rc, n := newRecursive(t, minimise)
+		rc.validator = nil
+
+		m := new(dns.Msg)
+		m.SetQuestion("www.example.", dns.TypeA)
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		if _, err := rc.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		expected := "www.example. A"
+		if minimise {
+			expected = "example. NS"
+		}
+		root := n.received("127.0.0.1:53")
+		if len(root) != 1 || root[0] != expected {
+			t.Errorf("Expected the root to receive %q (minimise %t), got %v", expected, minimise, root)
+		}
+	}
+}
+
+func TestRecursiveLame(t *testing.T) {
+	rc, n := newRecursive(t, true)
+	rc.validator = nil
+
+	for _, name := range []string{"www.example.", "a.wild.example."} {
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeA)
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		if _, err := rc.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %s: expected no error, got %s", name, err)
+		}
+		if len(rec.Msg.Answer) == 0 {
+			t.Errorf("Test %s: expected an answer", name)
+		}
+	}
+
+	// The lame server is only tried once, after that the other server is preferred.
+	if x := len(n.received("127.0.0.9:53")); x != 1 {
+		t.Errorf("Expected 1 query to the lame server, got %d", x)
+	}
+	// The delegation for example. is cached.
+	if x := len(n.received("127.0.0.1:53")); x != 1 {
+		t.Errorf("Expected 1 query to the root, got %d", x)
+	}
+}
+
+func TestRecursiveZones(t *testing.T) {
+	rc, _ := newRecursive(t, true)
+	rc.Zones = []string{"example."}
+	rc.Next = test.NextHandler(dns.RcodeRefused, nil)
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.other.", dns.TypeA)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	if rcode, _ := rc.ServeDNS(context.TODO(), rec, m); rcode != dns.RcodeRefused {
+		t.Errorf("Expected the query to be passed to the next middleware, got rcode %d", rcode)
+	}
+}
+
+const dbRoot = `
+.                 3600 IN SOA a.root. hostmaster.root. 1 7200 3600 1209600 3600
+.                 3600 IN NS  a.root.
+a.root.           3600 IN A   127.0.0.1
+example.          3600 IN NS  lame.example.
+example.          3600 IN NS  ns.example.
+lame.example.     3600 IN A   127.0.0.9
+ns.example.       3600 IN A   127.0.0.2
+other.            3600 IN NS  ns.insecure.example.
+`
+
+const dbExample = `
+example.                 3600 IN SOA   ns.example. hostmaster.example. 1 7200 3600 1209600 3600
+example.                 3600 IN NS    lame.example.
+example.                 3600 IN NS    ns.example.
+lame.example.            3600 IN A     127.0.0.9
+ns.example.              3600 IN A     127.0.0.2
+www.example.             3600 IN A     192.0.2.1
+*.wild.example.          3600 IN A     192.0.2.2
+cross.example.           3600 IN CNAME www.insecure.example.
+insecure.example.        3600 IN NS    ns.insecure.example.
+ns.insecure.example.     3600 IN A     127.0.0.3
+`
+
+const dbInsecure = `
+insecure.example.        3600 IN SOA   ns.insecure.example. hostmaster.example. 1 7200 3600 1209600 3600
+insecure.example.        3600 IN NS    ns.insecure.example.
+ns.insecure.example.     3600 IN A     127.0.0.3
+www.insecure.example.    3600 IN A     192.0.2.3
+`
+
+const dbOther = `
+other.                   3600 IN SOA   ns.insecure.example. hostmaster.example. 1 7200 3600 1209600 3600
+other.                   3600 IN NS    ns.insecure.example.
+www.other.               3600 IN A     192.0.2.4
+`
@@ -0,0 +1,465 @@
+package recursive
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Resolver is an iterative resolver: it starts at the root servers and follows the referrals until
+// it reaches the servers that are authoritative for a name. Delegations are cached.
+type Resolver struct {
+	root     *delegation
+	port     string
+	minimise bool // use QNAME minimisation, RFC 7816
+
+	// exchange sends m to addr and returns the response.
+	exchange func(m *dns.Msg, addr string) (*dns.Msg, error)
+
+	mu          sync.RWMutex
+	delegations map[string]*delegation // zone -> name servers
+	lame        map[string]time.Time   // zone + " " + address -> expiry
+
+	now func() time.Time
+}
+
+// delegation holds the name servers of a zone.
+type delegation struct {
+	ns     []string // lower cased names of the name servers
+	addrs  []string // addresses of the name servers, as host:port
+	expire time.Time
+}
+
+// NewResolver returns a Resolver that uses the root servers at hints, which are IP addresses.
+func NewResolver(hints []string) *Resolver {
+	r := &Resolver{
+		port:        "53",
+		minimise:    true,
+		exchange:    exchange,
+		delegations: make(map[string]*delegation),
+		lame:        make(map[string]time.Time),
+		now:         time.Now,
+	}
+	r.root = &delegation{}
+	for _, h := range hints {
+		r.root.addrs = append(r.root.addrs, net.JoinHostPort(h, r.port))
+	}
+	return r
+}
+
+// Resolve resolves name and qtype. CNAMEs are followed and the complete chain is returned in the
+// answer section. The response holds the DNSSEC records, but is not validated.
+func (r *Resolver) Resolve(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	return r.resolve(ctx, strings.ToLower(dns.Fqdn(name)), qtype, 0)
+}
+
+func (r *Resolver) resolve(ctx context.Context, name string, qtype uint16, depth int) (*dns.Msg, error) {
+	ret := new(dns.Msg)
+	ret.SetQuestion(name, qtype)
+
+	target := name
+	for i := 0; i <= maxCNAME; i++ {
+		m, zone, err := r.iterate(ctx, target, qtype, depth)
+		if err != nil {
+			return nil, err
+		}
+		// Only use what the server is authoritative for, anything else may be injected.
+		m.Answer, m.Ns = answer(m.Answer, target, zone), inZone(m.Ns, zone)
+		ret.Answer = append(ret.Answer, m.Answer...)
+		ret.Ns, ret.Rcode = m.Ns, m.Rcode
+
+		if target = chase(m, target, qtype); target == "" {
+			return ret, nil
+		}
+	}
+	return nil, errCNAMEChain
+}
+
+// iterate looks up name and qtype, starting at the closest delegation we know of. It returns the
+// response and the zone of the servers that sent it.
+func (r *Resolver) iterate(ctx context.Context, name string, qtype uint16, depth int) (*dns.Msg, string, error) {
+	if depth > maxDepth {
+		return nil, "", errMaxDepth
+	}
+
+	zone, d := r.closest(name, qtype)
+	below := zone // the longest name we know exists and is served by d
+	minimise := r.minimise
+
+	for i := 0; i < maxReferrals; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, "", err
+		}
+
+		qname, qt := name, qtype
+		if minimise {
+			qname, qt = minimised(name, below, qtype)
+		}
+
+		m, err := r.query(ctx, zone, d, qname, qt, depth)
+		if err != nil {
+			if qname != name {
+				// Some servers don't like the NS queries, fall back to asking for the full name.
+				minimise = false
+				continue
+			}
+			return nil, "", err
+		}
+
+		if child := referral(m, zone, qname); child != "" {
+			if qtype == dns.TypeDS && child == name {
+				// We asked the parent, which holds the DS records in the authority section.
+				return dsAnswer(m, name), zone, nil
+			}
+			zone, d, below = child, r.delegate(zone, child, m.Ns, m.Extra), child
+			continue
+		}
+
+		if qname == name {
+			return m, zone, nil
+		}
+
+		// Response to a minimised query.
+		switch {
+		case m.Rcode != dns.RcodeSuccess:
+			// NXDOMAIN for an empty non-terminal is a common mistake, RFC 7816, Section 3.
+			minimise = false
+		case hasNS(m.Answer, qname):
+			// The servers for zone are also authoritative for qname.
+			zone, d, below = qname, r.delegate(zone, qname, m.Answer, m.Extra), qname
+		default:
+			below = qname
+		}
+	}
+	return nil, "", errMaxReferrals
+}
+
+// query sends the query for qname and qtype to the servers of zone, until one of them gives a
+// usable response. Servers that don't are marked as lame.
+func (r *Resolver) query(ctx context.Context, zone string, d *delegation, qname string, qtype uint16, depth int) (*dns.Msg, error) {
+	addrs := r.addresses(ctx, d, depth)
+
+	m := new(dns.Msg)
+	m.SetQuestion(qname, qtype)
+	m.RecursionDesired = false
+	m.SetEdns0(bufSize, true)
+
+	var lastErr error = errNoServers
+	for _, addr := range r.order(zone, addrs) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		resp, err := r.exchange(m, addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !usable(resp, zone, qname) {
+			r.markLame(zone, addr)
+			lastErr = errLame
+			continue
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+// addresses returns the addresses of the name servers in d. When there was no glue the names of
+// the name servers are resolved.
+func (r *Resolver) addresses(ctx context.Context, d *delegation, depth int) []string {
+	r.mu.RLock()
+	addrs := d.addrs
+	r.mu.RUnlock()
+	if len(addrs) > 0 {
+		return addrs
+	}
+
+	for _, ns := range d.ns {
+		m, err := r.resolve(ctx, ns, dns.TypeA, depth+1)
+		if err != nil {
+			continue
+		}
+		for _, rr := range m.Answer {
+			if a, ok := rr.(*dns.A); ok {
+				addrs = append(addrs, net.JoinHostPort(a.A.String(), r.port))
+			}
+		}
+	}
+
+	r.mu.Lock()
+	d.addrs = addrs
+	r.mu.Unlock()
+	return addrs
+}
+
+// delegate caches and returns the delegation of zone to the name servers in nsrrs. Glue is taken
+// from extra, but only for names in parent, the zone of the servers that sent it.
+func (r *Resolver) delegate(parent, zone string, nsrrs, extra []dns.RR) *delegation {
+	d := &delegation{}
+	ttl := uint32(maxTTL)
+	for _, rr := range nsrrs {
+		ns, ok := rr.(*dns.NS)
+		if !ok || !strings.EqualFold(ns.Hdr.Name, zone) {
+			continue
+		}
+		d.ns = append(d.ns, strings.ToLower(ns.Ns))
+		if ns.Hdr.Ttl < ttl {
+			ttl = ns.Hdr.Ttl
+		}
+	}
+	for _, rr := range extra {
+		a, ok := rr.(*dns.A)
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(a.Hdr.Name)
+		if !dns.IsSubDomain(parent, name) || !contains(d.ns, name) {
+			continue
+		}
+		d.addrs = append(d.addrs, net.JoinHostPort(a.A.String(), r.port))
+	}
+	d.expire = r.now().Add(time.Duration(ttl) * time.Second)
+
+	r.mu.Lock()
+	r.delegations[zone] = d
+	r.mu.Unlock()
+	return d
+}
+
+// closest returns the closest delegation for name that we know of. DS records are served by the
+// parent, so for those the delegation of name itself is skipped.
+func (r *Resolver) closest(name string, qtype uint16) (string, *delegation) {
+	now := r.now()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for off, end := 0, false; !end; off, end = dns.NextLabel(name, off) {
+		zone := name[off:]
+		if qtype == dns.TypeDS && zone == name {
+			continue
+		}
+		if d, ok := r.delegations[zone]; ok && now.Before(d.expire) {
+			return zone, d
+		}
+	}
+	return ".", r.root
+}
+
+// order returns addrs with the servers that are lame for zone last.
+func (r *Resolver) order(zone string, addrs []string) []string {
+	now := r.now()
+	good, lame := []string{}, []string{}
+
+	r.mu.RLock()
+	for _, a := range addrs {
+		if t, ok := r.lame[zone+" "+a]; ok && now.Before(t) {
+			lame = append(lame, a)
+			continue
+		}
+		good = append(good, a)
+	}
+	r.mu.RUnlock()
+	return append(good, lame...)
+}
+
+func (r *Resolver) markLame(zone, addr string) {
+	r.mu.Lock()
+	r.lame[zone+" "+addr] = r.now().Add(lameTTL)
+	r.mu.Unlock()
+}
+
+// minimised returns the name and type to ask the servers for below, when looking up name and
+// qtype: the name one label longer than below, and type NS, until we reach name itself.
+func minimised(name, below string, qtype uint16) (string, uint16) {
+	n := dns.CountLabel(below) + 1
+	labels := dns.CountLabel(name)
+	if n >= labels {
+		return name, qtype
+	}
+	off, _ := dns.PrevLabel(name, n)
+	return name[off:], dns.TypeNS
+}
+
+// referral returns the zone m refers us to, or the empty string if m isn't a referral. The zone
+// must be below zone and qname must be in it.
+func referral(m *dns.Msg, zone, qname string) string {
+	if m.Rcode != dns.RcodeSuccess || len(m.Answer) > 0 {
+		return ""
+	}
+	for _, rr := range m.Ns {
+		if rr.Header().Rrtype != dns.TypeNS {
+			continue
+		}
+		owner := strings.ToLower(rr.Header().Name)
+		if owner != zone && dns.IsSubDomain(zone, owner) && dns.IsSubDomain(owner, qname) {
+			return owner
+		}
+	}
+	return ""
+}
+
+// usable returns true if m is a response we can use, the server is lame otherwise.
+func usable(m *dns.Msg, zone, qname string) bool {
+	if m.Rcode != dns.RcodeSuccess && m.Rcode != dns.RcodeNameError {
+		return false
+	}
+	if referral(m, zone, qname) != "" {
+		return true
+	}
+	return m.Authoritative || len(m.Answer) > 0
+}
+
+// chase returns the name at the end of the CNAME chain for name in m, if that still needs to be
+// looked up.
+func chase(m *dns.Msg, name string, qtype uint16) string {
+	if qtype == dns.TypeCNAME || m.Rcode != dns.RcodeSuccess {
+		return ""
+	}
+
+	seen := map[string]bool{}
+	for {
+		if seen[name] {
+			return "" // CNAME loop
+		}
+		seen[name] = true
+
+		next := ""
+		for _, rr := range m.Answer {
+			if !strings.EqualFold(rr.Header().Name, name) {
+				continue
+			}
+			if rr.Header().Rrtype == qtype || qtype == dns.TypeANY {
+				return ""
+			}
+			if c, ok := rr.(*dns.CNAME); ok {
+				next = strings.ToLower(c.Target)
+			}
+		}
+		if next == "" {
+			break
+		}
+		name = next
+	}
+	if len(seen) == 1 {
+		return "" // no CNAME, this is a NODATA response
+	}
+
+	// A negative response from the zone holding the target ends the chain.
+	for _, rr := range m.Ns {
+		if rr.Header().Rrtype == dns.TypeSOA && dns.IsSubDomain(rr.Header().Name, name) {
+			return ""
+		}
+	}
+	return name
+}
+
+// answer returns the records from rrs that answer name: those owned by the names in the CNAME chain
+// that starts at name, as far as the chain stays in zone, the zone of the server that sent rrs.
+// Records for other names are dropped, as the server may use them to inject data it is not
+// authoritative for.
+func answer(rrs []dns.RR, name, zone string) []dns.RR {
+	chain := map[string]bool{}
+	for n := name; n != "" && !chain[n] && dns.IsSubDomain(zone, n); {
+		chain[n] = true
+		next := ""
+		for _, rr := range rrs {
+			if c, ok := rr.(*dns.CNAME); ok && strings.EqualFold(c.Hdr.Name, n) {
+				next = strings.ToLower(c.Target)
+			}
+		}
+		n = next
+	}
+
+	ret := []dns.RR{}
+	for _, rr := range rrs {
+		if chain[strings.ToLower(rr.Header().Name)] {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
+
+// inZone returns the records from rrs that are owned by names in zone.
+func inZone(rrs []dns.RR, zone string) []dns.RR {
+	ret := []dns.RR{}
+	for _, rr := range rrs {
+		if dns.IsSubDomain(zone, strings.ToLower(rr.Header().Name)) {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
+
+// dsAnswer turns a referral for name, the response to a DS query, into an answer.
+func dsAnswer(m *dns.Msg, name string) *dns.Msg {
+	ret := new(dns.Msg)
+	ret.SetQuestion(name, dns.TypeDS)
+	for _, rr := range m.Ns {
+		t := rr.Header().Rrtype
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			t = sig.TypeCovered
+		}
+		switch {
+		case t == dns.TypeNS:
+		case t == dns.TypeDS && strings.EqualFold(rr.Header().Name, name):
+			ret.Answer = append(ret.Answer, rr)
+		default:
+			ret.Ns = append(ret.Ns, rr)
+		}
+	}
+	return ret
+}
+
+func hasNS(rrs []dns.RR, name string) bool {
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeNS && strings.EqualFold(rr.Header().Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(s []string, x string) bool {
+	for _, y := range s {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
+
+// exchange sends m to addr over UDP, and retries over TCP when the response is truncated.
+func exchange(m *dns.Msg, addr string) (*dns.Msg, error) {
+	c := new(dns.Client)
+	c.Timeout = timeout
+	r, _, err := c.Exchange(m, addr)
+	if err == nil && r.Truncated {
+		c.Net = "tcp"
+		r, _, err = c.Exchange(m, addr)
+	}
+	return r, err
+}
+
+const (
+	maxCNAME     = 8     // maximum length of a CNAME chain
+	maxDepth     = 5     // maximum nesting of name server lookups
+	maxReferrals = 32    // maximum number of referrals and minimised queries for one name
+	maxTTL       = 86400 // maximum time a delegation is cached, in seconds
+	bufSize      = 4096
+
+	timeout = 2 * time.Second
+	lameTTL = 15 * time.Minute
+)
+
+var (
+	errCNAMEChain   = errors.New("CNAME chain too long")
+	errMaxDepth     = errors.New("name server lookups nested too deep")
+	errMaxReferrals = errors.New("too many referrals")
+	errNoServers    = errors.New("no name server addresses")
+	errLame         = errors.New("lame delegation")
+)
@@ -0,0 +1,133 @@
+package recursive
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestMinimised(t *testing.T) {
+	tests := []struct {
+		name, below   string
+		qtype         uint16
+		expected      string
+		expectedQtype uint16
+	}{
+		{"www.example.org.", ".", dns.TypeA, "org.", dns.TypeNS},
+		{"www.example.org.", "org.", dns.TypeA, "example.org.", dns.TypeNS},
+		{"www.example.org.", "example.org.", dns.TypeA, "www.example.org.", dns.TypeA},
+		{"example.org.", ".", dns.TypeDS, "org.", dns.TypeNS},
+		{"org.", ".", dns.TypeMX, "org.", dns.TypeMX},
+		{".", ".", dns.TypeDNSKEY, ".", dns.TypeDNSKEY},
+	}
+	for _, tc := range tests {
+		name, qtype := minimised(tc.name, tc.below, tc.qtype)
+		if name != tc.expected || qtype != tc.expectedQtype {
+			t.Errorf("Test %s below %s: expected %s %d, got %s %d", tc.name, tc.below, tc.expected, tc.expectedQtype, name, qtype)
+		}
+	}
+}
+
+func TestReferral(t *testing.T) {
+	m := new(dns.Msg)
+	m.Ns = []dns.RR{test.NS("example.org. 3600 IN NS ns.example.org.")}
+
+	tests := []struct {
+		zone, qname string
+		expected    string
+	}{
+		{".", "www.example.org.", "example.org."},
+		{"org.", "example.org.", "example.org."},
+		{"example.org.", "www.example.org.", ""}, // not below the zone, lame
+		{".", "www.example.net.", ""},            // not for the qname
+	}
+	for _, tc := range tests {
+		if x := referral(m, tc.zone, tc.qname); x != tc.expected {
+			t.Errorf("Test %s in %s: expected referral to %q, got %q", tc.qname, tc.zone, tc.expected, x)
+		}
+	}
+
+	// A response with an answer is not a referral.
+	m.Answer = []dns.RR{test.A("www.example.org. 3600 IN A 127.0.0.1")}
+	if x := referral(m, ".", "www.example.org."); x != "" {
+		t.Errorf("Expected no referral for a response with an answer, got %q", x)
+	}
+}
+
+func TestChase(t *testing.T) {
+	cname := test.CNAME("www.example.org. 3600 IN CNAME www.example.net.")
+	a := test.A("www.example.net. 3600 IN A 127.0.0.1")
+	soa := test.SOA("example.net. 3600 IN SOA ns.example.net. hostmaster.example.net. 1 7200 3600 1209600 3600")
+
+	tests := []struct {
+		answer, ns []dns.RR
+		qtype      uint16
+		expected   string
+	}{
+		{[]dns.RR{cname}, nil, dns.TypeA, "www.example.net."},
+		{[]dns.RR{cname, a}, nil, dns.TypeA, ""},
+		{[]dns.RR{cname}, []dns.RR{soa}, dns.TypeA, ""}, // NODATA from the target's zone
+		{[]dns.RR{cname}, nil, dns.TypeCNAME, ""},
+		{nil, nil, dns.TypeA, ""},
+	}
+	for i, tc := range tests {
+		m := &dns.Msg{Answer: tc.answer, Ns: tc.ns}
+		if x := chase(m, "www.example.org.", tc.qtype); x != tc.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, tc.expected, x)
+		}
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	rrs := []dns.RR{
+		test.CNAME("www.example.org. 3600 IN CNAME web.example.org."),
+		test.CNAME("web.example.org. 3600 IN CNAME www.example.net."),
+		test.A("web.example.org. 3600 IN A 127.0.0.1"),
+		test.RRSIG("web.example.org. 3600 IN RRSIG A 13 3 3600 20161129153240 20161030153240 49035 example.org. rlNN"),
+		// Out of bailiwick, injected by the servers of example.org.
+		test.A("www.example.net. 3600 IN A 192.0.2.66"),
+		test.A("victim.example.org. 3600 IN A 192.0.2.66"),
+		test.NS("example.com. 3600 IN NS ns.attacker.example."),
+	}
+
+	x := answer(rrs, "www.example.org.", "example.org.")
+	if len(x) != 4 {
+		t.Fatalf("Expected the CNAME chain in example.org. and its records, got %v", x)
+	}
+	for _, rr := range x {
+		if n := rr.Header().Name; n != "www.example.org." && n != "web.example.org." {
+			t.Errorf("Expected only records in the CNAME chain, got %s", rr)
+		}
+	}
+
+	// A name that is not in the zone of the server gets nothing.
+	if x := answer(rrs, "www.example.net.", "example.org."); len(x) != 0 {
+		t.Errorf("Expected no records for a name outside of the zone, got %v", x)
+	}
+
+	ns := []dns.RR{
+		test.SOA("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 7200 3600 1209600 3600"),
+		test.NS("example.net. 3600 IN NS ns.attacker.example."),
+	}
+	if x := inZone(ns, "example.org."); len(x) != 1 || x[0].Header().Rrtype != dns.TypeSOA {
+		t.Errorf("Expected only the SOA record in the zone, got %v", x)
+	}
+}
+
+func TestDSAnswer(t *testing.T) {
+	m := new(dns.Msg)
+	m.Ns = []dns.RR{
+		test.NS("example.org. 3600 IN NS ns.example.org."),
+		test.DS("example.org. 3600 IN DS 10056 5 1 EE72CABD1927759CDDA92A10DBF431504B9E1F13"),
+		test.RRSIG("example.org. 3600 IN RRSIG DS 13 2 3600 20161129153240 20161030153240 49035 org. rlNNzcUmtbjLSl02ZzQGUbWX75yCUx0Mug1jHtKVqRq1hpPE2S3863tIWSlz+W9wz4o19OI4jbznKKqk+DGKog=="),
+	}
+	ds := dsAnswer(m, "example.org.")
+	if len(ds.Answer) != 2 {
+		t.Errorf("Expected the DS and its signature in the answer, got %v", ds.Answer)
+	}
+	if len(ds.Ns) != 0 {
+		t.Errorf("Expected no authority section, got %v", ds.Ns)
+	}
+}
@@ -0,0 +1,52 @@
+package recursive
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/miekg/dns"
+)
+
+// rootHints are the IPv4 addresses of a.root-servers.net to m.root-servers.net.
+var rootHints = []string{
+	"198.41.0.4",
+	"199.9.14.201",
+	"192.33.4.12",
+	"199.7.91.13",
+	"192.203.230.10",
+	"192.5.5.241",
+	"192.112.36.4",
+	"198.97.190.53",
+	"192.36.148.17",
+	"192.58.128.30",
+	"193.0.14.129",
+	"199.7.83.42",
+	"202.12.27.33",
+}
+
+// parseHints returns the IPv4 addresses from a root hints file, as published by IANA as named.root.
+func parseHints(r io.Reader, file string) ([]string, error) {
+	hints := []string{}
+	for x := range dns.ParseZone(r, ".", file) {
+		if x.Error != nil {
+			return nil, x.Error
+		}
+		if a, ok := x.RR.(*dns.A); ok {
+			hints = append(hints, a.A.String())
+		}
+	}
+	if len(hints) == 0 {
+		return nil, fmt.Errorf("no addresses found in %s", file)
+	}
+	return hints, nil
+}
+
+func readHints(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseHints(f, file)
+}
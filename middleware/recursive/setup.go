@@ -0,0 +1,138 @@
+package recursive
+
+import (
+	"path"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/validate"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func init() {
+	caddy.RegisterPlugin("recursive", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	rc, err := recursiveParse(c)
+	if err != nil {
+		return middleware.Error("recursive", err)
+	}
+
+	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
+		rc.Next = next
+		return rc
+	})
+
+	return nil
+}
+
+func recursiveParse(c *caddy.Controller) (*Recursive, error) {
+	var (
+		zones    []string
+		hints    = rootHints
+		anchors  []dns.RR
+		dnssec   = true
+		minimise = true
+	)
+
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, c.Err("recursive can only be specified once per server block")
+		}
+		i++
+
+		zones = c.RemainingArgs()
+		if len(zones) == 0 {
+			zones = make([]string, len(c.ServerBlockKeys))
+			copy(zones, c.ServerBlockKeys)
+		}
+		for j := range zones {
+			zones[j] = middleware.Host(zones[j]).Normalize()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "root_hints":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				h, err := readHints(fileName(config, c.Val()))
+				if err != nil {
+					return nil, err
+				}
+				hints = h
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+			case "trust_anchor":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, a := range args {
+					rrs, err := validate.ReadAnchors(fileName(config, a))
+					if err != nil {
+						return nil, err
+					}
+					anchors = append(anchors, rrs...)
+				}
+			case "dnssec":
+				on, err := onOff(c)
+				if err != nil {
+					return nil, err
+				}
+				dnssec = on
+			case "qname_minimisation":
+				on, err := onOff(c)
+				if err != nil {
+					return nil, err
+				}
+				minimise = on
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if !dnssec {
+		anchors = nil
+	} else if len(anchors) == 0 {
+		anchors = validate.RootAnchors()
+	}
+
+	resolver := NewResolver(hints)
+	resolver.minimise = minimise
+	return New(zones, resolver, anchors), nil
+}
+
+// onOff parses the single "on" or "off" argument of a property.
+func onOff(c *caddy.Controller) (bool, error) {
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return false, c.ArgErr()
+	}
+	switch args[0] {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, c.Errf("expected on or off, got '%s'", args[0])
+}
+
+// fileName returns name relative to the root directory of the server, when it is not absolute.
+func fileName(config *dnsserver.Config, name string) string {
+	if !path.IsAbs(name) && config.Root != "" {
+		return path.Join(config.Root, name)
+	}
+	return name
+}
@@ -0,0 +1,83 @@
+package recursive
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupRecursive(t *testing.T) {
+	hints, rmHints, _ := test.TempFile(".", "a.root-servers.net. 3600000 IN A 198.41.0.4\nb.root-servers.net. 3600000 IN A 199.9.14.201\n")
+	defer rmHints()
+	anchor, rmAnchor, _ := test.TempFile(".", ". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D\n")
+	defer rmAnchor()
+
+	tests := []struct {
+		input            string
+		shouldErr        bool
+		expectedZones    []string
+		expectedHints    int
+		expectedMinimise bool
+		expectedDNSSEC   bool
+	}{
+		{`recursive`, false, []string{"."}, len(rootHints), true, true},
+		{`recursive example.org {
+			qname_minimisation off
+			dnssec off
+		}`, false, []string{"example.org."}, len(rootHints), false, false},
+		{`recursive {
+			root_hints ` + hints + `
+			trust_anchor ` + anchor + `
+		}`, false, []string{"."}, 2, true, true},
+		// fails
+		{`recursive {
+			dnssec maybe
+		}`, true, nil, 0, false, false},
+		{`recursive {
+			root_hints
+		}`, true, nil, 0, false, false},
+		{`recursive {
+			root_hints /does/not/exist
+		}`, true, nil, 0, false, false},
+		{`recursive {
+			trust_anchor ` + hints + `
+		}`, true, nil, 0, false, false},
+		{`recursive {
+			forward 8.8.8.8
+		}`, true, nil, 0, false, false},
+		{"recursive\nrecursive", true, nil, 0, false, false},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		c.ServerBlockKeys = []string{"."}
+		rc, err := recursiveParse(c)
+
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, tc.input, err)
+			continue
+		}
+
+		if strings.Join(rc.Zones, " ") != strings.Join(tc.expectedZones, " ") {
+			t.Errorf("Test %d: expected zones %v, got %v", i, tc.expectedZones, rc.Zones)
+		}
+		if x := len(rc.resolver.root.addrs); x != tc.expectedHints {
+			t.Errorf("Test %d: expected %d root hints, got %d", i, tc.expectedHints, x)
+		}
+		if rc.resolver.minimise != tc.expectedMinimise {
+			t.Errorf("Test %d: expected minimise %t, got %t", i, tc.expectedMinimise, rc.resolver.minimise)
+		}
+		if (rc.validator != nil) != tc.expectedDNSSEC {
+			t.Errorf("Test %d: expected DNSSEC validation %t, got %t", i, tc.expectedDNSSEC, rc.validator != nil)
+		}
+	}
+}
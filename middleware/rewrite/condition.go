@@ -130,3 +130,19 @@ func NewIf(a, operator, b string) (If, error) {
 		B:        b,
 	}, nil
 }
+
+// conditionalRule is a Rule that is only applied when all of its conditions are true.
+type conditionalRule struct {
+	Rule
+	ifs []If
+}
+
+// Rewrite implements the Rule interface.
+func (c conditionalRule) Rewrite(r *dns.Msg) Result {
+	for _, i := range c.ifs {
+		if !i.True(r) {
+			return RewriteIgnored
+		}
+	}
+	return c.Rule.Rewrite(r)
+}
@@ -1,5 +1,12 @@
 package rewrite
 
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
 /*
 func TestConditions(t *testing.T) {
 	tests := []struct {
@@ -100,3 +107,51 @@ func TestConditions(t *testing.T) {
 	}
 }
 */
+
+func TestConditionECS(t *testing.T) {
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	r.SetEdns0(4096, false)
+	opt := r.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()})
+
+	tests := []struct {
+		a, op, b string
+		isTrue   bool
+	}{
+		{"{>ecs}", Is, "192.0.2.0/24", true},
+		{"{>ecs}", StartsWith, "192.0.2.", true},
+		{"{>ecs}", StartsWith, "10.", false},
+		{"{>ecs}", Match, `^192\.0\.2\.0/(2[0-4])$`, true},
+	}
+	for i, test := range tests {
+		ifCond, err := NewIf(test.a, test.op, test.b)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if x := ifCond.True(r); x != test.isTrue {
+			t.Errorf("Test %d: expected %t, got %t", i, test.isTrue, x)
+		}
+	}
+}
+
+func TestConditionalRule(t *testing.T) {
+	cond, _ := NewIf("{>ecs}", StartsWith, "192.0.2.")
+	rule := conditionalRule{Rule: &nameRule{"example.org.", "example.net."}, ifs: []If{cond}}
+
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	if x := rule.Rewrite(r); x != RewriteIgnored {
+		t.Errorf("Expected the rule to be ignored without a client subnet, got %d", x)
+	}
+
+	r.SetEdns0(4096, false)
+	opt := r.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()})
+	if x := rule.Rewrite(r); x != RewriteDone {
+		t.Errorf("Expected the rule to be applied, got %d", x)
+	}
+	if r.Question[0].Name != "example.net." {
+		t.Errorf("Expected the name to be rewritten to example.net., got %s", r.Question[0].Name)
+	}
+}
@@ -36,6 +36,27 @@ func rewriteParse(c *caddy.Controller) ([]Rule, error) {
 		if err != nil {
 			return nil, err
 		}
+
+		var ifs []If
+		for c.NextBlock() {
+			switch c.Val() {
+			case "if":
+				args := c.RemainingArgs()
+				if len(args) != 3 {
+					return nil, c.ArgErr()
+				}
+				cond, err := NewIf(args[0], args[1], args[2])
+				if err != nil {
+					return nil, err
+				}
+				ifs = append(ifs, cond)
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+		if len(ifs) > 0 {
+			rule = conditionalRule{Rule: rule, ifs: ifs}
+		}
 		rules = append(rules, rule)
 	}
 	return rules, nil
@@ -23,3 +23,50 @@ func TestParse(t *testing.T) {
 		t.Errorf("Expected success but found %s for `rewrite name a.com b.com`", err)
 	}
 }
+
+func TestParseIf(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		ifs       int
+	}{
+		{`rewrite name a.com b.com {
+			if {>ecs} starts_with 192.0.2.
+		}`, false, 1},
+		{`rewrite name a.com b.com {
+			if {>ecs} starts_with 192.0.2.
+			if {type} is A
+		}`, false, 2},
+		{`rewrite name a.com b.com {
+			if {>ecs} starts_with
+		}`, true, 0},
+		{`rewrite name a.com b.com {
+			if {>ecs} like 192.0.2.
+		}`, true, 0},
+		{`rewrite name a.com b.com {
+			unless {>ecs} is 192.0.2.0/24
+		}`, true, 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		rules, err := rewriteParse(c)
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if len(rules) != 1 {
+			t.Fatalf("Test %d expected 1 rule, got %d", i, len(rules))
+		}
+		cr, ok := rules[0].(conditionalRule)
+		if !ok {
+			t.Fatalf("Test %d expected a conditional rule, got %T", i, rules[0])
+		}
+		if len(cr.ifs) != test.ifs {
+			t.Errorf("Test %d expected %d conditions, got %d", i, test.ifs, len(cr.ifs))
+		}
+	}
+}
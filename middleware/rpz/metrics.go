@@ -0,0 +1,29 @@
+package rpz
+
+import (
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics the rpz middleware exports.
+var (
+	HitCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: middleware.Namespace,
+		Subsystem: "rpz",
+		Name:      "hits_total",
+		Help:      "Counter of queries that triggered a rule, per policy, trigger and action.",
+	}, []string{"policy", "trigger", "action"})
+
+	RuleCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: middleware.Namespace,
+		Subsystem: "rpz",
+		Name:      "rules",
+		Help:      "Number of rules in each policy.",
+	}, []string{"policy"})
+)
+
+func init() {
+	prometheus.MustRegister(HitCount)
+	prometheus.MustRegister(RuleCount)
+}
@@ -0,0 +1,356 @@
+package rpz
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// action is what a rule does with a query that triggered it.
+type action int
+
+const (
+	actionNXDOMAIN action = iota // CNAME .
+	actionNODATA                 // CNAME *.
+	actionPassthru               // CNAME rpz-passthru.
+	actionDrop                   // CNAME rpz-drop.
+	actionTCPOnly                // CNAME rpz-tcp-only.
+	actionLocal                  // any other data
+)
+
+var actionNames = map[action]string{
+	actionNXDOMAIN: "nxdomain",
+	actionNODATA:   "nodata",
+	actionPassthru: "passthru",
+	actionDrop:     "drop",
+	actionTCPOnly:  "tcp-only",
+	actionLocal:    "local-data",
+}
+
+func (a action) String() string { return actionNames[a] }
+
+// trigger is the part of a query or response that matched a rule.
+type trigger int
+
+const (
+	triggerClientIP trigger = iota
+	triggerQName
+	triggerResponseIP
+	triggerNSDName
+)
+
+var triggerNames = map[trigger]string{
+	triggerClientIP:   "client-ip",
+	triggerQName:      "qname",
+	triggerResponseIP: "response-ip",
+	triggerNSDName:    "nsdname",
+}
+
+func (t trigger) String() string { return triggerNames[t] }
+
+// Special names used in policy zones.
+const (
+	clientIPLabel = "rpz-client-ip"
+	ipLabel       = "rpz-ip"
+	nsdnameLabel  = "rpz-nsdname"
+	nsipLabel     = "rpz-nsip"
+
+	passthruTarget = "rpz-passthru."
+	dropTarget     = "rpz-drop."
+	tcpOnlyTarget  = "rpz-tcp-only."
+)
+
+// rule is the action of a single trigger.
+type rule struct {
+	action action
+	rrs    []dns.RR // local data, only used for actionLocal
+}
+
+// ipRule is a rule with a client-ip or response-ip trigger.
+type ipRule struct {
+	net  *net.IPNet
+	rule *rule
+}
+
+// policy is a compiled policy zone or blocklist.
+type policy struct {
+	name   string   // origin of the zone or the name of the blocklist
+	serial uint32   // serial of the zone
+	soa    *dns.SOA // added to NXDOMAIN and NODATA responses, may be nil
+
+	qname    map[string]*rule // exact names
+	wildcard map[string]*rule // names whose subdomains match, without the leading *.
+	nsdname  map[string]*rule
+	nsdwild  map[string]*rule
+
+	clientIP   []ipRule
+	responseIP []ipRule
+
+	rules int
+}
+
+func newPolicy(name string) *policy {
+	return &policy{
+		name:     name,
+		qname:    make(map[string]*rule),
+		wildcard: make(map[string]*rule),
+		nsdname:  make(map[string]*rule),
+		nsdwild:  make(map[string]*rule),
+	}
+}
+
+// compile compiles the records of the policy zone origin into a policy.
+func compile(origin string, rrs []dns.RR) (*policy, error) {
+	p := newPolicy(origin)
+
+	for _, rr := range rrs {
+		owner := strings.ToLower(rr.Header().Name)
+
+		switch rr.Header().Rrtype {
+		case dns.TypeSOA:
+			if owner == origin {
+				p.soa = rr.(*dns.SOA)
+				p.serial = p.soa.Serial
+			}
+			continue
+		case dns.TypeNS:
+			if owner == origin {
+				continue
+			}
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3, dns.TypeDNSKEY:
+			continue
+		}
+
+		if !dns.IsSubDomain(origin, owner) || owner == origin {
+			continue
+		}
+		name := strings.TrimSuffix(owner, "."+origin)
+
+		var err error
+		switch {
+		case strings.HasSuffix(name, "."+clientIPLabel):
+			err = p.addIP(&p.clientIP, strings.TrimSuffix(name, "."+clientIPLabel), rr)
+		case strings.HasSuffix(name, "."+ipLabel):
+			err = p.addIP(&p.responseIP, strings.TrimSuffix(name, "."+ipLabel), rr)
+		case strings.HasSuffix(name, "."+nsdnameLabel):
+			p.addName(p.nsdname, p.nsdwild, strings.TrimSuffix(name, "."+nsdnameLabel), rr)
+		case strings.HasSuffix(name, "."+nsipLabel):
+			err = fmt.Errorf("nsip triggers are not supported: %s", owner)
+		default:
+			p.addName(p.qname, p.wildcard, name, rr)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.soa == nil {
+		return nil, fmt.Errorf("no SOA record for policy zone %s", origin)
+	}
+	return p, nil
+}
+
+// addName adds the rule of rr for name to exact, or to wild when name is a wildcard.
+func (p *policy) addName(exact, wild map[string]*rule, name string, rr dns.RR) {
+	m := exact
+	if strings.HasPrefix(name, "*.") {
+		m, name = wild, name[2:]
+	}
+	name = dns.Fqdn(name)
+
+	r, ok := m[name]
+	if !ok {
+		r = &rule{action: actionLocal}
+		m[name] = r
+		p.rules++
+	}
+	r.add(rr)
+}
+
+// addIP adds the rule of rr for the ip trigger name to rules.
+func (p *policy) addIP(rules *[]ipRule, name string, rr dns.RR) error {
+	ipnet, err := parseIPTrigger(name)
+	if err != nil {
+		return err
+	}
+	for _, ir := range *rules {
+		if ir.net.String() == ipnet.String() {
+			ir.rule.add(rr)
+			return nil
+		}
+	}
+	r := &rule{action: actionLocal}
+	r.add(rr)
+	*rules = append(*rules, ipRule{net: ipnet, rule: r})
+	p.rules++
+	return nil
+}
+
+// add adds rr to r. A CNAME that encodes an action sets the action, any other record is local
+// data.
+func (r *rule) add(rr dns.RR) {
+	if cname, ok := rr.(*dns.CNAME); ok {
+		switch strings.ToLower(cname.Target) {
+		case ".":
+			r.action = actionNXDOMAIN
+			return
+		case "*.":
+			r.action = actionNODATA
+			return
+		case passthruTarget:
+			r.action = actionPassthru
+			return
+		case dropTarget:
+			r.action = actionDrop
+			return
+		case tcpOnlyTarget:
+			r.action = actionTCPOnly
+			return
+		}
+	}
+	r.rrs = append(r.rrs, rr)
+}
+
+// parseIPTrigger parses the owner name of a client-ip or response-ip trigger, without the
+// rpz-client-ip or rpz-ip label, e.g. 24.0.2.0.192 or 128.1.zz.db8.2001.
+func parseIPTrigger(name string) (*net.IPNet, error) {
+	labels := dns.SplitDomainName(name)
+	if len(labels) < 2 {
+		return nil, fmt.Errorf("invalid ip trigger: %s", name)
+	}
+	bits, err := strconv.Atoi(labels[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix length in ip trigger: %s", name)
+	}
+
+	addr := labels[1:]
+	for i, j := 0, len(addr)-1; i < j; i, j = i+1, j-1 {
+		addr[i], addr[j] = addr[j], addr[i]
+	}
+
+	var ip net.IP
+	size := 8 * net.IPv6len
+	if isIPv4Trigger(addr) {
+		ip = net.ParseIP(strings.Join(addr, ".")).To4()
+		size = 8 * net.IPv4len
+	} else {
+		// zz stands for the longest run of zeros, i.e. the ::.
+		s := strings.Join(addr, ":")
+		switch {
+		case strings.HasPrefix(s, "zz:"):
+			s = ":" + s[2:]
+		case strings.HasSuffix(s, ":zz"):
+			s = s[:len(s)-2] + ":"
+		default:
+			s = strings.Replace(s, "zz", "", 1)
+		}
+		ip = net.ParseIP(s)
+	}
+	if ip == nil || bits < 0 || bits > size {
+		return nil, fmt.Errorf("invalid ip trigger: %s", name)
+	}
+	return &net.IPNet{IP: ip.Mask(net.CIDRMask(bits, size)), Mask: net.CIDRMask(bits, size)}, nil
+}
+
+// isIPv4Trigger reports whether the (already reversed) address labels of an ip trigger hold an
+// IPv4 address: exactly four decimal labels. An IPv6 trigger uses hex groups and may shorten its
+// longest run of zeros to zz, so it can have four labels too, e.g. 1.zz.db8.2001.
+func isIPv4Trigger(addr []string) bool {
+	if len(addr) != 4 {
+		return false
+	}
+	for _, l := range addr {
+		if l == "" {
+			return false
+		}
+		for _, c := range l {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchName returns the rule for name, an exact match takes precedence over a wildcard, and the
+// wildcard closest to name takes precedence over the ones above it.
+func matchName(exact, wild map[string]*rule, name string) *rule {
+	if r, ok := exact[name]; ok {
+		return r
+	}
+	if len(wild) == 0 {
+		return nil
+	}
+	for off, end := dns.NextLabel(name, 0); !end; off, end = dns.NextLabel(name, off) {
+		if r, ok := wild[name[off:]]; ok {
+			return r
+		}
+	}
+	return nil
+}
+
+// matchIP returns the rule with the longest prefix that contains ip.
+func matchIP(rules []ipRule, ip net.IP) *rule {
+	var (
+		match *rule
+		best  = -1
+	)
+	for _, ir := range rules {
+		if !ir.net.Contains(ip) {
+			continue
+		}
+		if ones, _ := ir.net.Mask.Size(); ones > best {
+			match, best = ir.rule, ones
+		}
+	}
+	return match
+}
+
+// matchQuery returns the rule that is triggered by the client's address or the query name.
+func (p *policy) matchQuery(client net.IP, qname string) (*rule, trigger) {
+	if client != nil {
+		if r := matchIP(p.clientIP, client); r != nil {
+			return r, triggerClientIP
+		}
+	}
+	if r := matchName(p.qname, p.wildcard, qname); r != nil {
+		return r, triggerQName
+	}
+	return nil, 0
+}
+
+// matchResponse returns the rule that is triggered by an address in the answer of resp or by
+// one of the name server names in ns.
+func (p *policy) matchResponse(resp *dns.Msg, ns []string) (*rule, trigger) {
+	if len(p.responseIP) > 0 {
+		for _, rr := range resp.Answer {
+			var ip net.IP
+			switch x := rr.(type) {
+			case *dns.A:
+				ip = x.A
+			case *dns.AAAA:
+				ip = x.AAAA
+			default:
+				continue
+			}
+			if r := matchIP(p.responseIP, ip); r != nil {
+				return r, triggerResponseIP
+			}
+		}
+	}
+	for _, n := range ns {
+		if r := matchName(p.nsdname, p.nsdwild, n); r != nil {
+			return r, triggerNSDName
+		}
+	}
+	return nil, 0
+}
+
+// needsResponse returns true when p has triggers that need the response.
+func (p *policy) needsResponse() bool { return len(p.responseIP) > 0 || p.needsNS() }
+
+// needsNS returns true when p has nsdname triggers.
+func (p *policy) needsNS() bool { return len(p.nsdname) > 0 || len(p.nsdwild) > 0 }
@@ -0,0 +1,184 @@
+package rpz
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/file"
+)
+
+const policyZone = `$TTL 300
+$ORIGIN rpz.example.
+@                         IN SOA ns.rpz.example. hostmaster.rpz.example. 2017061601 3600 600 86400 60
+@                         IN NS  ns.rpz.example.
+
+bad.example               CNAME .
+*.bad.example             CNAME .
+ok.bad.example            CNAME rpz-passthru.
+empty.example             CNAME *.
+drop.example              CNAME rpz-drop.
+tcp.example               CNAME rpz-tcp-only.
+local.example             A     10.0.0.1
+local.example             TXT   "local"
+alias.example             CNAME www.example.org.
+
+24.0.2.0.192.rpz-ip       CNAME .
+ns.evil.example.rpz-nsdname CNAME .
+*.evil.example.rpz-nsdname  CNAME .
+`
+
+func testPolicy(t *testing.T, origin, zone string) *policy {
+	z, err := file.Parse(strings.NewReader(zone), origin, "stdin")
+	if err != nil {
+		t.Fatalf("Expected no error when parsing zone, got %s", err)
+	}
+	p, err := compile(origin, z.All())
+	if err != nil {
+		t.Fatalf("Expected no error when compiling policy, got %s", err)
+	}
+	return p
+}
+
+func TestCompile(t *testing.T) {
+	p := testPolicy(t, "rpz.example.", policyZone)
+
+	if p.serial != 2017061601 {
+		t.Errorf("Expected serial %d, got %d", 2017061601, p.serial)
+	}
+	if p.rules != 11 {
+		t.Errorf("Expected %d rules, got %d", 11, p.rules)
+	}
+
+	tests := []struct {
+		qname    string
+		expected action
+		match    bool
+	}{
+		{"bad.example.", actionNXDOMAIN, true},
+		{"www.bad.example.", actionNXDOMAIN, true},
+		{"a.b.bad.example.", actionNXDOMAIN, true},
+		{"ok.bad.example.", actionPassthru, true},
+		{"www.ok.bad.example.", actionNXDOMAIN, true},
+		{"empty.example.", actionNODATA, true},
+		{"drop.example.", actionDrop, true},
+		{"tcp.example.", actionTCPOnly, true},
+		{"local.example.", actionLocal, true},
+		{"www.local.example.", 0, false},
+		{"example.", 0, false},
+	}
+	for i, tc := range tests {
+		r, tr := p.matchQuery(nil, tc.qname)
+		if (r != nil) != tc.match {
+			t.Errorf("Test %d: expected match to be %t for %s", i, tc.match, tc.qname)
+			continue
+		}
+		if r == nil {
+			continue
+		}
+		if tr != triggerQName {
+			t.Errorf("Test %d: expected trigger %s, got %s", i, triggerQName, tr)
+		}
+		if r.action != tc.expected {
+			t.Errorf("Test %d: expected action %s, got %s", i, tc.expected, r.action)
+		}
+	}
+
+	if r, _ := p.matchQuery(nil, "local.example."); len(r.rrs) != 2 {
+		t.Errorf("Expected 2 records of local data, got %d", len(r.rrs))
+	}
+	if r := matchName(p.nsdname, p.nsdwild, "a.ns.evil.example."); r == nil || r.action != actionNXDOMAIN {
+		t.Errorf("Expected nsdname wildcard to match a.ns.evil.example.")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	const nsip = `$ORIGIN rpz.example.
+@ IN SOA ns.rpz.example. hostmaster.rpz.example. 1 3600 600 86400 60
+32.1.0.0.10.rpz-nsip CNAME .
+`
+	z, err := file.Parse(strings.NewReader(nsip), "rpz.example.", "stdin")
+	if err != nil {
+		t.Fatalf("Expected no error when parsing zone, got %s", err)
+	}
+	if _, err := compile("rpz.example.", z.All()); err == nil {
+		t.Errorf("Expected error for nsip trigger")
+	}
+}
+
+func TestParseIPTrigger(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  string
+		shouldErr bool
+	}{
+		{"32.1.0.0.10", "10.0.0.1/32", false},
+		{"24.0.2.0.192", "192.0.2.0/24", false},
+		{"8.0.0.0.10", "10.0.0.0/8", false},
+		{"128.1.zz.db8.2001", "2001:db8::1/128", false},
+		{"48.zz.122.db8.2001", "2001:db8:122::/48", false},
+		{"128.1.0.0.0.0.0.db8.2001", "2001:db8::1/128", false},
+		{"33.1.0.0.10", "", true},
+		{"foo.1.0.0.10", "", true},
+		{"32", "", true},
+		{"32.1.0.0.300", "", true},
+	}
+	for i, tc := range tests {
+		ipnet, err := parseIPTrigger(tc.name)
+		if err == nil && tc.shouldErr {
+			t.Errorf("Test %d: expected error for %s", i, tc.name)
+			continue
+		} else if err != nil && !tc.shouldErr {
+			t.Errorf("Test %d: expected no error for %s, got %s", i, tc.name, err)
+			continue
+		}
+		if tc.shouldErr {
+			continue
+		}
+		if ipnet.String() != tc.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.expected, ipnet)
+		}
+	}
+}
+
+func TestMatchIP(t *testing.T) {
+	_, n8, _ := net.ParseCIDR("10.0.0.0/8")
+	_, n24, _ := net.ParseCIDR("10.0.0.0/24")
+	r8, r24 := &rule{action: actionNXDOMAIN}, &rule{action: actionPassthru}
+	rules := []ipRule{{n8, r8}, {n24, r24}}
+
+	if r := matchIP(rules, net.ParseIP("10.0.0.1")); r != r24 {
+		t.Errorf("Expected the longest prefix to match 10.0.0.1")
+	}
+	if r := matchIP(rules, net.ParseIP("10.1.0.1")); r != r8 {
+		t.Errorf("Expected 10.0.0.0/8 to match 10.1.0.1")
+	}
+	if r := matchIP(rules, net.ParseIP("192.0.2.1")); r != nil {
+		t.Errorf("Expected no match for 192.0.2.1")
+	}
+}
+
+func TestParseBlocklist(t *testing.T) {
+	const blocklist = `# comment
+ads.example
+0.0.0.0 tracker.example  # trailing comment
+127.0.0.1 localhost
+127.0.0.1 Malware.Example other.example
+::1 ip6-localhost
+`
+	p := parseBlocklist(strings.NewReader(blocklist), "blocklist", actionNODATA)
+	if p.rules != 4 {
+		t.Errorf("Expected 4 rules, got %d", p.rules)
+	}
+	for _, name := range []string{"ads.example.", "www.ads.example.", "tracker.example.", "malware.example.", "other.example."} {
+		r, _ := p.matchQuery(nil, name)
+		if r == nil || r.action != actionNODATA {
+			t.Errorf("Expected %s to be blocked", name)
+		}
+	}
+	for _, name := range []string{"localhost.", "ip6-localhost.", "example."} {
+		if r, _ := p.matchQuery(nil, name); r != nil {
+			t.Errorf("Expected %s not to be blocked", name)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+// Package rpz implements response policy zones and blocklists.
+package rpz
+
+import (
+	"log"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/nonwriter"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// RPZ is a middleware that applies response policies to queries and their responses.
+type RPZ struct {
+	Next     middleware.Handler
+	Zones    []string
+	Policies []*Policy // in order of precedence
+}
+
+// Policy is a policy zone or blocklist.
+type Policy struct {
+	src source
+
+	sync.RWMutex
+	p      *policy
+	loaded bool
+}
+
+// newPolicyFrom returns a Policy that loads its rules from src. The policy is empty until
+// Reload is called.
+func newPolicyFrom(src source) *Policy {
+	return &Policy{src: src, p: newPolicy(src.name())}
+}
+
+// Reload loads the policy when it has changed.
+func (p *Policy) Reload() error {
+	var cur *policy
+	p.RLock()
+	if p.loaded {
+		cur = p.p
+	}
+	p.RUnlock()
+
+	np, err := p.src.load(cur)
+	if err != nil {
+		return err
+	}
+	if np == nil {
+		return nil
+	}
+
+	p.Lock()
+	p.p, p.loaded = np, true
+	p.Unlock()
+
+	RuleCount.WithLabelValues(np.name).Set(float64(np.rules))
+	log.Printf("[INFO] Loaded policy `%s' with %d rules (serial %d)", np.name, np.rules, np.serial)
+	return nil
+}
+
+func (p *Policy) policy() *policy {
+	p.RLock()
+	defer p.RUnlock()
+	return p.p
+}
+
+// ServeDNS implements the middleware.Handler interface.
+func (rp RPZ) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if middleware.Zones(rp.Zones).Matches(state.Name()) == "" {
+		return middleware.NextOrFailure(rp.Name(), rp.Next, ctx, w, r)
+	}
+
+	policies := make([]*policy, len(rp.Policies))
+	for i := range rp.Policies {
+		policies[i] = rp.Policies[i].policy()
+	}
+
+	// The first policy with a matching rule wins. The rules for the client's address and the
+	// query name are checked first, the response is only needed when a policy before the one
+	// that matched has rules that need it.
+	client := net.ParseIP(state.IP())
+	var (
+		hit *policy
+		rl  *rule
+		tr  trigger
+	)
+	for i, p := range policies {
+		if rl, tr = p.matchQuery(client, state.Name()); rl != nil {
+			hit = p
+			policies = policies[:i]
+			break
+		}
+	}
+
+	needsResponse := false
+	for _, p := range policies {
+		needsResponse = needsResponse || p.needsResponse()
+	}
+	if !needsResponse {
+		if hit == nil {
+			return middleware.NextOrFailure(rp.Name(), rp.Next, ctx, w, r)
+		}
+		return rp.apply(ctx, state, hit, rl, tr, nil)
+	}
+
+	nw := nonwriter.New(w)
+	rc, err := middleware.NextOrFailure(rp.Name(), rp.Next, ctx, nw, r)
+	if err != nil || nw.Msg == nil {
+		// Nothing has been written, the server will write the error.
+		return rc, err
+	}
+
+	var ns []string
+	for _, p := range policies {
+		if p.needsNS() && ns == nil {
+			ns = rp.nameservers(ctx, state, nw.Msg)
+		}
+		if rl, tr := p.matchResponse(nw.Msg, ns); rl != nil {
+			return rp.apply(ctx, state, p, rl, tr, nw.Msg)
+		}
+	}
+	if hit != nil {
+		return rp.apply(ctx, state, hit, rl, tr, nw.Msg)
+	}
+
+	w.WriteMsg(nw.Msg)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the middleware.Handler interface.
+func (rp RPZ) Name() string { return "rpz" }
+
+// apply applies the action of rl to the query in state. resp is the response from the rest
+// of the chain, it is nil when it has not been retrieved yet.
+func (rp RPZ) apply(ctx context.Context, state request.Request, p *policy, rl *rule, tr trigger, resp *dns.Msg) (int, error) {
+	HitCount.WithLabelValues(p.name, tr.String(), rl.action.String()).Inc()
+
+	switch rl.action {
+	case actionPassthru:
+		return rp.passthru(ctx, state, resp)
+
+	case actionDrop:
+		// Nothing is written and the server must not write either.
+		return dns.RcodeSuccess, nil
+
+	case actionTCPOnly:
+		if state.Proto() != "udp" {
+			return rp.passthru(ctx, state, resp)
+		}
+		m := new(dns.Msg)
+		m.SetReply(state.Req)
+		m.Truncated = true
+		state.SizeAndDo(m)
+		state.W.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+
+	case actionNXDOMAIN:
+		return rp.write(state, p.negative(state.Req, dns.RcodeNameError))
+
+	case actionNODATA:
+		return rp.write(state, p.negative(state.Req, dns.RcodeSuccess))
+	}
+
+	return rp.write(state, rp.localData(ctx, state, p, rl))
+}
+
+// passthru writes resp, or when it is nil, passes the query to the next middleware.
+func (rp RPZ) passthru(ctx context.Context, state request.Request, resp *dns.Msg) (int, error) {
+	if resp == nil {
+		return middleware.NextOrFailure(rp.Name(), rp.Next, ctx, state.W, state.Req)
+	}
+	state.W.WriteMsg(resp)
+	return dns.RcodeSuccess, nil
+}
+
+func (rp RPZ) write(state request.Request, m *dns.Msg) (int, error) {
+	state.SizeAndDo(m)
+	m, _ = state.Scrub(m)
+	state.W.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// negative returns a NXDOMAIN or NODATA response for r, with the SOA record of the policy zone.
+func (p *policy) negative(r *dns.Msg, rcode int) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetRcode(r, rcode)
+	m.RecursionAvailable = true
+	if p.soa != nil {
+		m.Ns = []dns.RR{dns.Copy(p.soa)}
+	}
+	return m
+}
+
+// localData returns the response made from the local data of rl. A CNAME is followed by
+// looking up the target with the rest of the chain.
+func (rp RPZ) localData(ctx context.Context, state request.Request, p *policy, rl *rule) *dns.Msg {
+	qtype := state.QType()
+
+	var (
+		answer []dns.RR
+		cname  *dns.CNAME
+	)
+	for _, rr := range rl.rrs {
+		rr = dns.Copy(rr)
+		rr.Header().Name = state.QName()
+		if rr.Header().Rrtype == qtype || qtype == dns.TypeANY {
+			answer = append(answer, rr)
+			continue
+		}
+		if c, ok := rr.(*dns.CNAME); ok {
+			cname = c
+		}
+	}
+
+	if len(answer) == 0 && cname == nil {
+		return p.negative(state.Req, dns.RcodeSuccess)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.RecursionAvailable, m.Compress = true, true
+	m.Answer = answer
+
+	if len(answer) == 0 {
+		m.Answer = []dns.RR{cname}
+		if resp := rp.lookup(ctx, state, cname.Target, qtype); resp != nil {
+			m.Rcode = resp.Rcode
+			m.Answer = append(m.Answer, resp.Answer...)
+		}
+	}
+	return m
+}
+
+// nameservers returns the names of the name servers of the zone of the query in state, as found
+// in resp or, if there are none, by looking them up with the rest of the chain.
+func (rp RPZ) nameservers(ctx context.Context, state request.Request, resp *dns.Msg) []string {
+	ns := nsTargets(resp.Answer, resp.Ns)
+	if len(ns) > 0 {
+		return ns
+	}
+
+	zone := state.Name()
+	for _, rr := range resp.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			zone = soa.Hdr.Name
+			break
+		}
+	}
+	for off, end := 0, false; !end && zone[off:] != "."; off, end = dns.NextLabel(zone, off) {
+		if m := rp.lookup(ctx, state, zone[off:], dns.TypeNS); m != nil {
+			if ns := nsTargets(m.Answer); len(ns) > 0 {
+				return ns
+			}
+		}
+	}
+	// Nothing found, return an empty, but non-nil, slice to prevent looking again.
+	return []string{}
+}
+
+func nsTargets(sections ...[]dns.RR) []string {
+	var ns []string
+	for _, rrs := range sections {
+		for _, rr := range rrs {
+			if n, ok := rr.(*dns.NS); ok {
+				ns = append(ns, strings.ToLower(dns.Fqdn(n.Ns)))
+			}
+		}
+	}
+	return ns
+}
+
+// lookup looks up name and typ with the rest of the chain.
+func (rp RPZ) lookup(ctx context.Context, state request.Request, name string, typ uint16) *dns.Msg {
+	req := state.Req.Copy()
+	req.Question[0].Name = name
+	req.Question[0].Qtype = typ
+
+	nw := nonwriter.New(state.W)
+	if _, err := middleware.NextOrFailure(rp.Name(), rp.Next, ctx, nw, req); err != nil {
+		return nil
+	}
+	return nw.Msg
+}
@@ -0,0 +1,237 @@
+package rpz
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// backend is the rest of the chain.
+func backend(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	q := r.Question[0]
+	switch {
+	case q.Name == "www.example.org." && q.Qtype == dns.TypeA:
+		m.Answer = []dns.RR{test.A("www.example.org. 300 IN A 10.1.1.1")}
+	case q.Name == "resp.example." && q.Qtype == dns.TypeA:
+		m.Answer = []dns.RR{test.A("resp.example. 300 IN A 192.0.2.5")}
+	case q.Name == "evilns.example." && q.Qtype == dns.TypeA:
+		m.Answer = []dns.RR{test.A("evilns.example. 300 IN A 10.2.2.2")}
+		m.Ns = []dns.RR{test.NS("evilns.example. 300 IN NS ns.evil.example.")}
+	case q.Name == "fine.example." && q.Qtype == dns.TypeA:
+		m.Answer = []dns.RR{test.A("fine.example. 300 IN A 10.3.3.3")}
+	case q.Name == "fine.example." && q.Qtype == dns.TypeNS:
+		m.Answer = []dns.RR{test.NS("fine.example. 300 IN NS ns.good.example.")}
+	case q.Name == "lookedup.example." && q.Qtype == dns.TypeA:
+		m.Answer = []dns.RR{test.A("lookedup.example. 300 IN A 10.4.4.4")}
+	case q.Name == "lookedup.example." && q.Qtype == dns.TypeNS:
+		m.Answer = []dns.RR{test.NS("lookedup.example. 300 IN NS a.ns.evil.example.")}
+	default:
+		m.Rcode = dns.RcodeNameError
+	}
+	w.WriteMsg(m)
+	return m.Rcode, nil
+}
+
+func testRPZ(policies ...*policy) RPZ {
+	rp := RPZ{Next: test.HandlerFunc(backend), Zones: []string{"."}}
+	for _, p := range policies {
+		rp.Policies = append(rp.Policies, &Policy{p: p, loaded: true})
+	}
+	return rp
+}
+
+const soa = "rpz.example. 300 IN SOA ns.rpz.example. hostmaster.rpz.example. 2017061601 3600 600 86400 60"
+
+var rpzTestCases = []test.Case{
+	{
+		Qname: "bad.example.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+		Ns:    []dns.RR{test.SOA(soa)},
+	},
+	{
+		Qname: "www.bad.example.", Qtype: dns.TypeAAAA,
+		Rcode: dns.RcodeNameError,
+		Ns:    []dns.RR{test.SOA(soa)},
+	},
+	{
+		// Passthru, the backend answers with NXDOMAIN.
+		Qname: "ok.bad.example.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+	},
+	{
+		Qname: "empty.example.", Qtype: dns.TypeA,
+		Ns: []dns.RR{test.SOA(soa)},
+	},
+	{
+		Qname: "local.example.", Qtype: dns.TypeA,
+		Answer: []dns.RR{test.A("local.example. 300 IN A 10.0.0.1")},
+	},
+	{
+		Qname: "local.example.", Qtype: dns.TypeTXT,
+		Answer: []dns.RR{test.TXT(`local.example. 300 IN TXT "local"`)},
+	},
+	{
+		Qname: "local.example.", Qtype: dns.TypeMX,
+		Ns: []dns.RR{test.SOA(soa)},
+	},
+	{
+		Qname: "alias.example.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.CNAME("alias.example. 300 IN CNAME www.example.org."),
+			test.A("www.example.org. 300 IN A 10.1.1.1"),
+		},
+	},
+	{
+		// Response IP trigger.
+		Qname: "resp.example.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+		Ns:    []dns.RR{test.SOA(soa)},
+	},
+	{
+		// NSDNAME trigger, from the authority section.
+		Qname: "evilns.example.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+		Ns:    []dns.RR{test.SOA(soa)},
+	},
+	{
+		// NSDNAME trigger, from a lookup of the NS records.
+		Qname: "lookedup.example.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+		Ns:    []dns.RR{test.SOA(soa)},
+	},
+	{
+		Qname: "fine.example.", Qtype: dns.TypeA,
+		Answer: []dns.RR{test.A("fine.example. 300 IN A 10.3.3.3")},
+	},
+}
+
+func TestRPZ(t *testing.T) {
+	rp := testRPZ(testPolicy(t, "rpz.example.", policyZone))
+	ctx := context.TODO()
+
+	for _, tc := range rpzTestCases {
+		m := tc.Msg()
+
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		_, err := rp.ServeDNS(ctx, rec, m)
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+			continue
+		}
+
+		resp := rec.Msg
+		if resp == nil {
+			t.Errorf("Expected a response for %s", tc.Qname)
+			continue
+		}
+		if !test.Header(t, tc, resp) {
+			t.Logf("%v\n", resp)
+			continue
+		}
+		if !test.Section(t, tc, test.Answer, resp.Answer) {
+			t.Logf("%v\n", resp)
+		}
+		if !test.Section(t, tc, test.Ns, resp.Ns) {
+			t.Logf("%v\n", resp)
+		}
+	}
+}
+
+func TestRPZDropAndTCPOnly(t *testing.T) {
+	rp := testRPZ(testPolicy(t, "rpz.example.", policyZone))
+	ctx := context.TODO()
+
+	m := new(dns.Msg)
+	m.SetQuestion("drop.example.", dns.TypeA)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	rcode, _ := rp.ServeDNS(ctx, rec, m)
+	if rec.Msg != nil {
+		t.Errorf("Expected no response for a dropped query, got %v", rec.Msg)
+	}
+	if rcode != dns.RcodeSuccess {
+		t.Errorf("Expected rcode %d for a dropped query, got %d", dns.RcodeSuccess, rcode)
+	}
+
+	m.SetQuestion("tcp.example.", dns.TypeA)
+	rec = dnsrecorder.New(&test.ResponseWriter{})
+	rp.ServeDNS(ctx, rec, m)
+	if rec.Msg == nil || !rec.Msg.Truncated {
+		t.Errorf("Expected a truncated response for a tcp-only query over UDP, got %v", rec.Msg)
+	}
+}
+
+func TestRPZClientIP(t *testing.T) {
+	// The test.ResponseWriter's remote address is 10.240.0.1.
+	const zone = `$ORIGIN rpz.example.
+@                           IN SOA ns.rpz.example. hostmaster.rpz.example. 1 3600 600 86400 60
+16.0.0.240.10.rpz-client-ip CNAME .
+32.1.0.240.10.rpz-client-ip CNAME rpz-passthru.
+32.2.0.240.10.rpz-client-ip CNAME .
+`
+	rp := testRPZ(testPolicy(t, "rpz.example.", zone))
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	rp.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg == nil || rec.Msg.Rcode != dns.RcodeSuccess || len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected the most specific client-ip rule (passthru) to be applied, got %v", rec.Msg)
+	}
+}
+
+func TestRPZPrecedence(t *testing.T) {
+	const qnameZone = `$ORIGIN qname.example.
+@                     IN SOA ns.qname.example. hostmaster.qname.example. 1 3600 600 86400 60
+www.example.org       CNAME *.
+`
+	const respZone = `$ORIGIN resp.example.
+@                     IN SOA ns.resp.example. hostmaster.resp.example. 1 3600 600 86400 60
+16.0.1.10.rpz-ip      CNAME .
+`
+	qname := testPolicy(t, "qname.example.", qnameZone)
+	resp := testPolicy(t, "resp.example.", respZone)
+	blocklist := parseBlocklist(strings.NewReader("example.org\n"), "blocklist", actionNXDOMAIN)
+
+	tests := []struct {
+		policies []*policy
+		rcode    int
+	}{
+		// The qname rule matches first.
+		{[]*policy{qname, resp}, dns.RcodeSuccess},
+		// The response-ip rule is in an earlier policy, so it takes precedence over the qname rule.
+		{[]*policy{resp, qname}, dns.RcodeNameError},
+		{[]*policy{blocklist, qname}, dns.RcodeNameError},
+		{[]*policy{qname, blocklist}, dns.RcodeSuccess},
+	}
+
+	for i, tc := range tests {
+		rp := testRPZ(tc.policies...)
+
+		m := new(dns.Msg)
+		m.SetQuestion("www.example.org.", dns.TypeA)
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		rcode, _ := rp.ServeDNS(context.TODO(), rec, m)
+		if rec.Msg == nil {
+			t.Errorf("Test %d: expected a response", i)
+			continue
+		}
+		// The response has been written, also for NXDOMAIN the server must not write another one.
+		if rcode != dns.RcodeSuccess {
+			t.Errorf("Test %d: expected rcode %d to be returned, got %d", i, dns.RcodeSuccess, rcode)
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rec.Msg.Rcode)
+		}
+		if len(rec.Msg.Answer) != 0 {
+			t.Errorf("Test %d: expected no answer, got %v", i, rec.Msg.Answer)
+		}
+	}
+}
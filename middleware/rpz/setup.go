@@ -0,0 +1,184 @@
+package rpz
+
+import (
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/dnsutil"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("rpz", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	rp, reload, err := rpzParse(c)
+	if err != nil {
+		return middleware.Error("rpz", err)
+	}
+
+	// Policies from files are loaded now, so errors in them are caught early. Zone transfers
+	// are done when the server starts.
+	for _, p := range rp.Policies {
+		if _, ok := p.src.(*transferSource); ok {
+			continue
+		}
+		if err := p.Reload(); err != nil {
+			return middleware.Error("rpz", err)
+		}
+	}
+
+	stop := make(chan bool)
+
+	c.OnStartup(func() error {
+		for _, p := range rp.Policies {
+			if _, ok := p.src.(*transferSource); ok {
+				go func(p *Policy) {
+					if err := p.Reload(); err != nil {
+						log.Printf("[ERROR] Failed to load policy `%s': %s", p.src.name(), err)
+					}
+				}(p)
+			}
+		}
+		if reload == 0 {
+			return nil
+		}
+
+		go func() {
+			ticker := time.NewTicker(reload)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					for _, p := range rp.Policies {
+						if err := p.Reload(); err != nil {
+							log.Printf("[ERROR] Failed to reload policy `%s': %s", p.src.name(), err)
+						}
+					}
+				}
+			}
+		}()
+		return nil
+	})
+
+	c.OnShutdown(func() error {
+		close(stop)
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
+		rp.Next = next
+		return rp
+	})
+
+	return nil
+}
+
+func rpzParse(c *caddy.Controller) (RPZ, time.Duration, error) {
+	rp := RPZ{}
+	reload := defaultReload
+
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return rp, 0, c.Err("rpz can only be specified once per server block")
+		}
+		i++
+
+		rp.Zones = c.RemainingArgs()
+		if len(rp.Zones) == 0 {
+			rp.Zones = make([]string, len(c.ServerBlockKeys))
+			copy(rp.Zones, c.ServerBlockKeys)
+		}
+		for j := range rp.Zones {
+			rp.Zones[j] = middleware.Host(rp.Zones[j]).Normalize()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "file":
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return rp, 0, c.ArgErr()
+				}
+				origin, fileName := middleware.Name(args[0]).Normalize(), args[1]
+				if !path.IsAbs(fileName) && config.Root != "" {
+					fileName = path.Join(config.Root, fileName)
+				}
+				rp.Policies = append(rp.Policies, newPolicyFrom(&fileSource{origin: origin, file: fileName}))
+
+			case "transfer":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return rp, 0, c.ArgErr()
+				}
+				masters, err := dnsutil.ParseHostPortOrFile(args[1:]...)
+				if err != nil {
+					return rp, 0, err
+				}
+				origin := middleware.Name(args[0]).Normalize()
+				rp.Policies = append(rp.Policies, newPolicyFrom(&transferSource{origin: origin, masters: masters}))
+
+			case "blocklist":
+				args := c.RemainingArgs()
+				if len(args) < 1 || len(args) > 2 {
+					return rp, 0, c.ArgErr()
+				}
+				fileName := args[0]
+				if !path.IsAbs(fileName) && config.Root != "" {
+					fileName = path.Join(config.Root, fileName)
+				}
+				a := actionNXDOMAIN
+				if len(args) == 2 {
+					var ok bool
+					if a, ok = blocklistActions[strings.ToLower(args[1])]; !ok {
+						return rp, 0, c.Errf("unknown blocklist action '%s'", args[1])
+					}
+				}
+				rp.Policies = append(rp.Policies, newPolicyFrom(&blocklistSource{file: fileName, action: a}))
+
+			case "reload":
+				if !c.NextArg() {
+					return rp, 0, c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil || d < 0 {
+					return rp, 0, c.Errf("invalid reload duration: %q", c.Val())
+				}
+				reload = d
+				if c.NextArg() {
+					return rp, 0, c.ArgErr()
+				}
+
+			default:
+				return rp, 0, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if len(rp.Policies) == 0 {
+		return rp, 0, c.Err("rpz needs at least one policy zone or blocklist")
+	}
+	return rp, reload, nil
+}
+
+var blocklistActions = map[string]action{
+	"nxdomain": actionNXDOMAIN,
+	"nodata":   actionNODATA,
+	"drop":     actionDrop,
+}
+
+const defaultReload = time.Minute
@@ -0,0 +1,88 @@
+package rpz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupRPZ(t *testing.T) {
+	tests := []struct {
+		input          string
+		shouldErr      bool
+		expectedZones  []string
+		expectedNames  []string
+		expectedReload time.Duration
+	}{
+		{`rpz {
+			file rpz.example db.rpz.example
+		}`, false, nil, []string{"rpz.example."}, defaultReload},
+		{`rpz example.org {
+			transfer rpz.example 10.0.0.1
+			blocklist /etc/blocklist nodata
+			reload 10s
+		}`, false, []string{"example.org."}, []string{"rpz.example.", "/etc/blocklist"}, 10 * time.Second},
+		{`rpz {
+			blocklist /etc/blocklist
+			file rpz.example db.rpz.example
+			reload 0
+		}`, false, nil, []string{"/etc/blocklist", "rpz.example."}, 0},
+		// fails
+		{`rpz`, true, nil, nil, 0},
+		{`rpz {
+			file rpz.example
+		}`, true, nil, nil, 0},
+		{`rpz {
+			transfer rpz.example
+		}`, true, nil, nil, 0},
+		{`rpz {
+			blocklist /etc/blocklist passthru
+		}`, true, nil, nil, 0},
+		{`rpz {
+			blocklist /etc/blocklist
+			reload -1s
+		}`, true, nil, nil, 0},
+		{`rpz {
+			blocklist /etc/blocklist
+			unknown
+		}`, true, nil, nil, 0},
+		{`rpz {
+			blocklist /etc/blocklist
+		}
+		rpz {
+			blocklist /etc/blocklist
+		}`, true, nil, nil, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		rp, reload, err := rpzParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+
+		if test.expectedZones != nil {
+			if len(rp.Zones) != len(test.expectedZones) || rp.Zones[0] != test.expectedZones[0] {
+				t.Errorf("Test %d expected zones %v, got %v", i, test.expectedZones, rp.Zones)
+			}
+		}
+		if len(rp.Policies) != len(test.expectedNames) {
+			t.Fatalf("Test %d expected %d policies, got %d", i, len(test.expectedNames), len(rp.Policies))
+		}
+		for j, p := range rp.Policies {
+			if p.src.name() != test.expectedNames[j] {
+				t.Errorf("Test %d expected policy %s, got %s", i, test.expectedNames[j], p.src.name())
+			}
+		}
+		if reload != test.expectedReload {
+			t.Errorf("Test %d expected reload of %s, got %s", i, test.expectedReload, reload)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package rpz
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/middleware/file"
+
+	"github.com/miekg/dns"
+)
+
+// source loads a policy. When the policy has not changed since cur was loaded, load returns nil.
+type source interface {
+	name() string
+	load(cur *policy) (*policy, error)
+}
+
+// fileSource loads a policy zone from a zone file.
+type fileSource struct {
+	origin string
+	file   string
+	stamp  fileStamp
+}
+
+func (s *fileSource) name() string { return s.origin }
+
+func (s *fileSource) load(cur *policy) (*policy, error) {
+	stamp, err := stat(s.file)
+	if err != nil {
+		return nil, err
+	}
+	if cur != nil && stamp == s.stamp {
+		return nil, nil
+	}
+
+	reader, err := os.Open(s.file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	z, err := file.Parse(reader, s.origin, s.file)
+	if err != nil {
+		return nil, err
+	}
+	s.stamp = stamp
+	if z.Apex.SOA == nil {
+		return nil, fmt.Errorf("no SOA record for policy zone %s", s.origin)
+	}
+	if cur != nil && cur.serial == z.Apex.SOA.Serial {
+		return nil, nil
+	}
+	return compile(s.origin, z.All())
+}
+
+// transferSource loads a policy zone with a zone transfer from its primaries.
+type transferSource struct {
+	origin  string
+	masters []string
+}
+
+func (s *transferSource) name() string { return s.origin }
+
+func (s *transferSource) load(cur *policy) (*policy, error) {
+	if cur != nil && cur.soa != nil {
+		serial, err := s.serial()
+		if err != nil {
+			return nil, err
+		}
+		if serial == cur.serial {
+			return nil, nil
+		}
+	}
+
+	z := file.NewZone(s.origin, "")
+	z.TransferFrom = s.masters
+	if err := z.TransferIn(); err != nil {
+		return nil, err
+	}
+	if z.Apex.SOA == nil {
+		return nil, fmt.Errorf("no SOA record for policy zone %s", s.origin)
+	}
+	return compile(s.origin, z.All())
+}
+
+// serial retrieves the serial of the zone from the first primary that answers.
+func (s *transferSource) serial() (uint32, error) {
+	c := new(dns.Client)
+	c.Net = "tcp" // do this query over TCP to minimize spoofing
+	m := new(dns.Msg)
+	m.SetQuestion(s.origin, dns.TypeSOA)
+
+	var Err error
+	for _, tr := range s.masters {
+		ret, _, err := c.Exchange(m, tr)
+		if err != nil {
+			Err = err
+			continue
+		}
+		for _, rr := range ret.Answer {
+			if soa, ok := rr.(*dns.SOA); ok {
+				return soa.Serial, nil
+			}
+		}
+	}
+	if Err == nil {
+		Err = fmt.Errorf("no SOA record for policy zone %s", s.origin)
+	}
+	return 0, Err
+}
+
+// blocklistSource loads a blocklist, a list of domain names, optionally prefixed with an address
+// as in a hosts file. The names and all names below them trigger the action.
+type blocklistSource struct {
+	file   string
+	action action
+	stamp  fileStamp
+}
+
+func (s *blocklistSource) name() string { return s.file }
+
+func (s *blocklistSource) load(cur *policy) (*policy, error) {
+	stamp, err := stat(s.file)
+	if err != nil {
+		return nil, err
+	}
+	if cur != nil && stamp == s.stamp {
+		return nil, nil
+	}
+
+	reader, err := os.Open(s.file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	p := parseBlocklist(reader, s.file, s.action)
+	s.stamp = stamp
+	return p, nil
+}
+
+// parseBlocklist parses the blocklist in r.
+func parseBlocklist(r io.Reader, name string, a action) *policy {
+	p := newPolicy(name)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		// Hosts file format, the address is ignored.
+		if net.ParseIP(fields[0]) != nil {
+			fields = fields[1:]
+		}
+		for _, f := range fields {
+			n := strings.ToLower(dns.Fqdn(f))
+			if _, ok := dns.IsDomainName(n); !ok || ignoredNames[n] {
+				continue
+			}
+			if _, ok := p.qname[n]; ok {
+				continue
+			}
+			r := &rule{action: a}
+			p.qname[n] = r
+			p.wildcard[n] = r
+			p.rules++
+		}
+	}
+	return p
+}
+
+// ignoredNames are names found in hosts files that should never be blocked.
+var ignoredNames = map[string]bool{
+	".":                      true,
+	"localhost.":             true,
+	"localhost.localdomain.": true,
+	"local.":                 true,
+	"broadcasthost.":         true,
+	"ip6-localhost.":         true,
+	"ip6-loopback.":          true,
+}
+
+type fileStamp struct {
+	mtime time.Time
+	size  int64
+}
+
+func stat(name string) (fileStamp, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{mtime: fi.ModTime(), size: fi.Size()}, nil
+}
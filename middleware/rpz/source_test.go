@@ -0,0 +1,98 @@
+package rpz
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileSourceReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "db.rpz.example")
+	if err := ioutil.WriteFile(name, []byte(policyZone), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := newPolicyFrom(&fileSource{origin: "rpz.example.", file: name})
+	if err := p.Reload(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if r, _ := p.policy().matchQuery(nil, "bad.example."); r == nil {
+		t.Fatalf("Expected bad.example. to match")
+	}
+
+	// Same serial, new content: not loaded.
+	changed := strings.Replace(policyZone, "bad.example ", "worse.example ", 1)
+	if err := ioutil.WriteFile(name, []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, name, time.Now().Add(1*time.Minute))
+	if err := p.Reload(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if r, _ := p.policy().matchQuery(nil, "worse.example."); r != nil {
+		t.Errorf("Expected policy not to be reloaded when the serial didn't change")
+	}
+
+	// New serial: loaded.
+	changed = strings.Replace(changed, "2017061601", "2017061602", 1)
+	if err := ioutil.WriteFile(name, []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, name, time.Now().Add(2*time.Minute))
+	if err := p.Reload(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if r, _ := p.policy().matchQuery(nil, "worse.example."); r == nil {
+		t.Errorf("Expected policy to be reloaded when the serial changed")
+	}
+	if p.policy().serial != 2017061602 {
+		t.Errorf("Expected serial %d, got %d", 2017061602, p.policy().serial)
+	}
+}
+
+func TestBlocklistSourceReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "blocklist")
+	if err := ioutil.WriteFile(name, []byte("ads.example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := newPolicyFrom(&blocklistSource{file: name, action: actionNXDOMAIN})
+	if err := p.Reload(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if r, _ := p.policy().matchQuery(nil, "ads.example."); r == nil {
+		t.Fatalf("Expected ads.example. to match")
+	}
+
+	if err := ioutil.WriteFile(name, []byte("ads.example\ntracker.example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, name, time.Now().Add(1*time.Minute))
+	if err := p.Reload(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if r, _ := p.policy().matchQuery(nil, "tracker.example."); r == nil {
+		t.Errorf("Expected tracker.example. to match after reload")
+	}
+}
+
+func touch(t *testing.T, name string, mtime time.Time) {
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,138 @@
+package template
+
+import (
+	"regexp"
+	"strings"
+	gotmpl "text/template"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/middleware"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func init() {
+	caddy.RegisterPlugin("template", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setupTemplate,
+	})
+}
+
+func setupTemplate(c *caddy.Controller) error {
+	templates, err := templateParse(c)
+	if err != nil {
+		return middleware.Error("template", err)
+	}
+
+	dnsserver.GetConfig(c).AddMiddleware(func(next middleware.Handler) middleware.Handler {
+		return Handler{Next: next, Templates: templates}
+	})
+
+	return nil
+}
+
+func templateParse(c *caddy.Controller) ([]template, error) {
+	var templates []template
+
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) < 2 {
+			return nil, c.ArgErr()
+		}
+
+		t := template{rcode: dns.RcodeSuccess}
+
+		class, ok := dns.StringToClass[strings.ToUpper(args[0])]
+		if !ok {
+			return nil, c.Errf("invalid query class %q", args[0])
+		}
+		t.qclass = class
+
+		qtype, ok := dns.StringToType[strings.ToUpper(args[1])]
+		if !ok {
+			return nil, c.Errf("invalid query type %q", args[1])
+		}
+		t.qtype = qtype
+
+		// Zones default to the zones of the server block.
+		t.zones = args[2:]
+		if len(t.zones) == 0 {
+			t.zones = make([]string, len(c.ServerBlockKeys))
+			for i := range c.ServerBlockKeys {
+				t.zones[i] = middleware.Host(c.ServerBlockKeys[i]).Normalize()
+			}
+		} else {
+			middleware.Zones(t.zones).Normalize()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "match":
+				regexps := c.RemainingArgs()
+				if len(regexps) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, r := range regexps {
+					regex, err := regexp.Compile(r)
+					if err != nil {
+						return nil, c.Errf("could not parse regex %q: %v", r, err)
+					}
+					t.regex = append(t.regex, regex)
+				}
+
+			case "answer", "additional", "authority":
+				section := c.Val()
+				rrs := c.RemainingArgs()
+				if len(rrs) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, rr := range rrs {
+					tmpl, err := gotmpl.New(section).Parse(rr)
+					if err != nil {
+						return nil, c.Errf("could not compile %s template %q: %v", section, rr, err)
+					}
+					switch section {
+					case "answer":
+						t.answer = append(t.answer, tmpl)
+					case "additional":
+						t.additional = append(t.additional, tmpl)
+					case "authority":
+						t.authority = append(t.authority, tmpl)
+					}
+				}
+
+			case "rcode":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				rcode, ok := dns.StringToRcode[strings.ToUpper(c.Val())]
+				if !ok {
+					return nil, c.Errf("unknown rcode %q", c.Val())
+				}
+				t.rcode = rcode
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+
+			case "fallthrough":
+				if len(c.RemainingArgs()) != 0 {
+					return nil, c.ArgErr()
+				}
+				t.fall = true
+
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+
+		// Without a regex every name in the zones matches.
+		if len(t.regex) == 0 {
+			t.regex = append(t.regex, regexp.MustCompile(".*"))
+		}
+
+		templates = append(templates, t)
+	}
+
+	return templates, nil
+}
@@ -0,0 +1,99 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func TestSetupParse(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		templates int
+	}{
+		{`template IN A`, false, 1},
+		{`template ANY ANY example.org example.net`, false, 1},
+		{`template IN A example.org {
+			match ^(.*)[.]example[.]org[.]$
+			answer "{{ .Name }} 60 IN A 10.0.0.1"
+			fallthrough
+		}
+		template IN AAAA example.org {
+			rcode nxdomain
+		}`, false, 2},
+		{`template IN MX {
+			answer "{{ .Name }} 60 IN MX 10 mx1.{{ .Zone }}" "{{ .Name }} 60 IN MX 20 mx2.{{ .Zone }}"
+			additional "mx1.{{ .Zone }} 60 IN A 10.0.0.1"
+			authority "{{ .Zone }} 60 IN NS ns.{{ .Zone }}"
+		}`, false, 1},
+		// fails
+		{`template`, true, 0},
+		{`template IN`, true, 0},
+		{`template FOO A`, true, 0},
+		{`template IN FOO`, true, 0},
+		{`template IN A {
+			match
+		}`, true, 0},
+		{`template IN A {
+			match [
+		}`, true, 0},
+		{`template IN A {
+			answer "{{ .Name"
+		}`, true, 0},
+		{`template IN A {
+			rcode
+		}`, true, 0},
+		{`template IN A {
+			rcode FOO
+		}`, true, 0},
+		{`template IN A {
+			fallthrough example.org
+		}`, true, 0},
+		{`template IN A {
+			unknown
+		}`, true, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		templates, err := templateParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if len(templates) != test.templates {
+			t.Errorf("Test %d expected %d templates, got %d", i, test.templates, len(templates))
+		}
+	}
+}
+
+func TestSetupParseValues(t *testing.T) {
+	c := caddy.NewTestController("dns", `template ANY aaaa Example.ORG {
+		rcode nxdomain
+		fallthrough
+	}`)
+	templates, err := templateParse(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	tmpl := templates[0]
+	if tmpl.qclass != dns.ClassANY || tmpl.qtype != dns.TypeAAAA {
+		t.Errorf("Expected class ANY and type AAAA, got %d and %d", tmpl.qclass, tmpl.qtype)
+	}
+	if len(tmpl.zones) != 1 || tmpl.zones[0] != "example.org." {
+		t.Errorf("Expected zones [example.org.], got %v", tmpl.zones)
+	}
+	if tmpl.rcode != dns.RcodeNameError {
+		t.Errorf("Expected rcode NXDOMAIN, got %d", tmpl.rcode)
+	}
+	if !tmpl.fall {
+		t.Errorf("Expected fallthrough to be set")
+	}
+	if len(tmpl.regex) != 1 || tmpl.regex[0].String() != ".*" {
+		t.Errorf("Expected default regex .*, got %v", tmpl.regex)
+	}
+}
@@ -0,0 +1,174 @@
+// Package template implements a middleware that synthesizes answers from templates, selected
+// by regular expressions matched against the query name.
+package template
+
+import (
+	"bytes"
+	"regexp"
+	gotmpl "text/template"
+
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Handler is a middleware that returns synthesized responses.
+type Handler struct {
+	Next      middleware.Handler
+	Templates []template
+}
+
+type template struct {
+	zones      []string
+	qclass     uint16
+	qtype      uint16
+	rcode      int
+	regex      []*regexp.Regexp
+	answer     []*gotmpl.Template
+	additional []*gotmpl.Template
+	authority  []*gotmpl.Template
+	fall       bool
+}
+
+// templateData is the data a template is executed with.
+type templateData struct {
+	Zone     string
+	Name     string
+	Regex    string
+	Match    []string
+	Group    map[string]string
+	Class    string
+	Type     string
+	Remote   string
+	Message  *dns.Msg
+	Question *dns.Question
+}
+
+// ServeDNS implements the middleware.Handler interface.
+func (h Handler) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	// nxdomain is set when a template is responsible for the name, but none of its regular
+	// expressions matched and it doesn't fall through.
+	nxdomain := false
+
+	for _, t := range h.Templates {
+		zone, data, match := t.match(state)
+		if zone == "" {
+			continue
+		}
+		if !match {
+			nxdomain = nxdomain || !t.fall
+			continue
+		}
+
+		m, err := t.reply(r, data)
+		if err != nil {
+			return dns.RcodeServerFailure, err
+		}
+
+		state.SizeAndDo(m)
+		m, _ = state.Scrub(m)
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	}
+
+	if !nxdomain {
+		return middleware.NextOrFailure(h.Name(), h.Next, ctx, w, r)
+	}
+
+	m := new(dns.Msg)
+	m.SetRcode(r, dns.RcodeNameError)
+	m.Authoritative, m.RecursionAvailable, m.Compress = true, true, true
+	state.SizeAndDo(m)
+	w.WriteMsg(m)
+	return dns.RcodeNameError, nil
+}
+
+// Name implements the middleware.Handler interface.
+func (h Handler) Name() string { return "template" }
+
+// match checks if the query in state is handled by t. It returns the matching zone, which is
+// empty if t is not responsible for the query at all, and when one of the regular expressions
+// matched the data to execute the templates with.
+func (t template) match(state request.Request) (string, *templateData, bool) {
+	if t.qclass != dns.ClassANY && t.qclass != state.QClass() {
+		return "", nil, false
+	}
+	if t.qtype != dns.TypeANY && t.qtype != state.QType() {
+		return "", nil, false
+	}
+	zone := middleware.Zones(t.zones).Matches(state.Name())
+	if zone == "" {
+		return "", nil, false
+	}
+
+	for _, regex := range t.regex {
+		matches := regex.FindStringSubmatch(state.Name())
+		if matches == nil {
+			continue
+		}
+
+		data := &templateData{
+			Zone:     zone,
+			Name:     state.Name(),
+			Regex:    regex.String(),
+			Match:    matches,
+			Group:    make(map[string]string),
+			Class:    state.Class(),
+			Type:     state.Type(),
+			Remote:   state.IP(),
+			Message:  state.Req,
+			Question: &state.Req.Question[0],
+		}
+		for i, name := range regex.SubexpNames() {
+			if name != "" {
+				data.Group[name] = matches[i]
+			}
+		}
+		return zone, data, true
+	}
+
+	return zone, nil, false
+}
+
+// reply executes the templates of t with data and returns the response for r.
+func (t template) reply(r *dns.Msg, data *templateData) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetRcode(r, t.rcode)
+	m.Authoritative, m.RecursionAvailable, m.Compress = true, true, true
+
+	var err error
+	if m.Answer, err = execute(t.answer, data); err != nil {
+		return nil, err
+	}
+	if m.Ns, err = execute(t.authority, data); err != nil {
+		return nil, err
+	}
+	if m.Extra, err = execute(t.additional, data); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// execute executes each template with data and parses the result as a resource record.
+func execute(tmpls []*gotmpl.Template, data *templateData) ([]dns.RR, error) {
+	var rrs []dns.RR
+	for _, tmpl := range tmpls {
+		buf := &bytes.Buffer{}
+		if err := tmpl.Execute(buf, data); err != nil {
+			return nil, err
+		}
+		rr, err := dns.NewRR(buf.String())
+		if err != nil {
+			return nil, err
+		}
+		if rr == nil {
+			continue
+		}
+		rrs = append(rrs, rr)
+	}
+	return rrs, nil
+}
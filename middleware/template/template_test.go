@@ -0,0 +1,164 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+const corefile = `template IN A example. {
+	match ^ip-(?P<a>[0-9]*)-(?P<b>[0-9]*)-(?P<c>[0-9]*)-(?P<d>[0-9]*)[.]example[.]$
+	answer "{{ .Name }} 60 IN A {{ .Group.a }}.{{ .Group.b }}.{{ .Group.c }}.{{ .Group.d }}"
+	additional "remote.example. 60 IN TXT \"{{ .Remote }}\""
+}
+template IN ANY local. {
+	rcode NXDOMAIN
+	authority "local. 60 IN SOA ns.local. hostmaster.local. 1 60 60 60 60"
+}
+template IN A example.org. {
+	match ^www[.]example[.]org[.]$
+	answer "{{ .Name }} 60 IN A 10.0.0.1"
+	fallthrough
+}`
+
+var templateTestCases = []test.Case{
+	{
+		Qname: "ip-10-0-0-1.example.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.A("ip-10-0-0-1.example. 60 IN A 10.0.0.1"),
+		},
+		Extra: []dns.RR{
+			test.TXT(`remote.example. 60 IN TXT "10.240.0.1"`),
+		},
+	},
+	{
+		// Regex doesn't match and no fallthrough.
+		Qname: "foo.example.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+	},
+	{
+		// Wrong type, this template doesn't handle it.
+		Qname: "ip-10-0-0-1.example.", Qtype: dns.TypeAAAA,
+		Rcode: dns.RcodeRefused,
+	},
+	{
+		Qname: "leak.local.", Qtype: dns.TypeMX,
+		Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("local. 60 IN SOA ns.local. hostmaster.local. 1 60 60 60 60"),
+		},
+	},
+	{
+		Qname: "www.example.org.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.A("www.example.org. 60 IN A 10.0.0.1"),
+		},
+	},
+	{
+		// Regex doesn't match, falls through.
+		Qname: "mail.example.org.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeRefused,
+	},
+	{
+		// Not in any of the zones.
+		Qname: "example.net.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeRefused,
+	},
+}
+
+func TestTemplate(t *testing.T) {
+	templates, err := templateParse(caddy.NewTestController("dns", corefile))
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	h := Handler{Next: test.NextHandler(dns.RcodeRefused, nil), Templates: templates}
+	ctx := context.TODO()
+
+	for _, tc := range templateTestCases {
+		m := tc.Msg()
+
+		rec := dnsrecorder.New(&test.ResponseWriter{})
+		rcode, err := h.ServeDNS(ctx, rec, m)
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+			continue
+		}
+
+		if tc.Rcode == dns.RcodeRefused {
+			// Handled by the next middleware.
+			if rcode != dns.RcodeRefused || rec.Msg != nil {
+				t.Errorf("Expected %s to be passed to the next middleware", tc.Qname)
+			}
+			continue
+		}
+
+		resp := rec.Msg
+		if !resp.Authoritative {
+			t.Errorf("Expected authoritative answer for %s", tc.Qname)
+		}
+		if !test.Header(t, tc, resp) {
+			t.Logf("%v\n", resp)
+			continue
+		}
+		if !test.Section(t, tc, test.Answer, resp.Answer) {
+			t.Logf("%v\n", resp)
+		}
+		if !test.Section(t, tc, test.Ns, resp.Ns) {
+			t.Logf("%v\n", resp)
+		}
+		if !test.Section(t, tc, test.Extra, resp.Extra) {
+			t.Logf("%v\n", resp)
+		}
+	}
+}
+
+func TestTemplateExecuteError(t *testing.T) {
+	templates, err := templateParse(caddy.NewTestController("dns", `template IN A example. {
+		answer "{{ .Name }} 60 IN A not-an-address"
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	h := Handler{Next: test.ErrorHandler(), Templates: templates}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.", dns.TypeA)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	rcode, err := h.ServeDNS(context.TODO(), rec, m)
+	if err == nil {
+		t.Errorf("Expected error for invalid record")
+	}
+	if rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeServerFailure, rcode)
+	}
+}
+
+func TestTemplateRcode(t *testing.T) {
+	templates, err := templateParse(caddy.NewTestController("dns", `template IN A example. {
+		rcode SERVFAIL
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	h := Handler{Next: test.ErrorHandler(), Templates: templates}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.", dns.TypeA)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	rcode, err := h.ServeDNS(context.TODO(), rec, m)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// The reply has been written, the server must not write another one.
+	if rcode != dns.RcodeSuccess {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeSuccess, rcode)
+	}
+	if rec.Msg == nil || rec.Msg.Rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected a written SERVFAIL reply, got %v", rec.Msg)
+	}
+}
@@ -85,6 +85,9 @@ func RRSIG(rr string) *dns.RRSIG { r, _ := dns.NewRR(rr); return r.(*dns.RRSIG)
 // NSEC returns an NSEC record from rr. It panics on errors.
 func NSEC(rr string) *dns.NSEC { r, _ := dns.NewRR(rr); return r.(*dns.NSEC) }
 
+// NSEC3 returns an NSEC3 record from rr. It panics on errors.
+func NSEC3(rr string) *dns.NSEC3 { r, _ := dns.NewRR(rr); return r.(*dns.NSEC3) }
+
 // DNSKEY returns a DNSKEY record from rr. It panics on errors.
 func DNSKEY(rr string) *dns.DNSKEY { r, _ := dns.NewRR(rr); return r.(*dns.DNSKEY) }
 
@@ -193,6 +196,11 @@ func Section(t *testing.T, tc Case, sec sect, rr []dns.RR) bool {
 				return false
 			}
 			// TypeBitMap
+		case *dns.NSEC3:
+			if x.NextDomain != section[i].(*dns.NSEC3).NextDomain {
+				t.Errorf("rr %d should have a NextDomain of %s, but has %s", i, section[i].(*dns.NSEC3).NextDomain, x.NextDomain)
+				return false
+			}
 		case *dns.A:
 			if x.A.String() != section[i].(*dns.A).A.String() {
 				t.Errorf("rr %d should have a Address of %q, but has %q", i, section[i].(*dns.A).A.String(), x.A.String())
@@ -0,0 +1,93 @@
+package trace
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/miekg/dns"
+	ot "github.com/opentracing/opentracing-go"
+	"golang.org/x/net/context"
+)
+
+// EDNS0Code is the EDNS0 option code used to carry the trace context. It is from the range reserved
+// for local use.
+const EDNS0Code = 0xFDE9
+
+var errNoTraceContext = errors.New("no trace context found")
+
+type propagateKey struct{}
+
+// Propagate returns true if the trace context in ctx may be sent to upstreams with InjectEDNS0. This
+// is only the case when the trace middleware is configured with propagate.
+func Propagate(ctx context.Context) bool {
+	p, _ := ctx.Value(propagateKey{}).(bool)
+	return p
+}
+
+// InjectEDNS0 returns a copy of m that carries the context of span in an EDNS0 option, so that the
+// server receiving m can join the trace. When m has no OPT record one is added, the second return
+// value is then true; the OPT record should be removed from the reply before it is sent to a
+// client that did not use EDNS0. If the context can't be injected, m is returned as is.
+func InjectEDNS0(span ot.Span, m *dns.Msg) (*dns.Msg, bool) {
+	carrier := ot.TextMapCarrier{}
+	if err := span.Tracer().Inject(span.Context(), ot.TextMap, carrier); err != nil || len(carrier) == 0 {
+		return m, false
+	}
+	values := url.Values{}
+	for k, v := range carrier {
+		values.Set(k, v)
+	}
+
+	m1 := m.Copy()
+	opt := m1.IsEdns0()
+	added := opt == nil
+	if added {
+		m1.SetEdns0(dns.MinMsgSize, false)
+		opt = m1.IsEdns0()
+	}
+
+	// Replace the trace context we might have received.
+	options := opt.Option[:0]
+	for _, o := range opt.Option {
+		if o.Option() != EDNS0Code {
+			options = append(options, o)
+		}
+	}
+	opt.Option = append(options, &dns.EDNS0_LOCAL{Code: EDNS0Code, Data: []byte(values.Encode())})
+	return m1, added
+}
+
+// ExtractEDNS0 returns the trace context carried by m, see InjectEDNS0.
+func ExtractEDNS0(tracer ot.Tracer, m *dns.Msg) (ot.SpanContext, error) {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return nil, errNoTraceContext
+	}
+	for _, o := range opt.Option {
+		local, ok := o.(*dns.EDNS0_LOCAL)
+		if !ok || local.Code != EDNS0Code {
+			continue
+		}
+		values, err := url.ParseQuery(string(local.Data))
+		if err != nil {
+			return nil, err
+		}
+		carrier := ot.TextMapCarrier{}
+		for k := range values {
+			carrier[k] = values.Get(k)
+		}
+		return tracer.Extract(ot.TextMap, carrier)
+	}
+	return nil, errNoTraceContext
+}
+
+// RemoveEDNS0 removes the OPT record from m.
+func RemoveEDNS0(m *dns.Msg) {
+	extra := m.Extra[:0]
+	for _, rr := range m.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			extra = append(extra, rr)
+		}
+	}
+	m.Extra = extra
+}
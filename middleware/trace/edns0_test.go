@@ -0,0 +1,54 @@
+package trace
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestEDNS0(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("test")
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+
+	m1, added := InjectEDNS0(span, m)
+	if !added {
+		t.Errorf("Expected an OPT record to be added")
+	}
+	if m.IsEdns0() != nil {
+		t.Errorf("Expected the original message to be left alone")
+	}
+
+	sc, err := ExtractEDNS0(tracer, m1)
+	if err != nil {
+		t.Fatalf("Expected to extract the trace context, got %s", err)
+	}
+	if sc.(mocktracer.MockSpanContext).SpanID != span.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Errorf("Expected the extracted context to be the injected one")
+	}
+
+	// Injecting again must replace the option, and keep the existing OPT record.
+	child := tracer.StartSpan("child")
+	m2, added := InjectEDNS0(child, m1)
+	if added {
+		t.Errorf("Expected no OPT record to be added")
+	}
+	if n := len(m2.IsEdns0().Option); n != 1 {
+		t.Errorf("Expected 1 EDNS0 option, got %d", n)
+	}
+	sc, _ = ExtractEDNS0(tracer, m2)
+	if sc.(mocktracer.MockSpanContext).SpanID != child.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Errorf("Expected the extracted context to be the child")
+	}
+
+	RemoveEDNS0(m2)
+	if m2.IsEdns0() != nil {
+		t.Errorf("Expected the OPT record to be removed")
+	}
+	if _, err := ExtractEDNS0(tracer, m2); err == nil {
+		t.Errorf("Expected an error extracting from a message without OPT record")
+	}
+}
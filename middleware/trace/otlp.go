@@ -0,0 +1,265 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openzipkin/zipkin-go-opentracing/thrift/gen-go/zipkincore"
+)
+
+// otlpCollector implements zipkin.Collector. It converts the spans to OpenTelemetry spans and sends
+// them in batches to an OTLP/HTTP endpoint, encoded as JSON.
+type otlpCollector struct {
+	url     string
+	service string
+	client  *http.Client
+
+	spans chan *zipkincore.Span
+	quit  chan struct{}
+	done  chan error
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newOTLPCollector(url, service string) *otlpCollector {
+	c := &otlpCollector{
+		url:     url,
+		service: service,
+		client:  &http.Client{Timeout: otlpTimeout},
+		spans:   make(chan *zipkincore.Span, otlpMaxBacklog),
+		quit:    make(chan struct{}),
+		done:    make(chan error, 1),
+	}
+	go c.loop()
+	return c
+}
+
+// Collect implements zipkin.Collector. The span is dropped when the backlog is full.
+func (c *otlpCollector) Collect(s *zipkincore.Span) error {
+	select {
+	case c.spans <- s:
+		return nil
+	default:
+		return errOTLPBacklog
+	}
+}
+
+// Close implements zipkin.Collector, it sends the spans that are still queued.
+func (c *otlpCollector) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.quit)
+		c.closeErr = <-c.done
+	})
+	return c.closeErr
+}
+
+func (c *otlpCollector) loop() {
+	ticker := time.NewTicker(otlpBatchInterval)
+	defer ticker.Stop()
+
+	var batch []*zipkincore.Span
+	for {
+		select {
+		case s := <-c.spans:
+			batch = append(batch, s)
+			if len(batch) < otlpBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		case <-c.quit:
+			for len(c.spans) > 0 {
+				batch = append(batch, <-c.spans)
+			}
+			var err error
+			if len(batch) > 0 {
+				err = c.send(batch)
+			}
+			c.done <- err
+			return
+		}
+
+		if err := c.send(batch); err != nil {
+			log.Printf("[WARNING] Failed to send %d spans to %s: %s", len(batch), c.url, err)
+		}
+		batch = nil
+	}
+}
+
+func (c *otlpCollector) send(spans []*zipkincore.Span) error {
+	buf, err := json.Marshal(c.request(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Post(c.url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// request returns the OTLP export request that holds spans.
+func (c *otlpCollector) request(spans []*zipkincore.Span) otlpRequest {
+	ss := otlpScopeSpans{Scope: otlpScope{Name: "coredns"}, Spans: make([]otlpSpan, len(spans))}
+	for i, s := range spans {
+		ss.Spans[i] = toOTLPSpan(s)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{stringAttr("service.name", c.service)}},
+		ScopeSpans: []otlpScopeSpans{ss},
+	}}}
+}
+
+// toOTLPSpan converts a Zipkin span to an OpenTelemetry span. The core annotations set the kind of
+// the span, the others become its events, the binary annotations become its attributes.
+func toOTLPSpan(s *zipkincore.Span) otlpSpan {
+	span := otlpSpan{
+		TraceID: fmt.Sprintf("%016x%016x", uint64(s.GetTraceIDHigh()), uint64(s.TraceID)),
+		SpanID:  fmt.Sprintf("%016x", uint64(s.ID)),
+		Name:    s.Name,
+		Kind:    otlpKindInternal,
+	}
+	if s.ParentID != nil {
+		span.ParentSpanID = fmt.Sprintf("%016x", uint64(*s.ParentID))
+	}
+	// Zipkin's timestamps are in microseconds.
+	span.StartTimeUnixNano = uint64(s.GetTimestamp()) * 1000
+	span.EndTimeUnixNano = span.StartTimeUnixNano + uint64(s.GetDuration())*1000
+
+	for _, a := range s.Annotations {
+		switch a.Value {
+		case zipkincore.SERVER_RECV, zipkincore.SERVER_SEND:
+			span.Kind = otlpKindServer
+		case zipkincore.CLIENT_SEND, zipkincore.CLIENT_RECV:
+			span.Kind = otlpKindClient
+		default:
+			span.Events = append(span.Events, otlpEvent{TimeUnixNano: uint64(a.Timestamp) * 1000, Name: a.Value})
+		}
+	}
+
+	for _, b := range s.BinaryAnnotations {
+		attr := toOTLPAttr(b)
+		span.Attributes = append(span.Attributes, attr)
+		if b.Key == "error" {
+			span.Status = &otlpStatus{Code: otlpStatusError}
+			if attr.Value.StringValue != nil {
+				span.Status.Message = *attr.Value.StringValue
+			}
+		}
+	}
+	return span
+}
+
+// toOTLPAttr converts a binary annotation to an attribute, numbers are converted to strings.
+func toOTLPAttr(b *zipkincore.BinaryAnnotation) otlpKeyValue {
+	switch b.AnnotationType {
+	case zipkincore.AnnotationType_BOOL:
+		v := len(b.Value) > 0 && b.Value[0] == 1
+		return otlpKeyValue{Key: b.Key, Value: otlpValue{BoolValue: &v}}
+	case zipkincore.AnnotationType_I16:
+		if len(b.Value) == 2 {
+			return stringAttr(b.Key, strconv.Itoa(int(int16(binary.BigEndian.Uint16(b.Value)))))
+		}
+	case zipkincore.AnnotationType_I32:
+		if len(b.Value) == 4 {
+			return stringAttr(b.Key, strconv.Itoa(int(int32(binary.BigEndian.Uint32(b.Value)))))
+		}
+	case zipkincore.AnnotationType_I64:
+		if len(b.Value) == 8 {
+			return stringAttr(b.Key, strconv.FormatInt(int64(binary.BigEndian.Uint64(b.Value)), 10))
+		}
+	case zipkincore.AnnotationType_DOUBLE:
+		if len(b.Value) == 8 {
+			return stringAttr(b.Key, strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(b.Value)), 'g', -1, 64))
+		}
+	}
+	return stringAttr(b.Key, string(b.Value))
+}
+
+func stringAttr(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+// The OTLP/HTTP JSON encoding of an export request, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano uint64         `json:"startTimeUnixNano,string"`
+		EndTimeUnixNano   uint64         `json:"endTimeUnixNano,string"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Events            []otlpEvent    `json:"events,omitempty"`
+		Status            *otlpStatus    `json:"status,omitempty"`
+	}
+	otlpEvent struct {
+		TimeUnixNano uint64 `json:"timeUnixNano,string"`
+		Name         string `json:"name"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		BoolValue   *bool   `json:"boolValue,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// Span kinds and status codes of OpenTelemetry.
+const (
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+	otlpKindClient   = 3
+
+	otlpStatusError = 2
+)
+
+const (
+	otlpTimeout       = 5 * time.Second
+	otlpBatchInterval = 1 * time.Second
+	otlpBatchSize     = 100
+	otlpMaxBacklog    = 1000
+)
+
+var errOTLPBacklog = errors.New("otlp backlog is full, span dropped")
@@ -0,0 +1,76 @@
+package trace
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go-opentracing/thrift/gen-go/zipkincore"
+)
+
+func TestOTLPCollector(t *testing.T) {
+	var req otlpRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected content type application/json, got %s", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode the export request: %s", err)
+		}
+	}))
+	defer srv.Close()
+
+	c := newOTLPCollector(srv.URL+"/v1/traces", "dnsproxy")
+	parent, ts, duration := int64(1), int64(1000), int64(10)
+	c.Collect(&zipkincore.Span{
+		TraceID:   2,
+		ID:        3,
+		ParentID:  &parent,
+		Name:      "servedns",
+		Timestamp: &ts,
+		Duration:  &duration,
+		Annotations: []*zipkincore.Annotation{
+			{Timestamp: 1000, Value: zipkincore.SERVER_RECV},
+			{Timestamp: 1005, Value: "cache miss"},
+		},
+		BinaryAnnotations: []*zipkincore.BinaryAnnotation{
+			{Key: TagName, Value: []byte("example.org."), AnnotationType: zipkincore.AnnotationType_STRING},
+			{Key: "error", Value: []byte{1}, AnnotationType: zipkincore.AnnotationType_BOOL},
+		},
+	})
+	// Close sends the queued spans.
+	if err := c.Close(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Expected one resource with one scope, got %+v", req)
+	}
+	if attrs := req.ResourceSpans[0].Resource.Attributes; len(attrs) != 1 || *attrs[0].Value.StringValue != "dnsproxy" {
+		t.Errorf("Expected service.name dnsproxy, got %+v", attrs)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.TraceID != "00000000000000000000000000000002" || span.SpanID != "0000000000000003" || span.ParentSpanID != "0000000000000001" {
+		t.Errorf("Unexpected ids: trace %s, span %s, parent %s", span.TraceID, span.SpanID, span.ParentSpanID)
+	}
+	if span.Name != "servedns" || span.Kind != otlpKindServer {
+		t.Errorf("Expected server span servedns, got %s of kind %d", span.Name, span.Kind)
+	}
+	if span.StartTimeUnixNano != 1000000 || span.EndTimeUnixNano != 1010000 {
+		t.Errorf("Expected span from 1000000 to 1010000, got %d to %d", span.StartTimeUnixNano, span.EndTimeUnixNano)
+	}
+	if len(span.Events) != 1 || span.Events[0].Name != "cache miss" {
+		t.Errorf("Expected the event cache miss, got %+v", span.Events)
+	}
+	if len(span.Attributes) != 2 || *span.Attributes[0].Value.StringValue != "example.org." || !*span.Attributes[1].Value.BoolValue {
+		t.Errorf("Unexpected attributes: %+v", span.Attributes)
+	}
+	if span.Status == nil || span.Status.Code != otlpStatusError {
+		t.Errorf("Expected an error status, got %+v", span.Status)
+	}
+}
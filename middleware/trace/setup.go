@@ -2,6 +2,7 @@ package trace
 
 import (
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -30,6 +31,7 @@ func setup(c *caddy.Controller) error {
 	})
 
 	c.OnStartup(t.OnStartup)
+	c.OnShutdown(t.OnShutdown)
 
 	return nil
 }
@@ -88,6 +90,25 @@ func traceParse(c *caddy.Controller) (*trace, error) {
 				if err != nil {
 					return nil, err
 				}
+			case "propagate":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				tr.propagate = true
+			case "trusted":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, a := range args {
+					_, n, err := net.ParseCIDR(a)
+					if err != nil {
+						return nil, err
+					}
+					tr.trusted = append(tr.trusted, n)
+				}
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
 			}
 		}
 	}
@@ -101,6 +122,19 @@ func normalizeEndpoint(epType, ep string) (string, error) {
 			ep = "http://" + ep + "/api/v1/spans"
 		}
 		return ep, nil
+	case "jaeger":
+		// Either the URL of a collector or the host:port of an agent.
+		if strings.Index(ep, "http") == -1 {
+			if _, _, err := net.SplitHostPort(ep); err != nil {
+				return "", fmt.Errorf("invalid jaeger agent address '%s': %s", ep, err)
+			}
+		}
+		return ep, nil
+	case "otlp":
+		if strings.Index(ep, "http") == -1 {
+			ep = "http://" + ep + "/v1/traces"
+		}
+		return ep, nil
 	default:
 		return "", fmt.Errorf("tracing endpoint type '%s' is not supported", epType)
 	}
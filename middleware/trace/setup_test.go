@@ -25,8 +25,13 @@ func TestTraceParse(t *testing.T) {
 		{"trace {\n every 100\n service foobar\nclient_server\n}", false, "http://localhost:9411/api/v1/spans", 100, `foobar`, true},
 		{"trace {\n every 2\n client_server true\n}", false, "http://localhost:9411/api/v1/spans", 2, `coredns`, true},
 		{"trace {\n client_server false\n}", false, "http://localhost:9411/api/v1/spans", 1, `coredns`, false},
+		{`trace jaeger localhost:6831`, false, "localhost:6831", 1, `coredns`, false},
+		{`trace jaeger http://localhost:14268/api/traces`, false, "http://localhost:14268/api/traces", 1, `coredns`, false},
+		{`trace otlp localhost:4318`, false, "http://localhost:4318/v1/traces", 1, `coredns`, false},
+		{`trace otlp https://otlp.example.org/v1/traces`, false, "https://otlp.example.org/v1/traces", 1, `coredns`, false},
 		// fails
 		{`trace footype localhost:4321`, true, "", 1, "", false},
+		{`trace jaeger localhost`, true, "", 1, "", false},
 		{"trace {\n every 2\n client_server junk\n}", true, "", 1, "", false},
 	}
 	for i, test := range tests {
@@ -58,3 +63,41 @@ func TestTraceParse(t *testing.T) {
 		}
 	}
 }
+
+func TestTraceParsePropagation(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		propagate bool
+		trusted   int
+	}{
+		{`trace`, false, false, 0},
+		{"trace {\n propagate\n}", false, true, 0},
+		{"trace {\n trusted 10.0.0.0/8 2001:db8::/32\n}", false, false, 2},
+		// fails
+		{"trace {\n propagate yes\n}", true, false, 0},
+		{"trace {\n trusted\n}", true, false, 0},
+		{"trace {\n trusted 10.0.0.1\n}", true, false, 0},
+		{"trace {\n unknown\n}", true, false, 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		m, err := traceParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr {
+			continue
+		}
+		if m.propagate != test.propagate {
+			t.Errorf("Test %v: Expected propagate %t but found: %t", i, test.propagate, m.propagate)
+		}
+		if len(m.trusted) != test.trusted {
+			t.Errorf("Test %v: Expected %d trusted networks but found: %d", i, test.trusted, len(m.trusted))
+		}
+	}
+}
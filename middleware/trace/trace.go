@@ -3,14 +3,24 @@ package trace
 
 import (
 	"fmt"
+	"io"
+	"net"
+	"strings"
 	"sync"
 	"sync/atomic"
 
 	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/pkg/rcode"
+	"github.com/coredns/coredns/request"
+
 	"github.com/miekg/dns"
 	ot "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
 	zipkin "github.com/openzipkin/zipkin-go-opentracing"
-
+	jaeger "github.com/uber/jaeger-client-go"
+	"github.com/uber/jaeger-client-go/transport"
 	"golang.org/x/net/context"
 )
 
@@ -26,10 +36,13 @@ type trace struct {
 	Endpoint        string
 	EndpointType    string
 	tracer          ot.Tracer
+	closer          io.Closer // stops the tracer, may be nil
 	serviceName     string
 	clientServer    bool
 	every           uint64
 	count           uint64
+	propagate       bool         // send the trace context to upstreams
+	trusted         []*net.IPNet // clients whose trace context we join
 	Once            sync.Once
 }
 
@@ -44,6 +57,10 @@ func (t *trace) OnStartup() error {
 		switch t.EndpointType {
 		case "zipkin":
 			err = t.setupZipkin()
+		case "jaeger":
+			err = t.setupJaeger()
+		case "otlp":
+			err = t.setupOTLP()
 		default:
 			err = fmt.Errorf("Unknown endpoint type: %s", t.EndpointType)
 		}
@@ -51,18 +68,57 @@ func (t *trace) OnStartup() error {
 	return err
 }
 
+// OnShutdown flushes the spans that have not been sent yet and stops the tracer.
+func (t *trace) OnShutdown() error {
+	if t.closer == nil {
+		return nil
+	}
+	return t.closer.Close()
+}
+
 func (t *trace) setupZipkin() error {
 
 	collector, err := zipkin.NewHTTPCollector(t.Endpoint)
 	if err != nil {
 		return err
 	}
+	return t.setupRecorder(collector)
+}
+
+// setupOTLP sets up a tracer that exports the spans with OTLP over HTTP.
+func (t *trace) setupOTLP() error {
+	return t.setupRecorder(newOTLPCollector(t.Endpoint, t.serviceName))
+}
 
+// setupRecorder sets up a Zipkin tracer that hands the finished spans to collector.
+func (t *trace) setupRecorder(collector zipkin.Collector) error {
 	recorder := zipkin.NewRecorder(collector, false, t.ServiceEndpoint, t.serviceName)
+	var err error
 	t.tracer, err = zipkin.NewTracer(recorder, zipkin.ClientServerSameSpan(t.clientServer))
 	if err != nil {
 		return err
 	}
+	t.closer = collector
+	return nil
+}
+
+// setupJaeger sets up a Jaeger tracer. An endpoint starting with http is the address of a Jaeger
+// collector, otherwise it is the host:port of a Jaeger agent, which is sent spans over UDP.
+func (t *trace) setupJaeger() error {
+	var (
+		sender jaeger.Transport
+		err    error
+	)
+	if strings.HasPrefix(t.Endpoint, "http") {
+		sender = transport.NewHTTPTransport(t.Endpoint)
+	} else {
+		sender, err = jaeger.NewUDPTransport(t.Endpoint, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	t.tracer, t.closer = jaeger.NewTracer(t.serviceName, jaeger.NewConstSampler(true), jaeger.NewRemoteReporter(sender))
 	return nil
 }
 
@@ -81,10 +137,72 @@ func (t *trace) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 			trace = true
 		}
 	}
-	if span := ot.SpanFromContext(ctx); span == nil && trace {
-		span := t.Tracer().StartSpan("servedns")
-		defer span.Finish()
-		ctx = ot.ContextWithSpan(ctx, span)
+	if span := ot.SpanFromContext(ctx); span != nil {
+		return middleware.NextOrFailure(t.Name(), t.Next, ctx, w, r)
 	}
-	return middleware.NextOrFailure(t.Name(), t.Next, ctx, w, r)
+
+	state := request.Request{W: w, Req: r}
+
+	// When the query carries the trace context of the server that sent it, we always join that trace,
+	// but only for trusted servers; anyone else could make us trace every query.
+	var opts []ot.StartSpanOption
+	if t.trust(state.IP()) {
+		if parent, err := ExtractEDNS0(t.Tracer(), r); err == nil {
+			opts = append(opts, ot.ChildOf(parent))
+			trace = true
+		}
+	}
+	if !trace {
+		return middleware.NextOrFailure(t.Name(), t.Next, ctx, w, r)
+	}
+
+	span := t.Tracer().StartSpan("servedns", opts...)
+	defer span.Finish()
+	span.SetTag(TagName, state.Name())
+	span.SetTag(TagType, state.Type())
+	span.SetTag(TagProto, state.Proto())
+	span.SetTag(TagRemote, state.IP())
+
+	ctx = ot.ContextWithSpan(ctx, span)
+	if t.propagate {
+		ctx = context.WithValue(ctx, propagateKey{}, true)
+	}
+	rw := dnsrecorder.New(w)
+	rc, err := middleware.NextOrFailure(t.Name(), t.Next, ctx, rw, r)
+
+	// The rcode written to the client, or if nothing was written, the one returned.
+	written := rc
+	if rw.Msg != nil {
+		written = rw.Rcode
+	}
+	span.SetTag(TagRcode, rcode.ToString(written))
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(otlog.Error(err))
+	}
+	return rc, err
+}
+
+// trust returns true if we join the trace context sent by the client at ip.
+func (t *trace) trust(ip string) bool {
+	if len(t.trusted) == 0 {
+		return false
+	}
+	addr := net.ParseIP(ip)
+	for _, n := range t.trusted {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
 }
+
+// Tags set on the spans.
+const (
+	TagName     = "coredns.io/name"
+	TagType     = "coredns.io/type"
+	TagRcode    = "coredns.io/rcode"
+	TagProto    = "coredns.io/proto"
+	TagRemote   = "coredns.io/remote"
+	TagUpstream = "coredns.io/upstream"
+)
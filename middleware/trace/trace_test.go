@@ -1,9 +1,17 @@
 package trace
 
 import (
+	"net"
 	"testing"
 
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/test"
+
 	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+	ot "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"golang.org/x/net/context"
 )
 
 // createTestTrace creates a trace middleware to be used in tests
@@ -31,3 +39,135 @@ func TestTrace(t *testing.T) {
 		t.Errorf("Error, no tracer created")
 	}
 }
+
+func TestTraceJaeger(t *testing.T) {
+	_, m, err := createTestTrace(`trace jaeger localhost:6831`)
+	if err != nil {
+		t.Fatalf("Error parsing test input: %s", err)
+	}
+	if err := m.OnStartup(); err != nil {
+		t.Fatalf("Error starting tracing middleware: %s", err)
+	}
+	defer m.OnShutdown()
+	if m.Tracer() == nil {
+		t.Errorf("Error, no tracer created")
+	}
+}
+
+func TestTraceOTLP(t *testing.T) {
+	_, m, err := createTestTrace(`trace otlp localhost:4318`)
+	if err != nil {
+		t.Fatalf("Error parsing test input: %s", err)
+	}
+	if err := m.OnStartup(); err != nil {
+		t.Fatalf("Error starting tracing middleware: %s", err)
+	}
+	defer m.OnShutdown()
+	if m.Tracer() == nil {
+		t.Errorf("Error, no tracer created")
+	}
+}
+
+func TestTraceServeDNS(t *testing.T) {
+	tracer := mocktracer.New()
+	tr := &trace{
+		tracer: tracer,
+		every:  1,
+		Next: test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			if ot.SpanFromContext(ctx) == nil {
+				t.Errorf("Expected a span in the context")
+			}
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeNameError)
+			w.WriteMsg(m)
+			return dns.RcodeNameError, nil
+		}),
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeAAAA)
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	if _, err := tr.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	span := rootSpan(tracer)
+	if span == nil {
+		t.Fatalf("Expected a servedns span")
+	}
+	tags := map[string]string{
+		TagName:   "example.org.",
+		TagType:   "AAAA",
+		TagRcode:  "NXDOMAIN",
+		TagProto:  "udp",
+		TagRemote: "10.240.0.1",
+	}
+	for tag, expected := range tags {
+		if got := span.Tag(tag); got != expected {
+			t.Errorf("Expected tag %s to be %q, got %q", tag, expected, got)
+		}
+	}
+}
+
+func TestTraceServeDNSJoin(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("upstream")
+
+	// every 0 never starts a trace by itself.
+	tr := &trace{tracer: tracer, Next: test.NextHandler(dns.RcodeSuccess, nil)}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m, _ = InjectEDNS0(parent, m)
+
+	// The client isn't trusted, its trace context is ignored.
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	tr.ServeDNS(context.TODO(), rec, m)
+	if spans := tracer.FinishedSpans(); len(spans) != 0 {
+		t.Fatalf("Expected no spans for an untrusted client, got %d", len(spans))
+	}
+
+	_, trusted, _ := net.ParseCIDR("10.240.0.0/16")
+	tr.trusted = []*net.IPNet{trusted}
+	tr.ServeDNS(context.TODO(), rec, m)
+
+	span := rootSpan(tracer)
+	if span == nil {
+		t.Fatalf("Expected a servedns span")
+	}
+	if span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Errorf("Expected the span to be a child of the propagated span")
+	}
+}
+
+func TestTracePropagate(t *testing.T) {
+	for _, propagate := range []bool{false, true} {
+		propagated := false
+		tr := &trace{
+			tracer:    mocktracer.New(),
+			every:     1,
+			propagate: propagate,
+			Next: test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+				propagated = Propagate(ctx)
+				return dns.RcodeSuccess, nil
+			}),
+		}
+
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		tr.ServeDNS(context.TODO(), dnsrecorder.New(&test.ResponseWriter{}), m)
+		if propagated != propagate {
+			t.Errorf("Expected Propagate to be %t, got %t", propagate, propagated)
+		}
+	}
+}
+
+// rootSpan returns the finished servedns span, the spans of the middleware are its children.
+func rootSpan(tracer *mocktracer.MockTracer) *mocktracer.MockSpan {
+	for _, s := range tracer.FinishedSpans() {
+		if s.OperationName == "servedns" {
+			return s
+		}
+	}
+	return nil
+}
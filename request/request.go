@@ -106,6 +106,20 @@ func (r *Request) Do() bool {
 	return false
 }
 
+// ECS returns the EDNS0 client subnet option of the request, or nil if there is none.
+func (r *Request) ECS() *dns.EDNS0_SUBNET {
+	o := r.Req.IsEdns0()
+	if o == nil {
+		return nil
+	}
+	for _, e := range o.Option {
+		if s, ok := e.(*dns.EDNS0_SUBNET); ok {
+			return s
+		}
+	}
+	return nil
+}
+
 // Len returns the length in bytes in the request.
 func (r *Request) Len() int { return r.Req.Len() }
 
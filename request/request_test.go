@@ -1,6 +1,7 @@
 package request
 
 import (
+	"net"
 	"testing"
 
 	"github.com/coredns/coredns/middleware/test"
@@ -31,6 +32,19 @@ func TestRequestRemote(t *testing.T) {
 	}
 }
 
+func TestRequestECS(t *testing.T) {
+	st := testRequest()
+	if st.ECS() != nil {
+		t.Fatalf("Expected no client subnet")
+	}
+
+	e := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()}
+	st.Req.IsEdns0().Option = append(st.Req.IsEdns0().Option, e)
+	if st.ECS() != e {
+		t.Fatalf("Expected client subnet %v, got %v", e, st.ECS())
+	}
+}
+
 func BenchmarkRequestDo(b *testing.B) {
 	st := testRequest()
 
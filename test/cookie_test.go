@@ -0,0 +1,136 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/coredns/coredns/middleware/pkg/cookie"
+
+	"github.com/miekg/dns"
+)
+
+func TestCookieRequire(t *testing.T) {
+	corefile := `.:0 {
+		cookie {
+			require
+		}
+		whoami
+}
+`
+	i, err := CoreDNSServer(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	udp, tcp := CoreDNSServerPorts(i, 0)
+	defer i.Stop()
+
+	client := []byte("abcdefgh")
+	query := func(server []byte) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		m.SetEdns0(4096, false)
+		cookie.Set(m, client, server)
+		return m
+	}
+
+	// No cookie at all, the client must retry over TCP.
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	r, err := dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Could not send message: %s", err)
+	}
+	if !r.Truncated || len(r.Answer) != 0 {
+		t.Errorf("Expected an empty truncated response for a query without a cookie, got %s", r)
+	}
+
+	c := new(dns.Client)
+	c.Net = "tcp"
+	r, _, err = c.Exchange(m, tcp)
+	if err != nil {
+		t.Fatalf("Could not send message: %s", err)
+	}
+	if r.Truncated || len(r.Extra) == 0 {
+		t.Errorf("Expected a response over TCP, got %s", r)
+	}
+
+	// Only a client cookie, we get BADCOOKIE and a server cookie.
+	r, err = dns.Exchange(query(nil), udp)
+	if err != nil {
+		t.Fatalf("Could not send message: %s", err)
+	}
+	if !cookie.IsBadCookie(r) {
+		t.Fatalf("Expected BADCOOKIE, got %s", r)
+	}
+	c1, server, err := cookie.Parse(r)
+	if err != nil || !bytes.Equal(c1, client) || server == nil {
+		t.Fatalf("Expected our client cookie and a server cookie, got %x%x (%v)", c1, server, err)
+	}
+
+	// With the server cookie the query is answered.
+	r, err = dns.Exchange(query(server), udp)
+	if err != nil {
+		t.Fatalf("Could not send message: %s", err)
+	}
+	if r.Rcode != dns.RcodeSuccess || len(r.Extra) == 0 {
+		t.Fatalf("Expected a response, got %s", r)
+	}
+	if c2, s2, _ := cookie.Parse(r); !bytes.Equal(c2, client) || !bytes.Equal(s2, server) {
+		t.Errorf("Expected cookie %x%x in the response, got %x%x", client, server, c2, s2)
+	}
+
+	// A malformed cookie.
+	m = new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	m.IsEdns0().Option = []dns.EDNS0{&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: "0102"}}
+	r, err = dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Could not send message: %s", err)
+	}
+	if r.Rcode != dns.RcodeFormatError {
+		t.Errorf("Expected FORMERR for a malformed cookie, got %s", dns.RcodeToString[r.Rcode])
+	}
+}
+
+func TestCookieProxy(t *testing.T) {
+	corefile := `.:0 {
+		cookie {
+			require
+		}
+		whoami
+}
+`
+	i, err := CoreDNSServer(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	udp, _ := CoreDNSServerPorts(i, 0)
+	defer i.Stop()
+
+	corefileProxy := `.:0 {
+		proxy . ` + udp + `
+}
+`
+	p, err := CoreDNSServer(corefileProxy)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	udpProxy, _ := CoreDNSServerPorts(p, 0)
+	defer p.Stop()
+
+	// The proxy gets BADCOOKIE first and retries with the server cookie it got.
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	r, err := dns.Exchange(m, udpProxy)
+	if err != nil {
+		t.Fatalf("Could not send message: %s", err)
+	}
+	if r.Rcode != dns.RcodeSuccess || len(r.Extra) == 0 {
+		t.Fatalf("Expected a response through the proxy, got %s", r)
+	}
+	if c, _, _ := cookie.Parse(r); c != nil {
+		t.Errorf("Expected no cookie in the response to a client that didn't send one, got %x", c)
+	}
+}
@@ -0,0 +1,274 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/middleware"
+	"github.com/coredns/coredns/middleware/file"
+	"github.com/coredns/coredns/middleware/pkg/dnskey"
+	"github.com/coredns/coredns/middleware/pkg/dnsrecorder"
+	"github.com/coredns/coredns/middleware/pkg/validate"
+	"github.com/coredns/coredns/middleware/test"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// signZone parses content as the zone origin and signs it with a newly generated key. It returns
+// the zone and the DS record for the key.
+func signZone(t *testing.T, origin, content string, nsec3 bool) (*file.Zone, *dns.DS) {
+	z, err := file.Parse(strings.NewReader(content), origin, "stdin")
+	if err != nil {
+		t.Fatalf("Failed to parse zone %s: %s", origin, err)
+	}
+
+	k := &dns.DNSKEY{Flags: 257, Protocol: 3, Algorithm: dns.ECDSAP256SHA256}
+	k.Hdr = dns.RR_Header{Name: origin, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600}
+	priv, err := k.Generate(256)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+	fPub, rmPub, _ := test.TempFile(".", k.String()+"\n")
+	defer rmPub()
+	fPriv, rmPriv, _ := test.TempFile(".", k.PrivateKeyString(priv))
+	defer rmPriv()
+	key, err := dnskey.ParseKeyFile(fPub, fPriv)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %s", err)
+	}
+
+	z.Signer = &file.Signer{Keys: []*dnskey.Key{key}, NSEC3: nsec3}
+	if err := z.Sign(time.Now().UTC()); err != nil {
+		t.Fatalf("Failed to sign zone %s: %s", origin, err)
+	}
+	return z, key.K.ToDS(dns.SHA256)
+}
+
+// hierarchy holds the zones used in the tests, a signed root, a signed example. and an unsigned
+// insecure.example.
+type hierarchy struct {
+	zones map[string]*file.Zone
+	root  *dns.DS
+}
+
+func newHierarchy(t *testing.T, nsec3 bool) *hierarchy {
+	h := &hierarchy{zones: make(map[string]*file.Zone)}
+
+	insecure, err := file.Parse(strings.NewReader(dbInsecure), "insecure.example.", "stdin")
+	if err != nil {
+		t.Fatalf("Failed to parse zone: %s", err)
+	}
+	h.zones["insecure.example."] = insecure
+
+	example, ds := signZone(t, "example.", dbExample, nsec3)
+	h.zones["example."] = example
+
+	root, rootDS := signZone(t, ".", dbRoot+ds.String()+"\n", false)
+	h.zones["."] = root
+	h.root = rootDS
+	return h
+}
+
+// lookup returns the response from the zone closest to name. DS queries for the apex of a zone are
+// sent to the parent.
+func (h *hierarchy) lookup(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	names := []string{}
+	for n := range h.zones {
+		if qtype == dns.TypeDS && n == name && n != "." {
+			continue
+		}
+		names = append(names, n)
+	}
+	zone := middleware.Zones(names).Matches(name)
+
+	f := file.File{Zones: file.Zones{Z: h.zones, Names: []string{zone}}}
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.SetEdns0(4096, true)
+
+	rec := dnsrecorder.New(&test.ResponseWriter{})
+	if _, err := f.ServeDNS(ctx, rec, m); err != nil {
+		return nil, err
+	}
+	return rec.Msg, nil
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		qname  string
+		qtype  uint16
+		secure validate.Security
+	}{
+		{"www.example.", dns.TypeA, validate.Secure},
+		{"alias.example.", dns.TypeA, validate.Secure},
+		{"a.wild.example.", dns.TypeA, validate.Secure},
+		{"example.", dns.TypeDNSKEY, validate.Secure},
+		{"nx.example.", dns.TypeA, validate.Secure},
+		{"www.example.", dns.TypeTXT, validate.Secure},
+		{"www.insecure.example.", dns.TypeA, validate.Insecure},
+		{"nx.insecure.example.", dns.TypeA, validate.Insecure},
+	}
+
+	for _, nsec3 := range []bool{false, true} {
+		h := newHierarchy(t, nsec3)
+		v := validate.New([]dns.RR{h.root}, h.lookup, nil)
+
+		for _, tc := range tests {
+			m, err := h.lookup(context.TODO(), tc.qname, tc.qtype)
+			if err != nil {
+				t.Fatalf("Failed to look up %s: %s", tc.qname, err)
+			}
+			sec, err := v.Validate(context.TODO(), m)
+			if sec != tc.secure {
+				t.Errorf("Test %s %d (nsec3 %t): expected %s, got %s (%v)", tc.qname, tc.qtype, nsec3, tc.secure, sec, err)
+			}
+		}
+	}
+}
+
+func TestValidateBogus(t *testing.T) {
+	h := newHierarchy(t, false)
+	v := validate.New([]dns.RR{h.root}, h.lookup, nil)
+
+	tests := []struct {
+		name   string
+		tamper func(m *dns.Msg)
+	}{
+		{"modified", func(m *dns.Msg) { m.Answer[0] = test.A("www.example. 3600 IN A 192.0.2.99") }},
+		{"unsigned", func(m *dns.Msg) { m.Answer = m.Answer[:1] }},
+		{"no denial", func(m *dns.Msg) { m.Rcode = dns.RcodeNameError; m.Answer = nil }},
+	}
+	for _, tc := range tests {
+		m, _ := h.lookup(context.TODO(), "www.example.", dns.TypeA)
+		tc.tamper(m)
+		if sec, _ := v.Validate(context.TODO(), m); sec != validate.Bogus {
+			t.Errorf("Test %s: expected %s, got %s", tc.name, validate.Bogus, sec)
+		}
+	}
+}
+
+func TestValidateExpired(t *testing.T) {
+	h := newHierarchy(t, false)
+	v := validate.New([]dns.RR{h.root}, h.lookup, nil)
+	v.Now = func() time.Time { return time.Now().Add(365 * 24 * time.Hour) }
+
+	m, _ := h.lookup(context.TODO(), "www.example.", dns.TypeA)
+	if sec, _ := v.Validate(context.TODO(), m); sec != validate.Bogus {
+		t.Errorf("Expected %s, got %s", validate.Bogus, sec)
+	}
+}
+
+func TestValidateNoAnchor(t *testing.T) {
+	h := newHierarchy(t, false)
+	m, _ := h.lookup(context.TODO(), "www.example.", dns.TypeA)
+
+	v := validate.New(nil, h.lookup, nil)
+	if sec, _ := v.Validate(context.TODO(), m); sec != validate.Indeterminate {
+		t.Errorf("Expected %s without trust anchors, got %s", validate.Indeterminate, sec)
+	}
+
+	// A trust anchor that doesn't cover the data.
+	other := test.DS("other. 3600 IN DS 1 13 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D")
+	v = validate.New([]dns.RR{other}, h.lookup, nil)
+	if sec, _ := v.Validate(context.TODO(), m); sec != validate.Indeterminate {
+		t.Errorf("Expected %s with an unrelated trust anchor, got %s", validate.Indeterminate, sec)
+	}
+}
+
+func TestValidateWrongAnchor(t *testing.T) {
+	h := newHierarchy(t, false)
+	m, _ := h.lookup(context.TODO(), "www.example.", dns.TypeA)
+
+	v := validate.New(validate.RootAnchors(), h.lookup, nil)
+	if sec, _ := v.Validate(context.TODO(), m); sec != validate.Bogus {
+		t.Errorf("Expected %s with the wrong root anchor, got %s", validate.Bogus, sec)
+	}
+}
+
+func TestValidateLookupError(t *testing.T) {
+	h := newHierarchy(t, false)
+
+	// failing returns a Lookup that fails for qtype.
+	failing := func(qtype uint16) validate.Lookup {
+		return func(ctx context.Context, name string, qt uint16) (*dns.Msg, error) {
+			if qt == qtype {
+				return nil, errors.New("timeout")
+			}
+			return h.lookup(ctx, name, qt)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		qtype  uint16 // type of the lookups that fail
+		tamper func(m *dns.Msg)
+	}{
+		{"stripped, DS lookup fails", dns.TypeDS, func(m *dns.Msg) { m.Answer = m.Answer[:1] }},
+		{"stripped, DNSKEY lookup fails", dns.TypeDNSKEY, func(m *dns.Msg) { m.Answer = m.Answer[:1] }},
+		{"signed, DNSKEY lookup fails", dns.TypeDNSKEY, func(m *dns.Msg) {}},
+	}
+	for _, tc := range tests {
+		v := validate.New([]dns.RR{h.root}, failing(tc.qtype), nil)
+		m, _ := h.lookup(context.TODO(), "www.example.", dns.TypeA)
+		tc.tamper(m)
+		if sec, err := v.Validate(context.TODO(), m); sec != validate.Bogus {
+			t.Errorf("Test %s: expected %s, got %s (%v)", tc.name, validate.Bogus, sec, err)
+		}
+	}
+
+	// Without a trust anchor covering the data, a failed lookup doesn't make it Bogus.
+	other := test.DS("other. 3600 IN DS 1 13 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D")
+	v := validate.New([]dns.RR{other}, failing(dns.TypeDS), nil)
+	m, _ := h.lookup(context.TODO(), "www.example.", dns.TypeA)
+	m.Answer = m.Answer[:1]
+	if sec, _ := v.Validate(context.TODO(), m); sec != validate.Indeterminate {
+		t.Errorf("Expected %s with an unrelated trust anchor, got %s", validate.Indeterminate, sec)
+	}
+}
+
+func TestValidateSharedCache(t *testing.T) {
+	h := newHierarchy(t, false)
+	m, _ := h.lookup(context.TODO(), "www.example.", dns.TypeA)
+
+	cache, _ := lru.New(validate.DefaultCapacity)
+	v := validate.New([]dns.RR{h.root}, h.lookup, cache)
+	if sec, _ := v.Validate(context.TODO(), m); sec != validate.Secure {
+		t.Fatalf("Expected %s, got %s", validate.Secure, sec)
+	}
+
+	// The keys validated by v must not be trusted by a Validator with other anchors.
+	v1 := validate.New(validate.RootAnchors(), h.lookup, cache)
+	if sec, _ := v1.Validate(context.TODO(), m); sec != validate.Bogus {
+		t.Errorf("Expected %s with the wrong root anchor and a shared cache, got %s", validate.Bogus, sec)
+	}
+}
+
+const dbRoot = `
+.                3600 IN SOA a.root. hostmaster.root. 1 7200 3600 1209600 3600
+.                3600 IN NS  a.root.
+a.root.          3600 IN A   127.0.0.1
+example.         3600 IN NS  ns.example.
+ns.example.      3600 IN A   127.0.0.2
+`
+
+const dbExample = `
+example.                 3600 IN SOA   ns.example. hostmaster.example. 1 7200 3600 1209600 3600
+example.                 3600 IN NS    ns.example.
+ns.example.              3600 IN A     127.0.0.2
+www.example.             3600 IN A     192.0.2.1
+alias.example.           3600 IN CNAME www.example.
+*.wild.example.          3600 IN A     192.0.2.2
+insecure.example.        3600 IN NS    ns.insecure.example.
+ns.insecure.example.     3600 IN A     127.0.0.3
+`
+
+const dbInsecure = `
+insecure.example.        3600 IN SOA   ns.insecure.example. hostmaster.example. 1 7200 3600 1209600 3600
+insecure.example.        3600 IN NS    ns.insecure.example.
+ns.insecure.example.     3600 IN A     127.0.0.3
+www.insecure.example.    3600 IN A     192.0.2.3
+`